@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"easybook/models"
+	"easybook/services/auth"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/context"
+)
+
+const claimsKey = "claims"
+
+// AuthFilter identifies the caller by the token in X-Token header. Requests
+// without a valid token are served anonymously.
+func AuthFilter(ctx *context.Context) {
+	token := ctx.Input.Header("X-Token")
+	if token == "" {
+		return
+	}
+	if claims, err := auth.Verify(token); err == nil {
+		ctx.Input.SetData(claimsKey, claims)
+	}
+}
+
+// callerOf returns the claims of the authenticated caller of c, or nil if
+// the caller is anonymous.
+func callerOf(c *beego.Controller) *auth.Claims {
+	claims, _ := c.Ctx.Input.GetData(claimsKey).(*auth.Claims)
+	return claims
+}
+
+// roleOf returns the role of the caller of c, anonymous callers have
+// models.RoleGuest.
+func roleOf(c *beego.Controller) int8 {
+	if claims := callerOf(c); claims != nil {
+		return claims.Role
+	}
+	return models.RoleGuest
+}
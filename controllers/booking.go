@@ -1,16 +1,26 @@
 package controllers
 
 import (
+	"easybook/queryspec"
 	"easybook/reqres"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"sort"
 	"strconv"
-	"strings"
+	"time"
 
 	"easybook/models"
+	"easybook/services/availability"
 	"easybook/services/easybook_chaincode"
+	"easybook/services/exchange"
+	"easybook/services/hold"
+	"easybook/services/invoice"
+	"easybook/services/notification"
+	"easybook/services/pricing"
+	"easybook/services/promotion"
+	"easybook/types"
 
 	"github.com/astaxie/beego"
 	"github.com/astaxie/beego/orm"
@@ -30,59 +40,76 @@ func (c *BookingController) URLMapping() {
 // SearchHotels ...
 // @Title Search Hotels
 // @Description search available Hotel
-// @Param	query	query	string	false	"Filter. e.g. col1:v1,col2:v2 ..."
+// @Param	query	query	string	false	"Filter. e.g. col1:v1,col2__gte:v2,(col3__in:v3|v4;!col4:v5) ..."
 // @Param	fields	query	string	false	"Fields returned. e.g. col1,col2 ..."
 // @Param	sortby	query	string	false	"Sorted-by fields. e.g. col1,col2 ..."
 // @Param	order	query	string	false	"Order corresponding to each sortby field, if single value, apply to all sortby fields. e.g. desc,asc ..."
 // @Param	limit	query	string	false	"Limit the size of result set. Must be an integer"
 // @Param	offset	query	string	false	"Start position of result set. Must be an integer"
-// @Success 200 {object} models.Hotel
+// @Param	cursor	query	string	false	"Keyset paging cursor ordered by created_at,id. Empty to start, then the token of the next link"
+// @Param	expand	query	string	false	"Relations to load. e.g. rel1,rel2.rel3 ..."
+// @Param	startDate	query	string	false	"First night of the stay, only hotels with a room free from it to endDate. e.g. 2020-12-24"
+// @Param	endDate	query	string	false	"Day of departure of the stay. e.g. 2020-12-26"
+// @Success 200 {object} reqres.ListResponse
 // @Failure 403
 // @router /search [get]
 func (c *BookingController) SearchHotels() {
-	var fields []string
-	var sortby []string
-	var order []string
-	var query = make(map[string]string)
-	var limit int64 = 10
-	var offset int64
-
-	// fields: col1,col2,entity.col3
-	if v := c.GetString("fields"); v != "" {
-		fields = strings.Split(v, ",")
-	}
-	// limit: 10 (default is 10)
-	if v, err := c.GetInt64("limit"); err == nil {
-		limit = v
-	}
-	// offset: 0 (default is 0)
-	if v, err := c.GetInt64("offset"); err == nil {
-		offset = v
-	}
-	// sortby: col1,col2
-	if v := c.GetString("sortby"); v != "" {
-		sortby = strings.Split(v, ",")
-	}
-	// order: desc,asc
-	if v := c.GetString("order"); v != "" {
-		order = strings.Split(v, ",")
-	}
-	// query: k:v,k:v
-	if v := c.GetString("query"); v != "" {
-		for _, cond := range strings.Split(v, ",") {
-			kv := strings.SplitN(cond, ":", 2)
-			if len(kv) != 2 {
-				c.Data["json"] = errors.New("Error: invalid query key/value pair")
-				c.ServeJSON()
-				return
-			}
-			k, v := kv[0], kv[1]
-			query[k] = v
+	spec, err := queryspec.Parse(c.Input())
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	spec.Role = roleOf(&c.Controller)
+
+	// ratings are looked up by id, so fields are trimmed after sorting
+	schema, _ := queryspec.SchemaOf(new(models.Hotel))
+	if err = spec.Validate(schema); err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	fields := spec.Fields
+	spec.Fields = nil
+	from, to, err := stayOf(&c.Controller)
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+
+	// hotels without a room free for the stay, reserved or held, are left
+	// out before paging
+	if !from.IsZero() {
+		ids, err := availability.FreeHotelIds(orm.NewOrm(), from, to, time.Now())
+		if err != nil {
+			c.Data["json"] = err.Error()
+			c.ServeJSON()
+			return
+		}
+		if len(ids) == 0 {
+			// no hotel has id 0, nothing matches
+			ids = []int{0}
+		}
+		free := &queryspec.Cond{Path: []string{"Id"}, Op: "in"}
+		for _, id := range ids {
+			free.Values = append(free.Values, strconv.Itoa(id))
+		}
+		if spec.Query != nil {
+			spec.Query = &queryspec.And{Nodes: []queryspec.Node{spec.Query, free}}
+		} else {
+			spec.Query = free
 		}
 	}
 
-	l, err := models.GetAllHotel(query, fields, sortby, order, offset, limit)
+	l, err := models.GetAllHotel(spec)
 	if err != nil {
+		if queryspec.IsError(err) {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		}
 		c.Data["json"] = err.Error()
 		c.ServeJSON()
 		return
@@ -96,7 +123,7 @@ func (c *BookingController) SearchHotels() {
 	}
 
 	hotels := []*models.Hotel{}
-	for _, h := range l {
+	for _, h := range l.Items {
 		hotel := h.(models.Hotel)
 		smJsonHotel, err := contract.EvaluateTransaction("ReadHotel", strconv.Itoa(hotel.Id))
 		if err == nil {
@@ -115,7 +142,12 @@ func (c *BookingController) SearchHotels() {
 	sort.SliceStable(hotels, func(i, j int) bool {
 		return hotels[i].Rating < hotels[j].Rating
 	})
-	c.Data["json"] = hotels
+	spec.Fields = fields
+	l.Items = l.Items[:0]
+	for _, hotel := range hotels {
+		l.Items = append(l.Items, spec.Project(schema, hotel))
+	}
+	c.Data["json"] = reqres.NewListResponse(c.Ctx.Request.URL, spec, l)
 	c.ServeJSON()
 }
 
@@ -123,15 +155,25 @@ func (c *BookingController) SearchHotels() {
 // @Title Reserve Rooms
 // @Description reserve hotel's rooms
 // @Param	body		body 	reqres.BookingReserveRoomsRequest	true		"body for Reservation content"
+// @Param	currency	query	string	false	"Currency the guest books in, an ISO 4217 code. Defaults to the currency of totalPrice"
+// @Param	Idempotency-Key	header	string	false	"Unique key of the request, a retry with the same key returns the original response"
 // @Success 201 {int} reqres.BookingReserveRoomsResponse
 // @Failure 403 body is empty
+// @Failure 400 rooms are missing, given twice, unknown or of several hotels
+// @Failure 409 rooms are not available, totalPrice is not the total quoted, or key is in use by a request in progress or was used for a different request
 // @router /reserve [post]
 func (c *BookingController) ReserveRooms() {
+	key, done := beginIdempotent(&c.Controller)
+	if done {
+		return
+	}
+
 	res := reqres.BookingReserveRoomsResponse{}
 	res.SetCode(reqres.Fail)
 
 	defer func() {
 		c.Data["json"] = res
+		finishIdempotent(&c.Controller, key)
 		c.ServeJSON()
 	}()
 
@@ -143,6 +185,38 @@ func (c *BookingController) ReserveRooms() {
 		return
 	}
 
+	// discounts other than promotion codes are granted by staff
+	if req.DiscountPercent != 0 && roleOf(&c.Controller) < models.RoleStaff {
+		c.Ctx.Output.SetStatus(http.StatusForbidden)
+		res.SetCode(reqres.Forbidden)
+		res.Message = "Error: discountPercent is staff only, use promoCodes"
+		return
+	}
+	if req.DiscountPercent < 0 || req.DiscountPercent > 100 {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		res.SetCode(reqres.InvalidParams)
+		res.Message = "Error: discountPercent must be from 0 to 100"
+		return
+	}
+	if req.TotalPrice.Currency == "" {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		res.SetCode(reqres.InvalidParams)
+		res.Message = "Error: totalPrice is required"
+		return
+	}
+
+	// a reservation is of one hotel, its invoice is numbered by the hotel
+	if msg, err := checkRooms(req.Rooms); err != nil {
+		c.Ctx.Output.SetStatus(http.StatusInternalServerError)
+		res.SetCode(reqres.SystemError)
+		return
+	} else if msg != "" {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		res.SetCode(reqres.InvalidParams)
+		res.Message = msg
+		return
+	}
+
 	guest, err := models.GetGuestById(req.GuestID)
 	if err != nil {
 		c.Ctx.Output.SetStatus(http.StatusNotFound)
@@ -150,18 +224,55 @@ func (c *BookingController) ReserveRooms() {
 		return
 	}
 
+	// hotels settle in the default currency, the guest may be shown the
+	// total in another one at the rate recorded with the reservation
+	currency, err := exchange.Currency(c.GetString("currency"))
+	if err == nil && currency == "" && req.TotalPrice.Currency != types.DefaultCurrency {
+		currency = req.TotalPrice.Currency
+	}
+	var rate float64
+	if err == nil && currency != "" {
+		rate, err = exchange.Rate(orm.NewOrm(), types.DefaultCurrency, currency, time.Now())
+	}
+	if err == nil && req.TotalPrice.Currency != types.DefaultCurrency && req.TotalPrice.Currency != currency {
+		err = types.ErrCurrencyMismatch
+	}
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		res.SetCode(reqres.InvalidParams)
+		res.Message = err.Error()
+		return
+	}
+
 	reservation := &models.Reservation{
 		GuestId:         guest,
 		StartDate:       req.StartDate.Time,
 		EndDate:         req.EndDate.Time,
 		DiscountPercent: req.DiscountPercent,
-		TotalPrice:      req.TotalPrice,
-		Status:          0,
+		Guests:          req.Guests,
+		TotalPrice:      types.NewMoney(0, types.DefaultCurrency),
+		DisplayCurrency: currency,
+		ExchangeRate:    rate,
+		Status:          models.ReservationConfirmed,
 	}
 
 	o := orm.NewOrm()
 	_ = o.Begin()
 	// transaction process
+	now := time.Now()
+	err = availability.Check(o, req.Rooms, reservation.StartDate, reservation.EndDate, now, req.HoldID)
+	if err != nil {
+		if err == availability.ErrUnavailable {
+			c.Ctx.Output.SetStatus(http.StatusConflict)
+			res.SetCode(reqres.InvalidParams)
+		} else {
+			c.Ctx.Output.SetStatus(http.StatusInternalServerError)
+			res.SetCode(reqres.FailedCreate)
+		}
+		res.Message = err.Error()
+		_ = o.Rollback()
+		return
+	}
 	reservationID, err := o.Insert(reservation)
 	if err != nil {
 		c.Ctx.Output.SetStatus(http.StatusInternalServerError)
@@ -171,6 +282,22 @@ func (c *BookingController) ReserveRooms() {
 	}
 	re := models.Reservation{Id: int(reservationID)}
 	_ = o.Read(&re)
+	if req.HoldID != 0 {
+		if err = hold.Convert(o, req.HoldID, &re, req.Rooms, now); err != nil {
+			if hold.IsError(err) {
+				c.Ctx.Output.SetStatus(http.StatusConflict)
+				res.SetCode(reqres.InvalidParams)
+			} else {
+				c.Ctx.Output.SetStatus(http.StatusInternalServerError)
+				res.SetCode(reqres.FailedCreate)
+			}
+			res.Message = err.Error()
+			_ = o.Rollback()
+			return
+		}
+	}
+	var hotelID int
+	nights := pricing.Nights(&re)
 	for _, roomID := range req.Rooms {
 		ro := models.Room{Id: roomID}
 		err := o.Read(&ro)
@@ -180,12 +307,19 @@ func (c *BookingController) ReserveRooms() {
 			_ = o.Rollback()
 			return
 		}
+		hotelID = ro.HotelId.Id
 		roomReserved := &models.RoomReserved{
 			ReservationId: &re,
 			RoomId:        &ro,
-			Price:         ro.CurrentPrice,
 		}
-		_, err = o.Insert(roomReserved)
+		err = pricing.ReserveRoom(o, roomReserved, nights)
+		if err == pricing.ErrMinStay {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+			res.SetCode(reqres.InvalidParams)
+			res.Message = err.Error()
+			_ = o.Rollback()
+			return
+		}
 		if err != nil {
 			c.Ctx.Output.SetStatus(http.StatusInternalServerError)
 			res.SetCode(reqres.FailedCreate)
@@ -193,9 +327,120 @@ func (c *BookingController) ReserveRooms() {
 			return
 		}
 	}
+	if len(req.PromoCodes) != 0 {
+		_, err = promotion.Redeem(o, &re, req.PromoCodes, now)
+		if err != nil {
+			if promotion.IsError(err) {
+				c.Ctx.Output.SetStatus(http.StatusBadRequest)
+				res.SetCode(reqres.InvalidParams)
+			} else {
+				c.Ctx.Output.SetStatus(http.StatusInternalServerError)
+				res.SetCode(reqres.FailedCreate)
+			}
+			res.Message = err.Error()
+			_ = o.Rollback()
+			return
+		}
+	}
+	// the total is priced night by night from the rate plans of the rooms
+	// and the tax rules of the hotel
+	breakdown, err := pricing.Quote(o, &re, hotelID)
+	if err == nil {
+		re.TotalPrice = breakdown.Total
+		_, err = o.Update(&re, "TotalPrice")
+	}
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusInternalServerError)
+		res.SetCode(reqres.FailedCreate)
+		res.Message = err.Error()
+		_ = o.Rollback()
+		return
+	}
+	// the guest agreed to the total shown, in the currency it was shown in
+	quoted := re.TotalPrice
+	if req.TotalPrice.Currency != quoted.Currency {
+		quoted = quoted.Convert(currency, rate)
+	}
+	if quoted != req.TotalPrice {
+		c.Ctx.Output.SetStatus(http.StatusConflict)
+		res.SetCode(reqres.InvalidParams)
+		res.Message = "Error: the total of the stay is " + quoted.String()
+		res.Breakdown = breakdown
+		_ = o.Rollback()
+		return
+	}
+	if err = notification.Confirmed(o, &re, hotelID, now); err != nil {
+		c.Ctx.Output.SetStatus(http.StatusInternalServerError)
+		res.SetCode(reqres.FailedCreate)
+		_ = o.Rollback()
+		return
+	}
+	if _, err = invoice.Generate(o, &re, hotelID, now); err != nil {
+		c.Ctx.Output.SetStatus(http.StatusInternalServerError)
+		res.SetCode(reqres.FailedCreate)
+		_ = o.Rollback()
+		return
+	}
 	_ = o.Commit()
 
+	if currency != "" {
+		p := re.TotalPrice.Convert(currency, rate)
+		re.DisplayPrice = &p
+	}
 	c.Ctx.Output.SetStatus(http.StatusCreated)
 	res.SetCode(reqres.Success)
 	res.Reservation = &re
+	res.Breakdown = breakdown
+}
+
+// stayOf returns the stay of the startDate and endDate query parameters,
+// zero dates if they are not given.
+func stayOf(c *beego.Controller) (from time.Time, to time.Time, err error) {
+	start, end := c.GetString("startDate"), c.GetString("endDate")
+	if start == "" && end == "" {
+		return
+	}
+	var d types.Date
+	if d, err = types.DateString(start); err != nil {
+		return
+	}
+	from = d.Time
+	if d, err = types.DateString(end); err != nil {
+		return
+	}
+	to = d.Time
+	if !from.Before(to) {
+		err = errors.New("endDate must follow startDate")
+	}
+	return
+}
+
+// checkRooms returns why rooms can't be reserved together, empty if they
+// can: rooms must be given, each once, exist and be of one hotel.
+func checkRooms(rooms []int) (string, error) {
+	if len(rooms) == 0 {
+		return "Error: rooms are required", nil
+	}
+	seen := make(map[int]bool, len(rooms))
+	for _, id := range rooms {
+		if seen[id] {
+			return fmt.Sprintf("Error: room %d is given twice", id), nil
+		}
+		seen[id] = true
+	}
+
+	var l []models.Room
+	if _, err := orm.NewOrm().QueryTable(new(models.Room)).
+		Filter("Id__in", rooms).Limit(len(rooms)).All(&l, "Id", "HotelId"); err != nil {
+		return "", err
+	}
+	if len(l) != len(rooms) {
+		return "Error: a room does not exist", nil
+	}
+	for _, room := range l {
+		if room.HotelId.Id != l[0].HotelId.Id {
+			return "Error: rooms must be of one hotel", nil
+		}
+	}
+	return "", nil
 }
@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"time"
+
+	"easybook/models"
+	"easybook/services/exchange"
+	"easybook/types"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/orm"
+)
+
+// converterOf returns a Converter to the currency query parameter at the
+// rates of today, nil if the parameter is not given.
+func converterOf(c *beego.Controller) (*exchange.Converter, error) {
+	currency, err := exchange.Currency(c.GetString("currency"))
+	if err != nil || currency == "" {
+		return nil, err
+	}
+	return exchange.NewConverter(orm.NewOrm(), currency, time.Now()), nil
+}
+
+// displayItems sets the display price of items, see displayItem.
+func displayItems(cv *exchange.Converter, items []interface{}) error {
+	for i, item := range items {
+		v, err := displayItem(cv, item)
+		if err != nil {
+			return err
+		}
+		items[i] = v
+	}
+	return nil
+}
+
+// displayItem sets the DisplayPrice of item, a Room or a Reservation, a
+// pointer to one or a map projected from one, converted by cv. Projected
+// maps get it only if they have the price. Reservations booked in the
+// currency of cv keep the rate of their booking.
+func displayItem(cv *exchange.Converter, item interface{}) (interface{}, error) {
+	switch v := item.(type) {
+	case models.Room:
+		err := displayRoom(cv, &v)
+		return v, err
+	case *models.Room:
+		return v, displayRoom(cv, v)
+	case models.Reservation:
+		err := displayReservation(cv, &v)
+		return v, err
+	case *models.Reservation:
+		return v, displayReservation(cv, v)
+	case map[string]interface{}:
+		if price, ok := v["CurrentPrice"].(types.Money); ok {
+			p, _, err := cv.Convert(price)
+			v["DisplayPrice"] = p
+			return v, err
+		}
+		if price, ok := v["TotalPrice"].(types.Money); ok {
+			r := &models.Reservation{TotalPrice: price}
+			r.DisplayCurrency, _ = v["DisplayCurrency"].(string)
+			r.ExchangeRate, _ = v["ExchangeRate"].(float64)
+			err := displayReservation(cv, r)
+			v["DisplayPrice"] = r.DisplayPrice
+			return v, err
+		}
+	}
+	return item, nil
+}
+
+func displayRoom(cv *exchange.Converter, v *models.Room) error {
+	p, _, err := cv.Convert(v.CurrentPrice)
+	v.DisplayPrice = &p
+	return err
+}
+
+func displayReservation(cv *exchange.Converter, v *models.Reservation) error {
+	if v.DisplayCurrency == cv.Currency() && v.ExchangeRate > 0 {
+		p := v.TotalPrice.Convert(v.DisplayCurrency, v.ExchangeRate)
+		v.DisplayPrice = &p
+		return nil
+	}
+	p, _, err := cv.Convert(v.TotalPrice)
+	v.DisplayPrice = &p
+	return err
+}
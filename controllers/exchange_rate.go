@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"bytes"
+	"easybook/models"
+	"easybook/queryspec"
+	"easybook/reqres"
+	"easybook/services/exchange"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/orm"
+)
+
+// ExchangeRateController operations for ExchangeRate. Rates are public,
+// admins maintain them.
+type ExchangeRateController struct {
+	beego.Controller
+}
+
+// URLMapping ...
+func (c *ExchangeRateController) URLMapping() {
+	c.Mapping("Post", c.Post)
+	c.Mapping("Import", c.Import)
+	c.Mapping("GetOne", c.GetOne)
+	c.Mapping("GetAll", c.GetAll)
+	c.Mapping("Delete", c.Delete)
+}
+
+// Prepare rejects changes by callers other than admins.
+func (c *ExchangeRateController) Prepare() {
+	if c.Ctx.Request.Method != http.MethodGet && roleOf(&c.Controller) < models.RoleAdmin {
+		c.Ctx.Output.SetStatus(http.StatusForbidden)
+		c.Data["json"] = "Error: admin only"
+		c.ServeJSON()
+		c.StopRun()
+	}
+}
+
+// Post ...
+// @Title Post
+// @Description set the rate of a currency pair on a date, admin only
+// @Param	X-Token	header	string	true	"Token of an admin"
+// @Param	body		body 	reqres.ExchangeRatePostRequest	true		"body for ExchangeRate content"
+// @Success 201 {int} models.ExchangeRate
+// @Failure 403 caller is not admin
+// @router / [post]
+func (c *ExchangeRateController) Post() {
+	var req reqres.ExchangeRatePostRequest
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &req); err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	v := models.ExchangeRate{Base: req.Base, Currency: req.Currency, Rate: req.Rate, Date: req.Date.Time}
+	if err := exchange.Validate(&v); err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+
+	if err := models.SetExchangeRate(orm.NewOrm(), &v); err == nil {
+		c.Ctx.Output.SetStatus(201)
+		c.Data["json"] = v
+	} else {
+		c.Data["json"] = err.Error()
+	}
+	c.ServeJSON()
+}
+
+// Import ...
+// @Title Import
+// @Description set rates from CSV lines date,base,currency,rate e.g. 2020-09-18,VND,USD,0.0000431, admin only
+// @Param	X-Token	header	string	true	"Token of an admin"
+// @Param	body		body 	string	true		"CSV rates"
+// @Success 200 {object} reqres.ExchangeRateImportResponse
+// @Failure 400 invalid line
+// @router /import [post]
+func (c *ExchangeRateController) Import() {
+	res := reqres.ExchangeRateImportResponse{}
+	res.SetCode(reqres.Fail)
+
+	defer func() {
+		c.Data["json"] = res
+		c.ServeJSON()
+	}()
+
+	n, err := exchange.Import(bytes.NewReader(c.Ctx.Input.RequestBody))
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		res.SetCode(reqres.InvalidParams)
+		res.Message = err.Error()
+		return
+	}
+	res.SetCode(reqres.Success)
+	res.Imported = n
+}
+
+// GetOne ...
+// @Title Get One
+// @Description get ExchangeRate by id
+// @Param	id		path 	string	true		"The key for staticblock"
+// @Param	fields	query	string	false	"Fields returned. e.g. col1,col2 ..."
+// @Success 200 {object} models.ExchangeRate
+// @Failure 403 :id is empty
+// @router /:id [get]
+func (c *ExchangeRateController) GetOne() {
+	idStr := c.Ctx.Input.Param(":id")
+	id, _ := strconv.Atoi(idStr)
+	spec, err := queryspec.Parse(c.Input())
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	spec.Role = roleOf(&c.Controller)
+
+	var item interface{}
+	v, err := models.GetExchangeRateById(id)
+	if err == nil {
+		item, err = models.Present(v, spec)
+	}
+	if err != nil {
+		if queryspec.IsError(err) {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		}
+		c.Data["json"] = err.Error()
+	} else {
+		c.Data["json"] = item
+	}
+	c.ServeJSON()
+}
+
+// GetAll ...
+// @Title Get All
+// @Description get ExchangeRate
+// @Param	query	query	string	false	"Filter. e.g. col1:v1,col2__gte:v2,(col3__in:v3|v4;!col4:v5) ..."
+// @Param	fields	query	string	false	"Fields returned. e.g. col1,col2 ..."
+// @Param	sortby	query	string	false	"Sorted-by fields. e.g. col1,col2 ..."
+// @Param	order	query	string	false	"Order corresponding to each sortby field, if single value, apply to all sortby fields. e.g. desc,asc ..."
+// @Param	limit	query	string	false	"Limit the size of result set. Must be an integer"
+// @Param	offset	query	string	false	"Start position of result set. Must be an integer"
+// @Param	cursor	query	string	false	"Keyset paging cursor ordered by created_at,id. Empty to start, then the token of the next link"
+// @Success 200 {object} reqres.ListResponse
+// @Failure 403
+// @router / [get]
+func (c *ExchangeRateController) GetAll() {
+	spec, err := queryspec.Parse(c.Input())
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	spec.Role = roleOf(&c.Controller)
+
+	l, err := models.GetAllExchangeRate(spec)
+	if err != nil {
+		if queryspec.IsError(err) {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		}
+		c.Data["json"] = err.Error()
+	} else {
+		c.Data["json"] = reqres.NewListResponse(c.Ctx.Request.URL, spec, l)
+	}
+	c.ServeJSON()
+}
+
+// Delete ...
+// @Title Delete
+// @Description delete the ExchangeRate, admin only
+// @Param	X-Token	header	string	true	"Token of an admin"
+// @Param	id		path 	string	true		"The id you want to delete"
+// @Success 200 {string} delete success!
+// @Failure 403 caller is not admin
+// @router /:id [delete]
+func (c *ExchangeRateController) Delete() {
+	idStr := c.Ctx.Input.Param(":id")
+	id, _ := strconv.Atoi(idStr)
+	if err := models.DeleteExchangeRate(id); err == nil {
+		c.Data["json"] = "OK"
+	} else {
+		c.Data["json"] = err.Error()
+	}
+	c.ServeJSON()
+}
@@ -0,0 +1,361 @@
+package controllers
+
+import (
+	"easybook/models"
+	"easybook/queryspec"
+	"easybook/reqres"
+	"easybook/services/availability"
+	"easybook/services/group"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/orm"
+)
+
+// GroupController operations for GroupBooking. Organizers reserve a block
+// of rooms and give them to the guests of their group until the block is
+// released.
+type GroupController struct {
+	beego.Controller
+}
+
+// URLMapping ...
+func (c *GroupController) URLMapping() {
+	c.Mapping("Post", c.Post)
+	c.Mapping("GetOne", c.GetOne)
+	c.Mapping("GetAll", c.GetAll)
+	c.Mapping("Assign", c.Assign)
+	c.Mapping("Release", c.Release)
+	c.Mapping("Invoice", c.Invoice)
+}
+
+// Prepare rejects anonymous callers.
+func (c *GroupController) Prepare() {
+	if callerOf(&c.Controller) == nil {
+		c.Ctx.Output.SetStatus(http.StatusUnauthorized)
+		c.Data["json"] = "Error: X-Token is required"
+		c.ServeJSON()
+		c.StopRun()
+	}
+}
+
+// Post ...
+// @Title Post
+// @Description reserve a block of rooms for a group. The rooms not given to a guest return to inventory once the release date has passed
+// @Param	X-Token	header	string	true	"Token of the organizer or a staff member"
+// @Param	body		body 	reqres.GroupPostRequest	true		"body for GroupBooking content"
+// @Success 201 {object} reqres.GroupResponse
+// @Failure 400 dates or rooms are invalid
+// @Failure 409 a room is reserved or held
+// @router / [post]
+func (c *GroupController) Post() {
+	res := reqres.GroupResponse{}
+	res.SetCode(reqres.Fail)
+
+	defer func() {
+		c.Data["json"] = res
+		c.ServeJSON()
+	}()
+
+	var req reqres.GroupPostRequest
+	if json.Unmarshal(c.Ctx.Input.RequestBody, &req) != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		res.SetCode(reqres.InvalidParams)
+		return
+	}
+	organizerID := callerOf(&c.Controller).GuestId
+	if roleOf(&c.Controller) >= models.RoleStaff && req.OrganizerID != 0 {
+		organizerID = req.OrganizerID
+	}
+
+	o := orm.NewOrm()
+	if o.Read(&models.Hotel{Id: req.HotelID}) != nil {
+		c.Ctx.Output.SetStatus(http.StatusNotFound)
+		res.SetCode(reqres.RecordNotExist)
+		return
+	}
+	v := &models.GroupBooking{
+		Name:        req.Name,
+		OrganizerId: &models.Guest{Id: organizerID},
+		HotelId:     &models.Hotel{Id: req.HotelID},
+		StartDate:   req.StartDate.Time,
+		EndDate:     req.EndDate.Time,
+		ReleaseDate: req.ReleaseDate.Time,
+	}
+	if req.MasterInvoice {
+		v.MasterInvoice = 1
+	}
+	_ = o.Begin()
+	if err := group.Create(o, v, req.Rooms, time.Now()); err != nil {
+		_ = o.Rollback()
+		c.serveError(&res.CommonResponse, err, reqres.FailedCreate)
+		return
+	}
+	_ = o.Commit()
+
+	c.Ctx.Output.SetStatus(http.StatusCreated)
+	res.SetCode(reqres.Success)
+	res.Group = v
+}
+
+// GetOne ...
+// @Title Get One
+// @Description get GroupBooking by id with the rooms of its block and the reservations of its guests
+// @Param	X-Token	header	string	true	"Token of the organizer or a staff member"
+// @Param	id		path 	string	true		"The key for staticblock"
+// @Success 200 {object} reqres.GroupResponse
+// @Failure 404 :id doesn't exist or belongs to another organizer
+// @router /:id [get]
+func (c *GroupController) GetOne() {
+	res := reqres.GroupResponse{}
+	res.SetCode(reqres.Fail)
+
+	defer func() {
+		c.Data["json"] = res
+		c.ServeJSON()
+	}()
+
+	idStr := c.Ctx.Input.Param(":id")
+	id, _ := strconv.Atoi(idStr)
+	o := orm.NewOrm()
+	v, err := models.GetGroupBookingById(o, id)
+	if err != nil || !c.organizes(v) {
+		c.Ctx.Output.SetStatus(http.StatusNotFound)
+		res.SetCode(reqres.RecordNotExist)
+		return
+	}
+	var rooms []models.RoomReserved
+	if v.BlockId != nil && v.ReleasedAt.IsZero() {
+		rooms, err = models.GetRoomReservedByReservation(o, v.BlockId.Id)
+	}
+	var members []models.Reservation
+	if err == nil {
+		members, err = models.GetGroupMembers(o, v)
+	}
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusInternalServerError)
+		res.SetCode(reqres.SystemError)
+		return
+	}
+
+	res.SetCode(reqres.Success)
+	res.Group = v
+	res.Rooms = rooms
+	res.Members = members
+}
+
+// GetAll ...
+// @Title Get All
+// @Description get the GroupBooking of the caller, or all of them for staff
+// @Param	X-Token	header	string	true	"Token of the organizer or a staff member"
+// @Param	query	query	string	false	"Filter. e.g. col1:v1,col2__gte:v2,(col3__in:v3|v4;!col4:v5) ..."
+// @Param	fields	query	string	false	"Fields returned. e.g. col1,col2,rel1.col3 ..."
+// @Param	sortby	query	string	false	"Sorted-by fields. e.g. col1,col2 ..."
+// @Param	order	query	string	false	"Order corresponding to each sortby field, if single value, apply to all sortby fields. e.g. desc,asc ..."
+// @Param	limit	query	string	false	"Limit the size of result set. Must be an integer"
+// @Param	offset	query	string	false	"Start position of result set. Must be an integer"
+// @Param	cursor	query	string	false	"Keyset paging cursor ordered by created_at,id. Empty to start, then the token of the next link"
+// @Param	expand	query	string	false	"Relations to load. e.g. rel1,rel2.rel3 ..."
+// @Success 200 {object} reqres.ListResponse
+// @Failure 401 caller is anonymous
+// @router / [get]
+func (c *GroupController) GetAll() {
+	spec, err := queryspec.Parse(c.Input())
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	spec.Role = roleOf(&c.Controller)
+
+	var l *models.Page
+	if spec.Role >= models.RoleStaff {
+		l, err = models.GetAllGroupBooking(spec)
+	} else {
+		l, err = models.GetOrganizerGroupBookings(callerOf(&c.Controller).GuestId, spec)
+	}
+	if err != nil {
+		if queryspec.IsError(err) {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		}
+		c.Data["json"] = err.Error()
+	} else {
+		c.Data["json"] = reqres.NewListResponse(c.Ctx.Request.URL, spec, l)
+	}
+	c.ServeJSON()
+}
+
+// Assign ...
+// @Title Assign
+// @Description give a room of the block to a guest of the group, the room moves to a confirmed reservation of the guest
+// @Param	X-Token	header	string	true	"Token of the organizer or a staff member"
+// @Param	id		path 	string	true		"The id of the group"
+// @Param	roomId		path 	string	true		"The id of the room of the block"
+// @Param	body		body 	reqres.GroupAssignRequest	true		"the guest given the room"
+// @Success 201 {object} reqres.GroupResponse
+// @Failure 404 :id doesn't exist or belongs to another organizer
+// @Failure 409 block is released or room is not in the block
+// @router /:id/rooms/:roomId/assign [post]
+func (c *GroupController) Assign() {
+	res := reqres.GroupResponse{}
+	res.SetCode(reqres.Fail)
+
+	defer func() {
+		c.Data["json"] = res
+		c.ServeJSON()
+	}()
+
+	var req reqres.GroupAssignRequest
+	if json.Unmarshal(c.Ctx.Input.RequestBody, &req) != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		res.SetCode(reqres.InvalidParams)
+		return
+	}
+	roomID, _ := strconv.Atoi(c.Ctx.Input.Param(":roomId"))
+
+	o := orm.NewOrm()
+	if o.Read(&models.Guest{Id: req.GuestID}) != nil {
+		c.Ctx.Output.SetStatus(http.StatusNotFound)
+		res.SetCode(reqres.RecordNotExist)
+		res.Message = "Error: guest doesn't exist"
+		return
+	}
+	_ = o.Begin()
+	v, ok := c.lock(o)
+	if !ok {
+		_ = o.Rollback()
+		c.Ctx.Output.SetStatus(http.StatusNotFound)
+		res.SetCode(reqres.RecordNotExist)
+		return
+	}
+	r, err := group.Assign(o, v, roomID, req.GuestID, req.Guests, time.Now())
+	if err != nil {
+		_ = o.Rollback()
+		c.serveError(&res.CommonResponse, err, reqres.FailedCreate)
+		return
+	}
+	_ = o.Commit()
+
+	c.Ctx.Output.SetStatus(http.StatusCreated)
+	res.SetCode(reqres.Success)
+	res.Group = v
+	res.Reservation = r
+}
+
+// Release ...
+// @Title Release
+// @Description return the rooms of the block not given to a guest to inventory before the release date
+// @Param	X-Token	header	string	true	"Token of the organizer or a staff member"
+// @Param	id		path 	string	true		"The id of the group"
+// @Success 200 {object} reqres.GroupResponse
+// @Failure 404 :id doesn't exist or belongs to another organizer
+// @Failure 409 block is released already
+// @router /:id/release [post]
+func (c *GroupController) Release() {
+	res := reqres.GroupResponse{}
+	res.SetCode(reqres.Fail)
+
+	defer func() {
+		c.Data["json"] = res
+		c.ServeJSON()
+	}()
+
+	o := orm.NewOrm()
+	_ = o.Begin()
+	v, ok := c.lock(o)
+	if !ok {
+		_ = o.Rollback()
+		c.Ctx.Output.SetStatus(http.StatusNotFound)
+		res.SetCode(reqres.RecordNotExist)
+		return
+	}
+	if err := group.Release(o, v, time.Now()); err != nil {
+		_ = o.Rollback()
+		c.serveError(&res.CommonResponse, err, reqres.FailedUpdate)
+		return
+	}
+	_ = o.Commit()
+
+	res.SetCode(reqres.Success)
+	res.Group = v
+}
+
+// Invoice ...
+// @Title Invoice
+// @Description issue or update the master invoice of the group, billed to the organizer over the reservations of its guests
+// @Param	X-Token	header	string	true	"Token of the organizer or a staff member"
+// @Param	id		path 	string	true		"The id of the group"
+// @Success 200 {object} reqres.InvoiceResponse
+// @Failure 404 :id doesn't exist or belongs to another organizer
+// @Failure 409 group is billed per guest
+// @router /:id/invoice [post]
+func (c *GroupController) Invoice() {
+	res := reqres.InvoiceResponse{}
+	res.SetCode(reqres.Fail)
+
+	defer func() {
+		c.Data["json"] = res
+		c.ServeJSON()
+	}()
+
+	o := orm.NewOrm()
+	_ = o.Begin()
+	v, ok := c.lock(o)
+	if !ok {
+		_ = o.Rollback()
+		c.Ctx.Output.SetStatus(http.StatusNotFound)
+		res.SetCode(reqres.RecordNotExist)
+		return
+	}
+	inv, err := group.MasterInvoice(o, v, time.Now())
+	var lines []models.InvoiceLine
+	if err == nil {
+		lines, err = models.GetInvoiceLines(o, inv.Id)
+	}
+	if err != nil {
+		_ = o.Rollback()
+		c.serveError(&res.CommonResponse, err, reqres.FailedCreate)
+		return
+	}
+	_ = o.Commit()
+
+	res.SetCode(reqres.Success)
+	res.Invoice = inv
+	res.Lines = lines
+}
+
+// lock reads and locks the group of the :id parameter, if the caller
+// organizes it.
+func (c *GroupController) lock(o orm.Ormer) (*models.GroupBooking, bool) {
+	id, _ := strconv.Atoi(c.Ctx.Input.Param(":id"))
+	v := &models.GroupBooking{Id: id}
+	if err := o.ReadForUpdate(v); err != nil || !c.organizes(v) {
+		return nil, false
+	}
+	return v, true
+}
+
+// organizes reports whether the caller may see and change group v.
+func (c *GroupController) organizes(v *models.GroupBooking) bool {
+	return roleOf(&c.Controller) >= models.RoleStaff || v.OrganizerId.Id == callerOf(&c.Controller).GuestId
+}
+
+func (c *GroupController) serveError(res *reqres.CommonResponse, err error, code int) {
+	switch {
+	case err == group.ErrReleased || err == group.ErrNotInBlock || err == group.ErrNoMaster ||
+		err == availability.ErrUnavailable:
+		c.Ctx.Output.SetStatus(http.StatusConflict)
+		res.SetCode(reqres.InvalidParams)
+	case group.IsError(err):
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		res.SetCode(reqres.InvalidParams)
+	default:
+		c.Ctx.Output.SetStatus(http.StatusInternalServerError)
+		res.SetCode(code)
+	}
+	res.Message = err.Error()
+}
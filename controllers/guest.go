@@ -3,12 +3,13 @@ package controllers
 import (
 	"crypto/md5"
 	"easybook/models"
+	"easybook/queryspec"
 	"easybook/reqres"
+	"easybook/services/auth"
 	"encoding/hex"
 	"encoding/json"
-	"errors"
+	"net/http"
 	"strconv"
-	"strings"
 
 	"github.com/astaxie/beego"
 )
@@ -25,6 +26,7 @@ func (c *GuestController) URLMapping() {
 	c.Mapping("GetAll", c.GetAll)
 	c.Mapping("Put", c.Put)
 	c.Mapping("Delete", c.Delete)
+	c.Mapping("Login", c.Login)
 }
 
 // Post ...
@@ -57,7 +59,12 @@ func (c *GuestController) Post() {
 		Phone:     req.Phone,
 		Address:   req.Address,
 		Detail:    req.Detail,
-		Role:      req.Role,
+		Role:      models.RoleGuest,
+		Language:  req.Language,
+	}
+	// only an admin can create staff and admin accounts
+	if roleOf(&c.Controller) >= models.RoleAdmin {
+		guest.Role = req.Role
 	}
 
 	if req.Password != "" && req.Password == req.ConfirmedPassword {
@@ -81,17 +88,35 @@ func (c *GuestController) Post() {
 // @Title Get One
 // @Description get Guest by id
 // @Param	id		path 	string	true		"The key for staticblock"
+// @Param	fields	query	string	false	"Fields returned. e.g. col1,col2,rel1.col3 ..."
+// @Param	expand	query	string	false	"Relations to load. e.g. rel1,rel2.rel3 ..."
 // @Success 200 {object} models.Guest
 // @Failure 403 :id is empty
 // @router /:id [get]
 func (c *GuestController) GetOne() {
 	idStr := c.Ctx.Input.Param(":id")
 	id, _ := strconv.Atoi(idStr)
+	spec, err := queryspec.Parse(c.Input())
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	spec.Role = roleOf(&c.Controller)
+
+	var item interface{}
 	v, err := models.GetGuestById(id)
+	if err == nil {
+		item, err = models.Present(v, spec)
+	}
 	if err != nil {
+		if queryspec.IsError(err) {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		}
 		c.Data["json"] = err.Error()
 	} else {
-		c.Data["json"] = v
+		c.Data["json"] = item
 	}
 	c.ServeJSON()
 }
@@ -99,62 +124,35 @@ func (c *GuestController) GetOne() {
 // GetAll ...
 // @Title Get All
 // @Description get Guest
-// @Param	query	query	string	false	"Filter. e.g. col1:v1,col2:v2 ..."
-// @Param	fields	query	string	false	"Fields returned. e.g. col1,col2 ..."
+// @Param	query	query	string	false	"Filter. e.g. col1:v1,col2__gte:v2,(col3__in:v3|v4;!col4:v5) ..."
+// @Param	fields	query	string	false	"Fields returned. e.g. col1,col2,rel1.col3 ..."
 // @Param	sortby	query	string	false	"Sorted-by fields. e.g. col1,col2 ..."
 // @Param	order	query	string	false	"Order corresponding to each sortby field, if single value, apply to all sortby fields. e.g. desc,asc ..."
 // @Param	limit	query	string	false	"Limit the size of result set. Must be an integer"
 // @Param	offset	query	string	false	"Start position of result set. Must be an integer"
-// @Success 200 {object} models.Guest
+// @Param	cursor	query	string	false	"Keyset paging cursor ordered by created_at,id. Empty to start, then the token of the next link"
+// @Param	expand	query	string	false	"Relations to load. e.g. rel1,rel2.rel3 ..."
+// @Success 200 {object} reqres.ListResponse
 // @Failure 403
 // @router / [get]
 func (c *GuestController) GetAll() {
-	var fields []string
-	var sortby []string
-	var order []string
-	var query = make(map[string]string)
-	var limit int64 = 10
-	var offset int64
-
-	// fields: col1,col2,entity.col3
-	if v := c.GetString("fields"); v != "" {
-		fields = strings.Split(v, ",")
-	}
-	// limit: 10 (default is 10)
-	if v, err := c.GetInt64("limit"); err == nil {
-		limit = v
-	}
-	// offset: 0 (default is 0)
-	if v, err := c.GetInt64("offset"); err == nil {
-		offset = v
-	}
-	// sortby: col1,col2
-	if v := c.GetString("sortby"); v != "" {
-		sortby = strings.Split(v, ",")
-	}
-	// order: desc,asc
-	if v := c.GetString("order"); v != "" {
-		order = strings.Split(v, ",")
-	}
-	// query: k:v,k:v
-	if v := c.GetString("query"); v != "" {
-		for _, cond := range strings.Split(v, ",") {
-			kv := strings.SplitN(cond, ":", 2)
-			if len(kv) != 2 {
-				c.Data["json"] = errors.New("Error: invalid query key/value pair")
-				c.ServeJSON()
-				return
-			}
-			k, v := kv[0], kv[1]
-			query[k] = v
-		}
+	spec, err := queryspec.Parse(c.Input())
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
 	}
+	spec.Role = roleOf(&c.Controller)
 
-	l, err := models.GetAllGuest(query, fields, sortby, order, offset, limit)
+	l, err := models.GetAllGuest(spec)
 	if err != nil {
+		if queryspec.IsError(err) {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		}
 		c.Data["json"] = err.Error()
 	} else {
-		c.Data["json"] = l
+		c.Data["json"] = reqres.NewListResponse(c.Ctx.Request.URL, spec, l)
 	}
 	c.ServeJSON()
 }
@@ -170,8 +168,31 @@ func (c *GuestController) GetAll() {
 func (c *GuestController) Put() {
 	idStr := c.Ctx.Input.Param(":id")
 	id, _ := strconv.Atoi(idStr)
+	caller := callerOf(&c.Controller)
+	if caller == nil || caller.GuestId != id && caller.Role < models.RoleStaff {
+		c.Ctx.Output.SetStatus(http.StatusForbidden)
+		c.Data["json"] = "Error: not allowed"
+		c.ServeJSON()
+		return
+	}
+
+	old, err := models.GetGuestById(id)
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusNotFound)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+
 	v := models.Guest{Id: id}
 	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &v); err == nil {
+		// the password is changed through its own flow and only an admin
+		// can change roles
+		v.Password = old.Password
+		v.CreatedAt = old.CreatedAt
+		if caller.Role < models.RoleAdmin {
+			v.Role = old.Role
+		}
 		if err := models.UpdateGuestById(&v); err == nil {
 			c.Data["json"] = "OK"
 		} else {
@@ -200,3 +221,47 @@ func (c *GuestController) Delete() {
 	}
 	c.ServeJSON()
 }
+
+// Login ...
+// @Title Login
+// @Description issue a token to be sent in X-Token header
+// @Param	body		body 	reqres.GuestLoginRequest	true		"body for Guest credentials"
+// @Success 200 {object} reqres.GuestLoginResponse
+// @Failure 401 email or password is wrong
+// @router /login [post]
+func (c *GuestController) Login() {
+	res := reqres.GuestLoginResponse{}
+	res.SetCode(reqres.Fail)
+
+	defer func() {
+		c.Data["json"] = res
+		c.ServeJSON()
+	}()
+
+	var req reqres.GuestLoginRequest
+	if json.Unmarshal(c.Ctx.Input.RequestBody, &req) != nil || req.Email == "" || req.Password == "" {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		res.SetCode(reqres.InvalidParams)
+		return
+	}
+
+	hash := md5.Sum([]byte(req.Password))
+	guest, err := models.GetGuestByEmail(req.Email)
+	if err != nil || guest.Password != hex.EncodeToString(hash[:]) {
+		c.Ctx.Output.SetStatus(http.StatusUnauthorized)
+		res.SetCode(reqres.Unauthorized)
+		return
+	}
+
+	token, err := auth.Sign(guest.Id, guest.Role)
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusInternalServerError)
+		res.SetCode(reqres.SystemError)
+		return
+	}
+
+	guest.Password = ""
+	res.SetCode(reqres.Success)
+	res.Token = token
+	res.Guest = guest
+}
@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"easybook/models"
+	"easybook/queryspec"
+	"easybook/reqres"
+	"easybook/services/availability"
+	"easybook/services/hold"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/orm"
+)
+
+// HoldController operations for Hold. Guests hold rooms while they check
+// out, then pass the hold to ReserveRooms.
+type HoldController struct {
+	beego.Controller
+}
+
+// URLMapping ...
+func (c *HoldController) URLMapping() {
+	c.Mapping("Post", c.Post)
+	c.Mapping("GetOne", c.GetOne)
+	c.Mapping("GetAll", c.GetAll)
+	c.Mapping("Delete", c.Delete)
+}
+
+// Prepare rejects anonymous callers.
+func (c *HoldController) Prepare() {
+	if callerOf(&c.Controller) == nil {
+		c.Ctx.Output.SetStatus(http.StatusUnauthorized)
+		c.Data["json"] = "Error: X-Token is required"
+		c.ServeJSON()
+		c.StopRun()
+	}
+}
+
+// Post ...
+// @Title Post
+// @Description hold rooms for a stay until the hold expires, holdttl seconds of app.conf
+// @Param	X-Token	header	string	true	"Token of the guest or a staff member"
+// @Param	body		body 	reqres.HoldPostRequest	true		"body for Hold content"
+// @Success 201 {object} reqres.HoldResponse
+// @Failure 409 a room is reserved or held
+// @router / [post]
+func (c *HoldController) Post() {
+	res := reqres.HoldResponse{}
+	res.SetCode(reqres.Fail)
+
+	defer func() {
+		c.Data["json"] = res
+		c.ServeJSON()
+	}()
+
+	var req reqres.HoldPostRequest
+	if json.Unmarshal(c.Ctx.Input.RequestBody, &req) != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		res.SetCode(reqres.InvalidParams)
+		return
+	}
+	guestID := callerOf(&c.Controller).GuestId
+	if roleOf(&c.Controller) >= models.RoleStaff && req.GuestID != 0 {
+		guestID = req.GuestID
+	}
+
+	o := orm.NewOrm()
+	_ = o.Begin()
+	v, err := hold.Place(o, guestID, req.Rooms, req.StartDate.Time, req.EndDate.Time, time.Now())
+	if err != nil {
+		_ = o.Rollback()
+		switch {
+		case err == availability.ErrUnavailable:
+			c.Ctx.Output.SetStatus(http.StatusConflict)
+			res.SetCode(reqres.InvalidParams)
+		case hold.IsError(err):
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+			res.SetCode(reqres.InvalidParams)
+		default:
+			c.Ctx.Output.SetStatus(http.StatusInternalServerError)
+			res.SetCode(reqres.FailedCreate)
+		}
+		res.Message = err.Error()
+		return
+	}
+	_ = o.Commit()
+
+	c.Ctx.Output.SetStatus(http.StatusCreated)
+	res.SetCode(reqres.Success)
+	res.Hold = v
+}
+
+// GetOne ...
+// @Title Get One
+// @Description get Hold by id with its rooms
+// @Param	X-Token	header	string	true	"Token of the guest or a staff member"
+// @Param	id		path 	string	true		"The key for staticblock"
+// @Success 200 {object} reqres.HoldResponse
+// @Failure 404 :id doesn't exist or belongs to another guest
+// @router /:id [get]
+func (c *HoldController) GetOne() {
+	res := reqres.HoldResponse{}
+	res.SetCode(reqres.Fail)
+
+	defer func() {
+		c.Data["json"] = res
+		c.ServeJSON()
+	}()
+
+	idStr := c.Ctx.Input.Param(":id")
+	id, _ := strconv.Atoi(idStr)
+	v, err := models.GetHoldById(orm.NewOrm(), id)
+	if err != nil || !c.canSee(v) {
+		c.Ctx.Output.SetStatus(http.StatusNotFound)
+		res.SetCode(reqres.RecordNotExist)
+		return
+	}
+
+	res.SetCode(reqres.Success)
+	res.Hold = v
+}
+
+// GetAll ...
+// @Title Get All
+// @Description get the Hold of the caller, or all of them for staff
+// @Param	X-Token	header	string	true	"Token of the guest or a staff member"
+// @Param	query	query	string	false	"Filter. e.g. col1:v1,col2__gte:v2,(col3__in:v3|v4;!col4:v5) ..."
+// @Param	fields	query	string	false	"Fields returned. e.g. col1,col2,rel1.col3 ..."
+// @Param	sortby	query	string	false	"Sorted-by fields. e.g. col1,col2 ..."
+// @Param	order	query	string	false	"Order corresponding to each sortby field, if single value, apply to all sortby fields. e.g. desc,asc ..."
+// @Param	limit	query	string	false	"Limit the size of result set. Must be an integer"
+// @Param	offset	query	string	false	"Start position of result set. Must be an integer"
+// @Param	expand	query	string	false	"Relations to load. e.g. rel1,rel2.rel3 ..."
+// @Success 200 {object} reqres.ListResponse
+// @Failure 401 caller is anonymous
+// @router / [get]
+func (c *HoldController) GetAll() {
+	spec, err := queryspec.Parse(c.Input())
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	spec.Role = roleOf(&c.Controller)
+
+	var l *models.Page
+	if spec.Role >= models.RoleStaff {
+		l, err = models.GetAllHold(spec)
+	} else {
+		l, err = models.GetGuestHolds(callerOf(&c.Controller).GuestId, spec)
+	}
+	if err != nil {
+		if queryspec.IsError(err) {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		}
+		c.Data["json"] = err.Error()
+	} else {
+		c.Data["json"] = reqres.NewListResponse(c.Ctx.Request.URL, spec, l)
+	}
+	c.ServeJSON()
+}
+
+// Delete ...
+// @Title Delete
+// @Description release the Hold before it expires
+// @Param	X-Token	header	string	true	"Token of the guest or a staff member"
+// @Param	id		path 	string	true		"The id you want to release"
+// @Success 200 {string} delete success!
+// @Failure 404 :id doesn't exist or belongs to another guest
+// @Failure 409 hold is converted
+// @router /:id [delete]
+func (c *HoldController) Delete() {
+	idStr := c.Ctx.Input.Param(":id")
+	id, _ := strconv.Atoi(idStr)
+	o := orm.NewOrm()
+	v, err := models.GetHoldById(o, id)
+	switch {
+	case err != nil || !c.canSee(v):
+		c.Ctx.Output.SetStatus(http.StatusNotFound)
+		c.Data["json"] = "Error: hold not found"
+	case v.ReservationId != nil:
+		c.Ctx.Output.SetStatus(http.StatusConflict)
+		c.Data["json"] = hold.ErrConverted.Error()
+	default:
+		if err = models.DeleteHold(o, id); err == nil {
+			c.Data["json"] = "OK"
+		} else {
+			c.Data["json"] = err.Error()
+		}
+	}
+	c.ServeJSON()
+}
+
+// canSee reports whether the caller may read hold v.
+func (c *HoldController) canSee(v *models.Hold) bool {
+	return roleOf(&c.Controller) >= models.RoleStaff || v.GuestId.Id == callerOf(&c.Controller).GuestId
+}
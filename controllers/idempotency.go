@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"easybook/models"
+	"easybook/reqres"
+	"easybook/services/idempotency"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/logs"
+)
+
+// beginIdempotent starts the request of c under its Idempotency-Key
+// header, if any. It returns done when the request is answered already,
+// by the response stored for the key or by a conflict, otherwise the key
+// to be given to finishIdempotent once the response is ready.
+func beginIdempotent(c *beego.Controller) (key *models.IdempotencyKey, done bool) {
+	header := c.Ctx.Input.Header("Idempotency-Key")
+	if header == "" {
+		return nil, false
+	}
+
+	scope := "anonymous"
+	if claims := callerOf(c); claims != nil {
+		scope = "guest:" + strconv.Itoa(claims.GuestId)
+	}
+	fingerprint := idempotency.Fingerprint(c.Ctx.Request.Method, c.Ctx.Request.URL.Path, c.Ctx.Request.URL.RawQuery, c.Ctx.Input.RequestBody)
+	key, err := idempotency.Begin(scope, header, fingerprint, time.Now())
+	if err != nil {
+		res := reqres.CommonResponse{}
+		switch err {
+		case idempotency.ErrConflict, idempotency.ErrInProgress:
+			c.Ctx.Output.SetStatus(http.StatusConflict)
+			res.SetCode(reqres.InvalidParams)
+			res.Message = err.Error()
+		default:
+			c.Ctx.Output.SetStatus(http.StatusInternalServerError)
+			res.SetCode(reqres.SystemError)
+		}
+		c.Data["json"] = res
+		c.ServeJSON()
+		return nil, true
+	}
+	if key.Status == 0 {
+		return key, false
+	}
+
+	c.Ctx.Output.Header("Content-Type", "application/json; charset=utf-8")
+	c.Ctx.Output.Header("Idempotent-Replayed", "true")
+	c.Ctx.Output.SetStatus(key.Status)
+	_ = c.Ctx.Output.Body([]byte(key.Response))
+	return nil, true
+}
+
+// finishIdempotent stores the response of c, its status and json data,
+// for the key of beginIdempotent.
+func finishIdempotent(c *beego.Controller, key *models.IdempotencyKey) {
+	if key == nil {
+		return
+	}
+	body, err := json.Marshal(c.Data["json"])
+	if err == nil {
+		err = idempotency.Finish(key, c.Ctx.Output.Status, body)
+	}
+	if err != nil {
+		logs.Error("idempotency: storing response of key %q: %v", key.Key, err)
+	}
+}
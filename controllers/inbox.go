@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"easybook/models"
+	"easybook/queryspec"
+	"easybook/reqres"
+	"easybook/services/notification"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/orm"
+)
+
+// inboxHeartbeat is the interval of keep-alive comments on idle streams.
+const inboxHeartbeat = 25 * time.Second
+
+// InboxController operations for the in-app notifications of the caller
+type InboxController struct {
+	beego.Controller
+}
+
+// URLMapping ...
+func (c *InboxController) URLMapping() {
+	c.Mapping("GetAll", c.GetAll)
+	c.Mapping("Read", c.Read)
+	c.Mapping("Unread", c.Unread)
+	c.Mapping("Archive", c.Archive)
+	c.Mapping("Unarchive", c.Unarchive)
+	c.Mapping("Stream", c.Stream)
+}
+
+// Prepare rejects anonymous callers.
+func (c *InboxController) Prepare() {
+	if callerOf(&c.Controller) == nil {
+		c.Ctx.Output.SetStatus(http.StatusUnauthorized)
+		c.Data["json"] = "Error: X-Token is required"
+		c.ServeJSON()
+		c.StopRun()
+	}
+}
+
+// GetAll ...
+// @Title Get All
+// @Description get the in-app notifications of the caller with the unread count
+// @Param	X-Token	header	string	true	"Token of the guest"
+// @Param	archived	query	bool	false	"List archived notifications instead of the inbox"
+// @Param	query	query	string	false	"Filter. e.g. col1:v1,col2__gte:v2,(col3__in:v3|v4;!col4:v5) ..."
+// @Param	fields	query	string	false	"Fields returned. e.g. col1,col2 ..."
+// @Param	sortby	query	string	false	"Sorted-by fields. e.g. col1,col2 ..."
+// @Param	order	query	string	false	"Order corresponding to each sortby field, if single value, apply to all sortby fields. e.g. desc,asc ..."
+// @Param	limit	query	string	false	"Limit the size of result set. Must be an integer"
+// @Param	offset	query	string	false	"Start position of result set. Must be an integer"
+// @Param	cursor	query	string	false	"Keyset paging cursor ordered by created_at,id. Empty to start, then the token of the next link"
+// @Success 200 {object} reqres.InboxListResponse
+// @Failure 401 caller is anonymous
+// @router / [get]
+func (c *InboxController) GetAll() {
+	guestID := callerOf(&c.Controller).GuestId
+	archived, _ := c.GetBool("archived")
+
+	values := c.Input()
+	values.Del("archived")
+	spec, err := queryspec.Parse(values)
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	spec.Role = roleOf(&c.Controller)
+
+	l, err := models.GetInboxNotifications(guestID, archived, spec)
+	if err == nil {
+		var unread int64
+		if unread, err = models.CountUnreadNotifications(guestID); err == nil {
+			c.Data["json"] = reqres.InboxListResponse{
+				ListResponse: reqres.NewListResponse(c.Ctx.Request.URL, spec, l),
+				Unread:       unread,
+			}
+		}
+	}
+	if err != nil {
+		if queryspec.IsError(err) {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		}
+		c.Data["json"] = err.Error()
+	}
+	c.ServeJSON()
+}
+
+// Read ...
+// @Title Read
+// @Description mark the notification as read
+// @Param	X-Token	header	string	true	"Token of the guest"
+// @Param	id		path 	string	true		"The id of the notification"
+// @Success 200 {object} models.Notification
+// @Failure 404 :id is not in the inbox of the caller
+// @router /:id/read [put]
+func (c *InboxController) Read() {
+	c.update(models.MarkNotificationRead, true)
+}
+
+// Unread ...
+// @Title Unread
+// @Description mark the notification as unread
+// @Param	X-Token	header	string	true	"Token of the guest"
+// @Param	id		path 	string	true		"The id of the notification"
+// @Success 200 {object} models.Notification
+// @Failure 404 :id is not in the inbox of the caller
+// @router /:id/unread [put]
+func (c *InboxController) Unread() {
+	c.update(models.MarkNotificationRead, false)
+}
+
+// Archive ...
+// @Title Archive
+// @Description move the notification to the archive
+// @Param	X-Token	header	string	true	"Token of the guest"
+// @Param	id		path 	string	true		"The id of the notification"
+// @Success 200 {object} models.Notification
+// @Failure 404 :id is not in the inbox of the caller
+// @router /:id/archive [put]
+func (c *InboxController) Archive() {
+	c.update(models.ArchiveNotification, true)
+}
+
+// Unarchive ...
+// @Title Unarchive
+// @Description move the notification back to the inbox
+// @Param	X-Token	header	string	true	"Token of the guest"
+// @Param	id		path 	string	true		"The id of the notification"
+// @Success 200 {object} models.Notification
+// @Failure 404 :id is not in the inbox of the caller
+// @router /:id/unarchive [put]
+func (c *InboxController) Unarchive() {
+	c.update(models.ArchiveNotification, false)
+}
+
+// Stream ...
+// @Title Stream
+// @Description push the in-app notifications of the caller as Server-Sent Events. An "unread" event with the unread count is sent on connect, then a "notification" event for every notification delivered.
+// @Param	X-Token	header	string	true	"Token of the guest"
+// @Success 200 {string} text/event-stream
+// @Failure 401 caller is anonymous
+// @router /stream [get]
+func (c *InboxController) Stream() {
+	guestID := callerOf(&c.Controller).GuestId
+	unread, err := models.CountUnreadNotifications(guestID)
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusInternalServerError)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+
+	ch, cancel := notification.Inbox.Subscribe(guestID)
+	defer cancel()
+
+	w := c.Ctx.ResponseWriter
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "event: unread\ndata: %d\n\n", unread)
+	w.Flush()
+
+	heartbeat := time.NewTicker(inboxHeartbeat)
+	defer heartbeat.Stop()
+	done := c.Ctx.Request.Context().Done()
+	for {
+		select {
+		case <-done:
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case n := <-ch:
+			data, _ := json.Marshal(n)
+			fmt.Fprintf(w, "id: %d\nevent: notification\ndata: %s\n\n", n.Id, data)
+		}
+		w.Flush()
+	}
+}
+
+func (c *InboxController) update(set func(int, int, bool, time.Time) (*models.Notification, error), on bool) {
+	idStr := c.Ctx.Input.Param(":id")
+	id, _ := strconv.Atoi(idStr)
+	v, err := set(id, callerOf(&c.Controller).GuestId, on, time.Now())
+	if err != nil {
+		if err == orm.ErrNoRows {
+			c.Ctx.Output.SetStatus(http.StatusNotFound)
+		}
+		c.Data["json"] = err.Error()
+	} else {
+		c.Data["json"] = v
+	}
+	c.ServeJSON()
+}
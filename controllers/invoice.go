@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"easybook/models"
+	"easybook/queryspec"
+	"easybook/reqres"
+	"easybook/services/invoice"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/orm"
+)
+
+// InvoiceController operations for Invoice. Guests see their own invoices,
+// staff see all of them.
+type InvoiceController struct {
+	beego.Controller
+}
+
+// URLMapping ...
+func (c *InvoiceController) URLMapping() {
+	c.Mapping("Post", c.Post)
+	c.Mapping("GetOne", c.GetOne)
+	c.Mapping("GetAll", c.GetAll)
+	c.Mapping("GetPDF", c.GetPDF)
+}
+
+// Prepare rejects anonymous callers.
+func (c *InvoiceController) Prepare() {
+	if callerOf(&c.Controller) == nil {
+		c.Ctx.Output.SetStatus(http.StatusUnauthorized)
+		c.Data["json"] = "Error: X-Token is required"
+		c.ServeJSON()
+		c.StopRun()
+	}
+}
+
+// Post ...
+// @Title Post
+// @Description issue the invoice of a confirmed reservation, staff only
+// @Param	X-Token	header	string	true	"Token of a staff member"
+// @Param	body		body 	reqres.InvoicePostRequest	true		"body for Invoice content"
+// @Success 201 {object} reqres.InvoiceResponse
+// @Failure 403 caller is not staff
+// @router / [post]
+func (c *InvoiceController) Post() {
+	res := reqres.InvoiceResponse{}
+	res.SetCode(reqres.Fail)
+
+	defer func() {
+		c.Data["json"] = res
+		c.ServeJSON()
+	}()
+
+	if roleOf(&c.Controller) < models.RoleStaff {
+		c.Ctx.Output.SetStatus(http.StatusForbidden)
+		res.SetCode(reqres.Forbidden)
+		return
+	}
+
+	var req reqres.InvoicePostRequest
+	if json.Unmarshal(c.Ctx.Input.RequestBody, &req) != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		res.SetCode(reqres.InvalidParams)
+		return
+	}
+
+	o := orm.NewOrm()
+	_ = o.Begin()
+	reservation := models.Reservation{Id: req.ReservationID}
+	if err := o.ReadForUpdate(&reservation); err != nil {
+		_ = o.Rollback()
+		c.Ctx.Output.SetStatus(http.StatusNotFound)
+		res.SetCode(reqres.RecordNotExist)
+		return
+	}
+	if reservation.Status != models.ReservationConfirmed {
+		_ = o.Rollback()
+		c.Ctx.Output.SetStatus(http.StatusConflict)
+		res.SetCode(reqres.InvalidParams)
+		res.Message = "reservation is not confirmed"
+		return
+	}
+	hotelID, err := models.GetReservationHotelId(o, reservation.Id)
+	if err != nil && err != orm.ErrNoRows {
+		_ = o.Rollback()
+		c.Ctx.Output.SetStatus(http.StatusInternalServerError)
+		res.SetCode(reqres.SystemError)
+		return
+	}
+	v, err := invoice.Generate(o, &reservation, hotelID, time.Now())
+	if err != nil {
+		_ = o.Rollback()
+		c.Ctx.Output.SetStatus(http.StatusInternalServerError)
+		res.SetCode(reqres.FailedCreate)
+		return
+	}
+	lines, err := models.GetInvoiceLines(o, v.Id)
+	if err != nil {
+		_ = o.Rollback()
+		c.Ctx.Output.SetStatus(http.StatusInternalServerError)
+		res.SetCode(reqres.FailedCreate)
+		return
+	}
+	_ = o.Commit()
+
+	c.Ctx.Output.SetStatus(http.StatusCreated)
+	res.SetCode(reqres.Success)
+	res.Invoice = v
+	res.Lines = lines
+}
+
+// GetOne ...
+// @Title Get One
+// @Description get Invoice by id with its lines
+// @Param	X-Token	header	string	true	"Token of the guest or a staff member"
+// @Param	id		path 	string	true		"The key for staticblock"
+// @Success 200 {object} reqres.InvoiceResponse
+// @Failure 404 :id doesn't exist or belongs to another guest
+// @router /:id [get]
+func (c *InvoiceController) GetOne() {
+	res := reqres.InvoiceResponse{}
+	res.SetCode(reqres.Fail)
+
+	defer func() {
+		c.Data["json"] = res
+		c.ServeJSON()
+	}()
+
+	idStr := c.Ctx.Input.Param(":id")
+	id, _ := strconv.Atoi(idStr)
+	v, err := models.GetInvoiceById(id)
+	if err != nil || !c.canSee(v) {
+		c.Ctx.Output.SetStatus(http.StatusNotFound)
+		res.SetCode(reqres.RecordNotExist)
+		return
+	}
+	lines, err := models.GetInvoiceLines(orm.NewOrm(), v.Id)
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusInternalServerError)
+		res.SetCode(reqres.SystemError)
+		return
+	}
+
+	res.SetCode(reqres.Success)
+	res.Invoice = v
+	res.Lines = lines
+}
+
+// GetAll ...
+// @Title Get All
+// @Description get the Invoice of the caller, or all of them for staff
+// @Param	X-Token	header	string	true	"Token of the guest or a staff member"
+// @Param	query	query	string	false	"Filter. e.g. col1:v1,col2__gte:v2,(col3__in:v3|v4;!col4:v5) ..."
+// @Param	fields	query	string	false	"Fields returned. e.g. col1,col2,rel1.col3 ..."
+// @Param	sortby	query	string	false	"Sorted-by fields. e.g. col1,col2 ..."
+// @Param	order	query	string	false	"Order corresponding to each sortby field, if single value, apply to all sortby fields. e.g. desc,asc ..."
+// @Param	limit	query	string	false	"Limit the size of result set. Must be an integer"
+// @Param	offset	query	string	false	"Start position of result set. Must be an integer"
+// @Param	expand	query	string	false	"Relations to load. e.g. rel1,rel2.rel3 ..."
+// @Success 200 {object} reqres.ListResponse
+// @Failure 401 caller is anonymous
+// @router / [get]
+func (c *InvoiceController) GetAll() {
+	spec, err := queryspec.Parse(c.Input())
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	spec.Role = roleOf(&c.Controller)
+
+	var l *models.Page
+	if spec.Role >= models.RoleStaff {
+		l, err = models.GetAllInvoice(spec)
+	} else {
+		l, err = models.GetGuestInvoices(callerOf(&c.Controller).GuestId, spec)
+	}
+	if err != nil {
+		if queryspec.IsError(err) {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		}
+		c.Data["json"] = err.Error()
+	} else {
+		c.Data["json"] = reqres.NewListResponse(c.Ctx.Request.URL, spec, l)
+	}
+	c.ServeJSON()
+}
+
+// GetPDF ...
+// @Title Get PDF
+// @Description download Invoice by id as a PDF document
+// @Param	X-Token	header	string	true	"Token of the guest or a staff member"
+// @Param	id		path 	string	true		"The key for staticblock"
+// @Success 200 {string} application/pdf
+// @Failure 404 :id doesn't exist or belongs to another guest
+// @router /:id/pdf [get]
+func (c *InvoiceController) GetPDF() {
+	idStr := c.Ctx.Input.Param(":id")
+	id, _ := strconv.Atoi(idStr)
+	v, err := models.GetInvoiceById(id)
+	if err != nil || !c.canSee(v) {
+		c.Ctx.Output.SetStatus(http.StatusNotFound)
+		c.Data["json"] = "Error: invoice doesn't exist"
+		c.ServeJSON()
+		return
+	}
+
+	b, err := invoice.PDF(v)
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusInternalServerError)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+
+	name := v.Number
+	if name == "" {
+		name = "invoice-" + idStr
+	}
+	c.Ctx.Output.Header("Content-Type", "application/pdf")
+	c.Ctx.Output.Header("Content-Disposition", `attachment; filename="`+name+`.pdf"`)
+	_ = c.Ctx.Output.Body(b)
+}
+
+// canSee reports whether the caller may read invoice v.
+func (c *InvoiceController) canSee(v *models.Invoice) bool {
+	return roleOf(&c.Controller) >= models.RoleStaff || v.GuestId.Id == callerOf(&c.Controller).GuestId
+}
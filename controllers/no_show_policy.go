@@ -0,0 +1,187 @@
+package controllers
+
+import (
+	"easybook/models"
+	"easybook/queryspec"
+	"easybook/reqres"
+	"easybook/services/noshow"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/astaxie/beego"
+)
+
+// NoShowPolicyController operations for NoShowPolicy. Policies are public,
+// staff maintain them.
+type NoShowPolicyController struct {
+	beego.Controller
+}
+
+// URLMapping ...
+func (c *NoShowPolicyController) URLMapping() {
+	c.Mapping("Post", c.Post)
+	c.Mapping("GetOne", c.GetOne)
+	c.Mapping("GetAll", c.GetAll)
+	c.Mapping("Put", c.Put)
+	c.Mapping("Delete", c.Delete)
+}
+
+// Prepare rejects changes by guests.
+func (c *NoShowPolicyController) Prepare() {
+	if c.Ctx.Request.Method != http.MethodGet && roleOf(&c.Controller) < models.RoleStaff {
+		c.Ctx.Output.SetStatus(http.StatusForbidden)
+		c.Data["json"] = "Error: staff only"
+		c.ServeJSON()
+		c.StopRun()
+	}
+}
+
+// Post ...
+// @Title Post
+// @Description create NoShowPolicy, the cut-off after which the guests of a hotel who did not arrive are no-show and the nights charged, staff only
+// @Param	X-Token	header	string	true	"Token of a staff"
+// @Param	body		body 	models.NoShowPolicy	true		"body for NoShowPolicy content"
+// @Success 201 {int} models.NoShowPolicy
+// @Failure 400 invalid policy
+// @Failure 403 caller is not staff
+// @router / [post]
+func (c *NoShowPolicyController) Post() {
+	var v models.NoShowPolicy
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &v); err == nil {
+		if err = noshow.Validate(&v); err != nil {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+			c.Data["json"] = err.Error()
+		} else if _, err := models.AddNoShowPolicy(&v); err == nil {
+			c.Ctx.Output.SetStatus(201)
+			c.Data["json"] = v
+		} else {
+			c.Data["json"] = err.Error()
+		}
+	} else {
+		c.Data["json"] = err.Error()
+	}
+	c.ServeJSON()
+}
+
+// GetOne ...
+// @Title Get One
+// @Description get NoShowPolicy by id
+// @Param	id		path 	string	true		"The key for staticblock"
+// @Param	fields	query	string	false	"Fields returned. e.g. col1,col2,rel1.col3 ..."
+// @Param	expand	query	string	false	"Relations to load. e.g. rel1,rel2.rel3 ..."
+// @Success 200 {object} models.NoShowPolicy
+// @Failure 403 :id is empty
+// @router /:id [get]
+func (c *NoShowPolicyController) GetOne() {
+	idStr := c.Ctx.Input.Param(":id")
+	id, _ := strconv.Atoi(idStr)
+	spec, err := queryspec.Parse(c.Input())
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	spec.Role = roleOf(&c.Controller)
+
+	var item interface{}
+	v, err := models.GetNoShowPolicyById(id)
+	if err == nil {
+		item, err = models.Present(v, spec)
+	}
+	if err != nil {
+		if queryspec.IsError(err) {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		}
+		c.Data["json"] = err.Error()
+	} else {
+		c.Data["json"] = item
+	}
+	c.ServeJSON()
+}
+
+// GetAll ...
+// @Title Get All
+// @Description get NoShowPolicy
+// @Param	query	query	string	false	"Filter. e.g. col1:v1,col2__gte:v2,(col3__in:v3|v4;!col4:v5) ..."
+// @Param	fields	query	string	false	"Fields returned. e.g. col1,col2,rel1.col3 ..."
+// @Param	sortby	query	string	false	"Sorted-by fields. e.g. col1,col2 ..."
+// @Param	order	query	string	false	"Order corresponding to each sortby field, if single value, apply to all sortby fields. e.g. desc,asc ..."
+// @Param	limit	query	string	false	"Limit the size of result set. Must be an integer"
+// @Param	offset	query	string	false	"Start position of result set. Must be an integer"
+// @Param	cursor	query	string	false	"Keyset paging cursor ordered by created_at,id. Empty to start, then the token of the next link"
+// @Param	expand	query	string	false	"Relations to load. e.g. rel1,rel2.rel3 ..."
+// @Success 200 {object} reqres.ListResponse
+// @Failure 403
+// @router / [get]
+func (c *NoShowPolicyController) GetAll() {
+	spec, err := queryspec.Parse(c.Input())
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	spec.Role = roleOf(&c.Controller)
+
+	l, err := models.GetAllNoShowPolicy(spec)
+	if err != nil {
+		if queryspec.IsError(err) {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		}
+		c.Data["json"] = err.Error()
+	} else {
+		c.Data["json"] = reqres.NewListResponse(c.Ctx.Request.URL, spec, l)
+	}
+	c.ServeJSON()
+}
+
+// Put ...
+// @Title Put
+// @Description update the NoShowPolicy, staff only
+// @Param	X-Token	header	string	true	"Token of a staff"
+// @Param	id		path 	string	true		"The id you want to update"
+// @Param	body		body 	models.NoShowPolicy	true		"body for NoShowPolicy content"
+// @Success 200 {object} models.NoShowPolicy
+// @Failure 400 invalid policy
+// @Failure 403 caller is not staff
+// @router /:id [put]
+func (c *NoShowPolicyController) Put() {
+	idStr := c.Ctx.Input.Param(":id")
+	id, _ := strconv.Atoi(idStr)
+	v := models.NoShowPolicy{Id: id}
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &v); err == nil {
+		v.Id = id
+		if err = noshow.Validate(&v); err != nil {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+			c.Data["json"] = err.Error()
+		} else if err := models.UpdateNoShowPolicyById(&v); err == nil {
+			c.Data["json"] = "OK"
+		} else {
+			c.Data["json"] = err.Error()
+		}
+	} else {
+		c.Data["json"] = err.Error()
+	}
+	c.ServeJSON()
+}
+
+// Delete ...
+// @Title Delete
+// @Description delete the NoShowPolicy, staff only
+// @Param	X-Token	header	string	true	"Token of a staff"
+// @Param	id		path 	string	true		"The id you want to delete"
+// @Success 200 {string} delete success!
+// @Failure 403 caller is not staff
+// @router /:id [delete]
+func (c *NoShowPolicyController) Delete() {
+	idStr := c.Ctx.Input.Param(":id")
+	id, _ := strconv.Atoi(idStr)
+	if err := models.DeleteNoShowPolicy(id); err == nil {
+		c.Data["json"] = "OK"
+	} else {
+		c.Data["json"] = err.Error()
+	}
+	c.ServeJSON()
+}
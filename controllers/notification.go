@@ -2,10 +2,11 @@ package controllers
 
 import (
 	"easybook/models"
+	"easybook/queryspec"
+	"easybook/reqres"
 	"encoding/json"
-	"errors"
+	"net/http"
 	"strconv"
-	"strings"
 
 	"github.com/astaxie/beego"
 )
@@ -24,9 +25,21 @@ func (c *NotificationController) URLMapping() {
 	c.Mapping("Delete", c.Delete)
 }
 
+// Prepare rejects callers other than staff, guests read their own
+// notifications through the inbox.
+func (c *NotificationController) Prepare() {
+	if roleOf(&c.Controller) < models.RoleStaff {
+		c.Ctx.Output.SetStatus(http.StatusForbidden)
+		c.Data["json"] = "Error: staff only"
+		c.ServeJSON()
+		c.StopRun()
+	}
+}
+
 // Post ...
 // @Title Post
 // @Description create Notification
+// @Param	X-Token	header	string	true	"Token of a staff"
 // @Param	body		body 	models.Notification	true		"body for Notification content"
 // @Success 201 {int} models.Notification
 // @Failure 403 body is empty
@@ -49,18 +62,37 @@ func (c *NotificationController) Post() {
 // GetOne ...
 // @Title Get One
 // @Description get Notification by id
+// @Param	X-Token	header	string	true	"Token of a staff"
 // @Param	id		path 	string	true		"The key for staticblock"
+// @Param	fields	query	string	false	"Fields returned. e.g. col1,col2,rel1.col3 ..."
+// @Param	expand	query	string	false	"Relations to load. e.g. rel1,rel2.rel3 ..."
 // @Success 200 {object} models.Notification
 // @Failure 403 :id is empty
 // @router /:id [get]
 func (c *NotificationController) GetOne() {
 	idStr := c.Ctx.Input.Param(":id")
 	id, _ := strconv.Atoi(idStr)
+	spec, err := queryspec.Parse(c.Input())
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	spec.Role = roleOf(&c.Controller)
+
+	var item interface{}
 	v, err := models.GetNotificationById(id)
+	if err == nil {
+		item, err = models.Present(v, spec)
+	}
 	if err != nil {
+		if queryspec.IsError(err) {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		}
 		c.Data["json"] = err.Error()
 	} else {
-		c.Data["json"] = v
+		c.Data["json"] = item
 	}
 	c.ServeJSON()
 }
@@ -68,62 +100,36 @@ func (c *NotificationController) GetOne() {
 // GetAll ...
 // @Title Get All
 // @Description get Notification
-// @Param	query	query	string	false	"Filter. e.g. col1:v1,col2:v2 ..."
-// @Param	fields	query	string	false	"Fields returned. e.g. col1,col2 ..."
+// @Param	X-Token	header	string	true	"Token of a staff"
+// @Param	query	query	string	false	"Filter. e.g. col1:v1,col2__gte:v2,(col3__in:v3|v4;!col4:v5) ..."
+// @Param	fields	query	string	false	"Fields returned. e.g. col1,col2,rel1.col3 ..."
 // @Param	sortby	query	string	false	"Sorted-by fields. e.g. col1,col2 ..."
 // @Param	order	query	string	false	"Order corresponding to each sortby field, if single value, apply to all sortby fields. e.g. desc,asc ..."
 // @Param	limit	query	string	false	"Limit the size of result set. Must be an integer"
 // @Param	offset	query	string	false	"Start position of result set. Must be an integer"
-// @Success 200 {object} models.Notification
+// @Param	cursor	query	string	false	"Keyset paging cursor ordered by created_at,id. Empty to start, then the token of the next link"
+// @Param	expand	query	string	false	"Relations to load. e.g. rel1,rel2.rel3 ..."
+// @Success 200 {object} reqres.ListResponse
 // @Failure 403
 // @router / [get]
 func (c *NotificationController) GetAll() {
-	var fields []string
-	var sortby []string
-	var order []string
-	var query = make(map[string]string)
-	var limit int64 = 10
-	var offset int64
-
-	// fields: col1,col2,entity.col3
-	if v := c.GetString("fields"); v != "" {
-		fields = strings.Split(v, ",")
-	}
-	// limit: 10 (default is 10)
-	if v, err := c.GetInt64("limit"); err == nil {
-		limit = v
-	}
-	// offset: 0 (default is 0)
-	if v, err := c.GetInt64("offset"); err == nil {
-		offset = v
-	}
-	// sortby: col1,col2
-	if v := c.GetString("sortby"); v != "" {
-		sortby = strings.Split(v, ",")
-	}
-	// order: desc,asc
-	if v := c.GetString("order"); v != "" {
-		order = strings.Split(v, ",")
-	}
-	// query: k:v,k:v
-	if v := c.GetString("query"); v != "" {
-		for _, cond := range strings.Split(v, ",") {
-			kv := strings.SplitN(cond, ":", 2)
-			if len(kv) != 2 {
-				c.Data["json"] = errors.New("Error: invalid query key/value pair")
-				c.ServeJSON()
-				return
-			}
-			k, v := kv[0], kv[1]
-			query[k] = v
-		}
+	spec, err := queryspec.Parse(c.Input())
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
 	}
+	spec.Role = roleOf(&c.Controller)
 
-	l, err := models.GetAllNotification(query, fields, sortby, order, offset, limit)
+	l, err := models.GetAllNotification(spec)
 	if err != nil {
+		if queryspec.IsError(err) {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		}
 		c.Data["json"] = err.Error()
 	} else {
-		c.Data["json"] = l
+		c.Data["json"] = reqres.NewListResponse(c.Ctx.Request.URL, spec, l)
 	}
 	c.ServeJSON()
 }
@@ -131,6 +137,7 @@ func (c *NotificationController) GetAll() {
 // Put ...
 // @Title Put
 // @Description update the Notification
+// @Param	X-Token	header	string	true	"Token of a staff"
 // @Param	id		path 	string	true		"The id you want to update"
 // @Param	body		body 	models.Notification	true		"body for Notification content"
 // @Success 200 {object} models.Notification
@@ -155,6 +162,7 @@ func (c *NotificationController) Put() {
 // Delete ...
 // @Title Delete
 // @Description delete the Notification
+// @Param	X-Token	header	string	true	"Token of a staff"
 // @Param	id		path 	string	true		"The id you want to delete"
 // @Success 200 {string} delete success!
 // @Failure 403 id is empty
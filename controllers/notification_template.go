@@ -0,0 +1,274 @@
+package controllers
+
+import (
+	"easybook/models"
+	"easybook/queryspec"
+	"easybook/reqres"
+	"easybook/services/notification"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/astaxie/beego"
+)
+
+// NotificationTemplateController operations for NotificationTemplate
+type NotificationTemplateController struct {
+	beego.Controller
+}
+
+// URLMapping ...
+func (c *NotificationTemplateController) URLMapping() {
+	c.Mapping("Post", c.Post)
+	c.Mapping("GetOne", c.GetOne)
+	c.Mapping("GetAll", c.GetAll)
+	c.Mapping("Put", c.Put)
+	c.Mapping("Delete", c.Delete)
+	c.Mapping("Preview", c.Preview)
+}
+
+// Prepare rejects callers other than staff, previews render the data of
+// any reservation.
+func (c *NotificationTemplateController) Prepare() {
+	if roleOf(&c.Controller) < models.RoleStaff {
+		c.Ctx.Output.SetStatus(http.StatusForbidden)
+		c.Data["json"] = "Error: staff only"
+		c.ServeJSON()
+		c.StopRun()
+	}
+}
+
+// Post ...
+// @Title Post
+// @Description create the first or next version of a NotificationTemplate
+// @Param	X-Token	header	string	true	"Token of a staff"
+// @Param	body		body 	models.NotificationTemplate	true		"body for NotificationTemplate content"
+// @Success 201 {int} models.NotificationTemplate
+// @Failure 400 body or template syntax is invalid
+// @router / [post]
+func (c *NotificationTemplateController) Post() {
+	var v models.NotificationTemplate
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &v); err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	c.addVersion(&v)
+}
+
+// GetOne ...
+// @Title Get One
+// @Description get NotificationTemplate by id
+// @Param	X-Token	header	string	true	"Token of a staff"
+// @Param	id		path 	string	true		"The key for staticblock"
+// @Param	fields	query	string	false	"Fields returned. e.g. col1,col2,rel1.col3 ..."
+// @Param	expand	query	string	false	"Relations to load. e.g. rel1,rel2.rel3 ..."
+// @Success 200 {object} models.NotificationTemplate
+// @Failure 403 :id is empty
+// @router /:id [get]
+func (c *NotificationTemplateController) GetOne() {
+	idStr := c.Ctx.Input.Param(":id")
+	id, _ := strconv.Atoi(idStr)
+	spec, err := queryspec.Parse(c.Input())
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	spec.Role = roleOf(&c.Controller)
+
+	var item interface{}
+	v, err := models.GetNotificationTemplateById(id)
+	if err == nil {
+		item, err = models.Present(v, spec)
+	}
+	if err != nil {
+		if queryspec.IsError(err) {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		}
+		c.Data["json"] = err.Error()
+	} else {
+		c.Data["json"] = item
+	}
+	c.ServeJSON()
+}
+
+// GetAll ...
+// @Title Get All
+// @Description get NotificationTemplate
+// @Param	X-Token	header	string	true	"Token of a staff"
+// @Param	query	query	string	false	"Filter. e.g. col1:v1,col2__gte:v2,(col3__in:v3|v4;!col4:v5) ..."
+// @Param	fields	query	string	false	"Fields returned. e.g. col1,col2,rel1.col3 ..."
+// @Param	sortby	query	string	false	"Sorted-by fields. e.g. col1,col2 ..."
+// @Param	order	query	string	false	"Order corresponding to each sortby field, if single value, apply to all sortby fields. e.g. desc,asc ..."
+// @Param	limit	query	string	false	"Limit the size of result set. Must be an integer"
+// @Param	offset	query	string	false	"Start position of result set. Must be an integer"
+// @Param	cursor	query	string	false	"Keyset paging cursor ordered by created_at,id. Empty to start, then the token of the next link"
+// @Param	expand	query	string	false	"Relations to load. e.g. rel1,rel2.rel3 ..."
+// @Success 200 {object} reqres.ListResponse
+// @Failure 403
+// @router / [get]
+func (c *NotificationTemplateController) GetAll() {
+	spec, err := queryspec.Parse(c.Input())
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	spec.Role = roleOf(&c.Controller)
+
+	l, err := models.GetAllNotificationTemplate(spec)
+	if err != nil {
+		if queryspec.IsError(err) {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		}
+		c.Data["json"] = err.Error()
+	} else {
+		c.Data["json"] = reqres.NewListResponse(c.Ctx.Request.URL, spec, l)
+	}
+	c.ServeJSON()
+}
+
+// Put ...
+// @Title Put
+// @Description store the NotificationTemplate content as a new version of the template
+// @Param	X-Token	header	string	true	"Token of a staff"
+// @Param	id		path 	string	true		"The id of a version of the template"
+// @Param	body		body 	models.NotificationTemplate	true		"body for NotificationTemplate content"
+// @Success 201 {object} models.NotificationTemplate
+// @Failure 400 body or template syntax is invalid
+// @router /:id [put]
+func (c *NotificationTemplateController) Put() {
+	idStr := c.Ctx.Input.Param(":id")
+	id, _ := strconv.Atoi(idStr)
+	old, err := models.GetNotificationTemplateById(id)
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusNotFound)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+
+	v := *old
+	if err = json.Unmarshal(c.Ctx.Input.RequestBody, &v); err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	// a version belongs to the template it was derived from
+	v.Name, v.Type, v.Language = old.Name, old.Type, old.Language
+	c.addVersion(&v)
+}
+
+// Delete ...
+// @Title Delete
+// @Description delete the NotificationTemplate
+// @Param	X-Token	header	string	true	"Token of a staff"
+// @Param	id		path 	string	true		"The id you want to delete"
+// @Success 200 {string} delete success!
+// @Failure 403 id is empty
+// @router /:id [delete]
+func (c *NotificationTemplateController) Delete() {
+	idStr := c.Ctx.Input.Param(":id")
+	id, _ := strconv.Atoi(idStr)
+	if err := models.DeleteNotificationTemplate(id); err == nil {
+		c.Data["json"] = "OK"
+	} else {
+		c.Data["json"] = err.Error()
+	}
+	c.ServeJSON()
+}
+
+// Preview ...
+// @Title Preview
+// @Description render the NotificationTemplate against a reservation
+// @Param	X-Token	header	string	true	"Token of a staff"
+// @Param	id		path 	string	true		"The id of the template version"
+// @Param	reservation	query	int	true	"The id of the reservation to render"
+// @Success 200 {object} reqres.NotificationTemplatePreviewResponse
+// @Failure 404 template or reservation doesn't exist
+// @router /:id/preview [get]
+func (c *NotificationTemplateController) Preview() {
+	res := reqres.NotificationTemplatePreviewResponse{}
+	res.SetCode(reqres.Fail)
+
+	defer func() {
+		c.Data["json"] = res
+		c.ServeJSON()
+	}()
+
+	idStr := c.Ctx.Input.Param(":id")
+	id, _ := strconv.Atoi(idStr)
+	reservationID, err := c.GetInt("reservation")
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		res.SetCode(reqres.InvalidParams)
+		return
+	}
+
+	t, err := models.GetNotificationTemplateById(id)
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusNotFound)
+		res.SetCode(reqres.RecordNotExist)
+		return
+	}
+	data, err := notification.LoadTemplateData(reservationID)
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusNotFound)
+		res.SetCode(reqres.RecordNotExist)
+		return
+	}
+	data.Notification = &models.Notification{
+		Type:          t.Type,
+		TriggerAt:     time.Now(),
+		ReservationId: data.Reservation,
+		Event:         t.Name,
+	}
+
+	subject, body, err := notification.Render(t, data)
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusUnprocessableEntity)
+		res.SetCode(reqres.InvalidParams)
+		res.Message = err.Error()
+		return
+	}
+
+	res.SetCode(reqres.Success)
+	res.Subject = subject
+	res.Body = body
+	res.Format = t.Format
+}
+
+func (c *NotificationTemplateController) addVersion(v *models.NotificationTemplate) {
+	if v.Format == "" {
+		v.Format = models.FormatText
+	}
+	if v.Language == "" {
+		v.Language = notification.DefaultLanguage()
+	}
+	if v.Name == "" || v.Type == "" || (v.Format != models.FormatText && v.Format != models.FormatHTML) {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = "Error: name, type and a format of text or html are required"
+		c.ServeJSON()
+		return
+	}
+	if err := notification.CheckTemplate(v); err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+
+	if _, err := models.AddNotificationTemplate(v); err == nil {
+		c.Ctx.Output.SetStatus(http.StatusCreated)
+		c.Data["json"] = v
+	} else {
+		c.Data["json"] = err.Error()
+	}
+	c.ServeJSON()
+}
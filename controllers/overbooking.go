@@ -0,0 +1,301 @@
+package controllers
+
+import (
+	"easybook/models"
+	"easybook/queryspec"
+	"easybook/reqres"
+	"easybook/services/availability"
+	"easybook/services/overbooking"
+	"easybook/types"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/orm"
+)
+
+// OverbookingController operations for OverbookingAllowance and
+// Relocation, staff only. Hotels reserve more rooms than they have up to
+// their allowance and relocate the guests who arrive in excess.
+type OverbookingController struct {
+	beego.Controller
+}
+
+// URLMapping ...
+func (c *OverbookingController) URLMapping() {
+	c.Mapping("PostAllowance", c.PostAllowance)
+	c.Mapping("GetAllowances", c.GetAllowances)
+	c.Mapping("PutAllowance", c.PutAllowance)
+	c.Mapping("DeleteAllowance", c.DeleteAllowance)
+	c.Mapping("Report", c.Report)
+	c.Mapping("Relocate", c.Relocate)
+	c.Mapping("GetRelocations", c.GetRelocations)
+}
+
+// Prepare rejects guests.
+func (c *OverbookingController) Prepare() {
+	if roleOf(&c.Controller) < models.RoleStaff {
+		c.Ctx.Output.SetStatus(http.StatusForbidden)
+		c.Data["json"] = "Error: staff only"
+		c.ServeJSON()
+		c.StopRun()
+	}
+}
+
+// PostAllowance ...
+// @Title Post Allowance
+// @Description create OverbookingAllowance, the rooms a hotel or one of its service levels may reserve more than it has for each night
+// @Param	X-Token	header	string	true	"Token of a staff"
+// @Param	body		body 	models.OverbookingAllowance	true		"body for OverbookingAllowance content"
+// @Success 201 {object} models.OverbookingAllowance
+// @Failure 400 invalid allowance or allowance exists already
+// @Failure 403 caller is not staff
+// @router /allowances [post]
+func (c *OverbookingController) PostAllowance() {
+	var v models.OverbookingAllowance
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &v); err == nil {
+		if err = overbooking.Validate(orm.NewOrm(), &v); err != nil {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+			c.Data["json"] = err.Error()
+		} else if _, err := models.AddOverbookingAllowance(&v); err == nil {
+			c.Ctx.Output.SetStatus(201)
+			c.Data["json"] = v
+		} else {
+			c.Data["json"] = err.Error()
+		}
+	} else {
+		c.Data["json"] = err.Error()
+	}
+	c.ServeJSON()
+}
+
+// GetAllowances ...
+// @Title Get Allowances
+// @Description get OverbookingAllowance
+// @Param	X-Token	header	string	true	"Token of a staff"
+// @Param	query	query	string	false	"Filter. e.g. col1:v1,col2__gte:v2,(col3__in:v3|v4;!col4:v5) ..."
+// @Param	fields	query	string	false	"Fields returned. e.g. col1,col2,rel1.col3 ..."
+// @Param	sortby	query	string	false	"Sorted-by fields. e.g. col1,col2 ..."
+// @Param	order	query	string	false	"Order corresponding to each sortby field, if single value, apply to all sortby fields. e.g. desc,asc ..."
+// @Param	limit	query	string	false	"Limit the size of result set. Must be an integer"
+// @Param	offset	query	string	false	"Start position of result set. Must be an integer"
+// @Param	cursor	query	string	false	"Keyset paging cursor ordered by created_at,id. Empty to start, then the token of the next link"
+// @Param	expand	query	string	false	"Relations to load. e.g. rel1,rel2.rel3 ..."
+// @Success 200 {object} reqres.ListResponse
+// @Failure 403 caller is not staff
+// @router /allowances [get]
+func (c *OverbookingController) GetAllowances() {
+	spec, err := queryspec.Parse(c.Input())
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	spec.Role = roleOf(&c.Controller)
+
+	l, err := models.GetAllOverbookingAllowance(spec)
+	if err != nil {
+		if queryspec.IsError(err) {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		}
+		c.Data["json"] = err.Error()
+	} else {
+		c.Data["json"] = reqres.NewListResponse(c.Ctx.Request.URL, spec, l)
+	}
+	c.ServeJSON()
+}
+
+// PutAllowance ...
+// @Title Put Allowance
+// @Description update the OverbookingAllowance
+// @Param	X-Token	header	string	true	"Token of a staff"
+// @Param	id		path 	string	true		"The id you want to update"
+// @Param	body		body 	models.OverbookingAllowance	true		"body for OverbookingAllowance content"
+// @Success 200 {object} models.OverbookingAllowance
+// @Failure 400 invalid allowance or allowance exists already
+// @Failure 403 caller is not staff
+// @router /allowances/:id [put]
+func (c *OverbookingController) PutAllowance() {
+	idStr := c.Ctx.Input.Param(":id")
+	id, _ := strconv.Atoi(idStr)
+	v := models.OverbookingAllowance{Id: id}
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &v); err == nil {
+		v.Id = id
+		if err = overbooking.Validate(orm.NewOrm(), &v); err != nil {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+			c.Data["json"] = err.Error()
+		} else if err := models.UpdateOverbookingAllowanceById(&v); err == nil {
+			c.Data["json"] = "OK"
+		} else {
+			c.Data["json"] = err.Error()
+		}
+	} else {
+		c.Data["json"] = err.Error()
+	}
+	c.ServeJSON()
+}
+
+// DeleteAllowance ...
+// @Title Delete Allowance
+// @Description delete the OverbookingAllowance, the hotel or service level is no longer overbooked
+// @Param	X-Token	header	string	true	"Token of a staff"
+// @Param	id		path 	string	true		"The id you want to delete"
+// @Success 200 {string} delete success!
+// @Failure 403 caller is not staff
+// @router /allowances/:id [delete]
+func (c *OverbookingController) DeleteAllowance() {
+	idStr := c.Ctx.Input.Param(":id")
+	id, _ := strconv.Atoi(idStr)
+	if err := models.DeleteOverbookingAllowance(id); err == nil {
+		c.Data["json"] = "OK"
+	} else {
+		c.Data["json"] = err.Error()
+	}
+	c.ServeJSON()
+}
+
+// Report ...
+// @Title Report
+// @Description get the nights a service level of the hotel has more rooms reserved than rooms, with the reserved rooms sharing a room to relocate
+// @Param	X-Token	header	string	true	"Token of a staff"
+// @Param	hotelId	query	int	true	"The id of the hotel"
+// @Param	from	query	string	true	"First night. e.g. 2020-12-01"
+// @Param	to	query	string	true	"Last night. e.g. 2020-12-31"
+// @Success 200 {object} reqres.OverbookingReportResponse
+// @Failure 400 invalid range
+// @Failure 403 caller is not staff
+// @router /report [get]
+func (c *OverbookingController) Report() {
+	res := reqres.OverbookingReportResponse{}
+	res.SetCode(reqres.Fail)
+
+	defer func() {
+		c.Data["json"] = res
+		c.ServeJSON()
+	}()
+
+	hotelID, err := c.GetInt("hotelId")
+	var from, to types.Date
+	if err == nil {
+		from, err = types.DateString(c.GetString("from"))
+	}
+	if err == nil {
+		to, err = types.DateString(c.GetString("to"))
+	}
+	if err == nil && (to.Time.Before(from.Time) || to.Time.Sub(from.Time) >= maxCalendarDays*24*time.Hour) {
+		err = fmt.Errorf("to must be on or after from, at most %d nights", maxCalendarDays)
+	}
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		res.SetCode(reqres.InvalidParams)
+		res.Message = err.Error()
+		return
+	}
+
+	nights, err := overbooking.Report(orm.NewOrm(), hotelID, from.Time, to.Time.AddDate(0, 0, 1))
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusInternalServerError)
+		res.Message = err.Error()
+		return
+	}
+	res.SetCode(reqres.Success)
+	res.Nights = nights
+}
+
+// Relocate ...
+// @Title Relocate
+// @Description move a reserved room to a vacant room for its whole stay at the price booked and notify the guest, when more guests arrive than the hotel has rooms
+// @Param	X-Token	header	string	true	"Token of a staff"
+// @Param	body		body 	reqres.RelocationPostRequest	true		"the reserved room and the vacant room"
+// @Success 201 {object} reqres.RelocationResponse
+// @Failure 400 room doesn't exist or is in another hotel
+// @Failure 403 caller is not staff
+// @Failure 404 reserved room doesn't exist
+// @Failure 409 room is not vacant, reservation is canceled or room is checked in
+// @router /relocations [post]
+func (c *OverbookingController) Relocate() {
+	res := reqres.RelocationResponse{}
+	res.SetCode(reqres.Fail)
+
+	defer func() {
+		c.Data["json"] = res
+		c.ServeJSON()
+	}()
+
+	var req reqres.RelocationPostRequest
+	if json.Unmarshal(c.Ctx.Input.RequestBody, &req) != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		res.SetCode(reqres.InvalidParams)
+		return
+	}
+
+	o := orm.NewOrm()
+	_ = o.Begin()
+	v, err := overbooking.Relocate(o, req.RoomReservedID, req.RoomID, req.Reason, callerOf(&c.Controller).GuestId, time.Now())
+	if err != nil {
+		_ = o.Rollback()
+		switch {
+		case err == orm.ErrNoRows:
+			c.Ctx.Output.SetStatus(http.StatusNotFound)
+			res.SetCode(reqres.RecordNotExist)
+		case err == availability.ErrUnavailable || err == overbooking.ErrCanceled ||
+			err == overbooking.ErrCheckedIn || err == overbooking.ErrSameRoom:
+			c.Ctx.Output.SetStatus(http.StatusConflict)
+			res.SetCode(reqres.InvalidParams)
+		case overbooking.IsError(err):
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+			res.SetCode(reqres.InvalidParams)
+		default:
+			c.Ctx.Output.SetStatus(http.StatusInternalServerError)
+			res.SetCode(reqres.FailedCreate)
+		}
+		res.Message = err.Error()
+		return
+	}
+	_ = o.Commit()
+
+	c.Ctx.Output.SetStatus(http.StatusCreated)
+	res.SetCode(reqres.Success)
+	res.Relocation = v
+}
+
+// GetRelocations ...
+// @Title Get Relocations
+// @Description get Relocation
+// @Param	X-Token	header	string	true	"Token of a staff"
+// @Param	query	query	string	false	"Filter. e.g. col1:v1,col2__gte:v2,(col3__in:v3|v4;!col4:v5) ..."
+// @Param	fields	query	string	false	"Fields returned. e.g. col1,col2,rel1.col3 ..."
+// @Param	sortby	query	string	false	"Sorted-by fields. e.g. col1,col2 ..."
+// @Param	order	query	string	false	"Order corresponding to each sortby field, if single value, apply to all sortby fields. e.g. desc,asc ..."
+// @Param	limit	query	string	false	"Limit the size of result set. Must be an integer"
+// @Param	offset	query	string	false	"Start position of result set. Must be an integer"
+// @Param	cursor	query	string	false	"Keyset paging cursor ordered by created_at,id. Empty to start, then the token of the next link"
+// @Param	expand	query	string	false	"Relations to load. e.g. rel1,rel2.rel3 ..."
+// @Success 200 {object} reqres.ListResponse
+// @Failure 403 caller is not staff
+// @router /relocations [get]
+func (c *OverbookingController) GetRelocations() {
+	spec, err := queryspec.Parse(c.Input())
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	spec.Role = roleOf(&c.Controller)
+
+	l, err := models.GetAllRelocation(spec)
+	if err != nil {
+		if queryspec.IsError(err) {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		}
+		c.Data["json"] = err.Error()
+	} else {
+		c.Data["json"] = reqres.NewListResponse(c.Ctx.Request.URL, spec, l)
+	}
+	c.ServeJSON()
+}
@@ -0,0 +1,379 @@
+package controllers
+
+import (
+	"easybook/models"
+	"easybook/queryspec"
+	"easybook/reqres"
+	"easybook/services/payment"
+	"easybook/types"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/orm"
+)
+
+// PaymentController operations for Payment. Guests pay and see the
+// payments of their own invoices, staff capture, void and refund them.
+type PaymentController struct {
+	beego.Controller
+}
+
+// URLMapping ...
+func (c *PaymentController) URLMapping() {
+	c.Mapping("Post", c.Post)
+	c.Mapping("GetOne", c.GetOne)
+	c.Mapping("GetAll", c.GetAll)
+	c.Mapping("Capture", c.Capture)
+	c.Mapping("Void", c.Void)
+	c.Mapping("Refund", c.Refund)
+	c.Mapping("Webhook", c.Webhook)
+}
+
+// Prepare rejects anonymous callers, webhooks are authenticated by the
+// provider signature instead.
+func (c *PaymentController) Prepare() {
+	if _, action := c.GetControllerAndAction(); action == "Webhook" {
+		return
+	}
+	if callerOf(&c.Controller) == nil {
+		c.Ctx.Output.SetStatus(http.StatusUnauthorized)
+		c.Data["json"] = "Error: X-Token is required"
+		c.ServeJSON()
+		c.StopRun()
+	}
+}
+
+// Post ...
+// @Title Post
+// @Description authorize, and optionally capture, a payment of an invoice of the caller
+// @Param	X-Token	header	string	true	"Token of the guest"
+// @Param	body		body 	reqres.PaymentPostRequest	true		"body for Payment content"
+// @Param	Idempotency-Key	header	string	false	"Unique key of the request, a retry with the same key returns the original response"
+// @Success 201 {object} reqres.PaymentResponse
+// @Failure 402 payment is declined
+// @Failure 409 key is in use by a request in progress or was used for a different request
+// @router / [post]
+func (c *PaymentController) Post() {
+	key, done := beginIdempotent(&c.Controller)
+	if done {
+		return
+	}
+
+	res := reqres.PaymentResponse{}
+	res.SetCode(reqres.Fail)
+
+	defer func() {
+		c.Data["json"] = res
+		finishIdempotent(&c.Controller, key)
+		c.ServeJSON()
+	}()
+
+	var req reqres.PaymentPostRequest
+	if json.Unmarshal(c.Ctx.Input.RequestBody, &req) != nil || req.Token == "" {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		res.SetCode(reqres.InvalidParams)
+		return
+	}
+
+	inv, err := models.GetInvoiceById(req.InvoiceID)
+	if err != nil || !c.owns(inv) {
+		c.Ctx.Output.SetStatus(http.StatusNotFound)
+		res.SetCode(reqres.RecordNotExist)
+		return
+	}
+	p, ok := payment.Default()
+	if !ok {
+		c.Ctx.Output.SetStatus(http.StatusInternalServerError)
+		res.SetCode(reqres.SystemError)
+		return
+	}
+
+	v, err := payment.Authorize(p, inv.Id, req.Amount, req.Token, c.Ctx.Input.Header("Idempotency-Key"), req.Capture, time.Now())
+	res.Payment = v
+	if err != nil {
+		c.serveError(&res.CommonResponse, err)
+		return
+	}
+
+	c.Ctx.Output.SetStatus(http.StatusCreated)
+	res.SetCode(reqres.Success)
+}
+
+// GetOne ...
+// @Title Get One
+// @Description get Payment by id with its refunds
+// @Param	X-Token	header	string	true	"Token of the guest or a staff member"
+// @Param	id		path 	string	true		"The key for staticblock"
+// @Success 200 {object} reqres.PaymentResponse
+// @Failure 404 :id doesn't exist or belongs to another guest
+// @router /:id [get]
+func (c *PaymentController) GetOne() {
+	res := reqres.PaymentResponse{}
+	res.SetCode(reqres.Fail)
+
+	defer func() {
+		c.Data["json"] = res
+		c.ServeJSON()
+	}()
+
+	idStr := c.Ctx.Input.Param(":id")
+	id, _ := strconv.Atoi(idStr)
+	v, err := models.GetPaymentById(id)
+	if err == nil {
+		var inv *models.Invoice
+		if inv, err = models.GetInvoiceById(v.InvoiceId.Id); err == nil && !c.owns(inv) {
+			err = orm.ErrNoRows
+		}
+	}
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusNotFound)
+		res.SetCode(reqres.RecordNotExist)
+		return
+	}
+	refunds, err := models.GetRefundsByPayment(orm.NewOrm(), v.Id)
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusInternalServerError)
+		res.SetCode(reqres.SystemError)
+		return
+	}
+
+	res.SetCode(reqres.Success)
+	res.Payment = v
+	res.Refunds = refunds
+}
+
+// GetAll ...
+// @Title Get All
+// @Description get the Payment of the invoices of the caller, or all of them for staff
+// @Param	X-Token	header	string	true	"Token of the guest or a staff member"
+// @Param	query	query	string	false	"Filter. e.g. col1:v1,col2__gte:v2,(col3__in:v3|v4;!col4:v5) ..."
+// @Param	fields	query	string	false	"Fields returned. e.g. col1,col2,rel1.col3 ..."
+// @Param	sortby	query	string	false	"Sorted-by fields. e.g. col1,col2 ..."
+// @Param	order	query	string	false	"Order corresponding to each sortby field, if single value, apply to all sortby fields. e.g. desc,asc ..."
+// @Param	limit	query	string	false	"Limit the size of result set. Must be an integer"
+// @Param	offset	query	string	false	"Start position of result set. Must be an integer"
+// @Param	cursor	query	string	false	"Keyset paging cursor ordered by created_at,id. Empty to start, then the token of the next link"
+// @Param	expand	query	string	false	"Relations to load. e.g. rel1,rel2.rel3 ..."
+// @Success 200 {object} reqres.ListResponse
+// @Failure 401 caller is anonymous
+// @router / [get]
+func (c *PaymentController) GetAll() {
+	spec, err := queryspec.Parse(c.Input())
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	spec.Role = roleOf(&c.Controller)
+
+	var l *models.Page
+	if spec.Role >= models.RoleStaff {
+		l, err = models.GetAllPayment(spec)
+	} else {
+		l, err = models.GetGuestPayments(callerOf(&c.Controller).GuestId, spec)
+	}
+	if err != nil {
+		if queryspec.IsError(err) {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		}
+		c.Data["json"] = err.Error()
+	} else {
+		c.Data["json"] = reqres.NewListResponse(c.Ctx.Request.URL, spec, l)
+	}
+	c.ServeJSON()
+}
+
+// Capture ...
+// @Title Capture
+// @Description capture the authorized Payment, staff only
+// @Param	X-Token	header	string	true	"Token of a staff member"
+// @Param	id		path 	string	true		"The id of the payment"
+// @Param	Idempotency-Key	header	string	false	"Unique key of the request, a retry with the same key returns the original response"
+// @Success 200 {object} reqres.PaymentResponse
+// @Failure 409 payment is not authorized
+// @router /:id/capture [post]
+func (c *PaymentController) Capture() {
+	c.staffOperation(payment.Capture)
+}
+
+// Void ...
+// @Title Void
+// @Description release the authorized Payment, staff only
+// @Param	X-Token	header	string	true	"Token of a staff member"
+// @Param	id		path 	string	true		"The id of the payment"
+// @Param	Idempotency-Key	header	string	false	"Unique key of the request, a retry with the same key returns the original response"
+// @Success 200 {object} reqres.PaymentResponse
+// @Failure 409 payment is not authorized
+// @router /:id/void [post]
+func (c *PaymentController) Void() {
+	c.staffOperation(payment.Void)
+}
+
+// Refund ...
+// @Title Refund
+// @Description refund the captured Payment, staff only
+// @Param	X-Token	header	string	true	"Token of a staff member"
+// @Param	id		path 	string	true		"The id of the payment"
+// @Param	body		body 	reqres.PaymentRefundRequest	false		"amount and reason of the refund"
+// @Param	Idempotency-Key	header	string	false	"Unique key of the request, a retry with the same key returns the original response"
+// @Success 201 {object} reqres.PaymentRefundResponse
+// @Failure 409 payment is not captured or amount exceeds it
+// @router /:id/refunds [post]
+func (c *PaymentController) Refund() {
+	key, done := beginIdempotent(&c.Controller)
+	if done {
+		return
+	}
+
+	res := reqres.PaymentRefundResponse{}
+	res.SetCode(reqres.Fail)
+
+	defer func() {
+		c.Data["json"] = res
+		finishIdempotent(&c.Controller, key)
+		c.ServeJSON()
+	}()
+
+	if roleOf(&c.Controller) < models.RoleStaff {
+		c.Ctx.Output.SetStatus(http.StatusForbidden)
+		res.SetCode(reqres.Forbidden)
+		return
+	}
+	var req reqres.PaymentRefundRequest
+	if len(c.Ctx.Input.RequestBody) != 0 && json.Unmarshal(c.Ctx.Input.RequestBody, &req) != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		res.SetCode(reqres.InvalidParams)
+		return
+	}
+
+	idStr := c.Ctx.Input.Param(":id")
+	id, _ := strconv.Atoi(idStr)
+	p, ok := c.providerOf(id)
+	if !ok {
+		c.Ctx.Output.SetStatus(http.StatusNotFound)
+		res.SetCode(reqres.RecordNotExist)
+		return
+	}
+	r, err := payment.Refund(p, id, req.Amount, req.Reason, time.Now())
+	res.Refund = r
+	if err != nil {
+		c.serveError(&res.CommonResponse, err)
+		return
+	}
+
+	c.Ctx.Output.SetStatus(http.StatusCreated)
+	res.SetCode(reqres.Success)
+}
+
+// Webhook ...
+// @Title Webhook
+// @Description receive an asynchronous outcome of a payment provider. Redelivered events are acknowledged without being applied again.
+// @Param	provider	path 	string	true		"The name of the provider"
+// @Success 200 {string} OK
+// @Failure 400 signature or event is invalid
+// @router /webhooks/:provider [post]
+func (c *PaymentController) Webhook() {
+	p, ok := payment.Lookup(c.Ctx.Input.Param(":provider"))
+	if !ok {
+		c.Ctx.Output.SetStatus(http.StatusNotFound)
+		c.Data["json"] = "Error: unknown provider"
+		c.ServeJSON()
+		return
+	}
+
+	_, err := payment.HandleWebhook(p, c.Ctx.Request.Header, c.Ctx.Input.RequestBody, time.Now())
+	switch {
+	case err == payment.ErrInvalidSignature:
+		c.Ctx.Output.SetStatus(http.StatusUnauthorized)
+		c.Data["json"] = err.Error()
+	case err == orm.ErrNoRows:
+		c.Ctx.Output.SetStatus(http.StatusNotFound)
+		c.Data["json"] = err.Error()
+	case err != nil:
+		// providers redeliver on failure
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+	default:
+		c.Data["json"] = "OK"
+	}
+	c.ServeJSON()
+}
+
+func (c *PaymentController) staffOperation(op func(payment.Provider, int, time.Time) (*models.Payment, error)) {
+	key, done := beginIdempotent(&c.Controller)
+	if done {
+		return
+	}
+
+	res := reqres.PaymentResponse{}
+	res.SetCode(reqres.Fail)
+
+	defer func() {
+		c.Data["json"] = res
+		finishIdempotent(&c.Controller, key)
+		c.ServeJSON()
+	}()
+
+	if roleOf(&c.Controller) < models.RoleStaff {
+		c.Ctx.Output.SetStatus(http.StatusForbidden)
+		res.SetCode(reqres.Forbidden)
+		return
+	}
+
+	idStr := c.Ctx.Input.Param(":id")
+	id, _ := strconv.Atoi(idStr)
+	p, ok := c.providerOf(id)
+	if !ok {
+		c.Ctx.Output.SetStatus(http.StatusNotFound)
+		res.SetCode(reqres.RecordNotExist)
+		return
+	}
+	v, err := op(p, id, time.Now())
+	if err != nil {
+		c.serveError(&res.CommonResponse, err)
+		return
+	}
+
+	res.SetCode(reqres.Success)
+	res.Payment = v
+}
+
+// providerOf returns the provider of the payment by id.
+func (c *PaymentController) providerOf(id int) (payment.Provider, bool) {
+	v, err := models.GetPaymentById(id)
+	if err != nil {
+		return nil, false
+	}
+	return payment.Lookup(v.Provider)
+}
+
+// owns reports whether the caller may pay or see the payments of inv.
+func (c *PaymentController) owns(inv *models.Invoice) bool {
+	return roleOf(&c.Controller) >= models.RoleStaff || inv.GuestId.Id == callerOf(&c.Controller).GuestId
+}
+
+func (c *PaymentController) serveError(res *reqres.CommonResponse, err error) {
+	switch err {
+	case payment.ErrDeclined:
+		c.Ctx.Output.SetStatus(http.StatusPaymentRequired)
+		res.SetCode(reqres.Fail)
+	case payment.ErrInvalidState:
+		c.Ctx.Output.SetStatus(http.StatusConflict)
+		res.SetCode(reqres.InvalidParams)
+	case types.ErrCurrencyMismatch:
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		res.SetCode(reqres.InvalidParams)
+	case orm.ErrNoRows:
+		c.Ctx.Output.SetStatus(http.StatusNotFound)
+		res.SetCode(reqres.RecordNotExist)
+		return
+	default:
+		c.Ctx.Output.SetStatus(http.StatusBadGateway)
+		res.SetCode(reqres.SystemError)
+	}
+	res.Message = err.Error()
+}
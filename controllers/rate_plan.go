@@ -0,0 +1,276 @@
+package controllers
+
+import (
+	"easybook/models"
+	"easybook/queryspec"
+	"easybook/reqres"
+	"easybook/services/pricing"
+	"easybook/types"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/orm"
+)
+
+// RatePlanController operations for RatePlan. Plans are public, staff
+// maintain them.
+type RatePlanController struct {
+	beego.Controller
+}
+
+// URLMapping ...
+func (c *RatePlanController) URLMapping() {
+	c.Mapping("Post", c.Post)
+	c.Mapping("SetCalendar", c.SetCalendar)
+	c.Mapping("GetOne", c.GetOne)
+	c.Mapping("GetAll", c.GetAll)
+	c.Mapping("Put", c.Put)
+	c.Mapping("Delete", c.Delete)
+}
+
+// Prepare rejects changes by guests.
+func (c *RatePlanController) Prepare() {
+	if c.Ctx.Request.Method != http.MethodGet && roleOf(&c.Controller) < models.RoleStaff {
+		c.Ctx.Output.SetStatus(http.StatusForbidden)
+		c.Data["json"] = "Error: staff only"
+		c.ServeJSON()
+		c.StopRun()
+	}
+}
+
+// Post ...
+// @Title Post
+// @Description create RatePlan of a room or a service level, staff only
+// @Param	X-Token	header	string	true	"Token of a staff"
+// @Param	body		body 	models.RatePlan	true		"body for RatePlan content"
+// @Success 201 {int} models.RatePlan
+// @Failure 400 invalid plan
+// @Failure 403 caller is not staff
+// @router / [post]
+func (c *RatePlanController) Post() {
+	var v models.RatePlan
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &v); err == nil {
+		if err = pricing.ValidatePlan(&v); err != nil {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+			c.Data["json"] = err.Error()
+		} else if _, err := models.AddRatePlan(&v); err == nil {
+			c.Ctx.Output.SetStatus(201)
+			c.Data["json"] = v
+		} else {
+			c.Data["json"] = err.Error()
+		}
+	} else {
+		c.Data["json"] = err.Error()
+	}
+	c.ServeJSON()
+}
+
+// SetCalendar ...
+// @Title Set Calendar
+// @Description set the rates of a room or of the rooms of a service level over ranges of nights, a plan per range, staff only
+// @Param	X-Token	header	string	true	"Token of a staff"
+// @Param	body		body 	reqres.RatePlanCalendarRequest	true		"body for the ranges"
+// @Success 201 {object} reqres.RatePlanCalendarResponse
+// @Failure 400 invalid range
+// @Failure 403 caller is not staff
+// @router /calendar [post]
+func (c *RatePlanController) SetCalendar() {
+	res := reqres.RatePlanCalendarResponse{}
+	res.SetCode(reqres.Fail)
+
+	defer func() {
+		c.Data["json"] = res
+		c.ServeJSON()
+	}()
+
+	var req reqres.RatePlanCalendarRequest
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &req)
+	if err == nil && len(req.Ranges) == 0 {
+		err = errors.New("ranges are required")
+	}
+	var plans []*models.RatePlan
+	for i := 0; err == nil && i < len(req.Ranges); i++ {
+		var v *models.RatePlan
+		if v, err = planOf(&req, &req.Ranges[i]); err == nil {
+			err = pricing.ValidatePlan(v)
+		}
+		plans = append(plans, v)
+	}
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		res.SetCode(reqres.InvalidParams)
+		res.Message = err.Error()
+		return
+	}
+
+	o := orm.NewOrm()
+	_ = o.Begin()
+	for _, v := range plans {
+		if _, err = o.Insert(v); err != nil {
+			c.Ctx.Output.SetStatus(http.StatusInternalServerError)
+			res.SetCode(reqres.FailedCreate)
+			_ = o.Rollback()
+			return
+		}
+	}
+	_ = o.Commit()
+
+	c.Ctx.Output.SetStatus(http.StatusCreated)
+	res.SetCode(reqres.Success)
+	res.Plans = plans
+}
+
+// planOf returns the plan of a range of the calendar.
+func planOf(req *reqres.RatePlanCalendarRequest, r *reqres.RatePlanRange) (*models.RatePlan, error) {
+	v := &models.RatePlan{
+		Name:       r.Name,
+		Price:      r.Price,
+		MinStay:    r.MinStay,
+		Priority:   r.Priority,
+		EffectFrom: r.From.Time,
+		ExpireOn:   r.To.Time.AddDate(0, 0, 1),
+	}
+	if req.RoomID != 0 {
+		v.RoomId = &models.Room{Id: req.RoomID}
+	}
+	if req.ServiceLevelID != 0 {
+		v.ServiceLevelId = &models.ServiceLevel{Id: req.ServiceLevelID}
+	}
+	for _, d := range r.Weekdays {
+		if d < int(time.Sunday) || d > int(time.Saturday) {
+			return nil, fmt.Errorf("weekday %d is not 0 (Sunday) to 6 (Saturday)", d)
+		}
+		v.Weekdays |= 1 << uint(d)
+	}
+	if v.Name == "" {
+		v.Name = fmt.Sprintf("%s to %s", r.From.Format(types.JSONDate), r.To.Format(types.JSONDate))
+	}
+	return v, nil
+}
+
+// GetOne ...
+// @Title Get One
+// @Description get RatePlan by id
+// @Param	id		path 	string	true		"The key for staticblock"
+// @Param	fields	query	string	false	"Fields returned. e.g. col1,col2,rel1.col3 ..."
+// @Param	expand	query	string	false	"Relations to load. e.g. rel1,rel2.rel3 ..."
+// @Success 200 {object} models.RatePlan
+// @Failure 403 :id is empty
+// @router /:id [get]
+func (c *RatePlanController) GetOne() {
+	idStr := c.Ctx.Input.Param(":id")
+	id, _ := strconv.Atoi(idStr)
+	spec, err := queryspec.Parse(c.Input())
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	spec.Role = roleOf(&c.Controller)
+
+	var item interface{}
+	v, err := models.GetRatePlanById(id)
+	if err == nil {
+		item, err = models.Present(v, spec)
+	}
+	if err != nil {
+		if queryspec.IsError(err) {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		}
+		c.Data["json"] = err.Error()
+	} else {
+		c.Data["json"] = item
+	}
+	c.ServeJSON()
+}
+
+// GetAll ...
+// @Title Get All
+// @Description get RatePlan
+// @Param	query	query	string	false	"Filter. e.g. col1:v1,col2__gte:v2,(col3__in:v3|v4;!col4:v5) ..."
+// @Param	fields	query	string	false	"Fields returned. e.g. col1,col2,rel1.col3 ..."
+// @Param	sortby	query	string	false	"Sorted-by fields. e.g. col1,col2 ..."
+// @Param	order	query	string	false	"Order corresponding to each sortby field, if single value, apply to all sortby fields. e.g. desc,asc ..."
+// @Param	limit	query	string	false	"Limit the size of result set. Must be an integer"
+// @Param	offset	query	string	false	"Start position of result set. Must be an integer"
+// @Param	cursor	query	string	false	"Keyset paging cursor ordered by created_at,id. Empty to start, then the token of the next link"
+// @Param	expand	query	string	false	"Relations to load. e.g. rel1,rel2.rel3 ..."
+// @Success 200 {object} reqres.ListResponse
+// @Failure 403
+// @router / [get]
+func (c *RatePlanController) GetAll() {
+	spec, err := queryspec.Parse(c.Input())
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	spec.Role = roleOf(&c.Controller)
+
+	l, err := models.GetAllRatePlan(spec)
+	if err != nil {
+		if queryspec.IsError(err) {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		}
+		c.Data["json"] = err.Error()
+	} else {
+		c.Data["json"] = reqres.NewListResponse(c.Ctx.Request.URL, spec, l)
+	}
+	c.ServeJSON()
+}
+
+// Put ...
+// @Title Put
+// @Description update the RatePlan, staff only
+// @Param	X-Token	header	string	true	"Token of a staff"
+// @Param	id		path 	string	true		"The id you want to update"
+// @Param	body		body 	models.RatePlan	true		"body for RatePlan content"
+// @Success 200 {object} models.RatePlan
+// @Failure 400 invalid plan
+// @Failure 403 caller is not staff
+// @router /:id [put]
+func (c *RatePlanController) Put() {
+	idStr := c.Ctx.Input.Param(":id")
+	id, _ := strconv.Atoi(idStr)
+	v := models.RatePlan{Id: id}
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &v); err == nil {
+		v.Id = id
+		if err = pricing.ValidatePlan(&v); err != nil {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+			c.Data["json"] = err.Error()
+		} else if err := models.UpdateRatePlanById(&v); err == nil {
+			c.Data["json"] = "OK"
+		} else {
+			c.Data["json"] = err.Error()
+		}
+	} else {
+		c.Data["json"] = err.Error()
+	}
+	c.ServeJSON()
+}
+
+// Delete ...
+// @Title Delete
+// @Description delete the RatePlan, staff only
+// @Param	X-Token	header	string	true	"Token of a staff"
+// @Param	id		path 	string	true		"The id you want to delete"
+// @Success 200 {string} delete success!
+// @Failure 403 caller is not staff
+// @router /:id [delete]
+func (c *RatePlanController) Delete() {
+	idStr := c.Ctx.Input.Param(":id")
+	id, _ := strconv.Atoi(idStr)
+	if err := models.DeleteRatePlan(id); err == nil {
+		c.Data["json"] = "OK"
+	} else {
+		c.Data["json"] = err.Error()
+	}
+	c.ServeJSON()
+}
@@ -2,12 +2,22 @@ package controllers
 
 import (
 	"easybook/models"
+	"easybook/queryspec"
+	"easybook/reqres"
+	"easybook/services/availability"
+	"easybook/services/exchange"
+	"easybook/services/invoice"
+	"easybook/services/modification"
+	"easybook/services/notification"
+	"easybook/services/promotion"
+	"easybook/services/waitlist"
 	"encoding/json"
-	"errors"
+	"net/http"
 	"strconv"
-	"strings"
+	"time"
 
 	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/orm"
 )
 
 // ReservationController operations for Reservation
@@ -22,6 +32,9 @@ func (c *ReservationController) URLMapping() {
 	c.Mapping("GetAll", c.GetAll)
 	c.Mapping("Put", c.Put)
 	c.Mapping("Delete", c.Delete)
+	c.Mapping("Cancel", c.Cancel)
+	c.Mapping("Modify", c.Modify)
+	c.Mapping("GetChanges", c.GetChanges)
 }
 
 // Post ...
@@ -50,17 +63,46 @@ func (c *ReservationController) Post() {
 // @Title Get One
 // @Description get Reservation by id
 // @Param	id		path 	string	true		"The key for staticblock"
+// @Param	fields	query	string	false	"Fields returned. e.g. col1,col2,rel1.col3 ..."
+// @Param	expand	query	string	false	"Relations to load. e.g. rel1,rel2.rel3 ..."
+// @Param	currency	query	string	false	"Currency of DisplayPrice, an ISO 4217 code. e.g. USD"
 // @Success 200 {object} models.Reservation
 // @Failure 403 :id is empty
 // @router /:id [get]
 func (c *ReservationController) GetOne() {
 	idStr := c.Ctx.Input.Param(":id")
 	id, _ := strconv.Atoi(idStr)
+	spec, err := queryspec.Parse(c.Input())
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	spec.Role = roleOf(&c.Controller)
+	cv, err := converterOf(&c.Controller)
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+
+	var item interface{}
 	v, err := models.GetReservationById(id)
+	if err == nil {
+		item, err = models.Present(v, spec)
+	}
+	if err == nil && cv != nil {
+		item, err = displayItem(cv, item)
+	}
 	if err != nil {
+		if queryspec.IsError(err) || err == exchange.ErrNoRate {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		}
 		c.Data["json"] = err.Error()
 	} else {
-		c.Data["json"] = v
+		c.Data["json"] = item
 	}
 	c.ServeJSON()
 }
@@ -68,86 +110,299 @@ func (c *ReservationController) GetOne() {
 // GetAll ...
 // @Title Get All
 // @Description get Reservation
-// @Param	query	query	string	false	"Filter. e.g. col1:v1,col2:v2 ..."
-// @Param	fields	query	string	false	"Fields returned. e.g. col1,col2 ..."
+// @Param	query	query	string	false	"Filter. e.g. col1:v1,col2__gte:v2,(col3__in:v3|v4;!col4:v5) ..."
+// @Param	fields	query	string	false	"Fields returned. e.g. col1,col2,rel1.col3 ..."
 // @Param	sortby	query	string	false	"Sorted-by fields. e.g. col1,col2 ..."
 // @Param	order	query	string	false	"Order corresponding to each sortby field, if single value, apply to all sortby fields. e.g. desc,asc ..."
 // @Param	limit	query	string	false	"Limit the size of result set. Must be an integer"
 // @Param	offset	query	string	false	"Start position of result set. Must be an integer"
-// @Success 200 {object} models.Reservation
+// @Param	cursor	query	string	false	"Keyset paging cursor ordered by created_at,id. Empty to start, then the token of the next link"
+// @Param	expand	query	string	false	"Relations to load. e.g. rel1,rel2.rel3 ..."
+// @Param	currency	query	string	false	"Currency of DisplayPrice, an ISO 4217 code. e.g. USD"
+// @Success 200 {object} reqres.ListResponse
 // @Failure 403
 // @router / [get]
 func (c *ReservationController) GetAll() {
-	var fields []string
-	var sortby []string
-	var order []string
-	var query = make(map[string]string)
-	var limit int64 = 10
-	var offset int64
-
-	// fields: col1,col2,entity.col3
-	if v := c.GetString("fields"); v != "" {
-		fields = strings.Split(v, ",")
-	}
-	// limit: 10 (default is 10)
-	if v, err := c.GetInt64("limit"); err == nil {
-		limit = v
-	}
-	// offset: 0 (default is 0)
-	if v, err := c.GetInt64("offset"); err == nil {
-		offset = v
-	}
-	// sortby: col1,col2
-	if v := c.GetString("sortby"); v != "" {
-		sortby = strings.Split(v, ",")
-	}
-	// order: desc,asc
-	if v := c.GetString("order"); v != "" {
-		order = strings.Split(v, ",")
-	}
-	// query: k:v,k:v
-	if v := c.GetString("query"); v != "" {
-		for _, cond := range strings.Split(v, ",") {
-			kv := strings.SplitN(cond, ":", 2)
-			if len(kv) != 2 {
-				c.Data["json"] = errors.New("Error: invalid query key/value pair")
-				c.ServeJSON()
-				return
-			}
-			k, v := kv[0], kv[1]
-			query[k] = v
-		}
+	spec, err := queryspec.Parse(c.Input())
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	spec.Role = roleOf(&c.Controller)
+	cv, err := converterOf(&c.Controller)
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
 	}
 
-	l, err := models.GetAllReservation(query, fields, sortby, order, offset, limit)
+	l, err := models.GetAllReservation(spec)
+	if err == nil && cv != nil {
+		err = displayItems(cv, l.Items)
+	}
 	if err != nil {
+		if queryspec.IsError(err) || err == exchange.ErrNoRate {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		}
 		c.Data["json"] = err.Error()
 	} else {
-		c.Data["json"] = l
+		c.Data["json"] = reqres.NewListResponse(c.Ctx.Request.URL, spec, l)
 	}
 	c.ServeJSON()
 }
 
 // Put ...
 // @Title Put
-// @Description update the Reservation
+// @Description update the Reservation. Only the status is changed, guests can cancel their reservations and staff set any status. Prices and discounts are kept
+// @Param	X-Token	header	string	true	"Token of the guest or a staff member"
 // @Param	id		path 	string	true		"The id you want to update"
 // @Param	body		body 	models.Reservation	true		"body for Reservation content"
 // @Success 200 {object} models.Reservation
-// @Failure 403 :id is not int
+// @Failure 400 body is invalid
+// @Failure 401 no token
+// @Failure 404 :id doesn't exist or belongs to another guest
+// @Failure 409 dates differ, they are changed by /:id/modify
 // @router /:id [put]
 func (c *ReservationController) Put() {
 	idStr := c.Ctx.Input.Param(":id")
 	id, _ := strconv.Atoi(idStr)
-	v := models.Reservation{Id: id}
-	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &v); err == nil {
-		if err := models.UpdateReservationById(&v); err == nil {
-			c.Data["json"] = "OK"
-		} else {
-			c.Data["json"] = err.Error()
+	if callerOf(&c.Controller) == nil {
+		c.Ctx.Output.SetStatus(http.StatusUnauthorized)
+		c.Data["json"] = "Error: token required"
+		c.ServeJSON()
+		return
+	}
+
+	o := orm.NewOrm()
+	_ = o.Begin()
+	old := models.Reservation{Id: id}
+	if err := o.ReadForUpdate(&old); err != nil || !c.owns(&old) {
+		_ = o.Rollback()
+		c.Ctx.Output.SetStatus(http.StatusNotFound)
+		c.Data["json"] = "Error: reservation not found"
+		c.ServeJSON()
+		return
+	}
+
+	req := struct {
+		StartDate time.Time
+		EndDate   time.Time
+		Status    *uint8
+	}{StartDate: old.StartDate, EndDate: old.EndDate}
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &req); err != nil {
+		_ = o.Rollback()
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	if day(old.StartDate) != day(req.StartDate) || day(old.EndDate) != day(req.EndDate) {
+		// the rooms reserved, prices and invoice follow the dates
+		_ = o.Rollback()
+		c.Ctx.Output.SetStatus(http.StatusConflict)
+		c.Data["json"] = "Error: dates are changed by POST /v1/reservations/:id/modify"
+		c.ServeJSON()
+		return
+	}
+
+	// only the status is written, prices and discounts are kept and
+	// guests can only cancel
+	v := old
+	if req.Status != nil && (roleOf(&c.Controller) >= models.RoleStaff || *req.Status == models.ReservationCancelled) {
+		v.Status = *req.Status
+	}
+	if _, err := o.Update(&v, "Status"); err != nil {
+		_ = o.Rollback()
+		c.Ctx.Output.SetStatus(http.StatusInternalServerError)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	if err := reservationChanged(o, &old, &v, time.Now()); err != nil {
+		_ = o.Rollback()
+		c.Ctx.Output.SetStatus(http.StatusInternalServerError)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	_ = o.Commit()
+
+	c.Data["json"] = "OK"
+	c.ServeJSON()
+}
+
+// Cancel ...
+// @Title Cancel
+// @Description cancel the Reservation and its pending notifications
+// @Param	X-Token	header	string	true	"Token of the guest or a staff member"
+// @Param	id		path 	string	true		"The id you want to cancel"
+// @Success 200 {object} models.Reservation
+// @Failure 401 no token
+// @Failure 404 :id doesn't exist or belongs to another guest
+// @router /:id/cancel [post]
+func (c *ReservationController) Cancel() {
+	idStr := c.Ctx.Input.Param(":id")
+	id, _ := strconv.Atoi(idStr)
+	if callerOf(&c.Controller) == nil {
+		c.Ctx.Output.SetStatus(http.StatusUnauthorized)
+		c.Data["json"] = "Error: token required"
+		c.ServeJSON()
+		return
+	}
+
+	o := orm.NewOrm()
+	_ = o.Begin()
+	old := models.Reservation{Id: id}
+	if err := o.ReadForUpdate(&old); err != nil || !c.owns(&old) {
+		_ = o.Rollback()
+		c.Ctx.Output.SetStatus(http.StatusNotFound)
+		c.Data["json"] = "Error: reservation not found"
+		c.ServeJSON()
+		return
+	}
+	v := old
+	v.Status = models.ReservationCancelled
+	if _, err := o.Update(&v, "Status"); err != nil {
+		_ = o.Rollback()
+		c.Ctx.Output.SetStatus(http.StatusInternalServerError)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	if err := reservationChanged(o, &old, &v, time.Now()); err != nil {
+		_ = o.Rollback()
+		c.Ctx.Output.SetStatus(http.StatusInternalServerError)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	_ = o.Commit()
+
+	c.Data["json"] = v
+	c.ServeJSON()
+}
+
+// Modify ...
+// @Title Modify
+// @Description change the dates or rooms of the Reservation. The rooms are checked free, the nights added are priced at the current rates, the unpaid invoice is updated and the change is recorded
+// @Param	X-Token	header	string	true	"Token of the guest or a staff member"
+// @Param	id		path 	string	true		"The id you want to modify"
+// @Param	body		body 	reqres.ReservationModifyRequest	true		"new dates or rooms, the others are kept"
+// @Success 200 {object} reqres.ReservationModifyResponse
+// @Failure 400 dates, rooms or minimum stay are invalid
+// @Failure 404 :id doesn't exist or belongs to another guest
+// @Failure 409 rooms are not available, a room is checked in or the invoice is paid
+// @router /:id/modify [post]
+func (c *ReservationController) Modify() {
+	res := reqres.ReservationModifyResponse{}
+	res.SetCode(reqres.Fail)
+
+	defer func() {
+		c.Data["json"] = res
+		c.ServeJSON()
+	}()
+
+	caller := callerOf(&c.Controller)
+	if caller == nil {
+		c.Ctx.Output.SetStatus(http.StatusUnauthorized)
+		res.SetCode(reqres.Unauthorized)
+		return
+	}
+	var req reqres.ReservationModifyRequest
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &req); err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		res.SetCode(reqres.InvalidParams)
+		return
+	}
+
+	idStr := c.Ctx.Input.Param(":id")
+	id, _ := strconv.Atoi(idStr)
+	o := orm.NewOrm()
+	_ = o.Begin()
+	v := &models.Reservation{Id: id}
+	if err := o.ReadForUpdate(v); err != nil || !c.owns(v) {
+		_ = o.Rollback()
+		c.Ctx.Output.SetStatus(http.StatusNotFound)
+		res.SetCode(reqres.RecordNotExist)
+		return
+	}
+	change, breakdown, err := modification.Modify(o, v, &modification.Change{
+		StartDate: req.StartDate.Time,
+		EndDate:   req.EndDate.Time,
+		Rooms:     req.Rooms,
+		Reason:    req.Reason,
+		ChangedBy: caller.GuestId,
+	}, time.Now())
+	if err != nil {
+		_ = o.Rollback()
+		switch {
+		case err == availability.ErrUnavailable || err == modification.ErrCheckedIn ||
+			err == modification.ErrCanceled || err == invoice.ErrPaid:
+			c.Ctx.Output.SetStatus(http.StatusConflict)
+			res.SetCode(reqres.InvalidParams)
+		case modification.IsError(err):
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+			res.SetCode(reqres.InvalidParams)
+		default:
+			c.Ctx.Output.SetStatus(http.StatusInternalServerError)
+			res.SetCode(reqres.FailedUpdate)
+		}
+		res.Message = err.Error()
+		return
+	}
+	_ = o.Commit()
+
+	res.SetCode(reqres.Success)
+	res.Reservation = v
+	res.Change = change
+	res.Breakdown = breakdown
+}
+
+// GetChanges ...
+// @Title Get Changes
+// @Description get the changes of dates and rooms of the Reservation
+// @Param	X-Token	header	string	true	"Token of the guest or a staff member"
+// @Param	id		path 	string	true		"The id of the reservation"
+// @Param	query	query	string	false	"Filter. e.g. col1:v1,col2__gte:v2,(col3__in:v3|v4;!col4:v5) ..."
+// @Param	fields	query	string	false	"Fields returned. e.g. col1,col2,rel1.col3 ..."
+// @Param	sortby	query	string	false	"Sorted-by fields. e.g. col1,col2 ..."
+// @Param	order	query	string	false	"Order corresponding to each sortby field, if single value, apply to all sortby fields. e.g. desc,asc ..."
+// @Param	limit	query	string	false	"Limit the size of result set. Must be an integer"
+// @Param	offset	query	string	false	"Start position of result set. Must be an integer"
+// @Param	cursor	query	string	false	"Keyset paging cursor ordered by created_at,id. Empty to start, then the token of the next link"
+// @Success 200 {object} reqres.ListResponse
+// @Failure 404 :id doesn't exist or belongs to another guest
+// @router /:id/changes [get]
+func (c *ReservationController) GetChanges() {
+	idStr := c.Ctx.Input.Param(":id")
+	id, _ := strconv.Atoi(idStr)
+	v, err := models.GetReservationById(id)
+	if err != nil || callerOf(&c.Controller) == nil || !c.owns(v) {
+		c.Ctx.Output.SetStatus(http.StatusNotFound)
+		c.Data["json"] = "Error: reservation doesn't exist"
+		c.ServeJSON()
+		return
+	}
+	spec, err := queryspec.Parse(c.Input())
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	spec.Role = roleOf(&c.Controller)
+
+	l, err := models.GetReservationChanges(id, spec)
+	if err != nil {
+		if queryspec.IsError(err) {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
 		}
-	} else {
 		c.Data["json"] = err.Error()
+	} else {
+		c.Data["json"] = reqres.NewListResponse(c.Ctx.Request.URL, spec, l)
 	}
 	c.ServeJSON()
 }
@@ -169,3 +424,49 @@ func (c *ReservationController) Delete() {
 	}
 	c.ServeJSON()
 }
+
+// owns reports whether the caller may change or see v, callers must be
+// known.
+func (c *ReservationController) owns(v *models.Reservation) bool {
+	return roleOf(&c.Controller) >= models.RoleStaff || v.GuestId.Id == callerOf(&c.Controller).GuestId
+}
+
+// day returns the date of t, e.g. 2006-01-02.
+func day(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+// reservationChanged schedules the notifications and issues or cancels the
+// invoice following a reservation update from old to v. Canceling releases
+// the promotion codes redeemed and offers the rooms to the waitlist.
+func reservationChanged(o orm.Ormer, old *models.Reservation, v *models.Reservation, now time.Time) error {
+	if err := notification.Changed(o, old, v, now); err != nil {
+		return err
+	}
+	if old.Status == v.Status {
+		return nil
+	}
+
+	switch v.Status {
+	case models.ReservationConfirmed:
+		hotelID, err := models.GetReservationHotelId(o, v.Id)
+		if err != nil && err != orm.ErrNoRows {
+			return err
+		}
+		_, err = invoice.Generate(o, v, hotelID, now)
+		return err
+	case models.ReservationCancelled:
+		if err := promotion.Release(o, v.Id, now); err != nil {
+			return err
+		}
+		if err := invoice.Cancel(o, v.Id, now); err != nil {
+			return err
+		}
+		hotelID, err := models.GetReservationHotelId(o, v.Id)
+		if err != nil && err != orm.ErrNoRows {
+			return err
+		}
+		return waitlist.Freed(o, v, hotelID, now)
+	}
+	return nil
+}
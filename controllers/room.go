@@ -2,14 +2,24 @@ package controllers
 
 import (
 	"easybook/models"
+	"easybook/queryspec"
+	"easybook/reqres"
+	"easybook/services/exchange"
+	"easybook/services/pricing"
+	"easybook/types"
 	"encoding/json"
-	"errors"
+	"fmt"
+	"net/http"
 	"strconv"
-	"strings"
+	"time"
 
 	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/orm"
 )
 
+// maxCalendarDays is the longest range of nights of a calendar.
+const maxCalendarDays = 366
+
 // RoomController operations for Room
 type RoomController struct {
 	beego.Controller
@@ -20,6 +30,7 @@ func (c *RoomController) URLMapping() {
 	c.Mapping("Post", c.Post)
 	c.Mapping("GetOne", c.GetOne)
 	c.Mapping("GetAll", c.GetAll)
+	c.Mapping("Calendar", c.Calendar)
 	c.Mapping("Put", c.Put)
 	c.Mapping("Delete", c.Delete)
 }
@@ -34,7 +45,10 @@ func (c *RoomController) URLMapping() {
 func (c *RoomController) Post() {
 	var v models.Room
 	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &v); err == nil {
-		if _, err := models.AddRoom(&v); err == nil {
+		if err = types.Stored(v.CurrentPrice); err != nil {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+			c.Data["json"] = err.Error()
+		} else if _, err := models.AddRoom(&v); err == nil {
 			c.Ctx.Output.SetStatus(201)
 			c.Data["json"] = v
 		} else {
@@ -50,17 +64,46 @@ func (c *RoomController) Post() {
 // @Title Get One
 // @Description get Room by id
 // @Param	id		path 	string	true		"The key for staticblock"
+// @Param	fields	query	string	false	"Fields returned. e.g. col1,col2,rel1.col3 ..."
+// @Param	expand	query	string	false	"Relations to load. e.g. rel1,rel2.rel3 ..."
+// @Param	currency	query	string	false	"Currency of DisplayPrice, an ISO 4217 code. e.g. USD"
 // @Success 200 {object} models.Room
 // @Failure 403 :id is empty
 // @router /:id [get]
 func (c *RoomController) GetOne() {
 	idStr := c.Ctx.Input.Param(":id")
 	id, _ := strconv.Atoi(idStr)
+	spec, err := queryspec.Parse(c.Input())
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	spec.Role = roleOf(&c.Controller)
+	cv, err := converterOf(&c.Controller)
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+
+	var item interface{}
 	v, err := models.GetRoomById(id)
+	if err == nil {
+		item, err = models.Present(v, spec)
+	}
+	if err == nil && cv != nil {
+		item, err = displayItem(cv, item)
+	}
 	if err != nil {
+		if queryspec.IsError(err) || err == exchange.ErrNoRate {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		}
 		c.Data["json"] = err.Error()
 	} else {
-		c.Data["json"] = v
+		c.Data["json"] = item
 	}
 	c.ServeJSON()
 }
@@ -68,66 +111,104 @@ func (c *RoomController) GetOne() {
 // GetAll ...
 // @Title Get All
 // @Description get Room
-// @Param	query	query	string	false	"Filter. e.g. col1:v1,col2:v2 ..."
-// @Param	fields	query	string	false	"Fields returned. e.g. col1,col2 ..."
+// @Param	query	query	string	false	"Filter. e.g. col1:v1,col2__gte:v2,(col3__in:v3|v4;!col4:v5) ..."
+// @Param	fields	query	string	false	"Fields returned. e.g. col1,col2,rel1.col3 ..."
 // @Param	sortby	query	string	false	"Sorted-by fields. e.g. col1,col2 ..."
 // @Param	order	query	string	false	"Order corresponding to each sortby field, if single value, apply to all sortby fields. e.g. desc,asc ..."
 // @Param	limit	query	string	false	"Limit the size of result set. Must be an integer"
 // @Param	offset	query	string	false	"Start position of result set. Must be an integer"
-// @Success 200 {object} models.Room
+// @Param	cursor	query	string	false	"Keyset paging cursor ordered by created_at,id. Empty to start, then the token of the next link"
+// @Param	expand	query	string	false	"Relations to load. e.g. rel1,rel2.rel3 ..."
+// @Param	currency	query	string	false	"Currency of DisplayPrice, an ISO 4217 code. e.g. USD"
+// @Success 200 {object} reqres.ListResponse
 // @Failure 403
 // @router / [get]
 func (c *RoomController) GetAll() {
-	var fields []string
-	var sortby []string
-	var order []string
-	var query = make(map[string]string)
-	var limit int64 = 10
-	var offset int64
-
-	// fields: col1,col2,entity.col3
-	if v := c.GetString("fields"); v != "" {
-		fields = strings.Split(v, ",")
-	}
-	// limit: 10 (default is 10)
-	if v, err := c.GetInt64("limit"); err == nil {
-		limit = v
-	}
-	// offset: 0 (default is 0)
-	if v, err := c.GetInt64("offset"); err == nil {
-		offset = v
-	}
-	// sortby: col1,col2
-	if v := c.GetString("sortby"); v != "" {
-		sortby = strings.Split(v, ",")
-	}
-	// order: desc,asc
-	if v := c.GetString("order"); v != "" {
-		order = strings.Split(v, ",")
-	}
-	// query: k:v,k:v
-	if v := c.GetString("query"); v != "" {
-		for _, cond := range strings.Split(v, ",") {
-			kv := strings.SplitN(cond, ":", 2)
-			if len(kv) != 2 {
-				c.Data["json"] = errors.New("Error: invalid query key/value pair")
-				c.ServeJSON()
-				return
-			}
-			k, v := kv[0], kv[1]
-			query[k] = v
-		}
+	spec, err := queryspec.Parse(c.Input())
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	spec.Role = roleOf(&c.Controller)
+	cv, err := converterOf(&c.Controller)
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
 	}
 
-	l, err := models.GetAllRoom(query, fields, sortby, order, offset, limit)
+	l, err := models.GetAllRoom(spec)
+	if err == nil && cv != nil {
+		err = displayItems(cv, l.Items)
+	}
 	if err != nil {
+		if queryspec.IsError(err) || err == exchange.ErrNoRate {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		}
 		c.Data["json"] = err.Error()
 	} else {
-		c.Data["json"] = l
+		c.Data["json"] = reqres.NewListResponse(c.Ctx.Request.URL, spec, l)
 	}
 	c.ServeJSON()
 }
 
+// Calendar ...
+// @Title Calendar
+// @Description get the rate of the Room for each night from its rate plans
+// @Param	id		path 	string	true		"The key for staticblock"
+// @Param	from	query	string	true	"First night. e.g. 2020-12-01"
+// @Param	to	query	string	true	"Last night. e.g. 2020-12-31"
+// @Success 200 {object} reqres.RoomCalendarResponse
+// @Failure 400 invalid range
+// @router /:id/calendar [get]
+func (c *RoomController) Calendar() {
+	res := reqres.RoomCalendarResponse{}
+	res.SetCode(reqres.Fail)
+
+	defer func() {
+		c.Data["json"] = res
+		c.ServeJSON()
+	}()
+
+	idStr := c.Ctx.Input.Param(":id")
+	id, _ := strconv.Atoi(idStr)
+	from, err := types.DateString(c.GetString("from"))
+	var to types.Date
+	if err == nil {
+		to, err = types.DateString(c.GetString("to"))
+	}
+	if err == nil && (to.Time.Before(from.Time) || to.Time.Sub(from.Time) >= maxCalendarDays*24*time.Hour) {
+		err = fmt.Errorf("to must be on or after from, at most %d nights", maxCalendarDays)
+	}
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		res.SetCode(reqres.InvalidParams)
+		res.Message = err.Error()
+		return
+	}
+
+	o := orm.NewOrm()
+	room := &models.Room{Id: id}
+	if err = o.Read(room); err != nil {
+		c.Ctx.Output.SetStatus(http.StatusNotFound)
+		res.SetCode(reqres.RecordNotExist)
+		return
+	}
+	var nights []time.Time
+	for night := from.Time; !night.After(to.Time); night = night.AddDate(0, 0, 1) {
+		nights = append(nights, night)
+	}
+	if res.Rates, err = pricing.Rates(o, room, nights); err != nil {
+		c.Ctx.Output.SetStatus(http.StatusInternalServerError)
+		res.Message = err.Error()
+		return
+	}
+	res.SetCode(reqres.Success)
+}
+
 // Put ...
 // @Title Put
 // @Description update the Room
@@ -141,7 +222,10 @@ func (c *RoomController) Put() {
 	id, _ := strconv.Atoi(idStr)
 	v := models.Room{Id: id}
 	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &v); err == nil {
-		if err := models.UpdateRoomById(&v); err == nil {
+		if err = types.Stored(v.CurrentPrice); err != nil {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+			c.Data["json"] = err.Error()
+		} else if err := models.UpdateRoomById(&v); err == nil {
 			c.Data["json"] = "OK"
 		} else {
 			c.Data["json"] = err.Error()
@@ -0,0 +1,288 @@
+package controllers
+
+import (
+	"easybook/models"
+	"easybook/queryspec"
+	"easybook/reqres"
+	"easybook/services/waitlist"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/orm"
+)
+
+// WaitlistController operations for WaitlistEntry. Guests wait for rooms of
+// sold out hotels and accept the offers made when rooms free up.
+type WaitlistController struct {
+	beego.Controller
+}
+
+// URLMapping ...
+func (c *WaitlistController) URLMapping() {
+	c.Mapping("Post", c.Post)
+	c.Mapping("GetOne", c.GetOne)
+	c.Mapping("GetAll", c.GetAll)
+	c.Mapping("Accept", c.Accept)
+	c.Mapping("Delete", c.Delete)
+}
+
+// Prepare rejects anonymous callers.
+func (c *WaitlistController) Prepare() {
+	if callerOf(&c.Controller) == nil {
+		c.Ctx.Output.SetStatus(http.StatusUnauthorized)
+		c.Data["json"] = "Error: X-Token is required"
+		c.ServeJSON()
+		c.StopRun()
+	}
+}
+
+// Post ...
+// @Title Post
+// @Description wait for rooms of a hotel for a stay. Rooms freed are offered to the entries in order, an offer is a pending reservation to accept within waitlistofferttl seconds of app.conf
+// @Param	X-Token	header	string	true	"Token of the guest or a staff member"
+// @Param	body		body 	reqres.WaitlistPostRequest	true		"body for WaitlistEntry content"
+// @Success 201 {object} reqres.WaitlistResponse
+// @Failure 400 stay or rooms are invalid
+// @Failure 404 hotel doesn't exist
+// @router / [post]
+func (c *WaitlistController) Post() {
+	res := reqres.WaitlistResponse{}
+	res.SetCode(reqres.Fail)
+
+	defer func() {
+		c.Data["json"] = res
+		c.ServeJSON()
+	}()
+
+	var req reqres.WaitlistPostRequest
+	if json.Unmarshal(c.Ctx.Input.RequestBody, &req) != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		res.SetCode(reqres.InvalidParams)
+		return
+	}
+	guestID := callerOf(&c.Controller).GuestId
+	if roleOf(&c.Controller) >= models.RoleStaff && req.GuestID != 0 {
+		guestID = req.GuestID
+	}
+
+	now := time.Now()
+	v := &models.WaitlistEntry{
+		GuestId:   &models.Guest{Id: guestID},
+		StartDate: req.StartDate.Time,
+		EndDate:   req.EndDate.Time,
+		Rooms:     req.Rooms,
+		Guests:    req.Guests,
+		Status:    models.WaitlistWaiting,
+		CreatedAt: now,
+	}
+	if req.HotelID != 0 {
+		v.HotelId = &models.Hotel{Id: req.HotelID}
+	}
+	if req.ServiceLevelID != 0 {
+		v.ServiceLevelId = &models.ServiceLevel{Id: req.ServiceLevelID}
+	}
+	if v.Rooms == 0 {
+		v.Rooms = 1
+	}
+	if err := waitlist.Validate(v); err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		res.SetCode(reqres.InvalidParams)
+		res.Message = err.Error()
+		return
+	}
+
+	o := orm.NewOrm()
+	if o.Read(&models.Hotel{Id: req.HotelID}) != nil {
+		c.Ctx.Output.SetStatus(http.StatusNotFound)
+		res.SetCode(reqres.RecordNotExist)
+		return
+	}
+	_ = o.Begin()
+	_, err := o.Insert(v)
+	if err == nil {
+		// rooms may be free already
+		_, err = waitlist.Match(o, req.HotelID, now)
+	}
+	if err == nil {
+		err = o.Read(v)
+	}
+	if err != nil {
+		_ = o.Rollback()
+		c.Ctx.Output.SetStatus(http.StatusInternalServerError)
+		res.SetCode(reqres.FailedCreate)
+		return
+	}
+	_ = o.Commit()
+
+	c.Ctx.Output.SetStatus(http.StatusCreated)
+	res.SetCode(reqres.Success)
+	res.Entry = v
+}
+
+// GetOne ...
+// @Title Get One
+// @Description get WaitlistEntry by id
+// @Param	X-Token	header	string	true	"Token of the guest or a staff member"
+// @Param	id		path 	string	true		"The key for staticblock"
+// @Success 200 {object} reqres.WaitlistResponse
+// @Failure 404 :id doesn't exist or belongs to another guest
+// @router /:id [get]
+func (c *WaitlistController) GetOne() {
+	res := reqres.WaitlistResponse{}
+	res.SetCode(reqres.Fail)
+
+	defer func() {
+		c.Data["json"] = res
+		c.ServeJSON()
+	}()
+
+	idStr := c.Ctx.Input.Param(":id")
+	id, _ := strconv.Atoi(idStr)
+	v, err := models.GetWaitlistEntryById(orm.NewOrm(), id)
+	if err != nil || !c.canSee(v) {
+		c.Ctx.Output.SetStatus(http.StatusNotFound)
+		res.SetCode(reqres.RecordNotExist)
+		return
+	}
+
+	res.SetCode(reqres.Success)
+	res.Entry = v
+}
+
+// GetAll ...
+// @Title Get All
+// @Description get the WaitlistEntry of the caller, or all of them for staff
+// @Param	X-Token	header	string	true	"Token of the guest or a staff member"
+// @Param	query	query	string	false	"Filter. e.g. col1:v1,col2__gte:v2,(col3__in:v3|v4;!col4:v5) ..."
+// @Param	fields	query	string	false	"Fields returned. e.g. col1,col2,rel1.col3 ..."
+// @Param	sortby	query	string	false	"Sorted-by fields. e.g. col1,col2 ..."
+// @Param	order	query	string	false	"Order corresponding to each sortby field, if single value, apply to all sortby fields. e.g. desc,asc ..."
+// @Param	limit	query	string	false	"Limit the size of result set. Must be an integer"
+// @Param	offset	query	string	false	"Start position of result set. Must be an integer"
+// @Param	cursor	query	string	false	"Keyset paging cursor ordered by created_at,id. Empty to start, then the token of the next link"
+// @Param	expand	query	string	false	"Relations to load. e.g. rel1,rel2.rel3 ..."
+// @Success 200 {object} reqres.ListResponse
+// @Failure 401 caller is anonymous
+// @router / [get]
+func (c *WaitlistController) GetAll() {
+	spec, err := queryspec.Parse(c.Input())
+	if err != nil {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	spec.Role = roleOf(&c.Controller)
+
+	var l *models.Page
+	if spec.Role >= models.RoleStaff {
+		l, err = models.GetAllWaitlistEntry(spec)
+	} else {
+		l, err = models.GetGuestWaitlistEntries(callerOf(&c.Controller).GuestId, spec)
+	}
+	if err != nil {
+		if queryspec.IsError(err) {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		}
+		c.Data["json"] = err.Error()
+	} else {
+		c.Data["json"] = reqres.NewListResponse(c.Ctx.Request.URL, spec, l)
+	}
+	c.ServeJSON()
+}
+
+// Accept ...
+// @Title Accept
+// @Description accept the offer of the WaitlistEntry, its reservation is confirmed and invoiced
+// @Param	X-Token	header	string	true	"Token of the guest or a staff member"
+// @Param	id		path 	string	true		"The id of the entry"
+// @Success 200 {object} reqres.WaitlistResponse
+// @Failure 404 :id doesn't exist or belongs to another guest
+// @Failure 409 entry has no offer or the offer has expired
+// @router /:id/accept [post]
+func (c *WaitlistController) Accept() {
+	res := reqres.WaitlistResponse{}
+	res.SetCode(reqres.Fail)
+
+	defer func() {
+		c.Data["json"] = res
+		c.ServeJSON()
+	}()
+
+	idStr := c.Ctx.Input.Param(":id")
+	id, _ := strconv.Atoi(idStr)
+	o := orm.NewOrm()
+	_ = o.Begin()
+	v := &models.WaitlistEntry{Id: id}
+	if err := o.ReadForUpdate(v); err != nil || !c.canSee(v) {
+		_ = o.Rollback()
+		c.Ctx.Output.SetStatus(http.StatusNotFound)
+		res.SetCode(reqres.RecordNotExist)
+		return
+	}
+	r, err := waitlist.Accept(o, v, time.Now())
+	if err != nil {
+		_ = o.Rollback()
+		if waitlist.IsError(err) {
+			c.Ctx.Output.SetStatus(http.StatusConflict)
+			res.SetCode(reqres.InvalidParams)
+		} else {
+			c.Ctx.Output.SetStatus(http.StatusInternalServerError)
+			res.SetCode(reqres.FailedUpdate)
+		}
+		res.Message = err.Error()
+		return
+	}
+	_ = o.Commit()
+
+	res.SetCode(reqres.Success)
+	res.Entry = v
+	res.Reservation = r
+}
+
+// Delete ...
+// @Title Delete
+// @Description leave the waitlist, an offer is declined and goes to the next guest
+// @Param	X-Token	header	string	true	"Token of the guest or a staff member"
+// @Param	id		path 	string	true		"The id you want to withdraw"
+// @Success 200 {string} delete success!
+// @Failure 404 :id doesn't exist or belongs to another guest
+// @Failure 409 entry is closed
+// @router /:id [delete]
+func (c *WaitlistController) Delete() {
+	idStr := c.Ctx.Input.Param(":id")
+	id, _ := strconv.Atoi(idStr)
+	o := orm.NewOrm()
+	_ = o.Begin()
+	v := &models.WaitlistEntry{Id: id}
+	if err := o.ReadForUpdate(v); err != nil || !c.canSee(v) {
+		_ = o.Rollback()
+		c.Ctx.Output.SetStatus(http.StatusNotFound)
+		c.Data["json"] = "Error: waitlist entry not found"
+		c.ServeJSON()
+		return
+	}
+	if err := waitlist.Withdraw(o, v, time.Now()); err != nil {
+		_ = o.Rollback()
+		if waitlist.IsError(err) {
+			c.Ctx.Output.SetStatus(http.StatusConflict)
+		} else {
+			c.Ctx.Output.SetStatus(http.StatusInternalServerError)
+		}
+		c.Data["json"] = err.Error()
+		c.ServeJSON()
+		return
+	}
+	_ = o.Commit()
+
+	c.Data["json"] = "OK"
+	c.ServeJSON()
+}
+
+// canSee reports whether the caller may read or change entry v.
+func (c *WaitlistController) canSee(v *models.WaitlistEntry) bool {
+	return roleOf(&c.Controller) >= models.RoleStaff || v.GuestId.Id == callerOf(&c.Controller).GuestId
+}
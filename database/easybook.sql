@@ -62,6 +62,43 @@ INSERT INTO `city` (`id`, `name`, `post_code`, `created_at`, `updated_at`) VALUE
 
 -- --------------------------------------------------------
 
+--
+-- Table structure for table `exchange_rate`
+--
+
+CREATE TABLE `exchange_rate` (
+  `id` int(10) UNSIGNED NOT NULL,
+  `base` char(3) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `currency` char(3) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `rate` decimal(20,10) NOT NULL,
+  `date` date NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  `updated_at` timestamp NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp()
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+
+--
+-- Table structure for table `group_booking`
+--
+
+CREATE TABLE `group_booking` (
+  `id` int(10) UNSIGNED NOT NULL,
+  `name` varchar(100) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+  `organizer_id` int(10) UNSIGNED NOT NULL,
+  `hotel_id` int(10) UNSIGNED NOT NULL,
+  `start_date` date NOT NULL,
+  `end_date` date NOT NULL,
+  `release_date` date NOT NULL,
+  `master_invoice` tinyint(1) NOT NULL DEFAULT 0,
+  `block_id` int(10) UNSIGNED DEFAULT NULL,
+  `released_at` timestamp NULL DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  `updated_at` timestamp NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp()
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+
 --
 -- Table structure for table `guest`
 --
@@ -77,7 +114,36 @@ CREATE TABLE `guest` (
   `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
   `updated_at` timestamp NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp(),
   `role` tinyint(4) NOT NULL DEFAULT 0,
-  `password` varchar(255) COLLATE utf8mb4_unicode_ci DEFAULT NULL
+  `password` varchar(255) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `language` varchar(8) COLLATE utf8mb4_unicode_ci DEFAULT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+
+--
+-- Table structure for table `hold`
+--
+
+CREATE TABLE `hold` (
+  `id` int(10) UNSIGNED NOT NULL,
+  `guest_id` int(10) UNSIGNED NOT NULL,
+  `start_date` date NOT NULL,
+  `end_date` date NOT NULL,
+  `expires_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  `reservation_id` int(10) UNSIGNED DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp()
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+
+--
+-- Table structure for table `hold_room`
+--
+
+CREATE TABLE `hold_room` (
+  `id` int(10) UNSIGNED NOT NULL,
+  `hold_id` int(10) UNSIGNED NOT NULL,
+  `room_id` int(10) UNSIGNED NOT NULL
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
 -- --------------------------------------------------------
@@ -106,6 +172,22 @@ INSERT INTO `hotel` (`id`, `name`, `description`, `is_active`, `address`, `city_
 
 -- --------------------------------------------------------
 
+--
+-- Table structure for table `idempotency_key`
+--
+
+CREATE TABLE `idempotency_key` (
+  `id` int(10) UNSIGNED NOT NULL,
+  `scope` varchar(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `idem_key` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `fingerprint` varchar(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `status` int(11) NOT NULL DEFAULT 0,
+  `response` text COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp()
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+
 --
 -- Table structure for table `invoice`
 --
@@ -114,10 +196,82 @@ CREATE TABLE `invoice` (
   `id` int(10) UNSIGNED NOT NULL,
   `guest_id` int(10) UNSIGNED NOT NULL,
   `reservation_id` int(10) UNSIGNED NOT NULL,
-  `amount` float NOT NULL,
+  `hotel_id` int(10) UNSIGNED DEFAULT NULL,
+  `number` varchar(32) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `subtotal` bigint(20) NOT NULL DEFAULT 0,
+  `discount_amount` bigint(20) NOT NULL DEFAULT 0,
+  `tax_amount` bigint(20) NOT NULL DEFAULT 0,
+  `fee_amount` bigint(20) NOT NULL DEFAULT 0,
+  `amount` bigint(20) NOT NULL DEFAULT 0,
+  `display_currency` char(3) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `exchange_rate` decimal(20,10) DEFAULT NULL,
   `issued_at` timestamp NOT NULL DEFAULT current_timestamp(),
   `paid_at` timestamp NULL DEFAULT NULL,
-  `canceled_at` timestamp NULL DEFAULT NULL
+  `canceled_at` timestamp NULL DEFAULT NULL,
+  `updated_at` timestamp NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp()
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+
+--
+-- Table structure for table `invoice_line`
+--
+
+CREATE TABLE `invoice_line` (
+  `id` int(10) UNSIGNED NOT NULL,
+  `invoice_id` int(10) UNSIGNED NOT NULL,
+  `kind` varchar(20) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `description` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `room_reserved_id` int(10) UNSIGNED DEFAULT NULL,
+  `date` date DEFAULT NULL,
+  `quantity` int(10) UNSIGNED NOT NULL DEFAULT 1,
+  `unit_price` bigint(20) NOT NULL DEFAULT 0,
+  `amount` bigint(20) NOT NULL DEFAULT 0
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+
+--
+-- Table structure for table `invoice_sequence`
+--
+
+CREATE TABLE `invoice_sequence` (
+  `hotel_id` int(10) UNSIGNED NOT NULL,
+  `last_number` int(10) UNSIGNED NOT NULL DEFAULT 0
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+
+--
+-- Table structure for table `invoice_template`
+--
+
+CREATE TABLE `invoice_template` (
+  `id` int(10) UNSIGNED NOT NULL,
+  `hotel_id` int(10) UNSIGNED NOT NULL,
+  `logo_path` varchar(255) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `header` text COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `footer` text COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  `updated_at` timestamp NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp()
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+
+--
+-- Table structure for table `no_show_policy`
+--
+
+CREATE TABLE `no_show_policy` (
+  `id` int(10) UNSIGNED NOT NULL,
+  `hotel_id` int(10) UNSIGNED NOT NULL,
+  `timezone` varchar(64) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'Local',
+  `cutoff_days` int(10) UNSIGNED NOT NULL DEFAULT 1,
+  `cutoff_hour` tinyint(3) UNSIGNED NOT NULL DEFAULT 6,
+  `penalty_nights` int(10) UNSIGNED NOT NULL DEFAULT 1,
+  `is_active` tinyint(1) NOT NULL DEFAULT 1,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  `updated_at` timestamp NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp()
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
 -- --------------------------------------------------------
@@ -133,12 +287,109 @@ CREATE TABLE `notification` (
   `is_completed` tinyint(4) NOT NULL DEFAULT 0,
   `description` text COLLATE utf8mb4_unicode_ci DEFAULT NULL,
   `reservation_id` int(10) UNSIGNED NOT NULL,
+  `event` varchar(20) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `attempts` int(10) UNSIGNED NOT NULL DEFAULT 0,
+  `locked_by` varchar(64) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `locked_until` datetime DEFAULT NULL,
+  `last_error` text COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `read_at` datetime DEFAULT NULL,
+  `archived_at` datetime DEFAULT NULL,
   `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
   `updated_at` timestamp NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp()
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
 -- --------------------------------------------------------
 
+--
+-- Table structure for table `notification_rule`
+--
+
+CREATE TABLE `notification_rule` (
+  `id` int(10) UNSIGNED NOT NULL,
+  `hotel_id` int(10) UNSIGNED NOT NULL,
+  `event` varchar(20) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `type` char(3) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `offset_days` int(11) NOT NULL DEFAULT 0,
+  `hour` tinyint(3) UNSIGNED NOT NULL DEFAULT 9,
+  `is_active` tinyint(4) NOT NULL DEFAULT 1,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  `updated_at` timestamp NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp()
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+
+--
+-- Table structure for table `notification_template`
+--
+
+CREATE TABLE `notification_template` (
+  `id` int(10) UNSIGNED NOT NULL,
+  `name` varchar(50) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `type` char(3) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `language` varchar(8) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `version` int(10) UNSIGNED NOT NULL DEFAULT 1,
+  `format` varchar(10) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'text',
+  `subject` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `body` text COLLATE utf8mb4_unicode_ci NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  `updated_at` timestamp NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp()
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+
+--
+-- Table structure for table `overbooking_allowance`
+--
+
+CREATE TABLE `overbooking_allowance` (
+  `id` int(10) UNSIGNED NOT NULL,
+  `hotel_id` int(10) UNSIGNED NOT NULL,
+  `service_level_id` int(10) UNSIGNED DEFAULT NULL,
+  `rooms` int(10) UNSIGNED NOT NULL DEFAULT 0,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  `updated_at` timestamp NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp()
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+
+--
+-- Table structure for table `payment`
+--
+
+CREATE TABLE `payment` (
+  `id` int(10) UNSIGNED NOT NULL,
+  `invoice_id` int(10) UNSIGNED NOT NULL,
+  `provider` varchar(20) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `provider_ref` varchar(64) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `amount` bigint(20) NOT NULL DEFAULT 0,
+  `refunded_amount` bigint(20) NOT NULL DEFAULT 0,
+  `status` varchar(20) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `failure_reason` varchar(255) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `authorized_at` datetime DEFAULT NULL,
+  `captured_at` datetime DEFAULT NULL,
+  `voided_at` datetime DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  `updated_at` timestamp NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp()
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+
+--
+-- Table structure for table `payment_event`
+--
+
+CREATE TABLE `payment_event` (
+  `id` int(10) UNSIGNED NOT NULL,
+  `provider` varchar(20) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `event_id` varchar(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `type` varchar(40) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `payload` text COLLATE utf8mb4_unicode_ci NOT NULL,
+  `processed_at` datetime DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp()
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+
 --
 -- Table structure for table `penalty_rule`
 --
@@ -154,6 +405,102 @@ CREATE TABLE `penalty_rule` (
 
 -- --------------------------------------------------------
 
+--
+-- Table structure for table `promotion`
+--
+
+CREATE TABLE `promotion` (
+  `id` int(10) UNSIGNED NOT NULL,
+  `code` varchar(40) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `name` varchar(100) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+  `hotel_id` int(10) UNSIGNED DEFAULT NULL,
+  `room_id` int(10) UNSIGNED DEFAULT NULL,
+  `percent` decimal(5,2) NOT NULL DEFAULT 0.00,
+  `amount` bigint(20) NOT NULL DEFAULT 0,
+  `stackable` tinyint(1) NOT NULL DEFAULT 0,
+  `max_uses` int(11) NOT NULL DEFAULT 0,
+  `max_uses_per_guest` int(11) NOT NULL DEFAULT 0,
+  `uses` int(11) NOT NULL DEFAULT 0,
+  `valid_from` timestamp NOT NULL DEFAULT current_timestamp(),
+  `valid_to` timestamp NULL DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  `updated_at` timestamp NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp()
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+
+--
+-- Table structure for table `promotion_redemption`
+--
+
+CREATE TABLE `promotion_redemption` (
+  `id` int(10) UNSIGNED NOT NULL,
+  `promotion_id` int(10) UNSIGNED NOT NULL,
+  `reservation_id` int(10) UNSIGNED NOT NULL,
+  `guest_id` int(10) UNSIGNED NOT NULL,
+  `amount` bigint(20) NOT NULL DEFAULT 0,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  `released_at` timestamp NULL DEFAULT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+
+--
+-- Table structure for table `rate_plan`
+--
+
+CREATE TABLE `rate_plan` (
+  `id` int(10) UNSIGNED NOT NULL,
+  `room_id` int(10) UNSIGNED DEFAULT NULL,
+  `service_level_id` int(10) UNSIGNED DEFAULT NULL,
+  `name` varchar(100) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `price` bigint(20) NOT NULL DEFAULT 0,
+  `weekdays` tinyint(3) UNSIGNED NOT NULL DEFAULT 0,
+  `min_stay` tinyint(3) UNSIGNED NOT NULL DEFAULT 0,
+  `priority` int(11) NOT NULL DEFAULT 0,
+  `effect_from` date NOT NULL,
+  `expire_on` date DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  `updated_at` timestamp NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp()
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+
+--
+-- Table structure for table `refund`
+--
+
+CREATE TABLE `refund` (
+  `id` int(10) UNSIGNED NOT NULL,
+  `payment_id` int(10) UNSIGNED NOT NULL,
+  `provider_ref` varchar(64) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `amount` bigint(20) NOT NULL DEFAULT 0,
+  `reason` varchar(255) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `status` varchar(20) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `failure_reason` varchar(255) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  `updated_at` timestamp NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp()
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+
+--
+-- Table structure for table `relocation`
+--
+
+CREATE TABLE `relocation` (
+  `id` int(10) UNSIGNED NOT NULL,
+  `reservation_id` int(10) UNSIGNED NOT NULL,
+  `room_reserved_id` int(10) UNSIGNED NOT NULL,
+  `from_room_id` int(10) UNSIGNED NOT NULL,
+  `to_room_id` int(10) UNSIGNED NOT NULL,
+  `reason` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+  `relocated_by` int(10) UNSIGNED DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp()
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+
 --
 -- Table structure for table `reservation`
 --
@@ -164,10 +511,38 @@ CREATE TABLE `reservation` (
   `start_date` date NOT NULL,
   `end_date` date NOT NULL,
   `discount_percent` float NOT NULL DEFAULT 0,
-  `total_price` float NOT NULL,
+  `airport_shuttle` tinyint(3) UNSIGNED NOT NULL DEFAULT 0,
+  `guests` tinyint(3) UNSIGNED NOT NULL DEFAULT 1,
+  `total_price` bigint(20) NOT NULL DEFAULT 0,
+  `display_currency` char(3) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `exchange_rate` decimal(20,10) DEFAULT NULL,
   `status` tinyint(3) UNSIGNED NOT NULL DEFAULT 0,
   `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
-  `updated_at` timestamp NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp()
+  `updated_at` timestamp NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp(),
+  `group_id` int(10) UNSIGNED DEFAULT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+
+--
+-- Table structure for table `reservation_change`
+--
+
+CREATE TABLE `reservation_change` (
+  `id` int(10) UNSIGNED NOT NULL,
+  `reservation_id` int(10) UNSIGNED NOT NULL,
+  `changed_by` int(10) UNSIGNED DEFAULT NULL,
+  `old_start_date` date NOT NULL,
+  `old_end_date` date NOT NULL,
+  `old_rooms` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `new_start_date` date NOT NULL,
+  `new_end_date` date NOT NULL,
+  `new_rooms` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `old_total` bigint(20) NOT NULL DEFAULT 0,
+  `new_total` bigint(20) NOT NULL DEFAULT 0,
+  `difference` bigint(20) NOT NULL DEFAULT 0,
+  `reason` varchar(255) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp()
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
 -- --------------------------------------------------------
@@ -181,7 +556,7 @@ CREATE TABLE `room` (
   `name` varchar(100) COLLATE utf8mb4_unicode_ci NOT NULL,
   `number` int(4) NOT NULL,
   `description` text COLLATE utf8mb4_unicode_ci DEFAULT NULL,
-  `current_price` float NOT NULL DEFAULT 0,
+  `current_price` bigint(20) NOT NULL DEFAULT 0,
   `hotel_id` int(10) UNSIGNED NOT NULL,
   `service_level_id` int(10) UNSIGNED NOT NULL,
   `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
@@ -207,6 +582,20 @@ CREATE TABLE `room_facilitate` (
 
 -- --------------------------------------------------------
 
+--
+-- Table structure for table `room_night`
+--
+
+CREATE TABLE `room_night` (
+  `id` int(10) UNSIGNED NOT NULL,
+  `room_reserved_id` int(10) UNSIGNED NOT NULL,
+  `date` date NOT NULL,
+  `price` bigint(20) NOT NULL DEFAULT 0,
+  `rate_plan_id` int(10) UNSIGNED DEFAULT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- --------------------------------------------------------
+
 --
 -- Table structure for table `room_reserved`
 --
@@ -215,7 +604,8 @@ CREATE TABLE `room_reserved` (
   `id` int(10) UNSIGNED NOT NULL,
   `reservation_id` int(10) UNSIGNED NOT NULL,
   `room_id` int(10) UNSIGNED NOT NULL,
-  `price` float DEFAULT NULL,
+  `assigned_room_id` int(10) UNSIGNED DEFAULT NULL,
+  `price` bigint(20) DEFAULT NULL,
   `check_in` timestamp NULL DEFAULT NULL,
   `check_out` timestamp NULL DEFAULT NULL,
   `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
@@ -254,6 +644,59 @@ CREATE TABLE `stay_tracking` (
   `updated_at` timestamp NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp()
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
+-- --------------------------------------------------------
+
+--
+-- Table structure for table `tax_rule`
+--
+
+CREATE TABLE `tax_rule` (
+  `id` int(10) UNSIGNED NOT NULL,
+  `city_id` int(10) UNSIGNED DEFAULT NULL,
+  `hotel_id` int(10) UNSIGNED DEFAULT NULL,
+  `name` varchar(100) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `category` varchar(10) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'tax',
+  `basis` varchar(20) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `rate` decimal(9,4) NOT NULL DEFAULT 0,
+  `amount` bigint(20) NOT NULL DEFAULT 0,
+  `compound` tinyint(1) NOT NULL DEFAULT 0,
+  `sequence` int(11) NOT NULL DEFAULT 0,
+  `effect_from` date NOT NULL,
+  `expire_on` date DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  `updated_at` timestamp NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp()
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+--
+-- Dumping data for table `tax_rule`
+--
+
+INSERT INTO `tax_rule` (`id`, `city_id`, `hotel_id`, `name`, `category`, `basis`, `rate`, `amount`, `compound`, `sequence`, `effect_from`, `expire_on`, `created_at`, `updated_at`) VALUES
+(1, 1, NULL, 'Airport shuttle', 'fee', 'shuttle', '0.0000', 15, 0, 10, '2020-01-01', NULL, '2020-09-17 17:12:54', '2020-09-17 17:12:54'),
+(2, 1, NULL, 'VAT', 'tax', 'percent', '10.0000', '0', 1, 20, '2020-01-01', NULL, '2020-09-17 17:12:54', '2020-09-17 17:12:54');
+
+-- --------------------------------------------------------
+
+--
+-- Table structure for table `waitlist_entry`
+--
+
+CREATE TABLE `waitlist_entry` (
+  `id` int(10) UNSIGNED NOT NULL,
+  `guest_id` int(10) UNSIGNED NOT NULL,
+  `hotel_id` int(10) UNSIGNED NOT NULL,
+  `service_level_id` int(10) UNSIGNED DEFAULT NULL,
+  `start_date` date NOT NULL,
+  `end_date` date NOT NULL,
+  `rooms` tinyint(3) UNSIGNED NOT NULL DEFAULT 1,
+  `guests` tinyint(3) UNSIGNED NOT NULL DEFAULT 0,
+  `status` tinyint(3) UNSIGNED NOT NULL DEFAULT 0,
+  `reservation_id` int(10) UNSIGNED DEFAULT NULL,
+  `offer_expires_at` timestamp NULL DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  `updated_at` timestamp NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp()
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
 --
 -- Indexes for dumped tables
 --
@@ -272,6 +715,24 @@ ALTER TABLE `city`
   ADD PRIMARY KEY (`id`),
   ADD UNIQUE KEY `name` (`name`);
 
+--
+-- Indexes for table `exchange_rate`
+--
+ALTER TABLE `exchange_rate`
+  ADD PRIMARY KEY (`id`),
+  ADD UNIQUE KEY `pair_date` (`base`,`currency`,`date`);
+
+--
+-- Indexes for table `group_booking`
+--
+ALTER TABLE `group_booking`
+  ADD PRIMARY KEY (`id`),
+  ADD KEY `organizer_id` (`organizer_id`),
+  ADD KEY `hotel_id` (`hotel_id`),
+  ADD KEY `block_id` (`block_id`),
+  ADD KEY `released_at_release_date` (`released_at`,`release_date`),
+  ADD KEY `created_at_id` (`created_at`,`id`);
+
 --
 -- Indexes for table `guest`
 --
@@ -279,6 +740,23 @@ ALTER TABLE `guest`
   ADD PRIMARY KEY (`id`),
   ADD UNIQUE KEY `email` (`email`);
 
+--
+-- Indexes for table `hold`
+--
+ALTER TABLE `hold`
+  ADD PRIMARY KEY (`id`),
+  ADD KEY `guest_id` (`guest_id`),
+  ADD KEY `reservation_id` (`reservation_id`),
+  ADD KEY `expires_at` (`expires_at`);
+
+--
+-- Indexes for table `hold_room`
+--
+ALTER TABLE `hold_room`
+  ADD PRIMARY KEY (`id`),
+  ADD UNIQUE KEY `hold_room` (`hold_id`,`room_id`),
+  ADD KEY `room_id` (`room_id`);
+
 --
 -- Indexes for table `hotel`
 --
@@ -287,20 +765,100 @@ ALTER TABLE `hotel`
   ADD KEY `name` (`name`),
   ADD KEY `city_id` (`city_id`);
 
+--
+-- Indexes for table `idempotency_key`
+--
+ALTER TABLE `idempotency_key`
+  ADD PRIMARY KEY (`id`),
+  ADD UNIQUE KEY `scope_idem_key` (`scope`,`idem_key`),
+  ADD KEY `created_at` (`created_at`);
+
 --
 -- Indexes for table `invoice`
 --
 ALTER TABLE `invoice`
   ADD PRIMARY KEY (`id`),
+  ADD UNIQUE KEY `number` (`number`),
   ADD KEY `guest_id` (`guest_id`),
-  ADD KEY `reservation_id` (`reservation_id`);
+  ADD KEY `reservation_id` (`reservation_id`),
+  ADD KEY `hotel_id` (`hotel_id`);
+
+--
+-- Indexes for table `invoice_line`
+--
+ALTER TABLE `invoice_line`
+  ADD PRIMARY KEY (`id`),
+  ADD KEY `invoice_id` (`invoice_id`),
+  ADD KEY `room_reserved_id` (`room_reserved_id`);
+
+--
+-- Indexes for table `invoice_sequence`
+--
+ALTER TABLE `invoice_sequence`
+  ADD PRIMARY KEY (`hotel_id`);
+
+--
+-- Indexes for table `invoice_template`
+--
+ALTER TABLE `invoice_template`
+  ADD PRIMARY KEY (`id`),
+  ADD KEY `hotel_id` (`hotel_id`);
+
+--
+-- Indexes for table `no_show_policy`
+--
+ALTER TABLE `no_show_policy`
+  ADD PRIMARY KEY (`id`),
+  ADD UNIQUE KEY `hotel_id` (`hotel_id`),
+  ADD KEY `created_at_id` (`created_at`,`id`);
 
 --
 -- Indexes for table `notification`
 --
 ALTER TABLE `notification`
   ADD PRIMARY KEY (`id`),
-  ADD KEY `reservation_id` (`reservation_id`);
+  ADD KEY `reservation_id` (`reservation_id`),
+  ADD KEY `created_at_id` (`created_at`,`id`),
+  ADD KEY `is_completed_trigger_at` (`is_completed`,`trigger_at`);
+
+--
+-- Indexes for table `notification_rule`
+--
+ALTER TABLE `notification_rule`
+  ADD PRIMARY KEY (`id`),
+  ADD KEY `hotel_id` (`hotel_id`);
+
+--
+-- Indexes for table `notification_template`
+--
+ALTER TABLE `notification_template`
+  ADD PRIMARY KEY (`id`),
+  ADD UNIQUE KEY `name_type_language_version` (`name`,`type`,`language`,`version`);
+
+--
+-- Indexes for table `overbooking_allowance`
+--
+ALTER TABLE `overbooking_allowance`
+  ADD PRIMARY KEY (`id`),
+  ADD KEY `hotel_id` (`hotel_id`),
+  ADD KEY `service_level_id` (`service_level_id`),
+  ADD KEY `created_at_id` (`created_at`,`id`);
+
+--
+-- Indexes for table `payment`
+--
+ALTER TABLE `payment`
+  ADD PRIMARY KEY (`id`),
+  ADD KEY `invoice_id` (`invoice_id`),
+  ADD KEY `provider_provider_ref` (`provider`,`provider_ref`),
+  ADD KEY `created_at_id` (`created_at`,`id`);
+
+--
+-- Indexes for table `payment_event`
+--
+ALTER TABLE `payment_event`
+  ADD PRIMARY KEY (`id`),
+  ADD UNIQUE KEY `provider_event_id` (`provider`,`event_id`);
 
 --
 -- Indexes for table `penalty_rule`
@@ -309,12 +867,68 @@ ALTER TABLE `penalty_rule`
   ADD PRIMARY KEY (`id`),
   ADD KEY `agreement_id` (`agreement_id`);
 
+--
+-- Indexes for table `promotion`
+--
+ALTER TABLE `promotion`
+  ADD PRIMARY KEY (`id`),
+  ADD UNIQUE KEY `code` (`code`),
+  ADD KEY `hotel_id` (`hotel_id`),
+  ADD KEY `room_id` (`room_id`);
+
+--
+-- Indexes for table `promotion_redemption`
+--
+ALTER TABLE `promotion_redemption`
+  ADD PRIMARY KEY (`id`),
+  ADD KEY `promotion_guest` (`promotion_id`,`guest_id`),
+  ADD KEY `reservation_id` (`reservation_id`),
+  ADD KEY `guest_id` (`guest_id`);
+
+--
+-- Indexes for table `rate_plan`
+--
+ALTER TABLE `rate_plan`
+  ADD PRIMARY KEY (`id`),
+  ADD KEY `room_id` (`room_id`),
+  ADD KEY `service_level_id` (`service_level_id`);
+
+--
+-- Indexes for table `refund`
+--
+ALTER TABLE `refund`
+  ADD PRIMARY KEY (`id`),
+  ADD KEY `payment_id` (`payment_id`);
+
+--
+-- Indexes for table `relocation`
+--
+ALTER TABLE `relocation`
+  ADD PRIMARY KEY (`id`),
+  ADD KEY `reservation_id` (`reservation_id`),
+  ADD KEY `room_reserved_id` (`room_reserved_id`),
+  ADD KEY `from_room_id` (`from_room_id`),
+  ADD KEY `to_room_id` (`to_room_id`),
+  ADD KEY `relocated_by` (`relocated_by`),
+  ADD KEY `created_at_id` (`created_at`,`id`);
+
 --
 -- Indexes for table `reservation`
 --
 ALTER TABLE `reservation`
   ADD PRIMARY KEY (`id`),
-  ADD KEY `guest_id` (`guest_id`);
+  ADD KEY `guest_id` (`guest_id`),
+  ADD KEY `created_at_id` (`created_at`,`id`),
+  ADD KEY `group_id` (`group_id`),
+  ADD KEY `status_start_date` (`status`,`start_date`);
+
+--
+-- Indexes for table `reservation_change`
+--
+ALTER TABLE `reservation_change`
+  ADD PRIMARY KEY (`id`),
+  ADD KEY `reservation_id` (`reservation_id`),
+  ADD KEY `changed_by` (`changed_by`);
 
 --
 -- Indexes for table `room`
@@ -331,6 +945,14 @@ ALTER TABLE `room_facilitate`
   ADD PRIMARY KEY (`id`),
   ADD KEY `room_id` (`room_id`);
 
+--
+-- Indexes for table `room_night`
+--
+ALTER TABLE `room_night`
+  ADD PRIMARY KEY (`id`),
+  ADD UNIQUE KEY `room_reserved_date` (`room_reserved_id`,`date`),
+  ADD KEY `rate_plan_id` (`rate_plan_id`);
+
 --
 -- Indexes for table `room_reserved`
 --
@@ -353,6 +975,25 @@ ALTER TABLE `stay_tracking`
   ADD PRIMARY KEY (`id`),
   ADD KEY `reservation_id` (`reservation_id`);
 
+--
+-- Indexes for table `tax_rule`
+--
+ALTER TABLE `tax_rule`
+  ADD PRIMARY KEY (`id`),
+  ADD KEY `city_id` (`city_id`),
+  ADD KEY `hotel_id` (`hotel_id`);
+
+--
+-- Indexes for table `waitlist_entry`
+--
+ALTER TABLE `waitlist_entry`
+  ADD PRIMARY KEY (`id`),
+  ADD KEY `guest_id` (`guest_id`),
+  ADD KEY `hotel_id_status` (`hotel_id`,`status`,`created_at`),
+  ADD KEY `service_level_id` (`service_level_id`),
+  ADD KEY `reservation_id` (`reservation_id`),
+  ADD KEY `status_offer_expires_at` (`status`,`offer_expires_at`);
+
 --
 -- AUTO_INCREMENT for dumped tables
 --
@@ -369,42 +1010,156 @@ ALTER TABLE `agreement`
 ALTER TABLE `city`
   MODIFY `id` int(10) UNSIGNED NOT NULL AUTO_INCREMENT, AUTO_INCREMENT=2;
 
+--
+-- AUTO_INCREMENT for table `exchange_rate`
+--
+ALTER TABLE `exchange_rate`
+  MODIFY `id` int(10) UNSIGNED NOT NULL AUTO_INCREMENT;
+
+--
+-- AUTO_INCREMENT for table `group_booking`
+--
+ALTER TABLE `group_booking`
+  MODIFY `id` int(10) UNSIGNED NOT NULL AUTO_INCREMENT;
+
 --
 -- AUTO_INCREMENT for table `guest`
 --
 ALTER TABLE `guest`
   MODIFY `id` int(10) UNSIGNED NOT NULL AUTO_INCREMENT;
 
+--
+-- AUTO_INCREMENT for table `hold`
+--
+ALTER TABLE `hold`
+  MODIFY `id` int(10) UNSIGNED NOT NULL AUTO_INCREMENT;
+
+--
+-- AUTO_INCREMENT for table `hold_room`
+--
+ALTER TABLE `hold_room`
+  MODIFY `id` int(10) UNSIGNED NOT NULL AUTO_INCREMENT;
+
 --
 -- AUTO_INCREMENT for table `hotel`
 --
 ALTER TABLE `hotel`
   MODIFY `id` int(10) UNSIGNED NOT NULL AUTO_INCREMENT, AUTO_INCREMENT=2;
 
+--
+-- AUTO_INCREMENT for table `idempotency_key`
+--
+ALTER TABLE `idempotency_key`
+  MODIFY `id` int(10) UNSIGNED NOT NULL AUTO_INCREMENT;
+
 --
 -- AUTO_INCREMENT for table `invoice`
 --
 ALTER TABLE `invoice`
   MODIFY `id` int(10) UNSIGNED NOT NULL AUTO_INCREMENT;
 
+--
+-- AUTO_INCREMENT for table `invoice_line`
+--
+ALTER TABLE `invoice_line`
+  MODIFY `id` int(10) UNSIGNED NOT NULL AUTO_INCREMENT;
+
+--
+-- AUTO_INCREMENT for table `invoice_template`
+--
+ALTER TABLE `invoice_template`
+  MODIFY `id` int(10) UNSIGNED NOT NULL AUTO_INCREMENT;
+
+--
+-- AUTO_INCREMENT for table `no_show_policy`
+--
+ALTER TABLE `no_show_policy`
+  MODIFY `id` int(10) UNSIGNED NOT NULL AUTO_INCREMENT;
+
 --
 -- AUTO_INCREMENT for table `notification`
 --
 ALTER TABLE `notification`
   MODIFY `id` int(10) UNSIGNED NOT NULL AUTO_INCREMENT;
 
+--
+-- AUTO_INCREMENT for table `notification_rule`
+--
+ALTER TABLE `notification_rule`
+  MODIFY `id` int(10) UNSIGNED NOT NULL AUTO_INCREMENT;
+
+--
+-- AUTO_INCREMENT for table `notification_template`
+--
+ALTER TABLE `notification_template`
+  MODIFY `id` int(10) UNSIGNED NOT NULL AUTO_INCREMENT;
+
+--
+-- AUTO_INCREMENT for table `overbooking_allowance`
+--
+ALTER TABLE `overbooking_allowance`
+  MODIFY `id` int(10) UNSIGNED NOT NULL AUTO_INCREMENT;
+
+--
+-- AUTO_INCREMENT for table `payment`
+--
+ALTER TABLE `payment`
+  MODIFY `id` int(10) UNSIGNED NOT NULL AUTO_INCREMENT;
+
+--
+-- AUTO_INCREMENT for table `payment_event`
+--
+ALTER TABLE `payment_event`
+  MODIFY `id` int(10) UNSIGNED NOT NULL AUTO_INCREMENT;
+
 --
 -- AUTO_INCREMENT for table `penalty_rule`
 --
 ALTER TABLE `penalty_rule`
   MODIFY `id` int(10) UNSIGNED NOT NULL AUTO_INCREMENT;
 
+--
+-- AUTO_INCREMENT for table `promotion`
+--
+ALTER TABLE `promotion`
+  MODIFY `id` int(10) UNSIGNED NOT NULL AUTO_INCREMENT;
+
+--
+-- AUTO_INCREMENT for table `promotion_redemption`
+--
+ALTER TABLE `promotion_redemption`
+  MODIFY `id` int(10) UNSIGNED NOT NULL AUTO_INCREMENT;
+
+--
+-- AUTO_INCREMENT for table `rate_plan`
+--
+ALTER TABLE `rate_plan`
+  MODIFY `id` int(10) UNSIGNED NOT NULL AUTO_INCREMENT;
+
+--
+-- AUTO_INCREMENT for table `refund`
+--
+ALTER TABLE `refund`
+  MODIFY `id` int(10) UNSIGNED NOT NULL AUTO_INCREMENT;
+
+--
+-- AUTO_INCREMENT for table `relocation`
+--
+ALTER TABLE `relocation`
+  MODIFY `id` int(10) UNSIGNED NOT NULL AUTO_INCREMENT;
+
 --
 -- AUTO_INCREMENT for table `reservation`
 --
 ALTER TABLE `reservation`
   MODIFY `id` int(10) UNSIGNED NOT NULL AUTO_INCREMENT;
 
+--
+-- AUTO_INCREMENT for table `reservation_change`
+--
+ALTER TABLE `reservation_change`
+  MODIFY `id` int(10) UNSIGNED NOT NULL AUTO_INCREMENT;
+
 --
 -- AUTO_INCREMENT for table `room`
 --
@@ -417,6 +1172,12 @@ ALTER TABLE `room`
 ALTER TABLE `room_facilitate`
   MODIFY `id` int(10) UNSIGNED NOT NULL AUTO_INCREMENT;
 
+--
+-- AUTO_INCREMENT for table `room_night`
+--
+ALTER TABLE `room_night`
+  MODIFY `id` int(10) UNSIGNED NOT NULL AUTO_INCREMENT;
+
 --
 -- AUTO_INCREMENT for table `room_reserved`
 --
@@ -435,6 +1196,18 @@ ALTER TABLE `service_level`
 ALTER TABLE `stay_tracking`
   MODIFY `id` int(10) UNSIGNED NOT NULL AUTO_INCREMENT;
 
+--
+-- AUTO_INCREMENT for table `tax_rule`
+--
+ALTER TABLE `tax_rule`
+  MODIFY `id` int(10) UNSIGNED NOT NULL AUTO_INCREMENT, AUTO_INCREMENT=3;
+
+--
+-- AUTO_INCREMENT for table `waitlist_entry`
+--
+ALTER TABLE `waitlist_entry`
+  MODIFY `id` int(10) UNSIGNED NOT NULL AUTO_INCREMENT;
+
 --
 -- Constraints for dumped tables
 --
@@ -445,6 +1218,28 @@ ALTER TABLE `stay_tracking`
 ALTER TABLE `agreement`
   ADD CONSTRAINT `agreement_service_level_fk` FOREIGN KEY (`service_level_id`) REFERENCES `service_level` (`id`) ON UPDATE CASCADE;
 
+--
+-- Constraints for table `group_booking`
+--
+ALTER TABLE `group_booking`
+  ADD CONSTRAINT `group_booking_guest_fk` FOREIGN KEY (`organizer_id`) REFERENCES `guest` (`id`) ON UPDATE CASCADE,
+  ADD CONSTRAINT `group_booking_hotel_fk` FOREIGN KEY (`hotel_id`) REFERENCES `hotel` (`id`) ON UPDATE CASCADE,
+  ADD CONSTRAINT `group_booking_reservation_fk` FOREIGN KEY (`block_id`) REFERENCES `reservation` (`id`) ON DELETE SET NULL ON UPDATE CASCADE;
+
+--
+-- Constraints for table `hold`
+--
+ALTER TABLE `hold`
+  ADD CONSTRAINT `hold_guest_fk` FOREIGN KEY (`guest_id`) REFERENCES `guest` (`id`) ON UPDATE CASCADE,
+  ADD CONSTRAINT `hold_reservation_fk` FOREIGN KEY (`reservation_id`) REFERENCES `reservation` (`id`) ON DELETE SET NULL ON UPDATE CASCADE;
+
+--
+-- Constraints for table `hold_room`
+--
+ALTER TABLE `hold_room`
+  ADD CONSTRAINT `hold_room_hold_fk` FOREIGN KEY (`hold_id`) REFERENCES `hold` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+  ADD CONSTRAINT `hold_room_room_fk` FOREIGN KEY (`room_id`) REFERENCES `room` (`id`) ON UPDATE CASCADE;
+
 --
 -- Constraints for table `hotel`
 --
@@ -456,7 +1251,33 @@ ALTER TABLE `hotel`
 --
 ALTER TABLE `invoice`
   ADD CONSTRAINT `invoice_guest_fk` FOREIGN KEY (`guest_id`) REFERENCES `guest` (`id`) ON UPDATE CASCADE,
-  ADD CONSTRAINT `invoice_reservation_fk` FOREIGN KEY (`reservation_id`) REFERENCES `reservation` (`id`) ON UPDATE CASCADE;
+  ADD CONSTRAINT `invoice_reservation_fk` FOREIGN KEY (`reservation_id`) REFERENCES `reservation` (`id`) ON UPDATE CASCADE,
+  ADD CONSTRAINT `invoice_hotel_fk` FOREIGN KEY (`hotel_id`) REFERENCES `hotel` (`id`) ON UPDATE CASCADE;
+
+--
+-- Constraints for table `invoice_line`
+--
+ALTER TABLE `invoice_line`
+  ADD CONSTRAINT `invoice_line_invoice_fk` FOREIGN KEY (`invoice_id`) REFERENCES `invoice` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+  ADD CONSTRAINT `invoice_line_room_reserved_fk` FOREIGN KEY (`room_reserved_id`) REFERENCES `room_reserved` (`id`) ON UPDATE CASCADE;
+
+--
+-- Constraints for table `invoice_sequence`
+--
+ALTER TABLE `invoice_sequence`
+  ADD CONSTRAINT `invoice_sequence_hotel_fk` FOREIGN KEY (`hotel_id`) REFERENCES `hotel` (`id`) ON UPDATE CASCADE;
+
+--
+-- Constraints for table `invoice_template`
+--
+ALTER TABLE `invoice_template`
+  ADD CONSTRAINT `invoice_template_hotel_fk` FOREIGN KEY (`hotel_id`) REFERENCES `hotel` (`id`) ON UPDATE CASCADE;
+
+--
+-- Constraints for table `no_show_policy`
+--
+ALTER TABLE `no_show_policy`
+  ADD CONSTRAINT `no_show_policy_hotel_fk` FOREIGN KEY (`hotel_id`) REFERENCES `hotel` (`id`) ON DELETE CASCADE ON UPDATE CASCADE;
 
 --
 -- Constraints for table `notification`
@@ -464,18 +1285,83 @@ ALTER TABLE `invoice`
 ALTER TABLE `notification`
   ADD CONSTRAINT `notification_reservation_fk` FOREIGN KEY (`reservation_id`) REFERENCES `reservation` (`id`) ON UPDATE CASCADE;
 
+--
+-- Constraints for table `notification_rule`
+--
+ALTER TABLE `notification_rule`
+  ADD CONSTRAINT `notification_rule_hotel_fk` FOREIGN KEY (`hotel_id`) REFERENCES `hotel` (`id`) ON UPDATE CASCADE;
+
+--
+-- Constraints for table `overbooking_allowance`
+--
+ALTER TABLE `overbooking_allowance`
+  ADD CONSTRAINT `overbooking_allowance_hotel_fk` FOREIGN KEY (`hotel_id`) REFERENCES `hotel` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+  ADD CONSTRAINT `overbooking_allowance_service_level_fk` FOREIGN KEY (`service_level_id`) REFERENCES `service_level` (`id`) ON DELETE CASCADE ON UPDATE CASCADE;
+
+--
+-- Constraints for table `payment`
+--
+ALTER TABLE `payment`
+  ADD CONSTRAINT `payment_invoice_fk` FOREIGN KEY (`invoice_id`) REFERENCES `invoice` (`id`) ON UPDATE CASCADE;
+
 --
 -- Constraints for table `penalty_rule`
 --
 ALTER TABLE `penalty_rule`
   ADD CONSTRAINT `penalty_rule_agreement_fk` FOREIGN KEY (`agreement_id`) REFERENCES `agreement` (`id`) ON UPDATE CASCADE;
 
+--
+-- Constraints for table `promotion`
+--
+ALTER TABLE `promotion`
+  ADD CONSTRAINT `promotion_hotel_fk` FOREIGN KEY (`hotel_id`) REFERENCES `hotel` (`id`) ON UPDATE CASCADE,
+  ADD CONSTRAINT `promotion_room_fk` FOREIGN KEY (`room_id`) REFERENCES `room` (`id`) ON UPDATE CASCADE;
+
+--
+-- Constraints for table `promotion_redemption`
+--
+ALTER TABLE `promotion_redemption`
+  ADD CONSTRAINT `promotion_redemption_guest_fk` FOREIGN KEY (`guest_id`) REFERENCES `guest` (`id`) ON UPDATE CASCADE,
+  ADD CONSTRAINT `promotion_redemption_promotion_fk` FOREIGN KEY (`promotion_id`) REFERENCES `promotion` (`id`) ON UPDATE CASCADE,
+  ADD CONSTRAINT `promotion_redemption_reservation_fk` FOREIGN KEY (`reservation_id`) REFERENCES `reservation` (`id`) ON DELETE CASCADE ON UPDATE CASCADE;
+
+--
+-- Constraints for table `rate_plan`
+--
+ALTER TABLE `rate_plan`
+  ADD CONSTRAINT `rate_plan_room_fk` FOREIGN KEY (`room_id`) REFERENCES `room` (`id`) ON UPDATE CASCADE,
+  ADD CONSTRAINT `rate_plan_service_level_fk` FOREIGN KEY (`service_level_id`) REFERENCES `service_level` (`id`) ON UPDATE CASCADE;
+
+--
+-- Constraints for table `refund`
+--
+ALTER TABLE `refund`
+  ADD CONSTRAINT `refund_payment_fk` FOREIGN KEY (`payment_id`) REFERENCES `payment` (`id`) ON UPDATE CASCADE;
+
+--
+-- Constraints for table `relocation`
+--
+ALTER TABLE `relocation`
+  ADD CONSTRAINT `relocation_from_room_fk` FOREIGN KEY (`from_room_id`) REFERENCES `room` (`id`) ON UPDATE CASCADE,
+  ADD CONSTRAINT `relocation_guest_fk` FOREIGN KEY (`relocated_by`) REFERENCES `guest` (`id`) ON DELETE SET NULL ON UPDATE CASCADE,
+  ADD CONSTRAINT `relocation_reservation_fk` FOREIGN KEY (`reservation_id`) REFERENCES `reservation` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+  ADD CONSTRAINT `relocation_room_reserved_fk` FOREIGN KEY (`room_reserved_id`) REFERENCES `room_reserved` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+  ADD CONSTRAINT `relocation_to_room_fk` FOREIGN KEY (`to_room_id`) REFERENCES `room` (`id`) ON UPDATE CASCADE;
+
 --
 -- Constraints for table `reservation`
 --
 ALTER TABLE `reservation`
+  ADD CONSTRAINT `reservation_group_booking_fk` FOREIGN KEY (`group_id`) REFERENCES `group_booking` (`id`) ON DELETE SET NULL ON UPDATE CASCADE,
   ADD CONSTRAINT `reservation_guest_fk` FOREIGN KEY (`guest_id`) REFERENCES `guest` (`id`) ON UPDATE CASCADE;
 
+--
+-- Constraints for table `reservation_change`
+--
+ALTER TABLE `reservation_change`
+  ADD CONSTRAINT `reservation_change_guest_fk` FOREIGN KEY (`changed_by`) REFERENCES `guest` (`id`) ON DELETE SET NULL ON UPDATE CASCADE,
+  ADD CONSTRAINT `reservation_change_reservation_fk` FOREIGN KEY (`reservation_id`) REFERENCES `reservation` (`id`) ON DELETE CASCADE ON UPDATE CASCADE;
+
 --
 -- Constraints for table `room`
 --
@@ -489,6 +1375,13 @@ ALTER TABLE `room`
 ALTER TABLE `room_facilitate`
   ADD CONSTRAINT `room_facilitate_room_fk` FOREIGN KEY (`room_id`) REFERENCES `room` (`id`) ON UPDATE CASCADE;
 
+--
+-- Constraints for table `room_night`
+--
+ALTER TABLE `room_night`
+  ADD CONSTRAINT `room_night_rate_plan_fk` FOREIGN KEY (`rate_plan_id`) REFERENCES `rate_plan` (`id`) ON DELETE SET NULL ON UPDATE CASCADE,
+  ADD CONSTRAINT `room_night_room_reserved_fk` FOREIGN KEY (`room_reserved_id`) REFERENCES `room_reserved` (`id`) ON DELETE CASCADE ON UPDATE CASCADE;
+
 --
 -- Constraints for table `room_reserved`
 --
@@ -507,6 +1400,22 @@ ALTER TABLE `service_level`
 --
 ALTER TABLE `stay_tracking`
   ADD CONSTRAINT `stay_tracking_reservation_fk` FOREIGN KEY (`reservation_id`) REFERENCES `reservation` (`id`) ON UPDATE CASCADE;
+
+--
+-- Constraints for table `tax_rule`
+--
+ALTER TABLE `tax_rule`
+  ADD CONSTRAINT `tax_rule_city_fk` FOREIGN KEY (`city_id`) REFERENCES `city` (`id`) ON UPDATE CASCADE,
+  ADD CONSTRAINT `tax_rule_hotel_fk` FOREIGN KEY (`hotel_id`) REFERENCES `hotel` (`id`) ON UPDATE CASCADE;
+
+--
+-- Constraints for table `waitlist_entry`
+--
+ALTER TABLE `waitlist_entry`
+  ADD CONSTRAINT `waitlist_entry_guest_fk` FOREIGN KEY (`guest_id`) REFERENCES `guest` (`id`) ON UPDATE CASCADE,
+  ADD CONSTRAINT `waitlist_entry_hotel_fk` FOREIGN KEY (`hotel_id`) REFERENCES `hotel` (`id`) ON UPDATE CASCADE,
+  ADD CONSTRAINT `waitlist_entry_reservation_fk` FOREIGN KEY (`reservation_id`) REFERENCES `reservation` (`id`) ON DELETE SET NULL ON UPDATE CASCADE,
+  ADD CONSTRAINT `waitlist_entry_service_level_fk` FOREIGN KEY (`service_level_id`) REFERENCES `service_level` (`id`) ON UPDATE CASCADE;
 COMMIT;
 
 /*!40101 SET CHARACTER_SET_CLIENT=@OLD_CHARACTER_SET_CLIENT */;
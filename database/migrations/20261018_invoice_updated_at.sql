@@ -0,0 +1,5 @@
+-- Records when the totals and lines of an invoice change, the cached PDF
+-- of the invoice is keyed on it.
+
+ALTER TABLE `invoice`
+  ADD `updated_at` timestamp NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp() AFTER `canceled_at`;
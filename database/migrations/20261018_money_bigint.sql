@@ -0,0 +1,177 @@
+-- Stores money as a BIGINT number of minor units of VND, the configured
+-- currency (conf/app.conf currency), instead of VARCHAR text such as
+-- '12.34 USD', so that amounts filter and sort numerically.
+--
+-- Amounts in another currency are converted to VND at the latest rate of
+-- exchange_rate, stored either way. The migration stops before changing
+-- any table when a currency has no rate: add it and run it again. A text
+-- without currency is in VND. VND has no minor unit, so the amount in VND
+-- is the rounded amount in major units times the rate.
+
+-- value of one unit of each currency in VND
+CREATE TABLE `money_rate` (
+  `currency` char(3) COLLATE utf8mb4_unicode_ci NOT NULL PRIMARY KEY,
+  `rate` decimal(30,10) NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+INSERT INTO `money_rate` VALUES ('VND', 1);
+INSERT IGNORE INTO `money_rate`
+  SELECT r.`base`, r.`rate` FROM `exchange_rate` r
+  WHERE r.`currency` = 'VND' AND r.`date` = (SELECT MAX(`date`) FROM `exchange_rate`
+    WHERE `base` = r.`base` AND `currency` = 'VND');
+INSERT IGNORE INTO `money_rate`
+  SELECT r.`currency`, 1 / r.`rate` FROM `exchange_rate` r
+  WHERE r.`base` = 'VND' AND r.`date` = (SELECT MAX(`date`) FROM `exchange_rate`
+    WHERE `base` = 'VND' AND `currency` = r.`currency`);
+
+-- fails with "Column 'rate' cannot be null" on a currency without a rate
+INSERT INTO `money_rate` (`currency`, `rate`)
+  SELECT `used`.`currency`, NULL FROM (
+    SELECT SUBSTRING_INDEX(`amount`, ' ', -1) AS `currency` FROM `invoice` WHERE `amount` LIKE '% %'
+    UNION
+    SELECT SUBSTRING_INDEX(`amount`, ' ', -1) AS `currency` FROM `invoice_line` WHERE `amount` LIKE '% %'
+    UNION
+    SELECT SUBSTRING_INDEX(`amount`, ' ', -1) AS `currency` FROM `payment` WHERE `amount` LIKE '% %'
+    UNION
+    SELECT SUBSTRING_INDEX(`amount`, ' ', -1) AS `currency` FROM `refund` WHERE `amount` LIKE '% %'
+    UNION
+    SELECT SUBSTRING_INDEX(`amount`, ' ', -1) AS `currency` FROM `promotion` WHERE `amount` LIKE '% %'
+    UNION
+    SELECT SUBSTRING_INDEX(`amount`, ' ', -1) AS `currency` FROM `promotion_redemption` WHERE `amount` LIKE '% %'
+    UNION
+    SELECT SUBSTRING_INDEX(`price`, ' ', -1) AS `currency` FROM `rate_plan` WHERE `price` LIKE '% %'
+    UNION
+    SELECT SUBSTRING_INDEX(`total_price`, ' ', -1) AS `currency` FROM `reservation` WHERE `total_price` LIKE '% %'
+    UNION
+    SELECT SUBSTRING_INDEX(`new_total`, ' ', -1) AS `currency` FROM `reservation_change` WHERE `new_total` LIKE '% %'
+    UNION
+    SELECT SUBSTRING_INDEX(`current_price`, ' ', -1) AS `currency` FROM `room` WHERE `current_price` LIKE '% %'
+    UNION
+    SELECT SUBSTRING_INDEX(`price`, ' ', -1) AS `currency` FROM `room_reserved` WHERE `price` LIKE '% %'
+    UNION
+    SELECT SUBSTRING_INDEX(`price`, ' ', -1) AS `currency` FROM `room_night` WHERE `price` LIKE '% %'
+    UNION
+    SELECT SUBSTRING_INDEX(`amount`, ' ', -1) AS `currency` FROM `tax_rule` WHERE `amount` LIKE '% %'
+  ) AS `used`
+  WHERE `used`.`currency` NOT IN (SELECT `currency` FROM `money_rate`);
+
+-- invoice
+UPDATE `invoice` t JOIN `money_rate` m
+  ON m.`currency` = IF(t.`amount` LIKE '% %', SUBSTRING_INDEX(t.`amount`, ' ', -1), 'VND')
+SET
+  t.`subtotal` = ROUND(CAST(SUBSTRING_INDEX(t.`subtotal`, ' ', 1) AS DECIMAL(30,4)) * m.`rate`),
+  t.`discount_amount` = ROUND(CAST(SUBSTRING_INDEX(t.`discount_amount`, ' ', 1) AS DECIMAL(30,4)) * m.`rate`),
+  t.`tax_amount` = ROUND(CAST(SUBSTRING_INDEX(t.`tax_amount`, ' ', 1) AS DECIMAL(30,4)) * m.`rate`),
+  t.`fee_amount` = ROUND(CAST(SUBSTRING_INDEX(t.`fee_amount`, ' ', 1) AS DECIMAL(30,4)) * m.`rate`),
+  t.`amount` = ROUND(CAST(SUBSTRING_INDEX(t.`amount`, ' ', 1) AS DECIMAL(30,4)) * m.`rate`);
+ALTER TABLE `invoice`
+  MODIFY `subtotal` bigint(20) NOT NULL DEFAULT 0,
+  MODIFY `discount_amount` bigint(20) NOT NULL DEFAULT 0,
+  MODIFY `tax_amount` bigint(20) NOT NULL DEFAULT 0,
+  MODIFY `fee_amount` bigint(20) NOT NULL DEFAULT 0,
+  MODIFY `amount` bigint(20) NOT NULL DEFAULT 0;
+
+-- invoice_line
+UPDATE `invoice_line` t JOIN `money_rate` m
+  ON m.`currency` = IF(t.`amount` LIKE '% %', SUBSTRING_INDEX(t.`amount`, ' ', -1), 'VND')
+SET
+  t.`unit_price` = ROUND(CAST(SUBSTRING_INDEX(t.`unit_price`, ' ', 1) AS DECIMAL(30,4)) * m.`rate`),
+  t.`amount` = ROUND(CAST(SUBSTRING_INDEX(t.`amount`, ' ', 1) AS DECIMAL(30,4)) * m.`rate`);
+ALTER TABLE `invoice_line`
+  MODIFY `unit_price` bigint(20) NOT NULL DEFAULT 0,
+  MODIFY `amount` bigint(20) NOT NULL DEFAULT 0;
+
+-- payment
+UPDATE `payment` t JOIN `money_rate` m
+  ON m.`currency` = IF(t.`amount` LIKE '% %', SUBSTRING_INDEX(t.`amount`, ' ', -1), 'VND')
+SET
+  t.`refunded_amount` = ROUND(CAST(SUBSTRING_INDEX(t.`refunded_amount`, ' ', 1) AS DECIMAL(30,4)) * m.`rate`),
+  t.`amount` = ROUND(CAST(SUBSTRING_INDEX(t.`amount`, ' ', 1) AS DECIMAL(30,4)) * m.`rate`);
+ALTER TABLE `payment`
+  MODIFY `amount` bigint(20) NOT NULL DEFAULT 0,
+  MODIFY `refunded_amount` bigint(20) NOT NULL DEFAULT 0;
+
+-- refund
+UPDATE `refund` t JOIN `money_rate` m
+  ON m.`currency` = IF(t.`amount` LIKE '% %', SUBSTRING_INDEX(t.`amount`, ' ', -1), 'VND')
+SET
+  t.`amount` = ROUND(CAST(SUBSTRING_INDEX(t.`amount`, ' ', 1) AS DECIMAL(30,4)) * m.`rate`);
+ALTER TABLE `refund`
+  MODIFY `amount` bigint(20) NOT NULL DEFAULT 0;
+
+-- promotion
+UPDATE `promotion` t JOIN `money_rate` m
+  ON m.`currency` = IF(t.`amount` LIKE '% %', SUBSTRING_INDEX(t.`amount`, ' ', -1), 'VND')
+SET
+  t.`amount` = ROUND(CAST(SUBSTRING_INDEX(t.`amount`, ' ', 1) AS DECIMAL(30,4)) * m.`rate`);
+ALTER TABLE `promotion`
+  MODIFY `amount` bigint(20) NOT NULL DEFAULT 0;
+
+-- promotion_redemption
+UPDATE `promotion_redemption` t JOIN `money_rate` m
+  ON m.`currency` = IF(t.`amount` LIKE '% %', SUBSTRING_INDEX(t.`amount`, ' ', -1), 'VND')
+SET
+  t.`amount` = ROUND(CAST(SUBSTRING_INDEX(t.`amount`, ' ', 1) AS DECIMAL(30,4)) * m.`rate`);
+ALTER TABLE `promotion_redemption`
+  MODIFY `amount` bigint(20) NOT NULL DEFAULT 0;
+
+-- rate_plan
+UPDATE `rate_plan` t JOIN `money_rate` m
+  ON m.`currency` = IF(t.`price` LIKE '% %', SUBSTRING_INDEX(t.`price`, ' ', -1), 'VND')
+SET
+  t.`price` = ROUND(CAST(SUBSTRING_INDEX(t.`price`, ' ', 1) AS DECIMAL(30,4)) * m.`rate`);
+ALTER TABLE `rate_plan`
+  MODIFY `price` bigint(20) NOT NULL DEFAULT 0;
+
+-- reservation
+UPDATE `reservation` t JOIN `money_rate` m
+  ON m.`currency` = IF(t.`total_price` LIKE '% %', SUBSTRING_INDEX(t.`total_price`, ' ', -1), 'VND')
+SET
+  t.`total_price` = ROUND(CAST(SUBSTRING_INDEX(t.`total_price`, ' ', 1) AS DECIMAL(30,4)) * m.`rate`);
+ALTER TABLE `reservation`
+  MODIFY `total_price` bigint(20) NOT NULL DEFAULT 0;
+
+-- reservation_change
+UPDATE `reservation_change` t JOIN `money_rate` m
+  ON m.`currency` = IF(t.`new_total` LIKE '% %', SUBSTRING_INDEX(t.`new_total`, ' ', -1), 'VND')
+SET
+  t.`old_total` = ROUND(CAST(SUBSTRING_INDEX(t.`old_total`, ' ', 1) AS DECIMAL(30,4)) * m.`rate`),
+  t.`difference` = ROUND(CAST(SUBSTRING_INDEX(t.`difference`, ' ', 1) AS DECIMAL(30,4)) * m.`rate`),
+  t.`new_total` = ROUND(CAST(SUBSTRING_INDEX(t.`new_total`, ' ', 1) AS DECIMAL(30,4)) * m.`rate`);
+ALTER TABLE `reservation_change`
+  MODIFY `old_total` bigint(20) NOT NULL DEFAULT 0,
+  MODIFY `new_total` bigint(20) NOT NULL DEFAULT 0,
+  MODIFY `difference` bigint(20) NOT NULL DEFAULT 0;
+
+-- room
+UPDATE `room` t JOIN `money_rate` m
+  ON m.`currency` = IF(t.`current_price` LIKE '% %', SUBSTRING_INDEX(t.`current_price`, ' ', -1), 'VND')
+SET
+  t.`current_price` = ROUND(CAST(SUBSTRING_INDEX(t.`current_price`, ' ', 1) AS DECIMAL(30,4)) * m.`rate`);
+ALTER TABLE `room`
+  MODIFY `current_price` bigint(20) NOT NULL DEFAULT 0;
+
+-- room_reserved
+UPDATE `room_reserved` t JOIN `money_rate` m
+  ON m.`currency` = IF(t.`price` LIKE '% %', SUBSTRING_INDEX(t.`price`, ' ', -1), 'VND')
+SET
+  t.`price` = ROUND(CAST(SUBSTRING_INDEX(t.`price`, ' ', 1) AS DECIMAL(30,4)) * m.`rate`);
+ALTER TABLE `room_reserved`
+  MODIFY `price` bigint(20) DEFAULT NULL;
+
+-- room_night
+UPDATE `room_night` t JOIN `money_rate` m
+  ON m.`currency` = IF(t.`price` LIKE '% %', SUBSTRING_INDEX(t.`price`, ' ', -1), 'VND')
+SET
+  t.`price` = ROUND(CAST(SUBSTRING_INDEX(t.`price`, ' ', 1) AS DECIMAL(30,4)) * m.`rate`);
+ALTER TABLE `room_night`
+  MODIFY `price` bigint(20) NOT NULL DEFAULT 0;
+
+-- tax_rule
+UPDATE `tax_rule` t JOIN `money_rate` m
+  ON m.`currency` = IF(t.`amount` LIKE '% %', SUBSTRING_INDEX(t.`amount`, ' ', -1), 'VND')
+SET
+  t.`amount` = ROUND(CAST(SUBSTRING_INDEX(t.`amount`, ' ', 1) AS DECIMAL(30,4)) * m.`rate`);
+ALTER TABLE `tax_rule`
+  MODIFY `amount` bigint(20) NOT NULL DEFAULT 0;
+
+DROP TABLE `money_rate`;
@@ -0,0 +1,5 @@
+-- Records whether the guest booked the airport shuttle, which the shuttle
+-- fees of tax_rule are charged on.
+
+ALTER TABLE `reservation`
+  ADD `airport_shuttle` tinyint(3) UNSIGNED NOT NULL DEFAULT 0 AFTER `discount_percent`;
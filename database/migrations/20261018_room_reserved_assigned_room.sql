@@ -0,0 +1,5 @@
+-- Adds the room assigned at check-in, which RoomReserved maps but the
+-- schema was missing, so that reserved rooms can be inserted.
+
+ALTER TABLE `room_reserved`
+  ADD `assigned_room_id` int(10) UNSIGNED DEFAULT NULL AFTER `room_id`;
@@ -1,9 +1,21 @@
 package main
 
 import (
+	"os"
+
 	"easybook/controllers"
 	_ "easybook/routers"
+	"easybook/services/exchange"
+	"easybook/services/group"
+	"easybook/services/hold"
+	"easybook/services/idempotency"
+	"easybook/services/noshow"
+	"easybook/services/notification"
+	"easybook/services/payment"
+	"easybook/services/waitlist"
+	"easybook/types"
 	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/logs"
 	"github.com/astaxie/beego/orm"
 	"github.com/astaxie/beego/plugins/cors"
 	_ "github.com/go-sql-driver/mysql"
@@ -11,6 +23,7 @@ import (
 
 func main() {
 	orm.RegisterDataBase("default", "mysql", beego.AppConfig.String("sqlconn"))
+	types.DefaultCurrency = beego.AppConfig.DefaultString("currency", types.DefaultCurrency)
 	if beego.BConfig.RunMode == "dev" {
 		beego.BConfig.WebConfig.DirectoryIndex = true
 		beego.BConfig.WebConfig.StaticDir["/swagger"] = "swagger"
@@ -19,11 +32,46 @@ func main() {
 	beego.InsertFilter("*", beego.BeforeRouter, cors.Allow(&cors.Options{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "OPTIONS", "DELETE"},
-		AllowHeaders:     []string{"Origin", "Authorization", "Access-Control-Allow-Origin", "Content-Type", "X-Token"},
-		ExposeHeaders:    []string{"Content-Length", "Access-Control-Allow-Origin", "Content-Type"},
+		AllowHeaders:     []string{"Origin", "Authorization", "Access-Control-Allow-Origin", "Content-Type", "X-Token", "Idempotency-Key"},
+		ExposeHeaders:    []string{"Content-Length", "Access-Control-Allow-Origin", "Content-Type", "Idempotent-Replayed"},
 		AllowCredentials: true,
 	}))
 
+	beego.InsertFilter("/v1/*", beego.BeforeRouter, controllers.AuthFilter)
+
 	beego.ErrorController(&controllers.ErrorController{})
+
+	if path := beego.AppConfig.String("exchangeratefile"); path != "" {
+		if n, err := exchange.LoadFile(path); err != nil {
+			logs.Error("loading exchange rates from %s: %v", path, err)
+		} else {
+			logs.Info("loaded %d exchange rates from %s", n, path)
+		}
+	}
+	if err := payment.Setup(); err != nil {
+		logs.Critical(err)
+		os.Exit(1)
+	}
+	if err := notification.Setup(); err != nil {
+		logs.Error(err)
+	}
+	if beego.AppConfig.DefaultBool("notifydispatch", true) {
+		notification.NewDispatcher().Start()
+	}
+	if beego.AppConfig.DefaultBool("holdsweep", true) {
+		hold.NewSweeper().Start()
+	}
+	if beego.AppConfig.DefaultBool("idempotencysweep", true) {
+		idempotency.NewSweeper().Start()
+	}
+	if beego.AppConfig.DefaultBool("waitlistmatch", true) {
+		waitlist.NewMatcher().Start()
+	}
+	if beego.AppConfig.DefaultBool("groupreleasesweep", true) {
+		group.NewReleaser().Start()
+	}
+	if beego.AppConfig.DefaultBool("noshowdetect", true) {
+		noshow.NewDetector().Start()
+	}
 	beego.Run()
 }
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"easybook/queryspec"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// ExchangeRate is the value of one Base in Currency from Date on, until the
+// next rate of the pair.
+type ExchangeRate struct {
+	Id        int       `orm:"column(id);auto"`
+	Base      string    `orm:"column(base);size(3)"`
+	Currency  string    `orm:"column(currency);size(3)"`
+	Rate      float64   `orm:"column(rate);digits(20);decimals(10)"`
+	Date      time.Time `orm:"column(date);type(date)"`
+	CreatedAt time.Time `orm:"column(created_at);type(timestamp)"`
+	UpdatedAt time.Time `orm:"column(updated_at);type(timestamp)"`
+}
+
+var exchangeRateSchema = queryspec.NewSchema(new(ExchangeRate))
+
+func (t *ExchangeRate) TableName() string {
+	return "exchange_rate"
+}
+
+func init() {
+	orm.RegisterModel(new(ExchangeRate))
+}
+
+// SetExchangeRate inserts the rate of a pair on a date within the
+// transaction of o, or updates it if there is one already. m is filled
+// with the stored record.
+func SetExchangeRate(o orm.Ormer, m *ExchangeRate) error {
+	rate := m.Rate
+	created, _, err := o.ReadOrCreate(m, "Base", "Currency", "Date")
+	if err != nil || created || m.Rate == rate {
+		return err
+	}
+	m.Rate = rate
+	_, err = o.Update(m, "Rate")
+	return err
+}
+
+// GetExchangeRateById retrieves ExchangeRate by Id. Returns error if
+// Id doesn't exist
+func GetExchangeRateById(id int) (v *ExchangeRate, err error) {
+	o := orm.NewOrm()
+	v = &ExchangeRate{Id: id}
+	if err = o.Read(v); err == nil {
+		return v, nil
+	}
+	return nil, err
+}
+
+// GetExchangeRate retrieves the rate of a pair in effect on date. Returns
+// orm.ErrNoRows if there is none.
+func GetExchangeRate(o orm.Ormer, base string, currency string, date time.Time) (v *ExchangeRate, err error) {
+	v = &ExchangeRate{}
+	err = o.QueryTable(new(ExchangeRate)).
+		Filter("Base", base).Filter("Currency", currency).Filter("Date__lte", date).
+		OrderBy("-Date").One(v)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// GetAllExchangeRate retrieves all ExchangeRate matches certain condition. Returns empty list if
+// no records exist
+func GetAllExchangeRate(spec *queryspec.Spec) (page *Page, err error) {
+	o := orm.NewOrm()
+	qs := o.QueryTable(new(ExchangeRate))
+	var l []ExchangeRate
+	return getAll(qs, &l, exchangeRateSchema, spec)
+}
+
+// DeleteExchangeRate deletes ExchangeRate by Id and returns error if
+// the record to be deleted doesn't exist
+func DeleteExchangeRate(id int) (err error) {
+	o := orm.NewOrm()
+	v := ExchangeRate{Id: id}
+	// ascertain id exists in the database
+	if err = o.Read(&v); err == nil {
+		var num int64
+		if num, err = o.Delete(&ExchangeRate{Id: id}); err == nil {
+			fmt.Println("Number of records deleted in database:", num)
+		}
+	}
+	return
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"errors"
+	"reflect"
+
+	"easybook/queryspec"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// Present eager loads the relations listed in spec.Expand into v, which is
+// a pointer to a model, e.g. the result of GetHotelById, and trims it to
+// spec.Fields.
+func Present(v interface{}, spec *queryspec.Spec) (interface{}, error) {
+	schema, ok := queryspec.SchemaOf(v)
+	if !ok {
+		return nil, errors.New("Error: unknown model")
+	}
+	if err := spec.Validate(schema); err != nil {
+		return nil, err
+	}
+
+	records := reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(v)), 0, 1)
+	records = reflect.Append(records, reflect.ValueOf(v))
+	if err := expand(orm.NewOrm(), records, schema, spec.Expand); err != nil {
+		return nil, err
+	}
+	return spec.Project(schema, v), nil
+}
+
+// expand loads the relation paths of records, a slice of models or pointers
+// to models, with one query per relation and level.
+func expand(o orm.Ormer, records reflect.Value, schema *queryspec.Schema, paths [][]string) error {
+	// group paths by their first relation so it is loaded once
+	var names []string
+	nested := make(map[string][][]string)
+	for _, path := range paths {
+		if _, ok := nested[path[0]]; !ok {
+			names = append(names, path[0])
+			nested[path[0]] = nil
+		}
+		if len(path) > 1 {
+			nested[path[0]] = append(nested[path[0]], path[1:])
+		}
+	}
+
+	for _, name := range names {
+		f, _ := schema.Lookup(name)
+		rs, _ := schema.Related(f)
+
+		var ids []interface{}
+		seen := make(map[int64]bool)
+		for i := 0; i < records.Len(); i++ {
+			rel := reflect.Indirect(records.Index(i)).FieldByName(f.Name)
+			if rel.IsNil() {
+				continue
+			}
+			id := rel.Elem().FieldByName("Id").Int()
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) == 0 {
+			continue
+		}
+
+		related := reflect.New(reflect.SliceOf(rs.Type()))
+		qs := o.QueryTable(reflect.New(rs.Type()).Interface())
+		if _, err := qs.Filter("Id__in", ids...).Limit(len(ids)).All(related.Interface()); err != nil {
+			return err
+		}
+
+		l := related.Elem()
+		byID := make(map[int64]reflect.Value, l.Len())
+		for i := 0; i < l.Len(); i++ {
+			byID[l.Index(i).FieldByName("Id").Int()] = l.Index(i).Addr()
+		}
+		for i := 0; i < records.Len(); i++ {
+			rel := reflect.Indirect(records.Index(i)).FieldByName(f.Name)
+			if rel.IsNil() {
+				continue
+			}
+			if v, ok := byID[rel.Elem().FieldByName("Id").Int()]; ok {
+				rel.Set(v)
+			}
+		}
+
+		if len(nested[name]) != 0 {
+			if err := expand(o, l, rs, nested[name]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
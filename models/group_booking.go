@@ -0,0 +1,91 @@
+package models
+
+import (
+	"time"
+
+	"easybook/queryspec"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// GroupBooking is a block of rooms of a hotel reserved by an organizer for
+// the nights from StartDate to the day before EndDate. The rooms not yet
+// given to a guest are reserved by the pending reservation BlockId of the
+// organizer, they return to inventory once ReleaseDate has passed. Each
+// guest given a room gets a reservation of the group. With MasterInvoice
+// the group is billed to the organizer on one invoice instead of an invoice
+// per guest.
+type GroupBooking struct {
+	Id            int          `orm:"column(id);auto"`
+	Name          string       `orm:"column(name);size(100)"`
+	OrganizerId   *Guest       `orm:"column(organizer_id);rel(fk)"`
+	HotelId       *Hotel       `orm:"column(hotel_id);rel(fk)"`
+	StartDate     time.Time    `orm:"column(start_date);type(date)"`
+	EndDate       time.Time    `orm:"column(end_date);type(date)"`
+	ReleaseDate   time.Time    `orm:"column(release_date);type(date)"`
+	MasterInvoice int8         `orm:"column(master_invoice)"`
+	BlockId       *Reservation `orm:"column(block_id);rel(fk);null"`
+	ReleasedAt    time.Time    `orm:"column(released_at);type(timestamp);null"`
+	CreatedAt     time.Time    `orm:"column(created_at);type(timestamp)"`
+	UpdatedAt     time.Time    `orm:"column(updated_at);type(timestamp)"`
+}
+
+var groupBookingSchema = queryspec.NewSchema(new(GroupBooking)).
+	Restrict("OrganizerId", RoleStaff)
+
+func (t *GroupBooking) TableName() string {
+	return "group_booking"
+}
+
+func init() {
+	orm.RegisterModel(new(GroupBooking))
+}
+
+// GetGroupBookingById retrieves GroupBooking by Id. Returns error if
+// Id doesn't exist
+func GetGroupBookingById(o orm.Ormer, id int) (v *GroupBooking, err error) {
+	v = &GroupBooking{Id: id}
+	if err = o.Read(v); err == nil {
+		return v, nil
+	}
+	return nil, err
+}
+
+// GetAllGroupBooking retrieves all GroupBooking matches certain condition. Returns empty list if
+// no records exist
+func GetAllGroupBooking(spec *queryspec.Spec) (page *Page, err error) {
+	o := orm.NewOrm()
+	qs := o.QueryTable(new(GroupBooking))
+	var l []GroupBooking
+	return getAll(qs, &l, groupBookingSchema, spec)
+}
+
+// GetOrganizerGroupBookings retrieves the GroupBooking of an organizer
+// matching certain condition. Returns empty list if no records exist
+func GetOrganizerGroupBookings(guestID int, spec *queryspec.Spec) (page *Page, err error) {
+	o := orm.NewOrm()
+	qs := o.QueryTable(new(GroupBooking)).Filter("OrganizerId", guestID)
+	var l []GroupBooking
+	return getAll(qs, &l, groupBookingSchema, spec)
+}
+
+// GetGroupMembers retrieves the reservations of the guests of a group
+// which are not canceled, in order. Returns empty list if no records exist
+func GetGroupMembers(o orm.Ormer, g *GroupBooking) (l []Reservation, err error) {
+	qs := o.QueryTable(new(Reservation)).
+		Filter("GroupId", g.Id).Exclude("Status", ReservationCancelled)
+	if g.BlockId != nil {
+		qs = qs.Exclude("Id", g.BlockId.Id)
+	}
+	_, err = qs.OrderBy("Id").All(&l)
+	return
+}
+
+// GetGroupsToRelease retrieves the groups not released whose release date
+// is before date. Returns empty list if no records exist
+func GetGroupsToRelease(o orm.Ormer, date time.Time) (l []GroupBooking, err error) {
+	_, err = o.QueryTable(new(GroupBooking)).
+		Filter("ReleasedAt__isnull", true).Filter("ReleaseDate__lt", date).
+		OrderBy("Id").All(&l)
+	return
+}
@@ -1,12 +1,11 @@
 package models
 
 import (
-	"errors"
 	"fmt"
-	"reflect"
-	"strings"
 	"time"
 
+	"easybook/queryspec"
+
 	"github.com/astaxie/beego/orm"
 )
 
@@ -21,9 +20,19 @@ type Guest struct {
 	CreatedAt time.Time `orm:"column(created_at);type(timestamp)"`
 	UpdatedAt time.Time `orm:"column(updated_at);type(timestamp)"`
 	Role      int8      `orm:"column(role)"`
-	Password  string    `orm:"column(password);size(255);null"`
+	Password  string    `orm:"column(password);size(255);null" json:"-"`
+	Language  string    `orm:"column(language);size(8);null"`
 }
 
+// Roles of a Guest. Anonymous callers have RoleGuest.
+const (
+	RoleGuest int8 = iota
+	RoleStaff
+	RoleAdmin
+)
+
+var guestSchema = queryspec.NewSchema(new(Guest), "Password")
+
 func (t *Guest) TableName() string {
 	return "guest"
 }
@@ -51,82 +60,24 @@ func GetGuestById(id int) (v *Guest, err error) {
 	return nil, err
 }
 
+// GetGuestByEmail retrieves Guest by Email. Returns error if
+// Email doesn't exist
+func GetGuestByEmail(email string) (v *Guest, err error) {
+	o := orm.NewOrm()
+	v = &Guest{Email: email}
+	if err = o.Read(v, "Email"); err == nil {
+		return v, nil
+	}
+	return nil, err
+}
+
 // GetAllGuest retrieves all Guest matches certain condition. Returns empty list if
 // no records exist
-func GetAllGuest(query map[string]string, fields []string, sortby []string, order []string,
-	offset int64, limit int64) (ml []interface{}, err error) {
+func GetAllGuest(spec *queryspec.Spec) (page *Page, err error) {
 	o := orm.NewOrm()
 	qs := o.QueryTable(new(Guest))
-	// query k=v
-	for k, v := range query {
-		// rewrite dot-notation to Object__Attribute
-		k = strings.Replace(k, ".", "__", -1)
-		if strings.Contains(k, "isnull") {
-			qs = qs.Filter(k, (v == "true" || v == "1"))
-		} else {
-			qs = qs.Filter(k, v)
-		}
-	}
-	// order by:
-	var sortFields []string
-	if len(sortby) != 0 {
-		if len(sortby) == len(order) {
-			// 1) for each sort field, there is an associated order
-			for i, v := range sortby {
-				orderby := ""
-				if order[i] == "desc" {
-					orderby = "-" + v
-				} else if order[i] == "asc" {
-					orderby = v
-				} else {
-					return nil, errors.New("Error: Invalid order. Must be either [asc|desc]")
-				}
-				sortFields = append(sortFields, orderby)
-			}
-			qs = qs.OrderBy(sortFields...)
-		} else if len(sortby) != len(order) && len(order) == 1 {
-			// 2) there is exactly one order, all the sorted fields will be sorted by this order
-			for _, v := range sortby {
-				orderby := ""
-				if order[0] == "desc" {
-					orderby = "-" + v
-				} else if order[0] == "asc" {
-					orderby = v
-				} else {
-					return nil, errors.New("Error: Invalid order. Must be either [asc|desc]")
-				}
-				sortFields = append(sortFields, orderby)
-			}
-		} else if len(sortby) != len(order) && len(order) != 1 {
-			return nil, errors.New("Error: 'sortby', 'order' sizes mismatch or 'order' size is not 1")
-		}
-	} else {
-		if len(order) != 0 {
-			return nil, errors.New("Error: unused 'order' fields")
-		}
-	}
-
 	var l []Guest
-	qs = qs.OrderBy(sortFields...)
-	if _, err = qs.Limit(limit, offset).All(&l, fields...); err == nil {
-		if len(fields) == 0 {
-			for _, v := range l {
-				ml = append(ml, v)
-			}
-		} else {
-			// trim unused fields
-			for _, v := range l {
-				m := make(map[string]interface{})
-				val := reflect.ValueOf(v)
-				for _, fname := range fields {
-					m[fname] = val.FieldByName(fname).Interface()
-				}
-				ml = append(ml, m)
-			}
-		}
-		return ml, nil
-	}
-	return nil, err
+	return getAll(qs, &l, guestSchema, spec)
 }
 
 // UpdateGuest updates Guest by Id and returns error if
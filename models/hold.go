@@ -0,0 +1,171 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"easybook/queryspec"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// Hold keeps rooms for a guest for the nights from StartDate to the day
+// before EndDate until ExpiresAt, while the guest checks out. A hold
+// converted into a reservation keeps it in ReservationId and no longer
+// holds the rooms, the reservation does.
+type Hold struct {
+	Id            int          `orm:"column(id);auto"`
+	GuestId       *Guest       `orm:"column(guest_id);rel(fk)"`
+	StartDate     time.Time    `orm:"column(start_date);type(date)"`
+	EndDate       time.Time    `orm:"column(end_date);type(date)"`
+	ExpiresAt     time.Time    `orm:"column(expires_at);type(timestamp)"`
+	ReservationId *Reservation `orm:"column(reservation_id);rel(fk);null"`
+	CreatedAt     time.Time    `orm:"column(created_at);type(timestamp)"`
+	// Rooms are the ids of the rooms held.
+	Rooms []int `orm:"-" json:"rooms,omitempty"`
+}
+
+// HoldRoom is a room of a Hold.
+type HoldRoom struct {
+	Id     int   `orm:"column(id);auto"`
+	HoldId *Hold `orm:"column(hold_id);rel(fk)"`
+	RoomId *Room `orm:"column(room_id);rel(fk)"`
+}
+
+var holdSchema = queryspec.NewSchema(new(Hold)).
+	Restrict("GuestId", RoleStaff)
+
+func (t *Hold) TableName() string {
+	return "hold"
+}
+
+func (t *HoldRoom) TableName() string {
+	return "hold_room"
+}
+
+func init() {
+	orm.RegisterModel(new(Hold), new(HoldRoom))
+}
+
+// GetHoldById retrieves Hold by Id with its Rooms. Returns error if
+// Id doesn't exist
+func GetHoldById(o orm.Ormer, id int) (v *Hold, err error) {
+	v = &Hold{Id: id}
+	if err = o.Read(v); err != nil {
+		return nil, err
+	}
+	if v.Rooms, err = GetHoldRoomIds(o, id); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// GetAllHold retrieves all Hold matches certain condition. Returns empty list if
+// no records exist
+func GetAllHold(spec *queryspec.Spec) (page *Page, err error) {
+	o := orm.NewOrm()
+	qs := o.QueryTable(new(Hold))
+	var l []Hold
+	return getAll(qs, &l, holdSchema, spec)
+}
+
+// GetGuestHolds retrieves the Hold of a guest matching certain condition.
+// Returns empty list if no records exist
+func GetGuestHolds(guestID int, spec *queryspec.Spec) (page *Page, err error) {
+	o := orm.NewOrm()
+	qs := o.QueryTable(new(Hold)).Filter("GuestId", guestID)
+	var l []Hold
+	return getAll(qs, &l, holdSchema, spec)
+}
+
+// GetHoldRoomIds returns the ids of the rooms of a hold.
+func GetHoldRoomIds(o orm.Ormer, holdID int) ([]int, error) {
+	var l []HoldRoom
+	if _, err := o.QueryTable(new(HoldRoom)).Filter("HoldId", holdID).OrderBy("Id").All(&l); err != nil {
+		return nil, err
+	}
+	ids := make([]int, len(l))
+	for i, hr := range l {
+		ids[i] = hr.RoomId.Id
+	}
+	return ids, nil
+}
+
+// GetHeldRoomIds returns the ids of roomIDs held at now for any night from
+// from until the day before to by holds other than exceptHoldID which are
+// not converted.
+func GetHeldRoomIds(o orm.Ormer, roomIDs []int, from time.Time, to time.Time, now time.Time, exceptHoldID int) (ids []int, err error) {
+	var l orm.ParamsList
+	_, err = o.QueryTable(new(HoldRoom)).
+		Filter("RoomId__in", roomIDs).
+		Filter("HoldId__ReservationId__Id__isnull", true).
+		Filter("HoldId__ExpiresAt__gt", now).
+		Filter("HoldId__StartDate__lt", to).
+		Filter("HoldId__EndDate__gt", from).
+		Exclude("HoldId__Id", exceptHoldID).
+		ValuesFlat(&l, "RoomId")
+	return intsOf(l), err
+}
+
+// GetReservedRoomIds returns the ids of roomIDs reserved for any night
+// from from until the day before to by reservations other than
+// exceptReservationID which are not canceled.
+func GetReservedRoomIds(o orm.Ormer, roomIDs []int, from time.Time, to time.Time, exceptReservationID int) (ids []int, err error) {
+	var l orm.ParamsList
+	_, err = o.QueryTable(new(RoomReserved)).
+		Filter("RoomId__in", roomIDs).
+		Exclude("ReservationId__Status", ReservationCancelled).
+		Exclude("ReservationId__Id", exceptReservationID).
+		Filter("ReservationId__StartDate__lt", to).
+		Filter("ReservationId__EndDate__gt", from).
+		ValuesFlat(&l, "RoomId")
+	return intsOf(l), err
+}
+
+// GetVacantHotelIds returns the ids of the hotels with a room neither
+// reserved, see GetReservedRoomIds, nor held at now for any night from
+// from until the day before to, in ascending order.
+func GetVacantHotelIds(o orm.Ormer, from time.Time, to time.Time, now time.Time) (ids []int, err error) {
+	var l orm.ParamsList
+	_, err = o.Raw("SELECT h.id FROM hotel h WHERE EXISTS ("+
+		"SELECT 1 FROM room r WHERE r.hotel_id = h.id "+
+		"AND NOT EXISTS (SELECT 1 FROM room_reserved rr JOIN reservation re ON re.id = rr.reservation_id "+
+		"WHERE rr.room_id = r.id AND re.status <> ? AND re.start_date < ? AND re.end_date > ?) "+
+		"AND NOT EXISTS (SELECT 1 FROM hold_room hr JOIN hold ho ON ho.id = hr.hold_id "+
+		"WHERE hr.room_id = r.id AND ho.reservation_id IS NULL AND ho.expires_at > ? AND ho.start_date < ? AND ho.end_date > ?)"+
+		") ORDER BY h.id", ReservationCancelled, to, from, now, to, from).ValuesFlat(&l)
+	return intsOf(l), err
+}
+
+// DeleteExpiredHolds deletes the holds expired at now which are not
+// converted, their rooms are deleted on cascade, and returns their number.
+func DeleteExpiredHolds(o orm.Ormer, now time.Time) (int64, error) {
+	return o.QueryTable(new(Hold)).
+		Filter("ReservationId__isnull", true).Filter("ExpiresAt__lte", now).
+		Delete()
+}
+
+// DeleteHold deletes Hold by Id with its rooms. Returns error if the
+// record to be deleted doesn't exist
+func DeleteHold(o orm.Ormer, id int) (err error) {
+	v := Hold{Id: id}
+	// ascertain id exists in the database
+	if err = o.Read(&v); err == nil {
+		var num int64
+		if num, err = o.Delete(&Hold{Id: id}); err == nil {
+			fmt.Println("Number of records deleted in database:", num)
+		}
+	}
+	return
+}
+
+// intsOf returns the ids of a flat list of values.
+func intsOf(l orm.ParamsList) []int {
+	ids := make([]int, 0, len(l))
+	for _, v := range l {
+		id, _ := strconv.Atoi(fmt.Sprint(v))
+		ids = append(ids, id)
+	}
+	return ids
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// IdempotencyKey is a request made under an Idempotency-Key header. Keys
+// are unique per Scope, the caller, and Fingerprint identifies the request
+// first made with the key. Status is the HTTP status of its Response, zero
+// while the request is in progress.
+type IdempotencyKey struct {
+	Id          int       `orm:"column(id);auto"`
+	Scope       string    `orm:"column(scope);size(64)"`
+	Key         string    `orm:"column(idem_key);size(255)"`
+	Fingerprint string    `orm:"column(fingerprint);size(64)"`
+	Status      int       `orm:"column(status)"`
+	Response    string    `orm:"column(response);type(text);null"`
+	CreatedAt   time.Time `orm:"column(created_at);type(timestamp)"`
+}
+
+func (t *IdempotencyKey) TableName() string {
+	return "idempotency_key"
+}
+
+func init() {
+	orm.RegisterModel(new(IdempotencyKey))
+}
+
+// DeleteIdempotencyKeysBefore deletes the keys created before t and returns
+// their number.
+func DeleteIdempotencyKeysBefore(o orm.Ormer, t time.Time) (int64, error) {
+	return o.QueryTable(new(IdempotencyKey)).Filter("CreatedAt__lt", t).Delete()
+}
@@ -1,23 +1,68 @@
 package models
 
 import (
-	"errors"
 	"fmt"
-	"reflect"
-	"strings"
 	"time"
 
+	"easybook/queryspec"
+	"easybook/types"
+
 	"github.com/astaxie/beego/orm"
 )
 
 type Invoice struct {
-	Id            int          `orm:"column(id);auto"`
-	GuestId       *Guest       `orm:"column(guest_id);rel(fk)"`
-	ReservationId *Reservation `orm:"column(reservation_id);rel(fk)"`
-	Amount        float32      `orm:"column(amount)"`
-	IssuedAt      time.Time    `orm:"column(issued_at);type(timestamp)"`
-	PaidAt        time.Time    `orm:"column(paid_at);type(timestamp);null"`
-	CanceledAt    time.Time    `orm:"column(canceled_at);type(timestamp);null"`
+	Id             int          `orm:"column(id);auto"`
+	GuestId        *Guest       `orm:"column(guest_id);rel(fk)"`
+	ReservationId  *Reservation `orm:"column(reservation_id);rel(fk)"`
+	HotelId        *Hotel       `orm:"column(hotel_id);rel(fk);null"`
+	Number         string       `orm:"column(number);size(32);null"`
+	Subtotal       types.Money  `orm:"column(subtotal)"`
+	DiscountAmount types.Money  `orm:"column(discount_amount)"`
+	TaxAmount      types.Money  `orm:"column(tax_amount)"`
+	FeeAmount      types.Money  `orm:"column(fee_amount)"`
+	Amount         types.Money  `orm:"column(amount)"`
+	// DisplayCurrency and ExchangeRate are copied from the reservation, so
+	// the invoice shows the amount the guest booked in.
+	DisplayCurrency string    `orm:"column(display_currency);size(3);null"`
+	ExchangeRate    float64   `orm:"column(exchange_rate);digits(20);decimals(10);null"`
+	IssuedAt        time.Time `orm:"column(issued_at);type(timestamp)"`
+	PaidAt          time.Time `orm:"column(paid_at);type(timestamp);null"`
+	CanceledAt      time.Time `orm:"column(canceled_at);type(timestamp);null"`
+	// UpdatedAt changes with the totals and lines, e.g. when the invoice is
+	// repriced.
+	UpdatedAt time.Time `orm:"column(updated_at);type(timestamp);auto_now"`
+}
+
+var invoiceSchema = queryspec.NewSchema(new(Invoice)).
+	Restrict("GuestId", RoleStaff).
+	Restrict("ReservationId", RoleStaff)
+
+// InvoiceLine is an item of an Invoice. Discounts have a negative Amount.
+type InvoiceLine struct {
+	Id             int           `orm:"column(id);auto"`
+	InvoiceId      *Invoice      `orm:"column(invoice_id);rel(fk)"`
+	Kind           string        `orm:"column(kind);size(20)"`
+	Description    string        `orm:"column(description);size(255)"`
+	RoomReservedId *RoomReserved `orm:"column(room_reserved_id);rel(fk);null"`
+	Date           time.Time     `orm:"column(date);type(date);null"`
+	Quantity       int           `orm:"column(quantity)"`
+	UnitPrice      types.Money   `orm:"column(unit_price)"`
+	Amount         types.Money   `orm:"column(amount)"`
+}
+
+// Kinds of InvoiceLine.
+const (
+	LineRoomNight = "room_night"
+	LineDiscount  = "discount"
+	LineTax       = "tax"
+	LineFee       = "fee"
+)
+
+var invoiceLineSchema = queryspec.NewSchema(new(InvoiceLine)).
+	Restrict("InvoiceId", RoleStaff)
+
+func (t *InvoiceLine) TableName() string {
+	return "invoice_line"
 }
 
 func (t *Invoice) TableName() string {
@@ -25,7 +70,7 @@ func (t *Invoice) TableName() string {
 }
 
 func init() {
-	orm.RegisterModel(new(Invoice))
+	orm.RegisterModel(new(Invoice), new(InvoiceLine))
 }
 
 // AddInvoice insert a new Invoice into database and returns
@@ -49,80 +94,11 @@ func GetInvoiceById(id int) (v *Invoice, err error) {
 
 // GetAllInvoice retrieves all Invoice matches certain condition. Returns empty list if
 // no records exist
-func GetAllInvoice(query map[string]string, fields []string, sortby []string, order []string,
-	offset int64, limit int64) (ml []interface{}, err error) {
+func GetAllInvoice(spec *queryspec.Spec) (page *Page, err error) {
 	o := orm.NewOrm()
 	qs := o.QueryTable(new(Invoice))
-	// query k=v
-	for k, v := range query {
-		// rewrite dot-notation to Object__Attribute
-		k = strings.Replace(k, ".", "__", -1)
-		if strings.Contains(k, "isnull") {
-			qs = qs.Filter(k, (v == "true" || v == "1"))
-		} else {
-			qs = qs.Filter(k, v)
-		}
-	}
-	// order by:
-	var sortFields []string
-	if len(sortby) != 0 {
-		if len(sortby) == len(order) {
-			// 1) for each sort field, there is an associated order
-			for i, v := range sortby {
-				orderby := ""
-				if order[i] == "desc" {
-					orderby = "-" + v
-				} else if order[i] == "asc" {
-					orderby = v
-				} else {
-					return nil, errors.New("Error: Invalid order. Must be either [asc|desc]")
-				}
-				sortFields = append(sortFields, orderby)
-			}
-			qs = qs.OrderBy(sortFields...)
-		} else if len(sortby) != len(order) && len(order) == 1 {
-			// 2) there is exactly one order, all the sorted fields will be sorted by this order
-			for _, v := range sortby {
-				orderby := ""
-				if order[0] == "desc" {
-					orderby = "-" + v
-				} else if order[0] == "asc" {
-					orderby = v
-				} else {
-					return nil, errors.New("Error: Invalid order. Must be either [asc|desc]")
-				}
-				sortFields = append(sortFields, orderby)
-			}
-		} else if len(sortby) != len(order) && len(order) != 1 {
-			return nil, errors.New("Error: 'sortby', 'order' sizes mismatch or 'order' size is not 1")
-		}
-	} else {
-		if len(order) != 0 {
-			return nil, errors.New("Error: unused 'order' fields")
-		}
-	}
-
 	var l []Invoice
-	qs = qs.OrderBy(sortFields...)
-	if _, err = qs.Limit(limit, offset).All(&l, fields...); err == nil {
-		if len(fields) == 0 {
-			for _, v := range l {
-				ml = append(ml, v)
-			}
-		} else {
-			// trim unused fields
-			for _, v := range l {
-				m := make(map[string]interface{})
-				val := reflect.ValueOf(v)
-				for _, fname := range fields {
-					m[fname] = val.FieldByName(fname).Interface()
-				}
-				ml = append(ml, m)
-			}
-		}
-		return ml, nil
-	}
-	return nil, err
+	return getAll(qs, &l, invoiceSchema, spec)
 }
 
 // UpdateInvoice updates Invoice by Id and returns error if
@@ -154,3 +130,50 @@ func DeleteInvoice(id int) (err error) {
 	}
 	return
 }
+
+// GetGuestInvoices retrieves the Invoice of a guest matching certain
+// condition. Returns empty list if no records exist
+func GetGuestInvoices(guestID int, spec *queryspec.Spec) (page *Page, err error) {
+	o := orm.NewOrm()
+	qs := o.QueryTable(new(Invoice)).Filter("GuestId", guestID)
+	var l []Invoice
+	return getAll(qs, &l, invoiceSchema, spec)
+}
+
+// GetActiveInvoice retrieves the Invoice of a reservation which is not
+// canceled. Returns orm.ErrNoRows if none exists
+func GetActiveInvoice(o orm.Ormer, reservationID int) (v *Invoice, err error) {
+	v = &Invoice{}
+	err = o.QueryTable(new(Invoice)).
+		Filter("ReservationId", reservationID).Filter("CanceledAt__isnull", true).
+		OrderBy("-Id").One(v)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// GetInvoiceLines retrieves the InvoiceLine of an invoice in order. Returns
+// empty list if no records exist
+func GetInvoiceLines(o orm.Ormer, invoiceID int) (l []InvoiceLine, err error) {
+	_, err = o.QueryTable(new(InvoiceLine)).Filter("InvoiceId", invoiceID).OrderBy("Id").All(&l)
+	return
+}
+
+// DeleteInvoiceLines deletes the InvoiceLine of an invoice and returns their
+// number.
+func DeleteInvoiceLines(o orm.Ormer, invoiceID int) (int64, error) {
+	return o.QueryTable(new(InvoiceLine)).Filter("InvoiceId", invoiceID).Delete()
+}
+
+// NextInvoiceSequence increments and returns the invoice counter of a hotel.
+// The counter row is locked until the transaction of o ends, so numbers are
+// gapless and unique per hotel.
+func NextInvoiceSequence(o orm.Ormer, hotelID int) (int64, error) {
+	res, err := o.Raw("INSERT INTO invoice_sequence (hotel_id, last_number) VALUES (?, LAST_INSERT_ID(1)) "+
+		"ON DUPLICATE KEY UPDATE last_number = LAST_INSERT_ID(last_number + 1)", hotelID).Exec()
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
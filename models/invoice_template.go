@@ -0,0 +1,102 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"easybook/queryspec"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// InvoiceTemplate is the letterhead of the invoices of a hotel: the logo
+// image at LogoPath, Header lines like the legal name and tax id, and the
+// legal Footer.
+type InvoiceTemplate struct {
+	Id        int       `orm:"column(id);auto"`
+	HotelId   *Hotel    `orm:"column(hotel_id);rel(fk)"`
+	LogoPath  string    `orm:"column(logo_path);size(255);null"`
+	Header    string    `orm:"column(header);null"`
+	Footer    string    `orm:"column(footer);null"`
+	CreatedAt time.Time `orm:"column(created_at);type(timestamp)"`
+	UpdatedAt time.Time `orm:"column(updated_at);type(timestamp)"`
+}
+
+var invoiceTemplateSchema = queryspec.NewSchema(new(InvoiceTemplate))
+
+func (t *InvoiceTemplate) TableName() string {
+	return "invoice_template"
+}
+
+func init() {
+	orm.RegisterModel(new(InvoiceTemplate))
+}
+
+// AddInvoiceTemplate insert a new InvoiceTemplate into database and returns
+// last inserted Id on success.
+func AddInvoiceTemplate(m *InvoiceTemplate) (id int64, err error) {
+	o := orm.NewOrm()
+	id, err = o.Insert(m)
+	return
+}
+
+// GetInvoiceTemplateById retrieves InvoiceTemplate by Id. Returns error if
+// Id doesn't exist
+func GetInvoiceTemplateById(id int) (v *InvoiceTemplate, err error) {
+	o := orm.NewOrm()
+	v = &InvoiceTemplate{Id: id}
+	if err = o.Read(v); err == nil {
+		return v, nil
+	}
+	return nil, err
+}
+
+// GetAllInvoiceTemplate retrieves all InvoiceTemplate matches certain condition. Returns empty list if
+// no records exist
+func GetAllInvoiceTemplate(spec *queryspec.Spec) (page *Page, err error) {
+	o := orm.NewOrm()
+	qs := o.QueryTable(new(InvoiceTemplate))
+	var l []InvoiceTemplate
+	return getAll(qs, &l, invoiceTemplateSchema, spec)
+}
+
+// GetInvoiceTemplateByHotel retrieves the InvoiceTemplate of a hotel.
+// Returns error if none exists
+func GetInvoiceTemplateByHotel(hotelID int) (v *InvoiceTemplate, err error) {
+	o := orm.NewOrm()
+	v = &InvoiceTemplate{}
+	if err = o.QueryTable(new(InvoiceTemplate)).Filter("HotelId", hotelID).OrderBy("-Id").One(v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// UpdateInvoiceTemplate updates InvoiceTemplate by Id and returns error if
+// the record to be updated doesn't exist
+func UpdateInvoiceTemplateById(m *InvoiceTemplate) (err error) {
+	o := orm.NewOrm()
+	v := InvoiceTemplate{Id: m.Id}
+	// ascertain id exists in the database
+	if err = o.Read(&v); err == nil {
+		var num int64
+		if num, err = o.Update(m); err == nil {
+			fmt.Println("Number of records updated in database:", num)
+		}
+	}
+	return
+}
+
+// DeleteInvoiceTemplate deletes InvoiceTemplate by Id and returns error if
+// the record to be deleted doesn't exist
+func DeleteInvoiceTemplate(id int) (err error) {
+	o := orm.NewOrm()
+	v := InvoiceTemplate{Id: id}
+	// ascertain id exists in the database
+	if err = o.Read(&v); err == nil {
+		var num int64
+		if num, err = o.Delete(&InvoiceTemplate{Id: id}); err == nil {
+			fmt.Println("Number of records deleted in database:", num)
+		}
+	}
+	return
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"reflect"
+	"time"
+
+	"easybook/queryspec"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// Page is a page of records returned by GetAll functions.
+type Page struct {
+	Items []interface{}
+	// Total is the number of records matching the query, it is only
+	// counted in offset paging.
+	Total *int64
+	// NextCursor is the cursor of the next page in keyset paging, it is
+	// nil when there are no more records.
+	NextCursor *queryspec.Cursor
+}
+
+// getAll retrieves records of qs matching spec into container, which must be
+// a pointer to a slice of the model. Fields, sort keys and query fields of
+// spec are validated against schema. Returns empty list if no records exist.
+func getAll(qs orm.QuerySeter, container interface{}, schema *queryspec.Schema,
+	spec *queryspec.Spec) (page *Page, err error) {
+	if err = spec.Validate(schema); err != nil {
+		return nil, err
+	}
+
+	if spec.Query != nil {
+		cond := qs.GetCond()
+		if cond == nil {
+			cond = orm.NewCondition()
+		}
+		qs = qs.SetCond(cond.AndCond(spec.Query.Condition()))
+	}
+
+	page = &Page{Items: []interface{}{}}
+	fields := spec.Columns()
+	if spec.Cursor == nil {
+		var total int64
+		if total, err = qs.Count(); err != nil {
+			return nil, err
+		}
+		page.Total = &total
+		qs = qs.OrderBy(spec.OrderBy()...).Limit(spec.Limit, spec.Offset)
+	} else {
+		if !spec.Cursor.IsZero() {
+			qs = qs.SetCond(keysetCond(qs.GetCond(), spec.Cursor))
+		}
+		if len(fields) != 0 {
+			// the last record must carry its position for the next cursor
+			fields = append(fields[:len(fields):len(fields)], "CreatedAt", "Id")
+		}
+		// read one more record to know whether there is a next page
+		qs = qs.OrderBy(spec.Cursor.OrderBy()...).Limit(spec.Limit + 1)
+	}
+
+	if _, err = qs.All(container, fields...); err != nil {
+		return nil, err
+	}
+
+	l := reflect.ValueOf(container).Elem()
+	if spec.Cursor != nil && int64(l.Len()) > spec.Limit {
+		l = l.Slice(0, int(spec.Limit))
+		if last := l.Len() - 1; last >= 0 {
+			page.NextCursor = &queryspec.Cursor{
+				CreatedAt: l.Index(last).FieldByName("CreatedAt").Interface().(time.Time),
+				Id:        int(l.Index(last).FieldByName("Id").Int()),
+				Desc:      spec.Cursor.Desc,
+			}
+		}
+	}
+
+	if len(spec.Expand) != 0 {
+		if err = expand(orm.NewOrm(), l, schema, spec.Expand); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := 0; i < l.Len(); i++ {
+		page.Items = append(page.Items, spec.Project(schema, l.Index(i).Interface()))
+	}
+	return page, nil
+}
+
+// keysetCond narrows cond to records after c in (created_at, id) order.
+func keysetCond(cond *orm.Condition, c *queryspec.Cursor) *orm.Condition {
+	op := "__gt"
+	if c.Desc {
+		op = "__lt"
+	}
+	after := orm.NewCondition().
+		Or("CreatedAt"+op, c.CreatedAt).
+		OrCond(orm.NewCondition().And("CreatedAt", c.CreatedAt).And("Id"+op, c.Id))
+
+	if cond == nil {
+		return after
+	}
+	return cond.AndCond(after)
+}
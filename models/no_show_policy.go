@@ -0,0 +1,118 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"easybook/queryspec"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// NoShowPolicy is how a hotel handles the guests who do not arrive: a
+// confirmed reservation without any room checked in by CutoffHour o'clock,
+// CutoffDays days after its StartDate in the Timezone of the hotel, is
+// marked no-show. The first PenaltyNights nights are charged, the others
+// are released. Hotels without an active policy follow the default of
+// app.conf.
+type NoShowPolicy struct {
+	Id            int       `orm:"column(id);auto"`
+	HotelId       *Hotel    `orm:"column(hotel_id);rel(fk);unique"`
+	Timezone      string    `orm:"column(timezone);size(64)"`
+	CutoffDays    int       `orm:"column(cutoff_days)"`
+	CutoffHour    int       `orm:"column(cutoff_hour)"`
+	PenaltyNights int       `orm:"column(penalty_nights)"`
+	IsActive      int8      `orm:"column(is_active)"`
+	CreatedAt     time.Time `orm:"column(created_at);type(timestamp)"`
+	UpdatedAt     time.Time `orm:"column(updated_at);type(timestamp)"`
+}
+
+var noShowPolicySchema = queryspec.NewSchema(new(NoShowPolicy))
+
+func (t *NoShowPolicy) TableName() string {
+	return "no_show_policy"
+}
+
+func init() {
+	orm.RegisterModel(new(NoShowPolicy))
+}
+
+// AddNoShowPolicy insert a new NoShowPolicy into database and returns
+// last inserted Id on success.
+func AddNoShowPolicy(m *NoShowPolicy) (id int64, err error) {
+	o := orm.NewOrm()
+	id, err = o.Insert(m)
+	return
+}
+
+// GetNoShowPolicyById retrieves NoShowPolicy by Id. Returns error if
+// Id doesn't exist
+func GetNoShowPolicyById(id int) (v *NoShowPolicy, err error) {
+	o := orm.NewOrm()
+	v = &NoShowPolicy{Id: id}
+	if err = o.Read(v); err == nil {
+		return v, nil
+	}
+	return nil, err
+}
+
+// GetAllNoShowPolicy retrieves all NoShowPolicy matches certain condition. Returns empty list if
+// no records exist
+func GetAllNoShowPolicy(spec *queryspec.Spec) (page *Page, err error) {
+	o := orm.NewOrm()
+	qs := o.QueryTable(new(NoShowPolicy))
+	var l []NoShowPolicy
+	return getAll(qs, &l, noShowPolicySchema, spec)
+}
+
+// GetHotelNoShowPolicy retrieves the active NoShowPolicy of a hotel.
+// Returns orm.ErrNoRows if none exists
+func GetHotelNoShowPolicy(o orm.Ormer, hotelID int) (v *NoShowPolicy, err error) {
+	v = &NoShowPolicy{}
+	err = o.QueryTable(new(NoShowPolicy)).Filter("HotelId", hotelID).Filter("IsActive", 1).One(v)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// UpdateNoShowPolicy updates NoShowPolicy by Id and returns error if
+// the record to be updated doesn't exist
+func UpdateNoShowPolicyById(m *NoShowPolicy) (err error) {
+	o := orm.NewOrm()
+	v := NoShowPolicy{Id: m.Id}
+	// ascertain id exists in the database
+	if err = o.Read(&v); err == nil {
+		var num int64
+		if num, err = o.Update(m); err == nil {
+			fmt.Println("Number of records updated in database:", num)
+		}
+	}
+	return
+}
+
+// DeleteNoShowPolicy deletes NoShowPolicy by Id and returns error if
+// the record to be deleted doesn't exist
+func DeleteNoShowPolicy(id int) (err error) {
+	o := orm.NewOrm()
+	v := NoShowPolicy{Id: id}
+	// ascertain id exists in the database
+	if err = o.Read(&v); err == nil {
+		var num int64
+		if num, err = o.Delete(&NoShowPolicy{Id: id}); err == nil {
+			fmt.Println("Number of records deleted in database:", num)
+		}
+	}
+	return
+}
+
+// GetArrivingReservations retrieves the confirmed reservations starting
+// from from until the day before to, by start date. Returns empty list if
+// no records exist
+func GetArrivingReservations(o orm.Ormer, from time.Time, to time.Time) (l []Reservation, err error) {
+	_, err = o.QueryTable(new(Reservation)).
+		Filter("Status", ReservationConfirmed).
+		Filter("StartDate__gte", from).Filter("StartDate__lt", to).
+		OrderBy("StartDate", "Id").All(&l)
+	return
+}
@@ -1,12 +1,11 @@
 package models
 
 import (
-	"errors"
 	"fmt"
-	"reflect"
-	"strings"
 	"time"
 
+	"easybook/queryspec"
+
 	"github.com/astaxie/beego/orm"
 )
 
@@ -17,10 +16,27 @@ type Notification struct {
 	IsCompleted   int8         `orm:"column(is_completed)"`
 	Description   string       `orm:"column(description);null"`
 	ReservationId *Reservation `orm:"column(reservation_id);rel(fk)"`
+	Event         string       `orm:"column(event);size(20);null"`
+	Attempts      int          `orm:"column(attempts)"`
+	LockedBy      string       `orm:"column(locked_by);size(64);null"`
+	LockedUntil   time.Time    `orm:"column(locked_until);type(datetime);null"`
+	LastError     string       `orm:"column(last_error);null"`
+	ReadAt        time.Time    `orm:"column(read_at);type(datetime);null"`
+	ArchivedAt    time.Time    `orm:"column(archived_at);type(datetime);null"`
 	CreatedAt     time.Time    `orm:"column(created_at);type(timestamp)"`
 	UpdatedAt     time.Time    `orm:"column(updated_at);type(timestamp)"`
 }
 
+// Values of Notification.IsCompleted.
+const (
+	NotificationPending int8 = iota
+	NotificationSent
+	NotificationCancelled
+)
+
+var notificationSchema = queryspec.NewSchema(new(Notification), "LockedBy", "LockedUntil").
+	Restrict("ReservationId", RoleStaff)
+
 func (t *Notification) TableName() string {
 	return "notification"
 }
@@ -50,80 +66,11 @@ func GetNotificationById(id int) (v *Notification, err error) {
 
 // GetAllNotification retrieves all Notification matches certain condition. Returns empty list if
 // no records exist
-func GetAllNotification(query map[string]string, fields []string, sortby []string, order []string,
-	offset int64, limit int64) (ml []interface{}, err error) {
+func GetAllNotification(spec *queryspec.Spec) (page *Page, err error) {
 	o := orm.NewOrm()
 	qs := o.QueryTable(new(Notification))
-	// query k=v
-	for k, v := range query {
-		// rewrite dot-notation to Object__Attribute
-		k = strings.Replace(k, ".", "__", -1)
-		if strings.Contains(k, "isnull") {
-			qs = qs.Filter(k, (v == "true" || v == "1"))
-		} else {
-			qs = qs.Filter(k, v)
-		}
-	}
-	// order by:
-	var sortFields []string
-	if len(sortby) != 0 {
-		if len(sortby) == len(order) {
-			// 1) for each sort field, there is an associated order
-			for i, v := range sortby {
-				orderby := ""
-				if order[i] == "desc" {
-					orderby = "-" + v
-				} else if order[i] == "asc" {
-					orderby = v
-				} else {
-					return nil, errors.New("Error: Invalid order. Must be either [asc|desc]")
-				}
-				sortFields = append(sortFields, orderby)
-			}
-			qs = qs.OrderBy(sortFields...)
-		} else if len(sortby) != len(order) && len(order) == 1 {
-			// 2) there is exactly one order, all the sorted fields will be sorted by this order
-			for _, v := range sortby {
-				orderby := ""
-				if order[0] == "desc" {
-					orderby = "-" + v
-				} else if order[0] == "asc" {
-					orderby = v
-				} else {
-					return nil, errors.New("Error: Invalid order. Must be either [asc|desc]")
-				}
-				sortFields = append(sortFields, orderby)
-			}
-		} else if len(sortby) != len(order) && len(order) != 1 {
-			return nil, errors.New("Error: 'sortby', 'order' sizes mismatch or 'order' size is not 1")
-		}
-	} else {
-		if len(order) != 0 {
-			return nil, errors.New("Error: unused 'order' fields")
-		}
-	}
-
 	var l []Notification
-	qs = qs.OrderBy(sortFields...)
-	if _, err = qs.Limit(limit, offset).All(&l, fields...); err == nil {
-		if len(fields) == 0 {
-			for _, v := range l {
-				ml = append(ml, v)
-			}
-		} else {
-			// trim unused fields
-			for _, v := range l {
-				m := make(map[string]interface{})
-				val := reflect.ValueOf(v)
-				for _, fname := range fields {
-					m[fname] = val.FieldByName(fname).Interface()
-				}
-				ml = append(ml, m)
-			}
-		}
-		return ml, nil
-	}
-	return nil, err
+	return getAll(qs, &l, notificationSchema, spec)
 }
 
 // UpdateNotification updates Notification by Id and returns error if
@@ -155,3 +102,163 @@ func DeleteNotification(id int) (err error) {
 	}
 	return
 }
+
+// GetDueNotifications retrieves uncompleted Notification which are triggered
+// at or before now, failed less than maxAttempts times and are not claimed
+// by any dispatcher. Returns empty list if no records exist
+func GetDueNotifications(now time.Time, maxAttempts int, limit int) (l []Notification, err error) {
+	o := orm.NewOrm()
+	cond := unclaimedNotificationCond(now).
+		And("TriggerAt__lte", now).
+		And("Attempts__lt", maxAttempts)
+	_, err = o.QueryTable(new(Notification)).SetCond(cond).
+		OrderBy("TriggerAt", "Id").Limit(limit).All(&l)
+	return
+}
+
+// ClaimNotification locks Notification by Id for owner until the given time.
+// Returns false if the record is completed or claimed by another owner.
+func ClaimNotification(id int, owner string, now time.Time, until time.Time) (bool, error) {
+	o := orm.NewOrm()
+	num, err := o.QueryTable(new(Notification)).
+		SetCond(unclaimedNotificationCond(now).And("Id", id)).
+		Update(orm.Params{
+			"LockedBy":    owner,
+			"LockedUntil": until,
+		})
+	return num == 1, err
+}
+
+// CompleteNotification marks Notification claimed by owner as completed.
+func CompleteNotification(id int, owner string) (err error) {
+	o := orm.NewOrm()
+	_, err = o.QueryTable(new(Notification)).Filter("Id", id).Filter("LockedBy", owner).
+		Update(orm.Params{
+			"IsCompleted": NotificationSent,
+			"LockedBy":    nil,
+			"LockedUntil": nil,
+			"LastError":   nil,
+		})
+	return
+}
+
+// FailNotification records a failed delivery of Notification claimed by
+// owner. The record stays locked until retryAt, so it is retried afterwards.
+func FailNotification(id int, owner string, cause error, retryAt time.Time) (err error) {
+	o := orm.NewOrm()
+	_, err = o.QueryTable(new(Notification)).Filter("Id", id).Filter("LockedBy", owner).
+		Update(orm.Params{
+			"Attempts":    orm.ColValue(orm.ColAdd, 1),
+			"LockedBy":    nil,
+			"LockedUntil": retryAt,
+			"LastError":   cause.Error(),
+		})
+	return
+}
+
+func unclaimedNotificationCond(now time.Time) *orm.Condition {
+	unlocked := orm.NewCondition().
+		And("LockedUntil__isnull", true).
+		Or("LockedUntil__lte", now)
+	return orm.NewCondition().And("IsCompleted", NotificationPending).AndCond(unlocked)
+}
+
+// GetPendingNotifications retrieves the pending Notification of a
+// reservation for the given events, leaving out the ones a dispatcher is
+// sending right now. Returns empty list if no records exist
+func GetPendingNotifications(o orm.Ormer, reservationID int, now time.Time, events ...string) (l []Notification, err error) {
+	unsent := orm.NewCondition().
+		And("LockedBy__isnull", true).
+		Or("LockedUntil__lte", now)
+	cond := orm.NewCondition().
+		And("ReservationId", reservationID).
+		And("IsCompleted", NotificationPending).
+		AndCond(unsent)
+	if len(events) != 0 {
+		cond = cond.And("Event__in", events)
+	}
+	_, err = o.QueryTable(new(Notification)).SetCond(cond).OrderBy("Id").All(&l)
+	return
+}
+
+// RescheduleNotification moves pending Notification by Id to triggerAt.
+func RescheduleNotification(o orm.Ormer, id int, triggerAt time.Time) (err error) {
+	_, err = o.QueryTable(new(Notification)).
+		Filter("Id", id).Filter("IsCompleted", NotificationPending).
+		Update(orm.Params{
+			"TriggerAt": triggerAt,
+		})
+	return
+}
+
+// CancelNotification marks pending Notification by Id as cancelled so it is
+// never sent.
+func CancelNotification(o orm.Ormer, id int) (err error) {
+	_, err = o.QueryTable(new(Notification)).
+		Filter("Id", id).Filter("IsCompleted", NotificationPending).
+		Update(orm.Params{
+			"IsCompleted": NotificationCancelled,
+			"LockedBy":    nil,
+			"LockedUntil": nil,
+		})
+	return
+}
+
+// inboxQuery returns the QuerySeter of the in-app Notification sent to a
+// guest.
+func inboxQuery(o orm.Ormer, guestID int) orm.QuerySeter {
+	return o.QueryTable(new(Notification)).
+		Filter("Type", "APP").
+		Filter("IsCompleted", NotificationSent).
+		Filter("ReservationId__GuestId__Id", guestID)
+}
+
+// GetInboxNotifications retrieves the in-app Notification sent to a guest,
+// either archived or not. Returns empty list if no records exist
+func GetInboxNotifications(guestID int, archived bool, spec *queryspec.Spec) (page *Page, err error) {
+	o := orm.NewOrm()
+	qs := inboxQuery(o, guestID).Filter("ArchivedAt__isnull", !archived)
+	var l []Notification
+	return getAll(qs, &l, notificationSchema, spec)
+}
+
+// CountUnreadNotifications returns the number of unread and not archived
+// in-app Notification sent to a guest.
+func CountUnreadNotifications(guestID int) (int64, error) {
+	o := orm.NewOrm()
+	return inboxQuery(o, guestID).
+		Filter("ReadAt__isnull", true).
+		Filter("ArchivedAt__isnull", true).
+		Count()
+}
+
+// MarkNotificationRead marks the in-app Notification by Id sent to a guest
+// as read at now, or as unread. Returns error if the record doesn't exist
+// in the inbox of the guest
+func MarkNotificationRead(id int, guestID int, read bool, now time.Time) (*Notification, error) {
+	return setInboxTime(id, guestID, "ReadAt", read, now)
+}
+
+// ArchiveNotification moves the in-app Notification by Id sent to a guest
+// to the archive at now, or back to the inbox. Returns error if the record
+// doesn't exist in the inbox of the guest
+func ArchiveNotification(id int, guestID int, archived bool, now time.Time) (*Notification, error) {
+	return setInboxTime(id, guestID, "ArchivedAt", archived, now)
+}
+
+func setInboxTime(id int, guestID int, field string, set bool, now time.Time) (v *Notification, err error) {
+	o := orm.NewOrm()
+	v = &Notification{}
+	if err = inboxQuery(o, guestID).Filter("Id", id).One(v); err != nil {
+		return nil, err
+	}
+
+	var at interface{}
+	if set {
+		at = now
+	}
+	if _, err = o.QueryTable(new(Notification)).Filter("Id", id).Update(orm.Params{field: at}); err != nil {
+		return nil, err
+	}
+	return GetNotificationById(id)
+}
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"easybook/queryspec"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// Reservation lifecycle events that notifications are scheduled for.
+const (
+	// EventConfirmed is sent when the reservation is confirmed.
+	EventConfirmed = "confirmed"
+	// EventBeforeStart is sent OffsetDays days before StartDate.
+	EventBeforeStart = "before_start"
+	// EventCheckOut is sent OffsetDays days before EndDate, 0 is on check-out day.
+	EventCheckOut = "check_out"
+	// EventCancelled is sent when the reservation is cancelled.
+	EventCancelled = "cancelled"
+	// EventOffered is sent when rooms are offered to a waitlisted guest,
+	// the reservation is pending until the guest accepts.
+	EventOffered = "offered"
+	// EventRelocated is sent when a reserved room is moved to another room
+	// because more guests arrive than the hotel has rooms.
+	EventRelocated = "relocated"
+	// EventNoShow is sent when the reservation is marked no-show, to the
+	// staff of the hotel through a webhook rule.
+	EventNoShow = "no_show"
+)
+
+// NotificationRule schedules a notification of Type for the reservations of
+// a hotel when Event happens. Date based events are sent at Hour o'clock.
+type NotificationRule struct {
+	Id         int       `orm:"column(id);auto"`
+	HotelId    *Hotel    `orm:"column(hotel_id);rel(fk)"`
+	Event      string    `orm:"column(event);size(20)"`
+	Type       string    `orm:"column(type);size(3)"`
+	OffsetDays int       `orm:"column(offset_days)"`
+	Hour       int       `orm:"column(hour)"`
+	IsActive   int8      `orm:"column(is_active)"`
+	CreatedAt  time.Time `orm:"column(created_at);type(timestamp)"`
+	UpdatedAt  time.Time `orm:"column(updated_at);type(timestamp)"`
+}
+
+var notificationRuleSchema = queryspec.NewSchema(new(NotificationRule))
+
+func (t *NotificationRule) TableName() string {
+	return "notification_rule"
+}
+
+func init() {
+	orm.RegisterModel(new(NotificationRule))
+}
+
+// AddNotificationRule insert a new NotificationRule into database and returns
+// last inserted Id on success.
+func AddNotificationRule(m *NotificationRule) (id int64, err error) {
+	o := orm.NewOrm()
+	id, err = o.Insert(m)
+	return
+}
+
+// GetNotificationRuleById retrieves NotificationRule by Id. Returns error if
+// Id doesn't exist
+func GetNotificationRuleById(id int) (v *NotificationRule, err error) {
+	o := orm.NewOrm()
+	v = &NotificationRule{Id: id}
+	if err = o.Read(v); err == nil {
+		return v, nil
+	}
+	return nil, err
+}
+
+// GetAllNotificationRule retrieves all NotificationRule matches certain condition. Returns empty list if
+// no records exist
+func GetAllNotificationRule(spec *queryspec.Spec) (page *Page, err error) {
+	o := orm.NewOrm()
+	qs := o.QueryTable(new(NotificationRule))
+	var l []NotificationRule
+	return getAll(qs, &l, notificationRuleSchema, spec)
+}
+
+// GetActiveNotificationRules retrieves the active NotificationRule of a hotel.
+// Returns empty list if no records exist
+func GetActiveNotificationRules(o orm.Ormer, hotelID int) (l []NotificationRule, err error) {
+	_, err = o.QueryTable(new(NotificationRule)).
+		Filter("HotelId", hotelID).Filter("IsActive", 1).OrderBy("Id").All(&l)
+	return
+}
+
+// UpdateNotificationRule updates NotificationRule by Id and returns error if
+// the record to be updated doesn't exist
+func UpdateNotificationRuleById(m *NotificationRule) (err error) {
+	o := orm.NewOrm()
+	v := NotificationRule{Id: m.Id}
+	// ascertain id exists in the database
+	if err = o.Read(&v); err == nil {
+		var num int64
+		if num, err = o.Update(m); err == nil {
+			fmt.Println("Number of records updated in database:", num)
+		}
+	}
+	return
+}
+
+// DeleteNotificationRule deletes NotificationRule by Id and returns error if
+// the record to be deleted doesn't exist
+func DeleteNotificationRule(id int) (err error) {
+	o := orm.NewOrm()
+	v := NotificationRule{Id: id}
+	// ascertain id exists in the database
+	if err = o.Read(&v); err == nil {
+		var num int64
+		if num, err = o.Delete(&NotificationRule{Id: id}); err == nil {
+			fmt.Println("Number of records deleted in database:", num)
+		}
+	}
+	return
+}
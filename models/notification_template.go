@@ -0,0 +1,120 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"easybook/queryspec"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// Formats of NotificationTemplate.Body.
+const (
+	FormatText = "text"
+	FormatHTML = "html"
+)
+
+// NotificationTemplate renders the subject and body of notifications named
+// Name, e.g. an event like "confirmed", of Type in Language. Templates are
+// never updated in place, every change is stored as a new Version.
+type NotificationTemplate struct {
+	Id        int       `orm:"column(id);auto"`
+	Name      string    `orm:"column(name);size(50)"`
+	Type      string    `orm:"column(type);size(3)"`
+	Language  string    `orm:"column(language);size(8)"`
+	Version   int       `orm:"column(version)"`
+	Format    string    `orm:"column(format);size(10)"`
+	Subject   string    `orm:"column(subject);size(255)"`
+	Body      string    `orm:"column(body)"`
+	CreatedAt time.Time `orm:"column(created_at);type(timestamp)"`
+	UpdatedAt time.Time `orm:"column(updated_at);type(timestamp)"`
+}
+
+var notificationTemplateSchema = queryspec.NewSchema(new(NotificationTemplate))
+
+func (t *NotificationTemplate) TableName() string {
+	return "notification_template"
+}
+
+func init() {
+	orm.RegisterModel(new(NotificationTemplate))
+}
+
+// AddNotificationTemplate insert a new version of NotificationTemplate into
+// database and returns last inserted Id on success. m.Version is set to the
+// version following the latest one of the same name, type and language.
+func AddNotificationTemplate(m *NotificationTemplate) (id int64, err error) {
+	o := orm.NewOrm()
+	if err = o.Begin(); err != nil {
+		return 0, err
+	}
+
+	var latest NotificationTemplate
+	err = o.QueryTable(new(NotificationTemplate)).
+		Filter("Name", m.Name).Filter("Type", m.Type).Filter("Language", m.Language).
+		OrderBy("-Version").ForUpdate().One(&latest, "Version")
+	if err != nil && err != orm.ErrNoRows {
+		_ = o.Rollback()
+		return 0, err
+	}
+	m.Id = 0
+	m.Version = latest.Version + 1
+
+	if id, err = o.Insert(m); err != nil {
+		_ = o.Rollback()
+		return 0, err
+	}
+	err = o.Commit()
+	return
+}
+
+// GetNotificationTemplateById retrieves NotificationTemplate by Id. Returns error if
+// Id doesn't exist
+func GetNotificationTemplateById(id int) (v *NotificationTemplate, err error) {
+	o := orm.NewOrm()
+	v = &NotificationTemplate{Id: id}
+	if err = o.Read(v); err == nil {
+		return v, nil
+	}
+	return nil, err
+}
+
+// GetLatestNotificationTemplate retrieves the latest version of the
+// NotificationTemplate by name, type and language. Returns error if none
+// exists
+func GetLatestNotificationTemplate(name string, typ string, language string) (v *NotificationTemplate, err error) {
+	o := orm.NewOrm()
+	v = &NotificationTemplate{}
+	err = o.QueryTable(new(NotificationTemplate)).
+		Filter("Name", name).Filter("Type", typ).Filter("Language", language).
+		OrderBy("-Version").One(v)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// GetAllNotificationTemplate retrieves all NotificationTemplate matches certain condition. Returns empty list if
+// no records exist
+func GetAllNotificationTemplate(spec *queryspec.Spec) (page *Page, err error) {
+	o := orm.NewOrm()
+	qs := o.QueryTable(new(NotificationTemplate))
+	var l []NotificationTemplate
+	return getAll(qs, &l, notificationTemplateSchema, spec)
+}
+
+// DeleteNotificationTemplate deletes NotificationTemplate by Id and returns error if
+// the record to be deleted doesn't exist
+func DeleteNotificationTemplate(id int) (err error) {
+	o := orm.NewOrm()
+	v := NotificationTemplate{Id: id}
+	// ascertain id exists in the database
+	if err = o.Read(&v); err == nil {
+		var num int64
+		if num, err = o.Delete(&NotificationTemplate{Id: id}); err == nil {
+			fmt.Println("Number of records deleted in database:", num)
+		}
+	}
+	return
+}
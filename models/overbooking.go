@@ -0,0 +1,188 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"easybook/queryspec"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// OverbookingAllowance lets a hotel reserve Rooms rooms more than it has
+// for each night, expecting no-shows. The allowance of a service level
+// applies to its rooms, the allowance of the hotel without service level
+// to the rooms of the levels without their own.
+type OverbookingAllowance struct {
+	Id             int           `orm:"column(id);auto"`
+	HotelId        *Hotel        `orm:"column(hotel_id);rel(fk)"`
+	ServiceLevelId *ServiceLevel `orm:"column(service_level_id);rel(fk);null"`
+	Rooms          int           `orm:"column(rooms)"`
+	CreatedAt      time.Time     `orm:"column(created_at);type(timestamp)"`
+	UpdatedAt      time.Time     `orm:"column(updated_at);type(timestamp)"`
+}
+
+var overbookingAllowanceSchema = queryspec.NewSchema(new(OverbookingAllowance))
+
+// Relocation is the move of a reserved room RoomReservedId from room
+// FromRoomId to the free room ToRoomId for the whole stay, when more guests
+// arrive than the hotel has rooms.
+type Relocation struct {
+	Id             int           `orm:"column(id);auto"`
+	ReservationId  *Reservation  `orm:"column(reservation_id);rel(fk)"`
+	RoomReservedId *RoomReserved `orm:"column(room_reserved_id);rel(fk)"`
+	FromRoomId     *Room         `orm:"column(from_room_id);rel(fk)"`
+	ToRoomId       *Room         `orm:"column(to_room_id);rel(fk)"`
+	Reason         string        `orm:"column(reason);size(255)"`
+	RelocatedBy    *Guest        `orm:"column(relocated_by);rel(fk);null"`
+	CreatedAt      time.Time     `orm:"column(created_at);type(timestamp)"`
+}
+
+var relocationSchema = queryspec.NewSchema(new(Relocation))
+
+// ReservedNight is a night of a reserved room, counted against the rooms
+// of its service level.
+type ReservedNight struct {
+	RoomReservedId int
+	RoomId         int
+	ServiceLevelId int
+	Date           time.Time
+}
+
+func (t *OverbookingAllowance) TableName() string {
+	return "overbooking_allowance"
+}
+
+func (t *Relocation) TableName() string {
+	return "relocation"
+}
+
+func init() {
+	orm.RegisterModel(new(OverbookingAllowance), new(Relocation))
+}
+
+// AddOverbookingAllowance insert a new OverbookingAllowance into database and returns
+// last inserted Id on success.
+func AddOverbookingAllowance(m *OverbookingAllowance) (id int64, err error) {
+	o := orm.NewOrm()
+	id, err = o.Insert(m)
+	return
+}
+
+// GetOverbookingAllowanceById retrieves OverbookingAllowance by Id. Returns error if
+// Id doesn't exist
+func GetOverbookingAllowanceById(id int) (v *OverbookingAllowance, err error) {
+	o := orm.NewOrm()
+	v = &OverbookingAllowance{Id: id}
+	if err = o.Read(v); err == nil {
+		return v, nil
+	}
+	return nil, err
+}
+
+// GetAllOverbookingAllowance retrieves all OverbookingAllowance matches certain condition. Returns empty list if
+// no records exist
+func GetAllOverbookingAllowance(spec *queryspec.Spec) (page *Page, err error) {
+	o := orm.NewOrm()
+	qs := o.QueryTable(new(OverbookingAllowance))
+	var l []OverbookingAllowance
+	return getAll(qs, &l, overbookingAllowanceSchema, spec)
+}
+
+// UpdateOverbookingAllowanceById updates OverbookingAllowance by Id and returns error if
+// the record to be updated doesn't exist
+func UpdateOverbookingAllowanceById(m *OverbookingAllowance) (err error) {
+	o := orm.NewOrm()
+	v := OverbookingAllowance{Id: m.Id}
+	// ascertain id exists in the database
+	if err = o.Read(&v); err == nil {
+		var num int64
+		if num, err = o.Update(m); err == nil {
+			fmt.Println("Number of records updated in database:", num)
+		}
+	}
+	return
+}
+
+// DeleteOverbookingAllowance deletes OverbookingAllowance by Id and returns error if
+// the record to be deleted doesn't exist
+func DeleteOverbookingAllowance(id int) (err error) {
+	o := orm.NewOrm()
+	v := OverbookingAllowance{Id: id}
+	// ascertain id exists in the database
+	if err = o.Read(&v); err == nil {
+		var num int64
+		if num, err = o.Delete(&OverbookingAllowance{Id: id}); err == nil {
+			fmt.Println("Number of records deleted in database:", num)
+		}
+	}
+	return
+}
+
+// GetHotelAllowances retrieves the OverbookingAllowance of a hotel. Returns
+// empty list if no records exist
+func GetHotelAllowances(o orm.Ormer, hotelID int) (l []OverbookingAllowance, err error) {
+	_, err = o.QueryTable(new(OverbookingAllowance)).Filter("HotelId", hotelID).All(&l)
+	return
+}
+
+// GetOverbookingHotelIds returns the ids of the hotels other than
+// exceptHotelIDs allowed to overbook a room or more.
+func GetOverbookingHotelIds(o orm.Ormer, exceptHotelIDs []int) ([]int, error) {
+	qs := o.QueryTable(new(OverbookingAllowance)).Filter("Rooms__gt", 0)
+	if len(exceptHotelIDs) != 0 {
+		qs = qs.Exclude("HotelId__Id__in", exceptHotelIDs)
+	}
+	var l orm.ParamsList
+	if _, err := qs.Distinct().Limit(-1).ValuesFlat(&l, "HotelId"); err != nil {
+		return nil, err
+	}
+	return intsOf(l), nil
+}
+
+// CountLevelRooms returns the number of rooms of each service level of a
+// hotel.
+func CountLevelRooms(o orm.Ormer, hotelID int) (map[int]int, error) {
+	var l orm.ParamsList
+	if _, err := o.QueryTable(new(Room)).Filter("HotelId", hotelID).ValuesFlat(&l, "ServiceLevelId"); err != nil {
+		return nil, err
+	}
+	rooms := make(map[int]int)
+	for _, id := range intsOf(l) {
+		rooms[id]++
+	}
+	return rooms, nil
+}
+
+// GetReservedNights returns the nights from from until the day before to
+// of the rooms of a hotel reserved by reservations other than
+// exceptReservationID which are not canceled, by date.
+func GetReservedNights(o orm.Ormer, hotelID int, from time.Time, to time.Time, exceptReservationID int) ([]ReservedNight, error) {
+	var l []orm.ParamsList
+	_, err := o.QueryTable(new(RoomNight)).
+		Filter("RoomReservedId__RoomId__HotelId__Id", hotelID).
+		Exclude("RoomReservedId__ReservationId__Status", ReservationCancelled).
+		Exclude("RoomReservedId__ReservationId__Id", exceptReservationID).
+		Filter("Date__gte", from).Filter("Date__lt", to).
+		OrderBy("Date", "RoomReservedId").
+		ValuesList(&l, "RoomReservedId", "RoomReservedId__RoomId__Id", "RoomReservedId__RoomId__ServiceLevelId__Id", "Date")
+	if err != nil {
+		return nil, err
+	}
+	nights := make([]ReservedNight, len(l))
+	for i, v := range l {
+		ids := intsOf(v[:3])
+		nights[i] = ReservedNight{RoomReservedId: ids[0], RoomId: ids[1], ServiceLevelId: ids[2]}
+		nights[i].Date, _ = v[3].(time.Time)
+	}
+	return nights, nil
+}
+
+// GetAllRelocation retrieves all Relocation matches certain condition. Returns empty list if
+// no records exist
+func GetAllRelocation(spec *queryspec.Spec) (page *Page, err error) {
+	o := orm.NewOrm()
+	qs := o.QueryTable(new(Relocation))
+	var l []Relocation
+	return getAll(qs, &l, relocationSchema, spec)
+}
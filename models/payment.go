@@ -0,0 +1,180 @@
+package models
+
+import (
+	"time"
+
+	"easybook/queryspec"
+	"easybook/types"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// Values of Payment.Status.
+const (
+	PaymentPending    = "pending"
+	PaymentAuthorized = "authorized"
+	PaymentCaptured   = "captured"
+	PaymentVoided     = "voided"
+	PaymentFailed     = "failed"
+)
+
+// Values of Refund.Status.
+const (
+	RefundPending   = "pending"
+	RefundSucceeded = "succeeded"
+	RefundFailed    = "failed"
+)
+
+// Payment is an amount authorized and captured through a payment provider
+// to pay an invoice. ProviderRef is the id of the payment at the provider.
+type Payment struct {
+	Id             int         `orm:"column(id);auto"`
+	InvoiceId      *Invoice    `orm:"column(invoice_id);rel(fk)"`
+	Provider       string      `orm:"column(provider);size(20)"`
+	ProviderRef    string      `orm:"column(provider_ref);size(64);null"`
+	Amount         types.Money `orm:"column(amount)"`
+	RefundedAmount types.Money `orm:"column(refunded_amount)"`
+	Status         string      `orm:"column(status);size(20)"`
+	FailureReason  string      `orm:"column(failure_reason);size(255);null"`
+	AuthorizedAt   time.Time   `orm:"column(authorized_at);type(datetime);null"`
+	CapturedAt     time.Time   `orm:"column(captured_at);type(datetime);null"`
+	VoidedAt       time.Time   `orm:"column(voided_at);type(datetime);null"`
+	CreatedAt      time.Time   `orm:"column(created_at);type(timestamp)"`
+	UpdatedAt      time.Time   `orm:"column(updated_at);type(timestamp)"`
+}
+
+var paymentSchema = queryspec.NewSchema(new(Payment)).
+	Restrict("InvoiceId", RoleStaff)
+
+func (t *Payment) TableName() string {
+	return "payment"
+}
+
+// Refund gives back part or all of a captured Payment.
+type Refund struct {
+	Id            int         `orm:"column(id);auto"`
+	PaymentId     *Payment    `orm:"column(payment_id);rel(fk)"`
+	ProviderRef   string      `orm:"column(provider_ref);size(64);null"`
+	Amount        types.Money `orm:"column(amount)"`
+	Reason        string      `orm:"column(reason);size(255);null"`
+	Status        string      `orm:"column(status);size(20)"`
+	FailureReason string      `orm:"column(failure_reason);size(255);null"`
+	CreatedAt     time.Time   `orm:"column(created_at);type(timestamp)"`
+	UpdatedAt     time.Time   `orm:"column(updated_at);type(timestamp)"`
+}
+
+var refundSchema = queryspec.NewSchema(new(Refund)).
+	Restrict("PaymentId", RoleStaff)
+
+func (t *Refund) TableName() string {
+	return "refund"
+}
+
+// PaymentEvent is a webhook call of a payment provider. Events are stored
+// by their provider id so that a redelivered event is applied once.
+type PaymentEvent struct {
+	Id          int       `orm:"column(id);auto"`
+	Provider    string    `orm:"column(provider);size(20)"`
+	EventId     string    `orm:"column(event_id);size(64)"`
+	Type        string    `orm:"column(type);size(40)"`
+	Payload     string    `orm:"column(payload)"`
+	ProcessedAt time.Time `orm:"column(processed_at);type(datetime);null"`
+	CreatedAt   time.Time `orm:"column(created_at);type(timestamp)"`
+}
+
+func (t *PaymentEvent) TableName() string {
+	return "payment_event"
+}
+
+func init() {
+	orm.RegisterModel(new(Payment), new(Refund), new(PaymentEvent))
+}
+
+// GetPaymentById retrieves Payment by Id. Returns error if
+// Id doesn't exist
+func GetPaymentById(id int) (v *Payment, err error) {
+	o := orm.NewOrm()
+	v = &Payment{Id: id}
+	if err = o.Read(v); err == nil {
+		return v, nil
+	}
+	return nil, err
+}
+
+// GetPaymentByRef retrieves Payment by provider and provider reference for
+// update within the transaction of o. Returns error if it doesn't exist
+func GetPaymentByRef(o orm.Ormer, provider string, ref string) (v *Payment, err error) {
+	v = &Payment{}
+	err = o.QueryTable(new(Payment)).
+		Filter("Provider", provider).Filter("ProviderRef", ref).ForUpdate().One(v)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// GetAllPayment retrieves all Payment matches certain condition. Returns empty list if
+// no records exist
+func GetAllPayment(spec *queryspec.Spec) (page *Page, err error) {
+	o := orm.NewOrm()
+	qs := o.QueryTable(new(Payment))
+	var l []Payment
+	return getAll(qs, &l, paymentSchema, spec)
+}
+
+// GetGuestPayments retrieves the Payment of the invoices of a guest
+// matching certain condition. Returns empty list if no records exist
+func GetGuestPayments(guestID int, spec *queryspec.Spec) (page *Page, err error) {
+	o := orm.NewOrm()
+	qs := o.QueryTable(new(Payment)).Filter("InvoiceId__GuestId__Id", guestID)
+	var l []Payment
+	return getAll(qs, &l, paymentSchema, spec)
+}
+
+// GetRefundsByPayment retrieves the Refund of a payment. Returns empty list
+// if no records exist
+func GetRefundsByPayment(o orm.Ormer, paymentID int) (l []Refund, err error) {
+	_, err = o.QueryTable(new(Refund)).Filter("PaymentId", paymentID).OrderBy("Id").All(&l)
+	return
+}
+
+// GetRefundByRef retrieves Refund by the provider reference of its payment
+// and its own for update within the transaction of o. Returns error if it
+// doesn't exist
+func GetRefundByRef(o orm.Ormer, paymentID int, ref string) (v *Refund, err error) {
+	v = &Refund{}
+	err = o.QueryTable(new(Refund)).
+		Filter("PaymentId", paymentID).Filter("ProviderRef", ref).ForUpdate().One(v)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// CapturedAmount returns the amount captured and not refunded of the
+// payments of an invoice.
+func CapturedAmount(o orm.Ormer, invoiceID int) (types.Money, error) {
+	var l []Payment
+	_, err := o.QueryTable(new(Payment)).
+		Filter("InvoiceId", invoiceID).Filter("Status", PaymentCaptured).All(&l, "Amount", "RefundedAmount")
+	if err != nil {
+		return types.Money{}, err
+	}
+	var sum types.Money
+	for _, p := range l {
+		if sum, err = sum.Add(p.Amount); err != nil {
+			return types.Money{}, err
+		}
+		if sum, err = sum.Sub(p.RefundedAmount); err != nil {
+			return types.Money{}, err
+		}
+	}
+	return sum, nil
+}
+
+// AddPaymentEvent records a webhook event within the transaction of o.
+// Returns false if the event was already recorded.
+func AddPaymentEvent(o orm.Ormer, m *PaymentEvent) (bool, error) {
+	created, _, err := o.ReadOrCreate(m, "Provider", "EventId")
+	return created, err
+}
@@ -0,0 +1,151 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"easybook/queryspec"
+	"easybook/types"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// Promotion is a discount code, Percent off the room charges or a fixed
+// Amount off, valid from ValidFrom until ValidTo. A promotion of a hotel or
+// of a room discounts only the nights of its rooms. MaxUses and
+// MaxUsesPerGuest limit the redemptions, zero is unlimited, and Uses counts
+// them. Only Stackable promotions combine with other codes.
+type Promotion struct {
+	Id              int         `orm:"column(id);auto"`
+	Code            string      `orm:"column(code);size(40);unique"`
+	Name            string      `orm:"column(name);size(100)"`
+	HotelId         *Hotel      `orm:"column(hotel_id);rel(fk);null"`
+	RoomId          *Room       `orm:"column(room_id);rel(fk);null"`
+	Percent         float64     `orm:"column(percent);digits(5);decimals(2)"`
+	Amount          types.Money `orm:"column(amount)"`
+	Stackable       int8        `orm:"column(stackable)"`
+	MaxUses         int         `orm:"column(max_uses)"`
+	MaxUsesPerGuest int         `orm:"column(max_uses_per_guest)"`
+	Uses            int         `orm:"column(uses)"`
+	ValidFrom       time.Time   `orm:"column(valid_from);type(timestamp)"`
+	ValidTo         time.Time   `orm:"column(valid_to);type(timestamp);null"`
+	CreatedAt       time.Time   `orm:"column(created_at);type(timestamp)"`
+	UpdatedAt       time.Time   `orm:"column(updated_at);type(timestamp)"`
+}
+
+var promotionSchema = queryspec.NewSchema(new(Promotion)).
+	Restrict("Code", RoleStaff)
+
+// PromotionRedemption is a use of a Promotion by a reservation, Amount off
+// its room charges. Released redemptions, of canceled reservations, no
+// longer count.
+type PromotionRedemption struct {
+	Id            int          `orm:"column(id);auto"`
+	PromotionId   *Promotion   `orm:"column(promotion_id);rel(fk)"`
+	ReservationId *Reservation `orm:"column(reservation_id);rel(fk)"`
+	GuestId       *Guest       `orm:"column(guest_id);rel(fk)"`
+	Amount        types.Money  `orm:"column(amount)"`
+	CreatedAt     time.Time    `orm:"column(created_at);type(timestamp)"`
+	ReleasedAt    time.Time    `orm:"column(released_at);type(timestamp);null"`
+}
+
+func (t *Promotion) TableName() string {
+	return "promotion"
+}
+
+func (t *PromotionRedemption) TableName() string {
+	return "promotion_redemption"
+}
+
+func init() {
+	orm.RegisterModel(new(Promotion), new(PromotionRedemption))
+}
+
+// AddPromotion insert a new Promotion into database and returns
+// last inserted Id on success.
+func AddPromotion(m *Promotion) (id int64, err error) {
+	o := orm.NewOrm()
+	m.Uses = 0
+	id, err = o.Insert(m)
+	return
+}
+
+// GetPromotionById retrieves Promotion by Id. Returns error if
+// Id doesn't exist
+func GetPromotionById(id int) (v *Promotion, err error) {
+	o := orm.NewOrm()
+	v = &Promotion{Id: id}
+	if err = o.Read(v); err == nil {
+		return v, nil
+	}
+	return nil, err
+}
+
+// GetAllPromotion retrieves all Promotion matches certain condition. Returns empty list if
+// no records exist
+func GetAllPromotion(spec *queryspec.Spec) (page *Page, err error) {
+	o := orm.NewOrm()
+	qs := o.QueryTable(new(Promotion))
+	var l []Promotion
+	return getAll(qs, &l, promotionSchema, spec)
+}
+
+// GetPromotionByCodeForUpdate retrieves the Promotion of a code and locks
+// it until the transaction of o ends. Returns orm.ErrNoRows if none exists
+func GetPromotionByCodeForUpdate(o orm.Ormer, code string) (v *Promotion, err error) {
+	v = &Promotion{Code: code}
+	if err = o.ReadForUpdate(v, "Code"); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// UpdatePromotion updates Promotion by Id and returns error if
+// the record to be updated doesn't exist. Uses is kept, it is counted by
+// the redemptions
+func UpdatePromotionById(m *Promotion) (err error) {
+	o := orm.NewOrm()
+	v := Promotion{Id: m.Id}
+	// ascertain id exists in the database
+	if err = o.Read(&v); err == nil {
+		m.Uses = v.Uses
+		var num int64
+		if num, err = o.Update(m); err == nil {
+			fmt.Println("Number of records updated in database:", num)
+		}
+	}
+	return
+}
+
+// DeletePromotion deletes Promotion by Id and returns error if
+// the record to be deleted doesn't exist
+func DeletePromotion(id int) (err error) {
+	o := orm.NewOrm()
+	v := Promotion{Id: id}
+	// ascertain id exists in the database
+	if err = o.Read(&v); err == nil {
+		var num int64
+		if num, err = o.Delete(&Promotion{Id: id}); err == nil {
+			fmt.Println("Number of records deleted in database:", num)
+		}
+	}
+	return
+}
+
+// CountGuestRedemptions returns the number of redemptions of a promotion
+// by a guest which are not released.
+func CountGuestRedemptions(o orm.Ormer, promotionID int, guestID int) (int64, error) {
+	return o.QueryTable(new(PromotionRedemption)).
+		Filter("PromotionId", promotionID).Filter("GuestId", guestID).
+		Filter("ReleasedAt__isnull", true).Count()
+}
+
+// GetReservationRedemptions retrieves the redemptions of a reservation
+// which are not released, in order, with their Promotion loaded. Returns
+// empty list if no records exist
+func GetReservationRedemptions(o orm.Ormer, reservationID int) (l []PromotionRedemption, err error) {
+	_, err = o.QueryTable(new(PromotionRedemption)).
+		Filter("ReservationId", reservationID).Filter("ReleasedAt__isnull", true).
+		RelatedSel("PromotionId").OrderBy("Id").All(&l)
+	return
+}
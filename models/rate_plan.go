@@ -0,0 +1,119 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"easybook/queryspec"
+	"easybook/types"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// RatePlan is the nightly Price of a room, or of the rooms of a service
+// level, for the nights from EffectFrom until the day before ExpireOn.
+// Weekdays limits the plan to some days of the week, bit 1<<time.Weekday,
+// e.g. 0x60 for Friday and Saturday nights, zero is every day. Stays using
+// the plan for a night last at least MinStay nights.
+//
+// For a night the plans of the room win over those of its service level,
+// then the highest Priority, then the plan created last, so a plan set over
+// the calendar overrides the plans below it.
+type RatePlan struct {
+	Id             int           `orm:"column(id);auto"`
+	RoomId         *Room         `orm:"column(room_id);rel(fk);null"`
+	ServiceLevelId *ServiceLevel `orm:"column(service_level_id);rel(fk);null"`
+	Name           string        `orm:"column(name);size(100)"`
+	Price          types.Money   `orm:"column(price)"`
+	Weekdays       uint8         `orm:"column(weekdays)"`
+	MinStay        uint8         `orm:"column(min_stay)"`
+	Priority       int           `orm:"column(priority)"`
+	EffectFrom     time.Time     `orm:"column(effect_from);type(date)"`
+	ExpireOn       time.Time     `orm:"column(expire_on);type(date);null"`
+	CreatedAt      time.Time     `orm:"column(created_at);type(timestamp)"`
+	UpdatedAt      time.Time     `orm:"column(updated_at);type(timestamp)"`
+}
+
+var ratePlanSchema = queryspec.NewSchema(new(RatePlan))
+
+func (t *RatePlan) TableName() string {
+	return "rate_plan"
+}
+
+// On reports whether the plan applies on a day of the week.
+func (t *RatePlan) On(day time.Weekday) bool {
+	return t.Weekdays == 0 || t.Weekdays&(1<<uint(day)) != 0
+}
+
+func init() {
+	orm.RegisterModel(new(RatePlan))
+}
+
+// AddRatePlan insert a new RatePlan into database and returns
+// last inserted Id on success.
+func AddRatePlan(m *RatePlan) (id int64, err error) {
+	o := orm.NewOrm()
+	id, err = o.Insert(m)
+	return
+}
+
+// GetRatePlanById retrieves RatePlan by Id. Returns error if
+// Id doesn't exist
+func GetRatePlanById(id int) (v *RatePlan, err error) {
+	o := orm.NewOrm()
+	v = &RatePlan{Id: id}
+	if err = o.Read(v); err == nil {
+		return v, nil
+	}
+	return nil, err
+}
+
+// GetAllRatePlan retrieves all RatePlan matches certain condition. Returns empty list if
+// no records exist
+func GetAllRatePlan(spec *queryspec.Spec) (page *Page, err error) {
+	o := orm.NewOrm()
+	qs := o.QueryTable(new(RatePlan))
+	var l []RatePlan
+	return getAll(qs, &l, ratePlanSchema, spec)
+}
+
+// GetRatePlans retrieves the RatePlan of a room and of a service level in
+// effect for any night from from until the day before to. Returns empty
+// list if no records exist
+func GetRatePlans(o orm.Ormer, roomID int, serviceLevelID int, from time.Time, to time.Time) (l []RatePlan, err error) {
+	scope := orm.NewCondition().Or("RoomId", roomID).Or("ServiceLevelId", serviceLevelID)
+	effect := orm.NewCondition().Or("ExpireOn__isnull", true).Or("ExpireOn__gt", from)
+	cond := orm.NewCondition().AndCond(scope).And("EffectFrom__lt", to).AndCond(effect)
+	_, err = o.QueryTable(new(RatePlan)).SetCond(cond).OrderBy("Id").All(&l)
+	return
+}
+
+// UpdateRatePlan updates RatePlan by Id and returns error if
+// the record to be updated doesn't exist
+func UpdateRatePlanById(m *RatePlan) (err error) {
+	o := orm.NewOrm()
+	v := RatePlan{Id: m.Id}
+	// ascertain id exists in the database
+	if err = o.Read(&v); err == nil {
+		var num int64
+		if num, err = o.Update(m); err == nil {
+			fmt.Println("Number of records updated in database:", num)
+		}
+	}
+	return
+}
+
+// DeleteRatePlan deletes RatePlan by Id and returns error if
+// the record to be deleted doesn't exist
+func DeleteRatePlan(id int) (err error) {
+	o := orm.NewOrm()
+	v := RatePlan{Id: id}
+	// ascertain id exists in the database
+	if err = o.Read(&v); err == nil {
+		var num int64
+		if num, err = o.Delete(&RatePlan{Id: id}); err == nil {
+			fmt.Println("Number of records deleted in database:", num)
+		}
+	}
+	return
+}
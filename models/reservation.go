@@ -1,28 +1,50 @@
 package models
 
 import (
-	"errors"
 	"fmt"
-	"reflect"
-	"strings"
 	"time"
 
+	"easybook/queryspec"
+	"easybook/types"
+
 	"github.com/astaxie/beego/orm"
 )
 
 type Reservation struct {
-	Id              int       `orm:"column(id);auto"`
-	GuestId         *Guest    `orm:"column(guest_id);rel(fk)"`
-	StartDate       time.Time `orm:"column(start_date);type(date)"`
-	EndDate         time.Time `orm:"column(end_date);type(date)"`
-	DiscountPercent float32   `orm:"column(discount_percent)"`
-	AirportShuttle  uint8     `orm:"column(airport_shuttle)"`
-	TotalPrice      float32   `orm:"column(total_price)"`
+	Id              int         `orm:"column(id);auto"`
+	GuestId         *Guest      `orm:"column(guest_id);rel(fk)"`
+	StartDate       time.Time   `orm:"column(start_date);type(date)"`
+	EndDate         time.Time   `orm:"column(end_date);type(date)"`
+	DiscountPercent float32     `orm:"column(discount_percent)"`
+	AirportShuttle  uint8       `orm:"column(airport_shuttle)"`
+	Guests          uint8       `orm:"column(guests)"`
+	TotalPrice      types.Money `orm:"column(total_price)"`
+	// DisplayCurrency is the currency the guest booked in and ExchangeRate
+	// the value of one unit of TotalPrice in it at booking time.
+	DisplayCurrency string    `orm:"column(display_currency);size(3);null"`
+	ExchangeRate    float64   `orm:"column(exchange_rate);digits(20);decimals(10);null"`
 	Status          uint8     `orm:"column(status)"`
 	CreatedAt       time.Time `orm:"column(created_at);type(timestamp)"`
 	UpdatedAt       time.Time `orm:"column(updated_at);type(timestamp)"`
+	// GroupId is the group booking of the reservation, if any.
+	GroupId *GroupBooking `orm:"column(group_id);rel(fk);null"`
+	// DisplayPrice is TotalPrice in the currency asked by the client.
+	DisplayPrice *types.Money `orm:"-" json:",omitempty"`
 }
 
+// Values of Reservation.Status.
+const (
+	ReservationPending uint8 = iota
+	ReservationConfirmed
+	ReservationCancelled
+	// ReservationNoShow reservations were not checked in by the cut-off of
+	// their hotel, only the penalty nights are kept.
+	ReservationNoShow
+)
+
+var reservationSchema = queryspec.NewSchema(new(Reservation)).
+	Restrict("GuestId", RoleStaff)
+
 func (t *Reservation) TableName() string {
 	return "reservation"
 }
@@ -52,80 +74,11 @@ func GetReservationById(id int) (v *Reservation, err error) {
 
 // GetAllReservation retrieves all Reservation matches certain condition. Returns empty list if
 // no records exist
-func GetAllReservation(query map[string]string, fields []string, sortby []string, order []string,
-	offset int64, limit int64) (ml []interface{}, err error) {
+func GetAllReservation(spec *queryspec.Spec) (page *Page, err error) {
 	o := orm.NewOrm()
 	qs := o.QueryTable(new(Reservation))
-	// query k=v
-	for k, v := range query {
-		// rewrite dot-notation to Object__Attribute
-		k = strings.Replace(k, ".", "__", -1)
-		if strings.Contains(k, "isnull") {
-			qs = qs.Filter(k, (v == "true" || v == "1"))
-		} else {
-			qs = qs.Filter(k, v)
-		}
-	}
-	// order by:
-	var sortFields []string
-	if len(sortby) != 0 {
-		if len(sortby) == len(order) {
-			// 1) for each sort field, there is an associated order
-			for i, v := range sortby {
-				orderby := ""
-				if order[i] == "desc" {
-					orderby = "-" + v
-				} else if order[i] == "asc" {
-					orderby = v
-				} else {
-					return nil, errors.New("Error: Invalid order. Must be either [asc|desc]")
-				}
-				sortFields = append(sortFields, orderby)
-			}
-			qs = qs.OrderBy(sortFields...)
-		} else if len(sortby) != len(order) && len(order) == 1 {
-			// 2) there is exactly one order, all the sorted fields will be sorted by this order
-			for _, v := range sortby {
-				orderby := ""
-				if order[0] == "desc" {
-					orderby = "-" + v
-				} else if order[0] == "asc" {
-					orderby = v
-				} else {
-					return nil, errors.New("Error: Invalid order. Must be either [asc|desc]")
-				}
-				sortFields = append(sortFields, orderby)
-			}
-		} else if len(sortby) != len(order) && len(order) != 1 {
-			return nil, errors.New("Error: 'sortby', 'order' sizes mismatch or 'order' size is not 1")
-		}
-	} else {
-		if len(order) != 0 {
-			return nil, errors.New("Error: unused 'order' fields")
-		}
-	}
-
 	var l []Reservation
-	qs = qs.OrderBy(sortFields...)
-	if _, err = qs.Limit(limit, offset).All(&l, fields...); err == nil {
-		if len(fields) == 0 {
-			for _, v := range l {
-				ml = append(ml, v)
-			}
-		} else {
-			// trim unused fields
-			for _, v := range l {
-				m := make(map[string]interface{})
-				val := reflect.ValueOf(v)
-				for _, fname := range fields {
-					m[fname] = val.FieldByName(fname).Interface()
-				}
-				ml = append(ml, m)
-			}
-		}
-		return ml, nil
-	}
-	return nil, err
+	return getAll(qs, &l, reservationSchema, spec)
 }
 
 // UpdateReservation updates Reservation by Id and returns error if
@@ -157,3 +110,15 @@ func DeleteReservation(id int) (err error) {
 	}
 	return
 }
+
+// GetReservationHotelId returns the id of the hotel whose rooms are reserved
+// by Reservation by Id. Returns error if no room is reserved
+func GetReservationHotelId(o orm.Ormer, id int) (hotelID int, err error) {
+	var rr RoomReserved
+	err = o.QueryTable(new(RoomReserved)).Filter("ReservationId", id).
+		RelatedSel("RoomId").Limit(1).One(&rr)
+	if err != nil {
+		return 0, err
+	}
+	return rr.RoomId.HotelId.Id, nil
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"easybook/queryspec"
+	"easybook/types"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// ReservationChange is a modification of the dates or rooms of a
+// reservation by ChangedBy. OldRooms and NewRooms are the comma separated
+// ids of the rooms reserved, Difference is NewTotal less OldTotal.
+type ReservationChange struct {
+	Id            int          `orm:"column(id);auto"`
+	ReservationId *Reservation `orm:"column(reservation_id);rel(fk)"`
+	ChangedBy     *Guest       `orm:"column(changed_by);rel(fk);null"`
+	OldStartDate  time.Time    `orm:"column(old_start_date);type(date)"`
+	OldEndDate    time.Time    `orm:"column(old_end_date);type(date)"`
+	OldRooms      string       `orm:"column(old_rooms);size(255)"`
+	NewStartDate  time.Time    `orm:"column(new_start_date);type(date)"`
+	NewEndDate    time.Time    `orm:"column(new_end_date);type(date)"`
+	NewRooms      string       `orm:"column(new_rooms);size(255)"`
+	OldTotal      types.Money  `orm:"column(old_total)"`
+	NewTotal      types.Money  `orm:"column(new_total)"`
+	Difference    types.Money  `orm:"column(difference)"`
+	Reason        string       `orm:"column(reason);size(255);null"`
+	CreatedAt     time.Time    `orm:"column(created_at);type(timestamp)"`
+}
+
+var reservationChangeSchema = queryspec.NewSchema(new(ReservationChange)).
+	Restrict("ChangedBy", RoleStaff)
+
+// RoomsCSV returns the comma separated ids of rooms, as kept in OldRooms and
+// NewRooms.
+func RoomsCSV(rooms []int) string {
+	l := make([]string, len(rooms))
+	for i, id := range rooms {
+		l[i] = strconv.Itoa(id)
+	}
+	return strings.Join(l, ",")
+}
+
+func (t *ReservationChange) TableName() string {
+	return "reservation_change"
+}
+
+func init() {
+	orm.RegisterModel(new(ReservationChange))
+}
+
+// GetReservationChanges retrieves the ReservationChange of a reservation
+// matching certain condition. Returns empty list if no records exist
+func GetReservationChanges(reservationID int, spec *queryspec.Spec) (page *Page, err error) {
+	o := orm.NewOrm()
+	qs := o.QueryTable(new(ReservationChange)).Filter("ReservationId", reservationID)
+	var l []ReservationChange
+	return getAll(qs, &l, reservationChangeSchema, spec)
+}
@@ -1,12 +1,12 @@
 package models
 
 import (
-	"errors"
 	"fmt"
-	"reflect"
-	"strings"
 	"time"
 
+	"easybook/queryspec"
+	"easybook/types"
+
 	"github.com/astaxie/beego/orm"
 )
 
@@ -15,13 +15,17 @@ type Room struct {
 	Name           string        `orm:"column(name);size(100)"`
 	Number         int           `orm:"column(number)"`
 	Description    string        `orm:"column(description);null"`
-	CurrentPrice   float32       `orm:"column(current_price)"`
+	CurrentPrice   types.Money   `orm:"column(current_price)"`
 	HotelId        *Hotel        `orm:"column(hotel_id);rel(fk)"`
 	ServiceLevelId *ServiceLevel `orm:"column(service_level_id);rel(fk)"`
 	CreatedAt      time.Time     `orm:"column(created_at);type(timestamp)"`
 	UpdatedAt      time.Time     `orm:"column(updated_at);type(timestamp)"`
+	// DisplayPrice is CurrentPrice in the currency asked by the client.
+	DisplayPrice *types.Money `orm:"-" json:",omitempty"`
 }
 
+var roomSchema = queryspec.NewSchema(new(Room))
+
 func (t *Room) TableName() string {
 	return "room"
 }
@@ -51,80 +55,11 @@ func GetRoomById(id int) (v *Room, err error) {
 
 // GetAllRoom retrieves all Room matches certain condition. Returns empty list if
 // no records exist
-func GetAllRoom(query map[string]string, fields []string, sortby []string, order []string,
-	offset int64, limit int64) (ml []interface{}, err error) {
+func GetAllRoom(spec *queryspec.Spec) (page *Page, err error) {
 	o := orm.NewOrm()
 	qs := o.QueryTable(new(Room))
-	// query k=v
-	for k, v := range query {
-		// rewrite dot-notation to Object__Attribute
-		k = strings.Replace(k, ".", "__", -1)
-		if strings.Contains(k, "isnull") {
-			qs = qs.Filter(k, (v == "true" || v == "1"))
-		} else {
-			qs = qs.Filter(k, v)
-		}
-	}
-	// order by:
-	var sortFields []string
-	if len(sortby) != 0 {
-		if len(sortby) == len(order) {
-			// 1) for each sort field, there is an associated order
-			for i, v := range sortby {
-				orderby := ""
-				if order[i] == "desc" {
-					orderby = "-" + v
-				} else if order[i] == "asc" {
-					orderby = v
-				} else {
-					return nil, errors.New("Error: Invalid order. Must be either [asc|desc]")
-				}
-				sortFields = append(sortFields, orderby)
-			}
-			qs = qs.OrderBy(sortFields...)
-		} else if len(sortby) != len(order) && len(order) == 1 {
-			// 2) there is exactly one order, all the sorted fields will be sorted by this order
-			for _, v := range sortby {
-				orderby := ""
-				if order[0] == "desc" {
-					orderby = "-" + v
-				} else if order[0] == "asc" {
-					orderby = v
-				} else {
-					return nil, errors.New("Error: Invalid order. Must be either [asc|desc]")
-				}
-				sortFields = append(sortFields, orderby)
-			}
-		} else if len(sortby) != len(order) && len(order) != 1 {
-			return nil, errors.New("Error: 'sortby', 'order' sizes mismatch or 'order' size is not 1")
-		}
-	} else {
-		if len(order) != 0 {
-			return nil, errors.New("Error: unused 'order' fields")
-		}
-	}
-
 	var l []Room
-	qs = qs.OrderBy(sortFields...)
-	if _, err = qs.Limit(limit, offset).All(&l, fields...); err == nil {
-		if len(fields) == 0 {
-			for _, v := range l {
-				ml = append(ml, v)
-			}
-		} else {
-			// trim unused fields
-			for _, v := range l {
-				m := make(map[string]interface{})
-				val := reflect.ValueOf(v)
-				for _, fname := range fields {
-					m[fname] = val.FieldByName(fname).Interface()
-				}
-				ml = append(ml, m)
-			}
-		}
-		return ml, nil
-	}
-	return nil, err
+	return getAll(qs, &l, roomSchema, spec)
 }
 
 // UpdateRoom updates Room by Id and returns error if
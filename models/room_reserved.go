@@ -1,33 +1,53 @@
 package models
 
 import (
-	"errors"
 	"fmt"
-	"reflect"
-	"strings"
 	"time"
 
+	"easybook/queryspec"
+	"easybook/types"
+
 	"github.com/astaxie/beego/orm"
 )
 
+// RoomReserved is a room of a reservation. Price is the price of its first
+// night, the price of every night is kept in a RoomNight.
 type RoomReserved struct {
 	Id             int          `orm:"column(id);auto"`
 	ReservationId  *Reservation `orm:"column(reservation_id);rel(fk)"`
 	RoomId         *Room        `orm:"column(room_id);rel(fk)"`
 	AssignedRoomId *int         `orm:"column(assigned_room_id);null"`
-	Price          float32      `orm:"column(price);null"`
+	Price          types.Money  `orm:"column(price);null"`
 	CheckIn        time.Time    `orm:"column(check_in);type(timestamp);null"`
 	CheckOut       time.Time    `orm:"column(check_out);type(timestamp);null"`
 	CreatedAt      time.Time    `orm:"column(created_at);type(timestamp)"`
 	UpdatedAt      time.Time    `orm:"column(updated_at);type(timestamp)"`
 }
 
+var roomReservedSchema = queryspec.NewSchema(new(RoomReserved)).
+	Restrict("ReservationId", RoleStaff)
+
+// RoomNight is the price of a reserved room for the night of Date, from
+// the RatePlan in effect when booked, none for the current price of the
+// room.
+type RoomNight struct {
+	Id             int           `orm:"column(id);auto"`
+	RoomReservedId *RoomReserved `orm:"column(room_reserved_id);rel(fk)"`
+	Date           time.Time     `orm:"column(date);type(date)"`
+	Price          types.Money   `orm:"column(price)"`
+	RatePlanId     *RatePlan     `orm:"column(rate_plan_id);rel(fk);null"`
+}
+
 func (t *RoomReserved) TableName() string {
 	return "room_reserved"
 }
 
+func (t *RoomNight) TableName() string {
+	return "room_night"
+}
+
 func init() {
-	orm.RegisterModel(new(RoomReserved))
+	orm.RegisterModel(new(RoomReserved), new(RoomNight))
 }
 
 // AddRoomReserved insert a new RoomReserved into database and returns
@@ -51,80 +71,11 @@ func GetRoomReservedById(id int) (v *RoomReserved, err error) {
 
 // GetAllRoomReserved retrieves all RoomReserved matches certain condition. Returns empty list if
 // no records exist
-func GetAllRoomReserved(query map[string]string, fields []string, sortby []string, order []string,
-	offset int64, limit int64) (ml []interface{}, err error) {
+func GetAllRoomReserved(spec *queryspec.Spec) (page *Page, err error) {
 	o := orm.NewOrm()
 	qs := o.QueryTable(new(RoomReserved))
-	// query k=v
-	for k, v := range query {
-		// rewrite dot-notation to Object__Attribute
-		k = strings.Replace(k, ".", "__", -1)
-		if strings.Contains(k, "isnull") {
-			qs = qs.Filter(k, (v == "true" || v == "1"))
-		} else {
-			qs = qs.Filter(k, v)
-		}
-	}
-	// order by:
-	var sortFields []string
-	if len(sortby) != 0 {
-		if len(sortby) == len(order) {
-			// 1) for each sort field, there is an associated order
-			for i, v := range sortby {
-				orderby := ""
-				if order[i] == "desc" {
-					orderby = "-" + v
-				} else if order[i] == "asc" {
-					orderby = v
-				} else {
-					return nil, errors.New("Error: Invalid order. Must be either [asc|desc]")
-				}
-				sortFields = append(sortFields, orderby)
-			}
-			qs = qs.OrderBy(sortFields...)
-		} else if len(sortby) != len(order) && len(order) == 1 {
-			// 2) there is exactly one order, all the sorted fields will be sorted by this order
-			for _, v := range sortby {
-				orderby := ""
-				if order[0] == "desc" {
-					orderby = "-" + v
-				} else if order[0] == "asc" {
-					orderby = v
-				} else {
-					return nil, errors.New("Error: Invalid order. Must be either [asc|desc]")
-				}
-				sortFields = append(sortFields, orderby)
-			}
-		} else if len(sortby) != len(order) && len(order) != 1 {
-			return nil, errors.New("Error: 'sortby', 'order' sizes mismatch or 'order' size is not 1")
-		}
-	} else {
-		if len(order) != 0 {
-			return nil, errors.New("Error: unused 'order' fields")
-		}
-	}
-
 	var l []RoomReserved
-	qs = qs.OrderBy(sortFields...)
-	if _, err = qs.Limit(limit, offset).All(&l, fields...); err == nil {
-		if len(fields) == 0 {
-			for _, v := range l {
-				ml = append(ml, v)
-			}
-		} else {
-			// trim unused fields
-			for _, v := range l {
-				m := make(map[string]interface{})
-				val := reflect.ValueOf(v)
-				for _, fname := range fields {
-					m[fname] = val.FieldByName(fname).Interface()
-				}
-				ml = append(ml, m)
-			}
-		}
-		return ml, nil
-	}
-	return nil, err
+	return getAll(qs, &l, roomReservedSchema, spec)
 }
 
 // UpdateRoomReserved updates RoomReserved by Id and returns error if
@@ -156,3 +107,27 @@ func DeleteRoomReserved(id int) (err error) {
 	}
 	return
 }
+
+// GetRoomReservedByReservation retrieves the RoomReserved of a reservation
+// with their Room loaded. Returns empty list if no records exist
+func GetRoomReservedByReservation(o orm.Ormer, reservationID int) (l []RoomReserved, err error) {
+	_, err = o.QueryTable(new(RoomReserved)).Filter("ReservationId", reservationID).
+		RelatedSel("RoomId").OrderBy("Id").All(&l)
+	return
+}
+
+// GetRoomNightsByReservation retrieves the RoomNight of the rooms of a
+// reservation by date. Returns empty list if no records exist
+func GetRoomNightsByReservation(o orm.Ormer, reservationID int) (l []RoomNight, err error) {
+	_, err = o.QueryTable(new(RoomNight)).Filter("RoomReservedId__ReservationId__Id", reservationID).
+		OrderBy("RoomReservedId", "Date").All(&l)
+	return
+}
+
+// DeleteRoomNightsFrom deletes the RoomNight of the rooms of a reservation
+// from date on and returns their number.
+func DeleteRoomNightsFrom(o orm.Ormer, reservationID int, date time.Time) (int64, error) {
+	return o.QueryTable(new(RoomNight)).
+		Filter("RoomReservedId__ReservationId__Id", reservationID).Filter("Date__gte", date).
+		Delete()
+}
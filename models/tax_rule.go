@@ -0,0 +1,133 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"easybook/queryspec"
+	"easybook/types"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// Values of TaxRule.Category, taxes and fees are totaled apart on invoices.
+const (
+	CategoryTax = "tax"
+	CategoryFee = "fee"
+)
+
+// Values of TaxRule.Basis, what a rule is charged on.
+const (
+	// BasisPercent charges Rate percent of the room charges after discount.
+	BasisPercent = "percent"
+	// BasisNight charges Amount per room per night.
+	BasisNight = "night"
+	// BasisGuestNight charges Amount per guest per night, e.g. tourism tax.
+	BasisGuestNight = "guest_night"
+	// BasisStay charges Amount once per reservation.
+	BasisStay = "stay"
+	// BasisShuttle charges Amount once per reservation using the airport
+	// shuttle.
+	BasisShuttle = "shuttle"
+)
+
+// TaxRule is a tax or fee of the reservations of a city or of a hotel, in
+// effect for the nights from EffectFrom until the day before ExpireOn. A
+// hotel rule replaces the city rule of the same Name. Rules are applied in
+// Sequence order, a Compound percent rule is also charged on the rules
+// applied before it.
+type TaxRule struct {
+	Id         int         `orm:"column(id);auto"`
+	CityId     *City       `orm:"column(city_id);rel(fk);null"`
+	HotelId    *Hotel      `orm:"column(hotel_id);rel(fk);null"`
+	Name       string      `orm:"column(name);size(100)"`
+	Category   string      `orm:"column(category);size(10)"`
+	Basis      string      `orm:"column(basis);size(20)"`
+	Rate       float64     `orm:"column(rate);digits(9);decimals(4)"`
+	Amount     types.Money `orm:"column(amount)"`
+	Compound   int8        `orm:"column(compound)"`
+	Sequence   int         `orm:"column(sequence)"`
+	EffectFrom time.Time   `orm:"column(effect_from);type(date)"`
+	ExpireOn   time.Time   `orm:"column(expire_on);type(date);null"`
+	CreatedAt  time.Time   `orm:"column(created_at);type(timestamp)"`
+	UpdatedAt  time.Time   `orm:"column(updated_at);type(timestamp)"`
+}
+
+var taxRuleSchema = queryspec.NewSchema(new(TaxRule))
+
+func (t *TaxRule) TableName() string {
+	return "tax_rule"
+}
+
+func init() {
+	orm.RegisterModel(new(TaxRule))
+}
+
+// AddTaxRule insert a new TaxRule into database and returns
+// last inserted Id on success.
+func AddTaxRule(m *TaxRule) (id int64, err error) {
+	o := orm.NewOrm()
+	id, err = o.Insert(m)
+	return
+}
+
+// GetTaxRuleById retrieves TaxRule by Id. Returns error if
+// Id doesn't exist
+func GetTaxRuleById(id int) (v *TaxRule, err error) {
+	o := orm.NewOrm()
+	v = &TaxRule{Id: id}
+	if err = o.Read(v); err == nil {
+		return v, nil
+	}
+	return nil, err
+}
+
+// GetAllTaxRule retrieves all TaxRule matches certain condition. Returns empty list if
+// no records exist
+func GetAllTaxRule(spec *queryspec.Spec) (page *Page, err error) {
+	o := orm.NewOrm()
+	qs := o.QueryTable(new(TaxRule))
+	var l []TaxRule
+	return getAll(qs, &l, taxRuleSchema, spec)
+}
+
+// GetTaxRules retrieves the TaxRule of a city and of a hotel in effect for
+// any night from from until the day before to, in Sequence order. Returns
+// empty list if no records exist
+func GetTaxRules(o orm.Ormer, cityID int, hotelID int, from time.Time, to time.Time) (l []TaxRule, err error) {
+	scope := orm.NewCondition().Or("CityId", cityID).Or("HotelId", hotelID)
+	effect := orm.NewCondition().Or("ExpireOn__isnull", true).Or("ExpireOn__gt", from)
+	cond := orm.NewCondition().AndCond(scope).And("EffectFrom__lt", to).AndCond(effect)
+	_, err = o.QueryTable(new(TaxRule)).SetCond(cond).OrderBy("Sequence", "Id").All(&l)
+	return
+}
+
+// UpdateTaxRule updates TaxRule by Id and returns error if
+// the record to be updated doesn't exist
+func UpdateTaxRuleById(m *TaxRule) (err error) {
+	o := orm.NewOrm()
+	v := TaxRule{Id: m.Id}
+	// ascertain id exists in the database
+	if err = o.Read(&v); err == nil {
+		var num int64
+		if num, err = o.Update(m); err == nil {
+			fmt.Println("Number of records updated in database:", num)
+		}
+	}
+	return
+}
+
+// DeleteTaxRule deletes TaxRule by Id and returns error if
+// the record to be deleted doesn't exist
+func DeleteTaxRule(id int) (err error) {
+	o := orm.NewOrm()
+	v := TaxRule{Id: id}
+	// ascertain id exists in the database
+	if err = o.Read(&v); err == nil {
+		var num int64
+		if num, err = o.Delete(&TaxRule{Id: id}); err == nil {
+			fmt.Println("Number of records deleted in database:", num)
+		}
+	}
+	return
+}
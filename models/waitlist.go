@@ -0,0 +1,133 @@
+package models
+
+import (
+	"time"
+
+	"easybook/queryspec"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// WaitlistEntry is the interest of a guest in Rooms rooms of a hotel, of a
+// service level if given, for the nights from StartDate to the day before
+// EndDate. Entries waiting are offered rooms in order of creation when
+// rooms free up: the offer is the pending reservation ReservationId, which
+// the guest accepts until OfferExpiresAt or loses to the next guest.
+type WaitlistEntry struct {
+	Id             int           `orm:"column(id);auto"`
+	GuestId        *Guest        `orm:"column(guest_id);rel(fk)"`
+	HotelId        *Hotel        `orm:"column(hotel_id);rel(fk)"`
+	ServiceLevelId *ServiceLevel `orm:"column(service_level_id);rel(fk);null"`
+	StartDate      time.Time     `orm:"column(start_date);type(date)"`
+	EndDate        time.Time     `orm:"column(end_date);type(date)"`
+	Rooms          uint8         `orm:"column(rooms)"`
+	Guests         uint8         `orm:"column(guests)"`
+	Status         uint8         `orm:"column(status)"`
+	ReservationId  *Reservation  `orm:"column(reservation_id);rel(fk);null"`
+	OfferExpiresAt time.Time     `orm:"column(offer_expires_at);type(timestamp);null"`
+	CreatedAt      time.Time     `orm:"column(created_at);type(timestamp)"`
+	UpdatedAt      time.Time     `orm:"column(updated_at);type(timestamp)"`
+}
+
+// Values of WaitlistEntry.Status.
+const (
+	WaitlistWaiting uint8 = iota
+	WaitlistOffered
+	WaitlistAccepted
+	WaitlistDeclined
+	// WaitlistLapsed entries let their offer expire.
+	WaitlistLapsed
+	// WaitlistExpired entries were never offered rooms before their stay.
+	WaitlistExpired
+	WaitlistWithdrawn
+)
+
+var waitlistEntrySchema = queryspec.NewSchema(new(WaitlistEntry)).
+	Restrict("GuestId", RoleStaff)
+
+func (t *WaitlistEntry) TableName() string {
+	return "waitlist_entry"
+}
+
+func init() {
+	orm.RegisterModel(new(WaitlistEntry))
+}
+
+// GetWaitlistEntryById retrieves WaitlistEntry by Id. Returns error if
+// Id doesn't exist
+func GetWaitlistEntryById(o orm.Ormer, id int) (v *WaitlistEntry, err error) {
+	v = &WaitlistEntry{Id: id}
+	if err = o.Read(v); err == nil {
+		return v, nil
+	}
+	return nil, err
+}
+
+// GetAllWaitlistEntry retrieves all WaitlistEntry matches certain condition. Returns empty list if
+// no records exist
+func GetAllWaitlistEntry(spec *queryspec.Spec) (page *Page, err error) {
+	o := orm.NewOrm()
+	qs := o.QueryTable(new(WaitlistEntry))
+	var l []WaitlistEntry
+	return getAll(qs, &l, waitlistEntrySchema, spec)
+}
+
+// GetGuestWaitlistEntries retrieves the WaitlistEntry of a guest matching
+// certain condition. Returns empty list if no records exist
+func GetGuestWaitlistEntries(guestID int, spec *queryspec.Spec) (page *Page, err error) {
+	o := orm.NewOrm()
+	qs := o.QueryTable(new(WaitlistEntry)).Filter("GuestId", guestID)
+	var l []WaitlistEntry
+	return getAll(qs, &l, waitlistEntrySchema, spec)
+}
+
+// GetWaitingEntries retrieves the entries waiting for a hotel, in the order
+// they are offered rooms, and locks them until the transaction of o ends.
+// Returns empty list if no records exist
+func GetWaitingEntries(o orm.Ormer, hotelID int) (l []WaitlistEntry, err error) {
+	_, err = o.QueryTable(new(WaitlistEntry)).
+		Filter("HotelId", hotelID).Filter("Status", WaitlistWaiting).
+		OrderBy("CreatedAt", "Id").ForUpdate().All(&l)
+	return
+}
+
+// GetWaitingHotelIds returns the ids of the hotels with entries waiting
+// for a stay ending after date.
+func GetWaitingHotelIds(o orm.Ormer, date time.Time) ([]int, error) {
+	var l orm.ParamsList
+	_, err := o.QueryTable(new(WaitlistEntry)).
+		Filter("Status", WaitlistWaiting).Filter("EndDate__gt", date).
+		Distinct().ValuesFlat(&l, "HotelId")
+	return intsOf(l), err
+}
+
+// GetLapsedOffers retrieves the entries whose offer expired at now.
+// Returns empty list if no records exist
+func GetLapsedOffers(o orm.Ormer, now time.Time) (l []WaitlistEntry, err error) {
+	_, err = o.QueryTable(new(WaitlistEntry)).
+		Filter("Status", WaitlistOffered).Filter("OfferExpiresAt__lte", now).
+		OrderBy("Id").All(&l)
+	return
+}
+
+// GetOfferOf retrieves the entry offered reservation reservationID and
+// locks it until the transaction of o ends. Returns orm.ErrNoRows if none
+// exists
+func GetOfferOf(o orm.Ormer, reservationID int) (v *WaitlistEntry, err error) {
+	v = &WaitlistEntry{}
+	err = o.QueryTable(new(WaitlistEntry)).
+		Filter("ReservationId", reservationID).Filter("Status", WaitlistOffered).
+		ForUpdate().One(v)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ExpireWaitlistEntries expires the entries still waiting for a stay
+// starting before date and returns their number.
+func ExpireWaitlistEntries(o orm.Ormer, date time.Time) (int64, error) {
+	return o.QueryTable(new(WaitlistEntry)).
+		Filter("Status", WaitlistWaiting).Filter("StartDate__lt", date).
+		Update(orm.Params{"Status": WaitlistExpired})
+}
@@ -0,0 +1,56 @@
+package queryspec
+
+import (
+	"encoding/base64"
+	"fmt"
+	"time"
+)
+
+// Cursor is the position of the last record of a page in keyset paging
+// ordered by (created_at, id).
+type Cursor struct {
+	CreatedAt time.Time
+	Id        int
+	// Desc reports whether records are ordered newest first. It is given
+	// by the order parameter and not encoded in the token.
+	Desc bool
+}
+
+// ParseCursor decodes a token returned by Cursor.String. An empty token is
+// the zero Cursor which points before the first record.
+func ParseCursor(token string) (*Cursor, error) {
+	c := &Cursor{}
+	if token == "" {
+		return c, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errorf("Error: invalid cursor")
+	}
+	var sec, nsec int64
+	if _, err = fmt.Sscanf(string(b), "%d.%d:%d", &sec, &nsec, &c.Id); err != nil {
+		return nil, errorf("Error: invalid cursor")
+	}
+	c.CreatedAt = time.Unix(sec, nsec)
+	return c, nil
+}
+
+// IsZero reports whether c points before the first record.
+func (c *Cursor) IsZero() bool {
+	return c.Id == 0 && c.CreatedAt.IsZero()
+}
+
+// String encodes c to an opaque token.
+func (c *Cursor) String() string {
+	s := fmt.Sprintf("%d.%d:%d", c.CreatedAt.Unix(), c.CreatedAt.Nanosecond(), c.Id)
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+// OrderBy returns the order by expressions of keyset paging.
+func (c *Cursor) OrderBy() []string {
+	if c.Desc {
+		return []string{"-CreatedAt", "-Id"}
+	}
+	return []string{"CreatedAt", "Id"}
+}
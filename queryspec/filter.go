@@ -0,0 +1,339 @@
+package queryspec
+
+import (
+	"strings"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// Operators accepted in the query parameter. A condition without operator
+// is an exact match.
+var operators = map[string]int{
+	"exact":       1,
+	"iexact":      1,
+	"ne":          1,
+	"gt":          1,
+	"gte":         1,
+	"lt":          1,
+	"lte":         1,
+	"contains":    1,
+	"icontains":   1,
+	"startswith":  1,
+	"istartswith": 1,
+	"endswith":    1,
+	"iendswith":   1,
+	"isnull":      1,
+	"in":          -1,
+	"between":     2,
+}
+
+// Node is a node of the filter AST parsed from the query parameter.
+type Node interface {
+	// Condition translates the node to a beego orm condition.
+	Condition() *orm.Condition
+
+	validate(schema *Schema, role int8) error
+}
+
+// And matches when all of its nodes match.
+type And struct {
+	Nodes []Node
+}
+
+// Or matches when any of its nodes matches.
+type Or struct {
+	Nodes []Node
+}
+
+// Not matches when its node doesn't match.
+type Not struct {
+	Node Node
+}
+
+// Cond compares a field with values, e.g. status__in:1|2 is
+// Cond{Path: []string{"status"}, Op: "in", Values: []string{"1", "2"}}.
+type Cond struct {
+	// Path holds the field names, the leading ones traverse relations.
+	Path   []string
+	Op     string
+	Values []string
+}
+
+// Condition implements Node.
+func (n *And) Condition() *orm.Condition {
+	c := orm.NewCondition()
+	for _, node := range n.Nodes {
+		c = c.AndCond(node.Condition())
+	}
+	return c
+}
+
+// Condition implements Node.
+func (n *Or) Condition() *orm.Condition {
+	c := orm.NewCondition()
+	for _, node := range n.Nodes {
+		c = c.OrCond(node.Condition())
+	}
+	return c
+}
+
+// Condition implements Node.
+func (n *Not) Condition() *orm.Condition {
+	return orm.NewCondition().AndNotCond(n.Node.Condition())
+}
+
+// Condition implements Node.
+func (n *Cond) Condition() *orm.Condition {
+	expr := strings.Join(n.Path, orm.ExprSep)
+	c := orm.NewCondition()
+	switch n.Op {
+	case "":
+		return c.And(expr, n.Values[0])
+	case "ne":
+		return c.AndNot(expr, n.Values[0])
+	case "isnull":
+		return c.And(expr+orm.ExprSep+n.Op, n.Values[0] == "true" || n.Values[0] == "1")
+	}
+
+	args := make([]interface{}, 0, len(n.Values))
+	for _, v := range n.Values {
+		args = append(args, v)
+	}
+	return c.And(expr+orm.ExprSep+n.Op, args...)
+}
+
+func (n *And) validate(schema *Schema, role int8) error {
+	for _, node := range n.Nodes {
+		if err := node.validate(schema, role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *Or) validate(schema *Schema, role int8) error {
+	for _, node := range n.Nodes {
+		if err := node.validate(schema, role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *Not) validate(schema *Schema, role int8) error {
+	return n.Node.validate(schema, role)
+}
+
+// validate resolves the path of n through schema and its relations and
+// rewrites it to Go field names. Like expand, each relation of the path
+// must be allowed for role and the field must be readable by role.
+func (n *Cond) validate(schema *Schema, role int8) error {
+	key := strings.Join(n.Path, ".")
+	s := schema
+	for i, name := range n.Path {
+		if s == nil {
+			return errorf("Error: unknown relation in query field %q", key)
+		}
+		f, ok := s.Lookup(name)
+		if !ok {
+			return errorf("Error: unknown query field %q", key)
+		}
+		last := i == len(n.Path)-1
+		if !last && role < f.MinRole || last && !f.readable(role) {
+			return errorf("Error: query field %q is not allowed", key)
+		}
+		n.Path[i] = f.Name
+		s, _ = s.Related(f)
+		// beego reads a relation ending a path of relations from the table
+		// it joins for it, compare the primary key of that table instead
+		if last && i > 0 && f.Rel {
+			n.Path = append(n.Path, "Id")
+		}
+	}
+	return nil
+}
+
+// ParseFilter parses the query parameter into a filter AST. Conditions are
+// written as field:value with an optional operator suffix, e.g.
+// total_price__gte:100 or cityId.name__icontains:chi. Multiple values of in
+// and between are separated by "|". Conditions separated by "," must all
+// match, groups separated by ";" are alternatives, "!" negates the next
+// condition or group and parentheses group conditions:
+//
+//	status:1,(start_date__between:2020-01-01|2020-01-31;!total_price__lt:100)
+//
+// Values containing any of ,;|() can be written in double quotes. Money
+// fields compare numbers of minor units, e.g. cents.
+// ParseFilter returns nil if query is empty.
+func ParseFilter(query string) (Node, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, nil
+	}
+
+	p := &filterParser{s: query}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.eof() {
+		return nil, errorf("Error: unexpected %q in query at %d", p.s[p.pos], p.pos)
+	}
+	return n, nil
+}
+
+type filterParser struct {
+	s   string
+	pos int
+}
+
+func (p *filterParser) eof() bool {
+	return p.pos >= len(p.s)
+}
+
+func (p *filterParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *filterParser) parseOr() (Node, error) {
+	n, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != ';' {
+		return n, nil
+	}
+
+	or := &Or{Nodes: []Node{n}}
+	for p.peek() == ';' {
+		p.pos++
+		n, err = p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		or.Nodes = append(or.Nodes, n)
+	}
+	return or, nil
+}
+
+func (p *filterParser) parseAnd() (Node, error) {
+	n, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != ',' {
+		return n, nil
+	}
+
+	and := &And{Nodes: []Node{n}}
+	for p.peek() == ',' {
+		p.pos++
+		n, err = p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		and.Nodes = append(and.Nodes, n)
+	}
+	return and, nil
+}
+
+func (p *filterParser) parseTerm() (Node, error) {
+	switch p.peek() {
+	case '!':
+		p.pos++
+		n, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Node: n}, nil
+	case '(':
+		p.pos++
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, errorf("Error: missing ')' in query at %d", p.pos)
+		}
+		p.pos++
+		return n, nil
+	}
+	return p.parseCond()
+}
+
+func (p *filterParser) parseCond() (Node, error) {
+	start := p.pos
+	for !p.eof() && isKeyChar(p.peek()) {
+		p.pos++
+	}
+	key := p.s[start:p.pos]
+	if key == "" || p.peek() != ':' {
+		return nil, errorf("Error: invalid query key/value pair at %d", start)
+	}
+	p.pos++
+
+	n := &Cond{Path: strings.Split(strings.Replace(key, ".", orm.ExprSep, -1), orm.ExprSep)}
+	if last := n.Path[len(n.Path)-1]; len(n.Path) > 1 {
+		if _, ok := operators[last]; ok {
+			n.Op = last
+			n.Path = n.Path[:len(n.Path)-1]
+		}
+	}
+	if n.Op == "exact" {
+		n.Op = ""
+	}
+	for _, name := range n.Path {
+		if name == "" {
+			return nil, errorf("Error: invalid query key %q", key)
+		}
+	}
+
+	for {
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		n.Values = append(n.Values, v)
+		if p.peek() != '|' {
+			break
+		}
+		p.pos++
+	}
+
+	switch arity := operators[n.Op]; {
+	case n.Op == "" && len(n.Values) != 1, arity > 0 && len(n.Values) != arity:
+		return nil, errorf("Error: invalid number of values for %q", key)
+	}
+	return n, nil
+}
+
+func (p *filterParser) parseValue() (string, error) {
+	if p.peek() != '"' {
+		start := p.pos
+		for !p.eof() && !strings.ContainsRune(",;|()", rune(p.peek())) {
+			p.pos++
+		}
+		return p.s[start:p.pos], nil
+	}
+
+	var b strings.Builder
+	for p.pos++; !p.eof(); p.pos++ {
+		switch c := p.peek(); {
+		case c == '\\' && p.pos+1 < len(p.s):
+			p.pos++
+			b.WriteByte(p.s[p.pos])
+		case c == '"':
+			p.pos++
+			return b.String(), nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", errorf("Error: unterminated quoted value in query")
+}
+
+func isKeyChar(c byte) bool {
+	return c == '_' || c == '.' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
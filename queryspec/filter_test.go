@@ -0,0 +1,166 @@
+package queryspec
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testCity struct {
+	Id   int    `orm:"column(id);auto"`
+	Name string `orm:"column(name);size(40)"`
+}
+
+type testGuest struct {
+	Id     int       `orm:"column(id);auto"`
+	Email  string    `orm:"column(email);size(40)"`
+	CityId *testCity `orm:"column(city_id);rel(fk)"`
+}
+
+type testBooking struct {
+	Id         int        `orm:"column(id);auto"`
+	Status     uint8      `orm:"column(status)"`
+	TotalPrice int64      `orm:"column(total_price)"`
+	Code       string     `orm:"column(code);size(40)"`
+	GuestId    *testGuest `orm:"column(guest_id);rel(fk)"`
+	CityId     *testCity  `orm:"column(city_id);rel(fk);null"`
+}
+
+const (
+	testRoleGuest int8 = iota
+	testRoleStaff
+)
+
+var (
+	testCitySchema    = NewSchema(new(testCity))
+	testGuestSchema   = NewSchema(new(testGuest))
+	testBookingSchema = NewSchema(new(testBooking)).
+				Restrict("GuestId", testRoleStaff).
+				Restrict("Code", testRoleStaff)
+)
+
+func TestParseFilter(t *testing.T) {
+	cases := []struct {
+		query string
+		want  Node
+	}{
+		{"", nil},
+		{"status:1", &Cond{Path: []string{"status"}, Values: []string{"1"}}},
+		{"status__exact:1", &Cond{Path: []string{"status"}, Values: []string{"1"}}},
+		{"total_price__gte:100", &Cond{Path: []string{"total_price"}, Op: "gte", Values: []string{"100"}}},
+		{"cityId.name__icontains:chi", &Cond{Path: []string{"cityId", "name"}, Op: "icontains", Values: []string{"chi"}}},
+		{"city_id__name:Hue", &Cond{Path: []string{"city_id", "name"}, Values: []string{"Hue"}}},
+		{"status__in:1|2|3", &Cond{Path: []string{"status"}, Op: "in", Values: []string{"1", "2", "3"}}},
+		{"total_price__between:1|9", &Cond{Path: []string{"total_price"}, Op: "between", Values: []string{"1", "9"}}},
+		{`code:"a,b;(c)|d"`, &Cond{Path: []string{"code"}, Values: []string{"a,b;(c)|d"}}},
+		{`code:"say \"hi\""`, &Cond{Path: []string{"code"}, Values: []string{`say "hi"`}}},
+		{"status:1,code:x", &And{Nodes: []Node{
+			&Cond{Path: []string{"status"}, Values: []string{"1"}},
+			&Cond{Path: []string{"code"}, Values: []string{"x"}},
+		}}},
+		{"status:1;status:2", &Or{Nodes: []Node{
+			&Cond{Path: []string{"status"}, Values: []string{"1"}},
+			&Cond{Path: []string{"status"}, Values: []string{"2"}},
+		}}},
+		{"!status:1", &Not{Node: &Cond{Path: []string{"status"}, Values: []string{"1"}}}},
+		{"status:1,(code:x;!total_price__lt:100)", &And{Nodes: []Node{
+			&Cond{Path: []string{"status"}, Values: []string{"1"}},
+			&Or{Nodes: []Node{
+				&Cond{Path: []string{"code"}, Values: []string{"x"}},
+				&Not{Node: &Cond{Path: []string{"total_price"}, Op: "lt", Values: []string{"100"}}},
+			}},
+		}}},
+	}
+	for _, c := range cases {
+		got, err := ParseFilter(c.query)
+		if err != nil {
+			t.Errorf("ParseFilter(%q) error: %v", c.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("ParseFilter(%q) = %#v, want %#v", c.query, got, c.want)
+		}
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	cases := []string{
+		"status",
+		":1",
+		"status:1,",
+		"status:1)",
+		"(status:1",
+		"status..name:1",
+		"status:1|2",
+		"total_price__between:1",
+		"total_price__between:1|2|3",
+		`code:"open`,
+		"status-1:1",
+	}
+	for _, query := range cases {
+		if _, err := ParseFilter(query); !IsError(err) {
+			t.Errorf("ParseFilter(%q) error = %v, want a queryspec error", query, err)
+		}
+	}
+}
+
+func TestCondValidate(t *testing.T) {
+	cases := []struct {
+		query string
+		role  int8
+		// want is the validated path of the condition, nil when the query
+		// is rejected
+		want []string
+	}{
+		{"status:1", testRoleGuest, []string{"Status"}},
+		{"total_price__gte:100", testRoleGuest, []string{"TotalPrice"}},
+		{"cityId.name:Hue", testRoleGuest, []string{"CityId", "Name"}},
+		{"city_id__name:Hue", testRoleGuest, []string{"CityId", "Name"}},
+		{"guestId:7", testRoleGuest, []string{"GuestId"}},
+		{"guestId.email__startswith:a", testRoleGuest, nil},
+		{"guestId.email__startswith:a", testRoleStaff, []string{"GuestId", "Email"}},
+		{"guestId.cityId.name:Hue", testRoleGuest, nil},
+		{"guestId.cityId.name:Hue", testRoleStaff, []string{"GuestId", "CityId", "Name"}},
+		{"guestId.cityId:3", testRoleStaff, []string{"GuestId", "CityId", "Id"}},
+		{"guestId.cityId__in:3|4", testRoleStaff, []string{"GuestId", "CityId", "Id"}},
+		{"code:SUMMER", testRoleGuest, nil},
+		{"code:SUMMER", testRoleStaff, []string{"Code"}},
+		{"unknown:1", testRoleStaff, nil},
+		{"status.name:1", testRoleStaff, nil},
+		{"cityId.unknown:1", testRoleStaff, nil},
+	}
+	for _, c := range cases {
+		n, err := ParseFilter(c.query)
+		if err != nil {
+			t.Fatalf("ParseFilter(%q) error: %v", c.query, err)
+		}
+		err = n.validate(testBookingSchema, c.role)
+		switch {
+		case c.want == nil && !IsError(err):
+			t.Errorf("validate(%q) as role %d error = %v, want a queryspec error", c.query, c.role, err)
+		case c.want != nil && err != nil:
+			t.Errorf("validate(%q) as role %d error: %v", c.query, c.role, err)
+		case c.want != nil && !reflect.DeepEqual(n.(*Cond).Path, c.want):
+			t.Errorf("validate(%q) path = %v, want %v", c.query, n.(*Cond).Path, c.want)
+		}
+	}
+}
+
+func TestValidateNested(t *testing.T) {
+	cases := []struct {
+		query string
+		ok    bool
+	}{
+		{"status:1,(cityId.name:Hue;!total_price__lt:100)", true},
+		{"status:1,(cityId.name:Hue;!code:x)", false},
+		{"!(status:1;guestId.email:a)", false},
+	}
+	for _, c := range cases {
+		n, err := ParseFilter(c.query)
+		if err != nil {
+			t.Fatalf("ParseFilter(%q) error: %v", c.query, err)
+		}
+		if err = n.validate(testBookingSchema, testRoleGuest); (err == nil) != c.ok {
+			t.Errorf("validate(%q) error = %v, want ok %v", c.query, err, c.ok)
+		}
+	}
+}
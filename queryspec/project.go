@@ -0,0 +1,92 @@
+package queryspec
+
+import (
+	"reflect"
+)
+
+// Project trims v, a model or a pointer to a model, to the fields of s in a
+// map keyed by JSON names. Nested fields are projected from the relations
+// of v which must have been expanded. Fields the caller can't read are left
+// out. v is returned as is when s has no fields and the caller can read
+// all of them.
+func (s *Spec) Project(schema *Schema, v interface{}) interface{} {
+	val := reflect.Indirect(reflect.ValueOf(v))
+	if len(s.Fields) == 0 {
+		if !s.hides(schema, MaxExpandDepth) {
+			return v
+		}
+		return s.whole(schema, val)
+	}
+	return s.project(schema, val, s.Fields)
+}
+
+func (s *Spec) project(schema *Schema, val reflect.Value, paths [][]string) map[string]interface{} {
+	m := make(map[string]interface{})
+
+	var rels []*Field
+	nested := make(map[*Field][][]string)
+	for _, path := range paths {
+		f, _ := schema.Lookup(path[0])
+		if len(path) == 1 {
+			m[f.JSON] = s.value(schema, f, val.FieldByName(f.Name))
+			continue
+		}
+		if _, ok := nested[f]; !ok {
+			rels = append(rels, f)
+		}
+		nested[f] = append(nested[f], path[1:])
+	}
+
+	for _, f := range rels {
+		if _, ok := m[f.JSON]; ok {
+			// the whole relation is returned already
+			continue
+		}
+		rel := val.FieldByName(f.Name)
+		if rel.IsNil() {
+			m[f.JSON] = nil
+			continue
+		}
+		rs, _ := schema.Related(f)
+		m[f.JSON] = s.project(rs, rel.Elem(), nested[f])
+	}
+	return m
+}
+
+// whole returns all fields of val which the caller can read.
+func (s *Spec) whole(schema *Schema, val reflect.Value) map[string]interface{} {
+	m := make(map[string]interface{})
+	for _, f := range schema.Fields() {
+		if f.readable(s.Role) {
+			m[f.JSON] = s.value(schema, f, val.FieldByName(f.Name))
+		}
+	}
+	return m
+}
+
+// value returns the field f of a model, an expanded relation is trimmed to
+// the fields the caller can read.
+func (s *Spec) value(schema *Schema, f *Field, val reflect.Value) interface{} {
+	if f.Rel && !val.IsNil() {
+		if rs, ok := schema.Related(f); ok && s.hides(rs, MaxExpandDepth) {
+			return s.whole(rs, val.Elem())
+		}
+	}
+	return val.Interface()
+}
+
+// hides reports whether schema, or any schema within depth relations of
+// it, has fields the caller can't read.
+func (s *Spec) hides(schema *Schema, depth int) bool {
+	for _, f := range schema.Fields() {
+		if !f.readable(s.Role) {
+			return true
+		}
+		if f.Rel && depth > 0 {
+			if rs, ok := schema.Related(f); ok && s.hides(rs, depth-1) {
+				return true
+			}
+		}
+	}
+	return false
+}
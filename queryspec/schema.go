@@ -0,0 +1,173 @@
+package queryspec
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var columnTag = regexp.MustCompile(`column\(([^)]+)\)`)
+
+var (
+	schemasMu sync.RWMutex
+	schemas   = map[reflect.Type]*Schema{}
+)
+
+// Field describes a model field that clients are allowed to use in
+// fields, sortby and query parameters.
+type Field struct {
+	// Name is the Go field name, e.g. "CityId".
+	Name string
+	// Column is the database column name, e.g. "city_id".
+	Column string
+	// JSON is the key of the field in JSON output, e.g. "CityId".
+	JSON string
+	// Rel reports whether the field is a rel(fk) or rel(one) relation.
+	Rel bool
+	// MinRole is the lowest caller role allowed to read, filter and sort
+	// by the field or, for a relation, to expand it.
+	MinRole int8
+
+	typ reflect.Type
+}
+
+// Schema is the whitelist of fields of a model. Fields are looked up by Go
+// field name, by the same name starting in lower case, by JSON name or by
+// column name.
+type Schema struct {
+	typ    reflect.Type
+	fields []*Field
+	byName map[string]*Field
+}
+
+// NewSchema builds the Schema of model and registers it so that relations
+// of other models can resolve it. Fields tagged orm:"-" and fields listed in
+// omit are left out of the whitelist.
+func NewSchema(model interface{}, omit ...string) *Schema {
+	typ := indirectType(reflect.TypeOf(model))
+	s := &Schema{
+		typ:    typ,
+		byName: make(map[string]*Field),
+	}
+
+	omitted := make(map[string]bool, len(omit))
+	for _, name := range omit {
+		omitted[name] = true
+	}
+
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		tag := sf.Tag.Get("orm")
+		if sf.PkgPath != "" || tag == "-" || omitted[sf.Name] {
+			continue
+		}
+
+		f := &Field{
+			Name:   sf.Name,
+			Column: snakeString(sf.Name),
+			JSON:   sf.Name,
+			Rel:    strings.Contains(tag, "rel(fk)") || strings.Contains(tag, "rel(one)"),
+			typ:    indirectType(sf.Type),
+		}
+		if m := columnTag.FindStringSubmatch(tag); m != nil {
+			f.Column = m[1]
+		}
+		if name := strings.Split(sf.Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+			f.JSON = name
+		}
+
+		s.fields = append(s.fields, f)
+		s.byName[f.Name] = f
+		s.byName[strings.ToLower(f.Name[:1])+f.Name[1:]] = f
+		s.byName[f.JSON] = f
+		s.byName[f.Column] = f
+	}
+
+	schemasMu.Lock()
+	schemas[typ] = s
+	schemasMu.Unlock()
+
+	return s
+}
+
+// SchemaOf returns the registered Schema of model.
+func SchemaOf(model interface{}) (*Schema, bool) {
+	schemasMu.RLock()
+	defer schemasMu.RUnlock()
+	s, ok := schemas[indirectType(reflect.TypeOf(model))]
+	return s, ok
+}
+
+// Restrict allows only callers with role or higher to read, filter and
+// sort by field or, when field is a relation, to expand it.
+func (s *Schema) Restrict(field string, role int8) *Schema {
+	s.byName[field].MinRole = role
+	return s
+}
+
+// Type returns the struct type of the model.
+func (s *Schema) Type() reflect.Type {
+	return s.typ
+}
+
+// Lookup returns the whitelisted field by name.
+func (s *Schema) Lookup(name string) (*Field, bool) {
+	f, ok := s.byName[name]
+	return f, ok
+}
+
+// Fields returns all whitelisted fields in declaration order.
+func (s *Schema) Fields() []*Field {
+	return s.fields
+}
+
+// Names returns the Go names of all whitelisted fields in declaration order.
+func (s *Schema) Names() []string {
+	names := make([]string, 0, len(s.fields))
+	for _, f := range s.fields {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+// readable reports whether a caller with role can read f. The id of a
+// relation is readable by everyone, MinRole only restricts its expansion.
+func (f *Field) readable(role int8) bool {
+	return f.Rel || role >= f.MinRole
+}
+
+// Related returns the Schema of the model that the relation field points to.
+func (s *Schema) Related(f *Field) (*Schema, bool) {
+	if !f.Rel {
+		return nil, false
+	}
+
+	schemasMu.RLock()
+	defer schemasMu.RUnlock()
+	rs, ok := schemas[f.typ]
+	return rs, ok
+}
+
+func indirectType(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ
+}
+
+// snakeString converts a Go field name to the column name that beego orm
+// generates when no column tag is given, e.g. "CreatedAt" to "created_at".
+func snakeString(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if r >= 'A' && r <= 'Z' {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
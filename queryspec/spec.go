@@ -0,0 +1,304 @@
+// Package queryspec parses and validates the list parameters shared by all
+// GetAll endpoints: fields, sortby, order, limit, offset and query.
+package queryspec
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// DefaultLimit is the size of result set when limit is not given.
+const DefaultLimit int64 = 10
+
+// MaxExpandDepth is the maximum number of relations in an expand path.
+const MaxExpandDepth = 3
+
+// Order directions accepted by the order parameter.
+const (
+	Asc  = "asc"
+	Desc = "desc"
+)
+
+// Error is returned when list parameters are malformed or refer to fields
+// which are not whitelisted.
+type Error struct {
+	msg string
+}
+
+func (e *Error) Error() string {
+	return e.msg
+}
+
+func errorf(format string, args ...interface{}) error {
+	return &Error{msg: fmt.Sprintf(format, args...)}
+}
+
+// IsError reports whether err is caused by invalid list parameters.
+func IsError(err error) bool {
+	_, ok := err.(*Error)
+	return ok
+}
+
+// Sort is an order by clause.
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// Expr returns the order by expression in beego orm syntax, e.g. "-name".
+func (s Sort) Expr() string {
+	if s.Desc {
+		return "-" + s.Field
+	}
+	return s.Field
+}
+
+// Spec holds the list parameters of a request.
+type Spec struct {
+	Query Node
+	// Fields holds the paths of fields to return, the leading names of a
+	// path are relations which are expanded implicitly.
+	Fields [][]string
+	Sorts  []Sort
+	Limit  int64
+	Offset int64
+	// Cursor is set when the client opts in to keyset paging, it is
+	// zero on the first page.
+	Cursor *Cursor
+	// Expand holds the relation paths to eager load.
+	Expand [][]string
+	// Role is the role of the caller, it restricts the relations that can
+	// be expanded.
+	Role int8
+}
+
+// Parse reads list parameters from values:
+//
+//	fields: col1,col2,entity.col3 (Go, JSON or column names)
+//	sortby: col1,col2
+//	order: desc,asc (a single value applies to all sortby fields)
+//	limit: 10 (default is 10)
+//	offset: 0 (default is 0)
+//	query: k:v,k__op:v1|v2 (see ParseFilter)
+//	cursor: opaque token of the next page, an empty value starts keyset
+//	paging ordered by (created_at, id) which can't be combined with
+//	offset, sortby or limit 0; a single order value sets its direction.
+//	expand: rel1,rel2.rel3
+func Parse(values url.Values) (*Spec, error) {
+	s := &Spec{
+		Limit: DefaultLimit,
+	}
+
+	if v := values.Get("fields"); v != "" {
+		for _, path := range strings.Split(v, ",") {
+			names := strings.Split(path, ".")
+			if len(names) > MaxExpandDepth+1 {
+				return nil, errorf("Error: field %q is deeper than %d", path, MaxExpandDepth)
+			}
+			s.Fields = append(s.Fields, names)
+		}
+	}
+	if v := values.Get("expand"); v != "" {
+		for _, path := range strings.Split(v, ",") {
+			names := strings.Split(path, ".")
+			if len(names) > MaxExpandDepth {
+				return nil, errorf("Error: expand %q is deeper than %d", path, MaxExpandDepth)
+			}
+			s.Expand = append(s.Expand, names)
+		}
+	}
+	if v := values.Get("limit"); v != "" {
+		limit, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || limit < 0 {
+			return nil, errorf("Error: invalid limit %q", v)
+		}
+		s.Limit = limit
+	}
+	if v := values.Get("offset"); v != "" {
+		offset, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || offset < 0 {
+			return nil, errorf("Error: invalid offset %q", v)
+		}
+		s.Offset = offset
+	}
+
+	var sortby, order []string
+	if v := values.Get("sortby"); v != "" {
+		sortby = strings.Split(v, ",")
+	}
+	if v := values.Get("order"); v != "" {
+		order = strings.Split(v, ",")
+	}
+	if _, ok := values["cursor"]; ok {
+		if values.Get("offset") != "" || len(sortby) != 0 {
+			return nil, errorf("Error: 'cursor' can't be used with 'offset' or 'sortby'")
+		}
+		// a page of no record has no last record to carry on from
+		if s.Limit == 0 {
+			return nil, errorf("Error: 'cursor' needs a limit above 0")
+		}
+		cursor, err := ParseCursor(values.Get("cursor"))
+		if err != nil {
+			return nil, err
+		}
+		if len(order) > 1 || len(order) == 1 && order[0] != Asc && order[0] != Desc {
+			return nil, errorf("Error: Invalid order. Must be either [asc|desc]")
+		}
+		cursor.Desc = len(order) == 1 && order[0] == Desc
+		s.Cursor = cursor
+		order = nil
+	}
+	sorts, err := parseSorts(sortby, order)
+	if err != nil {
+		return nil, err
+	}
+	s.Sorts = sorts
+
+	query, err := ParseFilter(values.Get("query"))
+	if err != nil {
+		return nil, err
+	}
+	s.Query = query
+
+	return s, nil
+}
+
+func parseSorts(sortby []string, order []string) ([]Sort, error) {
+	if len(sortby) == 0 {
+		if len(order) != 0 {
+			return nil, errorf("Error: unused 'order' fields")
+		}
+		return nil, nil
+	}
+	if len(order) != len(sortby) && len(order) != 1 {
+		return nil, errorf("Error: 'sortby', 'order' sizes mismatch or 'order' size is not 1")
+	}
+
+	sorts := make([]Sort, 0, len(sortby))
+	for i, field := range sortby {
+		// there is either an order for each sort field or exactly one order
+		// for all of them
+		o := order[0]
+		if len(order) == len(sortby) {
+			o = order[i]
+		}
+		if o != Asc && o != Desc {
+			return nil, errorf("Error: Invalid order. Must be either [asc|desc]")
+		}
+		sorts = append(sorts, Sort{Field: field, Desc: o == Desc})
+	}
+	return sorts, nil
+}
+
+// Validate checks fields, sort keys, expand paths and query fields of s
+// against schema and rewrites them to their Go field names. Relations of
+// nested fields are added to the expand paths. Cursor paging requires the model
+// to have a CreatedAt field.
+func (s *Spec) Validate(schema *Schema) error {
+	for _, path := range s.Fields {
+		if err := s.validateField(schema, path); err != nil {
+			return err
+		}
+	}
+	for i, sort := range s.Sorts {
+		f, ok := schema.Lookup(sort.Field)
+		if !ok || !f.readable(s.Role) {
+			return errorf("Error: unknown sortby field %q", sort.Field)
+		}
+		s.Sorts[i].Field = f.Name
+	}
+	if s.Cursor != nil {
+		if _, ok := schema.Lookup("CreatedAt"); !ok {
+			return errorf("Error: cursor paging is not supported")
+		}
+	}
+	for _, path := range s.Expand {
+		if err := s.validateExpand(schema, path); err != nil {
+			return err
+		}
+	}
+	if s.Query != nil {
+		return s.Query.validate(schema, s.Role)
+	}
+	return nil
+}
+
+// validateField resolves path through schema and its relations, rewrites
+// it to Go field names and adds the relations of path to s.Expand.
+func (s *Spec) validateField(schema *Schema, path []string) error {
+	key := strings.Join(path, ".")
+	for i, name := range path {
+		f, ok := schema.Lookup(name)
+		if !ok {
+			return errorf("Error: unknown field %q", key)
+		}
+		path[i] = f.Name
+		if i == len(path)-1 {
+			if !f.readable(s.Role) {
+				return errorf("Error: field %q is not allowed", key)
+			}
+			break
+		}
+		if s.Role < f.MinRole {
+			return errorf("Error: field %q is not allowed", key)
+		}
+		if schema, ok = schema.Related(f); !ok {
+			return errorf("Error: unknown relation %q in field %q", name, key)
+		}
+	}
+
+	if rels := path[:len(path)-1]; len(rels) != 0 {
+		for _, expand := range s.Expand {
+			if strings.Join(expand, ".") == strings.Join(rels, ".") {
+				return nil
+			}
+		}
+		s.Expand = append(s.Expand, append([]string(nil), rels...))
+	}
+	return nil
+}
+
+// Columns returns the Go names of the top level fields to read from the
+// database, it is empty when all fields are returned.
+func (s *Spec) Columns() []string {
+	var cols []string
+	seen := make(map[string]bool)
+	for _, path := range s.Fields {
+		if !seen[path[0]] {
+			seen[path[0]] = true
+			cols = append(cols, path[0])
+		}
+	}
+	return cols
+}
+
+// validateExpand checks that each name of path is a relation the caller is
+// allowed to expand and rewrites path to Go field names.
+func (s *Spec) validateExpand(schema *Schema, path []string) error {
+	key := strings.Join(path, ".")
+	for i, name := range path {
+		f, ok := schema.Lookup(name)
+		if !ok || !f.Rel {
+			return errorf("Error: unknown relation %q in expand %q", name, key)
+		}
+		if s.Role < f.MinRole {
+			return errorf("Error: expand %q is not allowed", key)
+		}
+		if schema, ok = schema.Related(f); !ok {
+			return errorf("Error: unknown relation %q in expand %q", name, key)
+		}
+		path[i] = f.Name
+	}
+	return nil
+}
+
+// OrderBy returns the order by expressions of s in beego orm syntax.
+func (s *Spec) OrderBy() []string {
+	exprs := make([]string, 0, len(s.Sorts))
+	for _, sort := range s.Sorts {
+		exprs = append(exprs, sort.Expr())
+	}
+	return exprs
+}
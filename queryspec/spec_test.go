@@ -0,0 +1,31 @@
+package queryspec
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestParseCursor(t *testing.T) {
+	s, err := Parse(url.Values{"cursor": {""}, "limit": {"5"}, "order": {"desc"}})
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if s.Cursor == nil || !s.Cursor.IsZero() || !s.Cursor.Desc || s.Limit != 5 {
+		t.Errorf("Parse = %+v, want the first descending page of 5", s)
+	}
+
+	cases := []url.Values{
+		{"cursor": {""}, "limit": {"0"}},
+		{"cursor": {""}, "offset": {"10"}},
+		{"cursor": {""}, "sortby": {"id"}},
+		{"cursor": {""}, "order": {"asc,desc"}},
+	}
+	for _, values := range cases {
+		if _, err := Parse(values); err == nil || !IsError(err) {
+			t.Errorf("Parse(%v) error = %v, want a queryspec error", values, err)
+		}
+	}
+	if s, err = Parse(url.Values{"limit": {"0"}}); err != nil || s.Limit != 0 {
+		t.Errorf("Parse of limit 0 without cursor = %+v, %v, want limit 0", s, err)
+	}
+}
@@ -2,21 +2,27 @@ package reqres
 
 import (
 	"easybook/models"
+	"easybook/services/pricing"
 	"easybook/types"
 )
 
 // BookingReserveRoomsRequest is a struct for reserving rooms.
 type BookingReserveRoomsRequest struct {
-	GuestID         int        `json:"guestId" validate:"required"`
-	StartDate       types.Date `json:"startDate" validate:"required"`
-	EndDate         types.Date `json:"endDate" validate:"required"`
-	DiscountPercent float32    `json:"discountPercent,omitempty"`
-	TotalPrice      float32    `json:"totalPrice" validate:"required"`
-	Rooms           []int      `json:"rooms" validate:"required"`
+	GuestID         int         `json:"guestId" validate:"required"`
+	StartDate       types.Date  `json:"startDate" validate:"required"`
+	EndDate         types.Date  `json:"endDate" validate:"required"`
+	DiscountPercent float32     `json:"discountPercent,omitempty"`
+	Guests          uint8       `json:"guests,omitempty"`
+	TotalPrice      types.Money `json:"totalPrice" validate:"required"`
+	Rooms           []int       `json:"rooms" validate:"required"`
+	PromoCodes      []string    `json:"promoCodes,omitempty"`
+	// HoldID is the hold of the rooms converted into the reservation.
+	HoldID int `json:"holdId,omitempty"`
 }
 
 // BookingReserveRoomsResponse is a struct for reserving rooms.
 type BookingReserveRoomsResponse struct {
 	CommonResponse
 	Reservation *models.Reservation `json:"reservation,omitempty"`
+	Breakdown   *pricing.Breakdown  `json:"breakdown,omitempty"`
 }
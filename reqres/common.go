@@ -10,6 +10,8 @@ const (
 	FailedDelete
 	RecordNotExist
 	SystemError
+	Unauthorized
+	Forbidden
 )
 
 var code2text = map[int]string{
@@ -21,6 +23,8 @@ var code2text = map[int]string{
 	FailedDelete:   "delete record failed",
 	RecordNotExist: "record doesn't exist",
 	SystemError:    "system error",
+	Unauthorized:   "unauthorized",
+	Forbidden:      "forbidden",
 }
 
 // CommonResponse define
@@ -0,0 +1,21 @@
+package reqres
+
+import (
+	"easybook/types"
+)
+
+// ExchangeRatePostRequest is a struct for setting the rate of a currency
+// pair, the value of one Base in Currency from Date on.
+type ExchangeRatePostRequest struct {
+	Base     string     `json:"base" validate:"required"`
+	Currency string     `json:"currency" validate:"required"`
+	Rate     float64    `json:"rate" validate:"required"`
+	Date     types.Date `json:"date" validate:"required"`
+}
+
+// ExchangeRateImportResponse is a struct for return the number of rates
+// imported.
+type ExchangeRateImportResponse struct {
+	CommonResponse
+	Imported int `json:"imported"`
+}
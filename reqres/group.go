@@ -0,0 +1,36 @@
+package reqres
+
+import (
+	"easybook/models"
+	"easybook/types"
+)
+
+// GroupPostRequest is a struct for reserving a block of rooms for a group.
+// OrganizerID is for staff booking for an organizer, guests organize
+// themselves. ReleaseDate defaults to StartDate.
+type GroupPostRequest struct {
+	OrganizerID   int        `json:"organizerId,omitempty"`
+	HotelID       int        `json:"hotelId" validate:"required"`
+	Name          string     `json:"name"`
+	StartDate     types.Date `json:"startDate" validate:"required"`
+	EndDate       types.Date `json:"endDate" validate:"required"`
+	ReleaseDate   types.Date `json:"releaseDate,omitempty"`
+	Rooms         []int      `json:"rooms" validate:"required"`
+	MasterInvoice bool       `json:"masterInvoice,omitempty"`
+}
+
+// GroupAssignRequest is a struct for giving a room of a block to a guest.
+type GroupAssignRequest struct {
+	GuestID int   `json:"guestId" validate:"required"`
+	Guests  uint8 `json:"guests,omitempty"`
+}
+
+// GroupResponse is a struct for return a group with the rooms of its block
+// not given yet and the reservations of its guests.
+type GroupResponse struct {
+	CommonResponse
+	Group       *models.GroupBooking  `json:"group,omitempty"`
+	Rooms       []models.RoomReserved `json:"rooms,omitempty"`
+	Members     []models.Reservation  `json:"members,omitempty"`
+	Reservation *models.Reservation   `json:"reservation,omitempty"`
+}
@@ -13,7 +13,8 @@ type GuestPostRequest struct {
 	Phone             string `json:"phone,omitempty"`
 	Address           string `json:"address,omitempty"`
 	Detail            string `json:"detail,omitempty"`
-	Role              int8   `json:"role" validate:"required"`
+	Language          string `json:"language,omitempty"`
+	Role              int8   `json:"role,omitempty"`
 	Password          string `json:"password,omitempty"`
 	ConfirmedPassword string `json:"confirmedPassword,omitempty"`
 }
@@ -23,3 +24,16 @@ type GuestPostResponse struct {
 	CommonResponse
 	Guest *models.Guest `json:"guest,omitempty"`
 }
+
+// GuestLoginRequest is a struct for logging in guest.
+type GuestLoginRequest struct {
+	Email    string `json:"email" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// GuestLoginResponse is a struct for return token of logged in guest.
+type GuestLoginResponse struct {
+	CommonResponse
+	Token string        `json:"token,omitempty"`
+	Guest *models.Guest `json:"guest,omitempty"`
+}
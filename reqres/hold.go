@@ -0,0 +1,21 @@
+package reqres
+
+import (
+	"easybook/models"
+	"easybook/types"
+)
+
+// HoldPostRequest is a struct for holding rooms for a stay. GuestID is
+// for staff holding rooms for a guest, guests hold for themselves.
+type HoldPostRequest struct {
+	GuestID   int        `json:"guestId,omitempty"`
+	StartDate types.Date `json:"startDate" validate:"required"`
+	EndDate   types.Date `json:"endDate" validate:"required"`
+	Rooms     []int      `json:"rooms" validate:"required"`
+}
+
+// HoldResponse is a struct for return a hold with its rooms.
+type HoldResponse struct {
+	CommonResponse
+	Hold *models.Hold `json:"hold,omitempty"`
+}
@@ -0,0 +1,17 @@
+package reqres
+
+import (
+	"easybook/models"
+)
+
+// InvoicePostRequest is a struct for issuing the invoice of a reservation.
+type InvoicePostRequest struct {
+	ReservationID int `json:"reservationId" validate:"required"`
+}
+
+// InvoiceResponse is a struct for return an invoice with its lines.
+type InvoiceResponse struct {
+	CommonResponse
+	Invoice *models.Invoice      `json:"invoice,omitempty"`
+	Lines   []models.InvoiceLine `json:"lines,omitempty"`
+}
@@ -0,0 +1,44 @@
+package reqres
+
+import (
+	"net/url"
+	"strconv"
+
+	"easybook/models"
+	"easybook/queryspec"
+)
+
+// ListResponse is a page of records returned by GetAll endpoints.
+type ListResponse struct {
+	Items []interface{} `json:"items"`
+	// Total is omitted in cursor paging.
+	Total  *int64 `json:"total,omitempty"`
+	Limit  int64  `json:"limit"`
+	Offset int64  `json:"offset"`
+	// Next is the link of the next page, it is empty on the last page.
+	Next string `json:"next,omitempty"`
+}
+
+// NewListResponse creates a ListResponse of page which is retrieved by the
+// request to u with list parameters spec.
+func NewListResponse(u *url.URL, spec *queryspec.Spec, page *models.Page) *ListResponse {
+	res := &ListResponse{
+		Items:  page.Items,
+		Total:  page.Total,
+		Limit:  spec.Limit,
+		Offset: spec.Offset,
+	}
+
+	q := u.Query()
+	switch {
+	case spec.Cursor != nil && page.NextCursor != nil:
+		q.Set("cursor", page.NextCursor.String())
+	case spec.Cursor == nil && page.Total != nil && spec.Offset+spec.Limit < *page.Total:
+		q.Set("offset", strconv.FormatInt(spec.Offset+spec.Limit, 10))
+	default:
+		return res
+	}
+	next := url.URL{Path: u.Path, RawQuery: q.Encode()}
+	res.Next = next.String()
+	return res
+}
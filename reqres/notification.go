@@ -0,0 +1,18 @@
+package reqres
+
+// NotificationTemplatePreviewResponse is a struct for return a rendered
+// notification template.
+type NotificationTemplatePreviewResponse struct {
+	CommonResponse
+	Subject string `json:"subject,omitempty"`
+	Body    string `json:"body,omitempty"`
+	Format  string `json:"format,omitempty"`
+}
+
+// InboxListResponse is a page of the in-app notifications of the caller.
+type InboxListResponse struct {
+	*ListResponse
+	// Unread is the number of unread notifications in the inbox, archived
+	// ones are not counted.
+	Unread int64 `json:"unread"`
+}
@@ -0,0 +1,27 @@
+package reqres
+
+import (
+	"easybook/models"
+	"easybook/services/overbooking"
+)
+
+// OverbookingReportResponse is a struct for return the overbooked nights
+// of a hotel.
+type OverbookingReportResponse struct {
+	CommonResponse
+	Nights []overbooking.Night `json:"nights"`
+}
+
+// RelocationPostRequest is a struct for moving a reserved room of an
+// overbooked night to a vacant room.
+type RelocationPostRequest struct {
+	RoomReservedID int    `json:"roomReservedId" validate:"required"`
+	RoomID         int    `json:"roomId" validate:"required"`
+	Reason         string `json:"reason"`
+}
+
+// RelocationResponse is a struct for return a relocation.
+type RelocationResponse struct {
+	CommonResponse
+	Relocation *models.Relocation `json:"relocation,omitempty"`
+}
@@ -0,0 +1,37 @@
+package reqres
+
+import (
+	"easybook/models"
+	"easybook/types"
+)
+
+// PaymentPostRequest is a struct for paying an invoice.
+type PaymentPostRequest struct {
+	InvoiceID int `json:"invoiceId" validate:"required"`
+	// Amount defaults to the invoice amount.
+	Amount types.Money `json:"amount,omitempty"`
+	// Token identifies the payment method at the provider.
+	Token string `json:"token" validate:"required"`
+	// Capture collects the amount right after authorizing it.
+	Capture bool `json:"capture,omitempty"`
+}
+
+// PaymentRefundRequest is a struct for refunding a payment.
+type PaymentRefundRequest struct {
+	// Amount defaults to the remaining captured amount.
+	Amount types.Money `json:"amount,omitempty"`
+	Reason string      `json:"reason,omitempty"`
+}
+
+// PaymentResponse is a struct for return a payment with its refunds.
+type PaymentResponse struct {
+	CommonResponse
+	Payment *models.Payment `json:"payment,omitempty"`
+	Refunds []models.Refund `json:"refunds,omitempty"`
+}
+
+// PaymentRefundResponse is a struct for return a refund.
+type PaymentRefundResponse struct {
+	CommonResponse
+	Refund *models.Refund `json:"refund,omitempty"`
+}
@@ -0,0 +1,42 @@
+package reqres
+
+import (
+	"easybook/models"
+	"easybook/services/pricing"
+	"easybook/types"
+)
+
+// RatePlanCalendarRequest is a struct for setting the rates of a room, or
+// of the rooms of a service level, over ranges of the calendar. Ranges set
+// later override the rates before them.
+type RatePlanCalendarRequest struct {
+	RoomID         int             `json:"roomId,omitempty"`
+	ServiceLevelID int             `json:"serviceLevelId,omitempty"`
+	Ranges         []RatePlanRange `json:"ranges" validate:"required"`
+}
+
+// RatePlanRange is a struct for the rate of the nights from From to To
+// included, only on Weekdays if given, 0 for Sunday to 6 for Saturday.
+type RatePlanRange struct {
+	Name     string      `json:"name,omitempty"`
+	From     types.Date  `json:"from" validate:"required"`
+	To       types.Date  `json:"to" validate:"required"`
+	Weekdays []int       `json:"weekdays,omitempty"`
+	Price    types.Money `json:"price" validate:"required"`
+	MinStay  uint8       `json:"minStay,omitempty"`
+	Priority int         `json:"priority,omitempty"`
+}
+
+// RatePlanCalendarResponse is a struct for return the plans created over
+// the calendar.
+type RatePlanCalendarResponse struct {
+	CommonResponse
+	Plans []*models.RatePlan `json:"plans"`
+}
+
+// RoomCalendarResponse is a struct for return the rate of a room for each
+// night.
+type RoomCalendarResponse struct {
+	CommonResponse
+	Rates []pricing.Rate `json:"rates"`
+}
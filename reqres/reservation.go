@@ -0,0 +1,25 @@
+package reqres
+
+import (
+	"easybook/models"
+	"easybook/services/pricing"
+	"easybook/types"
+)
+
+// ReservationModifyRequest is a struct for changing the dates or rooms of
+// a reservation. Dates and rooms left out are kept.
+type ReservationModifyRequest struct {
+	StartDate types.Date `json:"startDate,omitempty"`
+	EndDate   types.Date `json:"endDate,omitempty"`
+	Rooms     []int      `json:"rooms,omitempty"`
+	Reason    string     `json:"reason,omitempty"`
+}
+
+// ReservationModifyResponse is a struct for return a modified reservation
+// with the change recorded and its new price.
+type ReservationModifyResponse struct {
+	CommonResponse
+	Reservation *models.Reservation       `json:"reservation,omitempty"`
+	Change      *models.ReservationChange `json:"change,omitempty"`
+	Breakdown   *pricing.Breakdown        `json:"breakdown,omitempty"`
+}
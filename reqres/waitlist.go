@@ -0,0 +1,26 @@
+package reqres
+
+import (
+	"easybook/models"
+	"easybook/types"
+)
+
+// WaitlistPostRequest is a struct for waiting for rooms of a sold out
+// hotel. GuestID is for staff adding a guest, guests add themselves.
+type WaitlistPostRequest struct {
+	GuestID        int        `json:"guestId,omitempty"`
+	HotelID        int        `json:"hotelId" validate:"required"`
+	ServiceLevelID int        `json:"serviceLevelId,omitempty"`
+	StartDate      types.Date `json:"startDate" validate:"required"`
+	EndDate        types.Date `json:"endDate" validate:"required"`
+	Rooms          uint8      `json:"rooms,omitempty"`
+	Guests         uint8      `json:"guests,omitempty"`
+}
+
+// WaitlistResponse is a struct for return a waitlist entry with the
+// reservation offered, if any.
+type WaitlistResponse struct {
+	CommonResponse
+	Entry       *models.WaitlistEntry `json:"entry,omitempty"`
+	Reservation *models.Reservation   `json:"reservation,omitempty"`
+}
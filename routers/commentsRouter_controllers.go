@@ -25,6 +25,105 @@ func init() {
 			Filters:          nil,
 			Params:           nil})
 
+	beego.GlobalControllerRouter["easybook/controllers:ExchangeRateController"] = append(beego.GlobalControllerRouter["easybook/controllers:ExchangeRateController"],
+		beego.ControllerComments{
+			Method:           "Post",
+			Router:           `/`,
+			AllowHTTPMethods: []string{"post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:ExchangeRateController"] = append(beego.GlobalControllerRouter["easybook/controllers:ExchangeRateController"],
+		beego.ControllerComments{
+			Method:           "Import",
+			Router:           `/import`,
+			AllowHTTPMethods: []string{"post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:ExchangeRateController"] = append(beego.GlobalControllerRouter["easybook/controllers:ExchangeRateController"],
+		beego.ControllerComments{
+			Method:           "GetOne",
+			Router:           `/:id`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:ExchangeRateController"] = append(beego.GlobalControllerRouter["easybook/controllers:ExchangeRateController"],
+		beego.ControllerComments{
+			Method:           "GetAll",
+			Router:           `/`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:ExchangeRateController"] = append(beego.GlobalControllerRouter["easybook/controllers:ExchangeRateController"],
+		beego.ControllerComments{
+			Method:           "Delete",
+			Router:           `/:id`,
+			AllowHTTPMethods: []string{"delete"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:GroupController"] = append(beego.GlobalControllerRouter["easybook/controllers:GroupController"],
+		beego.ControllerComments{
+			Method:           "Post",
+			Router:           `/`,
+			AllowHTTPMethods: []string{"post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:GroupController"] = append(beego.GlobalControllerRouter["easybook/controllers:GroupController"],
+		beego.ControllerComments{
+			Method:           "GetOne",
+			Router:           `/:id`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:GroupController"] = append(beego.GlobalControllerRouter["easybook/controllers:GroupController"],
+		beego.ControllerComments{
+			Method:           "GetAll",
+			Router:           `/`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:GroupController"] = append(beego.GlobalControllerRouter["easybook/controllers:GroupController"],
+		beego.ControllerComments{
+			Method:           "Assign",
+			Router:           `/:id/rooms/:roomId/assign`,
+			AllowHTTPMethods: []string{"post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:GroupController"] = append(beego.GlobalControllerRouter["easybook/controllers:GroupController"],
+		beego.ControllerComments{
+			Method:           "Release",
+			Router:           `/:id/release`,
+			AllowHTTPMethods: []string{"post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:GroupController"] = append(beego.GlobalControllerRouter["easybook/controllers:GroupController"],
+		beego.ControllerComments{
+			Method:           "Invoice",
+			Router:           `/:id/invoice`,
+			AllowHTTPMethods: []string{"post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
 	beego.GlobalControllerRouter["easybook/controllers:GuestController"] = append(beego.GlobalControllerRouter["easybook/controllers:GuestController"],
 		beego.ControllerComments{
 			Method:           "Post",
@@ -70,6 +169,51 @@ func init() {
 			Filters:          nil,
 			Params:           nil})
 
+	beego.GlobalControllerRouter["easybook/controllers:GuestController"] = append(beego.GlobalControllerRouter["easybook/controllers:GuestController"],
+		beego.ControllerComments{
+			Method:           "Login",
+			Router:           `/login`,
+			AllowHTTPMethods: []string{"post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:HoldController"] = append(beego.GlobalControllerRouter["easybook/controllers:HoldController"],
+		beego.ControllerComments{
+			Method:           "Post",
+			Router:           `/`,
+			AllowHTTPMethods: []string{"post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:HoldController"] = append(beego.GlobalControllerRouter["easybook/controllers:HoldController"],
+		beego.ControllerComments{
+			Method:           "GetOne",
+			Router:           `/:id`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:HoldController"] = append(beego.GlobalControllerRouter["easybook/controllers:HoldController"],
+		beego.ControllerComments{
+			Method:           "GetAll",
+			Router:           `/`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:HoldController"] = append(beego.GlobalControllerRouter["easybook/controllers:HoldController"],
+		beego.ControllerComments{
+			Method:           "Delete",
+			Router:           `/:id`,
+			AllowHTTPMethods: []string{"delete"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
 	beego.GlobalControllerRouter["easybook/controllers:HotelController"] = append(beego.GlobalControllerRouter["easybook/controllers:HotelController"],
 		beego.ControllerComments{
 			Method:           "Post",
@@ -115,7 +259,61 @@ func init() {
 			Filters:          nil,
 			Params:           nil})
 
-	beego.GlobalControllerRouter["easybook/controllers:NotificationController"] = append(beego.GlobalControllerRouter["easybook/controllers:NotificationController"],
+	beego.GlobalControllerRouter["easybook/controllers:InboxController"] = append(beego.GlobalControllerRouter["easybook/controllers:InboxController"],
+		beego.ControllerComments{
+			Method:           "GetAll",
+			Router:           `/`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:InboxController"] = append(beego.GlobalControllerRouter["easybook/controllers:InboxController"],
+		beego.ControllerComments{
+			Method:           "Read",
+			Router:           `/:id/read`,
+			AllowHTTPMethods: []string{"put"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:InboxController"] = append(beego.GlobalControllerRouter["easybook/controllers:InboxController"],
+		beego.ControllerComments{
+			Method:           "Unread",
+			Router:           `/:id/unread`,
+			AllowHTTPMethods: []string{"put"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:InboxController"] = append(beego.GlobalControllerRouter["easybook/controllers:InboxController"],
+		beego.ControllerComments{
+			Method:           "Archive",
+			Router:           `/:id/archive`,
+			AllowHTTPMethods: []string{"put"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:InboxController"] = append(beego.GlobalControllerRouter["easybook/controllers:InboxController"],
+		beego.ControllerComments{
+			Method:           "Unarchive",
+			Router:           `/:id/unarchive`,
+			AllowHTTPMethods: []string{"put"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:InboxController"] = append(beego.GlobalControllerRouter["easybook/controllers:InboxController"],
+		beego.ControllerComments{
+			Method:           "Stream",
+			Router:           `/stream`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:InvoiceController"] = append(beego.GlobalControllerRouter["easybook/controllers:InvoiceController"],
 		beego.ControllerComments{
 			Method:           "Post",
 			Router:           `/`,
@@ -124,7 +322,7 @@ func init() {
 			Filters:          nil,
 			Params:           nil})
 
-	beego.GlobalControllerRouter["easybook/controllers:NotificationController"] = append(beego.GlobalControllerRouter["easybook/controllers:NotificationController"],
+	beego.GlobalControllerRouter["easybook/controllers:InvoiceController"] = append(beego.GlobalControllerRouter["easybook/controllers:InvoiceController"],
 		beego.ControllerComments{
 			Method:           "GetAll",
 			Router:           `/`,
@@ -133,7 +331,7 @@ func init() {
 			Filters:          nil,
 			Params:           nil})
 
-	beego.GlobalControllerRouter["easybook/controllers:NotificationController"] = append(beego.GlobalControllerRouter["easybook/controllers:NotificationController"],
+	beego.GlobalControllerRouter["easybook/controllers:InvoiceController"] = append(beego.GlobalControllerRouter["easybook/controllers:InvoiceController"],
 		beego.ControllerComments{
 			Method:           "GetOne",
 			Router:           `/:id`,
@@ -142,7 +340,43 @@ func init() {
 			Filters:          nil,
 			Params:           nil})
 
-	beego.GlobalControllerRouter["easybook/controllers:NotificationController"] = append(beego.GlobalControllerRouter["easybook/controllers:NotificationController"],
+	beego.GlobalControllerRouter["easybook/controllers:InvoiceController"] = append(beego.GlobalControllerRouter["easybook/controllers:InvoiceController"],
+		beego.ControllerComments{
+			Method:           "GetPDF",
+			Router:           `/:id/pdf`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:InvoiceTemplateController"] = append(beego.GlobalControllerRouter["easybook/controllers:InvoiceTemplateController"],
+		beego.ControllerComments{
+			Method:           "Post",
+			Router:           `/`,
+			AllowHTTPMethods: []string{"post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:InvoiceTemplateController"] = append(beego.GlobalControllerRouter["easybook/controllers:InvoiceTemplateController"],
+		beego.ControllerComments{
+			Method:           "GetAll",
+			Router:           `/`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:InvoiceTemplateController"] = append(beego.GlobalControllerRouter["easybook/controllers:InvoiceTemplateController"],
+		beego.ControllerComments{
+			Method:           "GetOne",
+			Router:           `/:id`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:InvoiceTemplateController"] = append(beego.GlobalControllerRouter["easybook/controllers:InvoiceTemplateController"],
 		beego.ControllerComments{
 			Method:           "Put",
 			Router:           `/:id`,
@@ -151,7 +385,7 @@ func init() {
 			Filters:          nil,
 			Params:           nil})
 
-	beego.GlobalControllerRouter["easybook/controllers:NotificationController"] = append(beego.GlobalControllerRouter["easybook/controllers:NotificationController"],
+	beego.GlobalControllerRouter["easybook/controllers:InvoiceTemplateController"] = append(beego.GlobalControllerRouter["easybook/controllers:InvoiceTemplateController"],
 		beego.ControllerComments{
 			Method:           "Delete",
 			Router:           `/:id`,
@@ -160,7 +394,7 @@ func init() {
 			Filters:          nil,
 			Params:           nil})
 
-	beego.GlobalControllerRouter["easybook/controllers:ReservationController"] = append(beego.GlobalControllerRouter["easybook/controllers:ReservationController"],
+	beego.GlobalControllerRouter["easybook/controllers:NoShowPolicyController"] = append(beego.GlobalControllerRouter["easybook/controllers:NoShowPolicyController"],
 		beego.ControllerComments{
 			Method:           "Post",
 			Router:           `/`,
@@ -169,7 +403,16 @@ func init() {
 			Filters:          nil,
 			Params:           nil})
 
-	beego.GlobalControllerRouter["easybook/controllers:ReservationController"] = append(beego.GlobalControllerRouter["easybook/controllers:ReservationController"],
+	beego.GlobalControllerRouter["easybook/controllers:NoShowPolicyController"] = append(beego.GlobalControllerRouter["easybook/controllers:NoShowPolicyController"],
+		beego.ControllerComments{
+			Method:           "GetOne",
+			Router:           `/:id`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:NoShowPolicyController"] = append(beego.GlobalControllerRouter["easybook/controllers:NoShowPolicyController"],
 		beego.ControllerComments{
 			Method:           "GetAll",
 			Router:           `/`,
@@ -178,7 +421,43 @@ func init() {
 			Filters:          nil,
 			Params:           nil})
 
-	beego.GlobalControllerRouter["easybook/controllers:ReservationController"] = append(beego.GlobalControllerRouter["easybook/controllers:ReservationController"],
+	beego.GlobalControllerRouter["easybook/controllers:NoShowPolicyController"] = append(beego.GlobalControllerRouter["easybook/controllers:NoShowPolicyController"],
+		beego.ControllerComments{
+			Method:           "Put",
+			Router:           `/:id`,
+			AllowHTTPMethods: []string{"put"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:NoShowPolicyController"] = append(beego.GlobalControllerRouter["easybook/controllers:NoShowPolicyController"],
+		beego.ControllerComments{
+			Method:           "Delete",
+			Router:           `/:id`,
+			AllowHTTPMethods: []string{"delete"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:NotificationController"] = append(beego.GlobalControllerRouter["easybook/controllers:NotificationController"],
+		beego.ControllerComments{
+			Method:           "Post",
+			Router:           `/`,
+			AllowHTTPMethods: []string{"post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:NotificationController"] = append(beego.GlobalControllerRouter["easybook/controllers:NotificationController"],
+		beego.ControllerComments{
+			Method:           "GetAll",
+			Router:           `/`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:NotificationController"] = append(beego.GlobalControllerRouter["easybook/controllers:NotificationController"],
 		beego.ControllerComments{
 			Method:           "GetOne",
 			Router:           `/:id`,
@@ -187,7 +466,7 @@ func init() {
 			Filters:          nil,
 			Params:           nil})
 
-	beego.GlobalControllerRouter["easybook/controllers:ReservationController"] = append(beego.GlobalControllerRouter["easybook/controllers:ReservationController"],
+	beego.GlobalControllerRouter["easybook/controllers:NotificationController"] = append(beego.GlobalControllerRouter["easybook/controllers:NotificationController"],
 		beego.ControllerComments{
 			Method:           "Put",
 			Router:           `/:id`,
@@ -196,7 +475,7 @@ func init() {
 			Filters:          nil,
 			Params:           nil})
 
-	beego.GlobalControllerRouter["easybook/controllers:ReservationController"] = append(beego.GlobalControllerRouter["easybook/controllers:ReservationController"],
+	beego.GlobalControllerRouter["easybook/controllers:NotificationController"] = append(beego.GlobalControllerRouter["easybook/controllers:NotificationController"],
 		beego.ControllerComments{
 			Method:           "Delete",
 			Router:           `/:id`,
@@ -205,7 +484,7 @@ func init() {
 			Filters:          nil,
 			Params:           nil})
 
-	beego.GlobalControllerRouter["easybook/controllers:RoomController"] = append(beego.GlobalControllerRouter["easybook/controllers:RoomController"],
+	beego.GlobalControllerRouter["easybook/controllers:NotificationRuleController"] = append(beego.GlobalControllerRouter["easybook/controllers:NotificationRuleController"],
 		beego.ControllerComments{
 			Method:           "Post",
 			Router:           `/`,
@@ -214,7 +493,7 @@ func init() {
 			Filters:          nil,
 			Params:           nil})
 
-	beego.GlobalControllerRouter["easybook/controllers:RoomController"] = append(beego.GlobalControllerRouter["easybook/controllers:RoomController"],
+	beego.GlobalControllerRouter["easybook/controllers:NotificationRuleController"] = append(beego.GlobalControllerRouter["easybook/controllers:NotificationRuleController"],
 		beego.ControllerComments{
 			Method:           "GetAll",
 			Router:           `/`,
@@ -223,7 +502,7 @@ func init() {
 			Filters:          nil,
 			Params:           nil})
 
-	beego.GlobalControllerRouter["easybook/controllers:RoomController"] = append(beego.GlobalControllerRouter["easybook/controllers:RoomController"],
+	beego.GlobalControllerRouter["easybook/controllers:NotificationRuleController"] = append(beego.GlobalControllerRouter["easybook/controllers:NotificationRuleController"],
 		beego.ControllerComments{
 			Method:           "GetOne",
 			Router:           `/:id`,
@@ -232,7 +511,7 @@ func init() {
 			Filters:          nil,
 			Params:           nil})
 
-	beego.GlobalControllerRouter["easybook/controllers:RoomController"] = append(beego.GlobalControllerRouter["easybook/controllers:RoomController"],
+	beego.GlobalControllerRouter["easybook/controllers:NotificationRuleController"] = append(beego.GlobalControllerRouter["easybook/controllers:NotificationRuleController"],
 		beego.ControllerComments{
 			Method:           "Put",
 			Router:           `/:id`,
@@ -241,7 +520,502 @@ func init() {
 			Filters:          nil,
 			Params:           nil})
 
-	beego.GlobalControllerRouter["easybook/controllers:RoomController"] = append(beego.GlobalControllerRouter["easybook/controllers:RoomController"],
+	beego.GlobalControllerRouter["easybook/controllers:NotificationRuleController"] = append(beego.GlobalControllerRouter["easybook/controllers:NotificationRuleController"],
+		beego.ControllerComments{
+			Method:           "Delete",
+			Router:           `/:id`,
+			AllowHTTPMethods: []string{"delete"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:NotificationTemplateController"] = append(beego.GlobalControllerRouter["easybook/controllers:NotificationTemplateController"],
+		beego.ControllerComments{
+			Method:           "Post",
+			Router:           `/`,
+			AllowHTTPMethods: []string{"post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:NotificationTemplateController"] = append(beego.GlobalControllerRouter["easybook/controllers:NotificationTemplateController"],
+		beego.ControllerComments{
+			Method:           "GetAll",
+			Router:           `/`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:NotificationTemplateController"] = append(beego.GlobalControllerRouter["easybook/controllers:NotificationTemplateController"],
+		beego.ControllerComments{
+			Method:           "GetOne",
+			Router:           `/:id`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:NotificationTemplateController"] = append(beego.GlobalControllerRouter["easybook/controllers:NotificationTemplateController"],
+		beego.ControllerComments{
+			Method:           "Put",
+			Router:           `/:id`,
+			AllowHTTPMethods: []string{"put"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:NotificationTemplateController"] = append(beego.GlobalControllerRouter["easybook/controllers:NotificationTemplateController"],
+		beego.ControllerComments{
+			Method:           "Delete",
+			Router:           `/:id`,
+			AllowHTTPMethods: []string{"delete"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:NotificationTemplateController"] = append(beego.GlobalControllerRouter["easybook/controllers:NotificationTemplateController"],
+		beego.ControllerComments{
+			Method:           "Preview",
+			Router:           `/:id/preview`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:OverbookingController"] = append(beego.GlobalControllerRouter["easybook/controllers:OverbookingController"],
+		beego.ControllerComments{
+			Method:           "PostAllowance",
+			Router:           `/allowances`,
+			AllowHTTPMethods: []string{"post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:OverbookingController"] = append(beego.GlobalControllerRouter["easybook/controllers:OverbookingController"],
+		beego.ControllerComments{
+			Method:           "GetAllowances",
+			Router:           `/allowances`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:OverbookingController"] = append(beego.GlobalControllerRouter["easybook/controllers:OverbookingController"],
+		beego.ControllerComments{
+			Method:           "PutAllowance",
+			Router:           `/allowances/:id`,
+			AllowHTTPMethods: []string{"put"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:OverbookingController"] = append(beego.GlobalControllerRouter["easybook/controllers:OverbookingController"],
+		beego.ControllerComments{
+			Method:           "DeleteAllowance",
+			Router:           `/allowances/:id`,
+			AllowHTTPMethods: []string{"delete"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:OverbookingController"] = append(beego.GlobalControllerRouter["easybook/controllers:OverbookingController"],
+		beego.ControllerComments{
+			Method:           "Report",
+			Router:           `/report`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:OverbookingController"] = append(beego.GlobalControllerRouter["easybook/controllers:OverbookingController"],
+		beego.ControllerComments{
+			Method:           "Relocate",
+			Router:           `/relocations`,
+			AllowHTTPMethods: []string{"post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:OverbookingController"] = append(beego.GlobalControllerRouter["easybook/controllers:OverbookingController"],
+		beego.ControllerComments{
+			Method:           "GetRelocations",
+			Router:           `/relocations`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:PaymentController"] = append(beego.GlobalControllerRouter["easybook/controllers:PaymentController"],
+		beego.ControllerComments{
+			Method:           "Post",
+			Router:           `/`,
+			AllowHTTPMethods: []string{"post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:PaymentController"] = append(beego.GlobalControllerRouter["easybook/controllers:PaymentController"],
+		beego.ControllerComments{
+			Method:           "GetAll",
+			Router:           `/`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:PaymentController"] = append(beego.GlobalControllerRouter["easybook/controllers:PaymentController"],
+		beego.ControllerComments{
+			Method:           "GetOne",
+			Router:           `/:id`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:PaymentController"] = append(beego.GlobalControllerRouter["easybook/controllers:PaymentController"],
+		beego.ControllerComments{
+			Method:           "Capture",
+			Router:           `/:id/capture`,
+			AllowHTTPMethods: []string{"post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:PaymentController"] = append(beego.GlobalControllerRouter["easybook/controllers:PaymentController"],
+		beego.ControllerComments{
+			Method:           "Void",
+			Router:           `/:id/void`,
+			AllowHTTPMethods: []string{"post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:PaymentController"] = append(beego.GlobalControllerRouter["easybook/controllers:PaymentController"],
+		beego.ControllerComments{
+			Method:           "Refund",
+			Router:           `/:id/refunds`,
+			AllowHTTPMethods: []string{"post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:PaymentController"] = append(beego.GlobalControllerRouter["easybook/controllers:PaymentController"],
+		beego.ControllerComments{
+			Method:           "Webhook",
+			Router:           `/webhooks/:provider`,
+			AllowHTTPMethods: []string{"post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:PromotionController"] = append(beego.GlobalControllerRouter["easybook/controllers:PromotionController"],
+		beego.ControllerComments{
+			Method:           "Post",
+			Router:           `/`,
+			AllowHTTPMethods: []string{"post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:PromotionController"] = append(beego.GlobalControllerRouter["easybook/controllers:PromotionController"],
+		beego.ControllerComments{
+			Method:           "GetOne",
+			Router:           `/:id`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:PromotionController"] = append(beego.GlobalControllerRouter["easybook/controllers:PromotionController"],
+		beego.ControllerComments{
+			Method:           "GetAll",
+			Router:           `/`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:PromotionController"] = append(beego.GlobalControllerRouter["easybook/controllers:PromotionController"],
+		beego.ControllerComments{
+			Method:           "Put",
+			Router:           `/:id`,
+			AllowHTTPMethods: []string{"put"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:PromotionController"] = append(beego.GlobalControllerRouter["easybook/controllers:PromotionController"],
+		beego.ControllerComments{
+			Method:           "Delete",
+			Router:           `/:id`,
+			AllowHTTPMethods: []string{"delete"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:RatePlanController"] = append(beego.GlobalControllerRouter["easybook/controllers:RatePlanController"],
+		beego.ControllerComments{
+			Method:           "Post",
+			Router:           `/`,
+			AllowHTTPMethods: []string{"post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:RatePlanController"] = append(beego.GlobalControllerRouter["easybook/controllers:RatePlanController"],
+		beego.ControllerComments{
+			Method:           "SetCalendar",
+			Router:           `/calendar`,
+			AllowHTTPMethods: []string{"post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:RatePlanController"] = append(beego.GlobalControllerRouter["easybook/controllers:RatePlanController"],
+		beego.ControllerComments{
+			Method:           "GetOne",
+			Router:           `/:id`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:RatePlanController"] = append(beego.GlobalControllerRouter["easybook/controllers:RatePlanController"],
+		beego.ControllerComments{
+			Method:           "GetAll",
+			Router:           `/`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:RatePlanController"] = append(beego.GlobalControllerRouter["easybook/controllers:RatePlanController"],
+		beego.ControllerComments{
+			Method:           "Put",
+			Router:           `/:id`,
+			AllowHTTPMethods: []string{"put"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:RatePlanController"] = append(beego.GlobalControllerRouter["easybook/controllers:RatePlanController"],
+		beego.ControllerComments{
+			Method:           "Delete",
+			Router:           `/:id`,
+			AllowHTTPMethods: []string{"delete"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:ReservationController"] = append(beego.GlobalControllerRouter["easybook/controllers:ReservationController"],
+		beego.ControllerComments{
+			Method:           "Post",
+			Router:           `/`,
+			AllowHTTPMethods: []string{"post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:ReservationController"] = append(beego.GlobalControllerRouter["easybook/controllers:ReservationController"],
+		beego.ControllerComments{
+			Method:           "GetAll",
+			Router:           `/`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:ReservationController"] = append(beego.GlobalControllerRouter["easybook/controllers:ReservationController"],
+		beego.ControllerComments{
+			Method:           "GetOne",
+			Router:           `/:id`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:ReservationController"] = append(beego.GlobalControllerRouter["easybook/controllers:ReservationController"],
+		beego.ControllerComments{
+			Method:           "Put",
+			Router:           `/:id`,
+			AllowHTTPMethods: []string{"put"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:ReservationController"] = append(beego.GlobalControllerRouter["easybook/controllers:ReservationController"],
+		beego.ControllerComments{
+			Method:           "Delete",
+			Router:           `/:id`,
+			AllowHTTPMethods: []string{"delete"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:ReservationController"] = append(beego.GlobalControllerRouter["easybook/controllers:ReservationController"],
+		beego.ControllerComments{
+			Method:           "Cancel",
+			Router:           `/:id/cancel`,
+			AllowHTTPMethods: []string{"post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:ReservationController"] = append(beego.GlobalControllerRouter["easybook/controllers:ReservationController"],
+		beego.ControllerComments{
+			Method:           "Modify",
+			Router:           `/:id/modify`,
+			AllowHTTPMethods: []string{"post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:ReservationController"] = append(beego.GlobalControllerRouter["easybook/controllers:ReservationController"],
+		beego.ControllerComments{
+			Method:           "GetChanges",
+			Router:           `/:id/changes`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:RoomController"] = append(beego.GlobalControllerRouter["easybook/controllers:RoomController"],
+		beego.ControllerComments{
+			Method:           "Post",
+			Router:           `/`,
+			AllowHTTPMethods: []string{"post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:RoomController"] = append(beego.GlobalControllerRouter["easybook/controllers:RoomController"],
+		beego.ControllerComments{
+			Method:           "GetAll",
+			Router:           `/`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:RoomController"] = append(beego.GlobalControllerRouter["easybook/controllers:RoomController"],
+		beego.ControllerComments{
+			Method:           "GetOne",
+			Router:           `/:id`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:RoomController"] = append(beego.GlobalControllerRouter["easybook/controllers:RoomController"],
+		beego.ControllerComments{
+			Method:           "Put",
+			Router:           `/:id`,
+			AllowHTTPMethods: []string{"put"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:RoomController"] = append(beego.GlobalControllerRouter["easybook/controllers:RoomController"],
+		beego.ControllerComments{
+			Method:           "Delete",
+			Router:           `/:id`,
+			AllowHTTPMethods: []string{"delete"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:RoomController"] = append(beego.GlobalControllerRouter["easybook/controllers:RoomController"],
+		beego.ControllerComments{
+			Method:           "Calendar",
+			Router:           `/:id/calendar`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:TaxRuleController"] = append(beego.GlobalControllerRouter["easybook/controllers:TaxRuleController"],
+		beego.ControllerComments{
+			Method:           "Post",
+			Router:           `/`,
+			AllowHTTPMethods: []string{"post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:TaxRuleController"] = append(beego.GlobalControllerRouter["easybook/controllers:TaxRuleController"],
+		beego.ControllerComments{
+			Method:           "GetOne",
+			Router:           `/:id`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:TaxRuleController"] = append(beego.GlobalControllerRouter["easybook/controllers:TaxRuleController"],
+		beego.ControllerComments{
+			Method:           "GetAll",
+			Router:           `/`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:TaxRuleController"] = append(beego.GlobalControllerRouter["easybook/controllers:TaxRuleController"],
+		beego.ControllerComments{
+			Method:           "Put",
+			Router:           `/:id`,
+			AllowHTTPMethods: []string{"put"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:TaxRuleController"] = append(beego.GlobalControllerRouter["easybook/controllers:TaxRuleController"],
+		beego.ControllerComments{
+			Method:           "Delete",
+			Router:           `/:id`,
+			AllowHTTPMethods: []string{"delete"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:WaitlistController"] = append(beego.GlobalControllerRouter["easybook/controllers:WaitlistController"],
+		beego.ControllerComments{
+			Method:           "Post",
+			Router:           `/`,
+			AllowHTTPMethods: []string{"post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:WaitlistController"] = append(beego.GlobalControllerRouter["easybook/controllers:WaitlistController"],
+		beego.ControllerComments{
+			Method:           "GetOne",
+			Router:           `/:id`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:WaitlistController"] = append(beego.GlobalControllerRouter["easybook/controllers:WaitlistController"],
+		beego.ControllerComments{
+			Method:           "GetAll",
+			Router:           `/`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:WaitlistController"] = append(beego.GlobalControllerRouter["easybook/controllers:WaitlistController"],
+		beego.ControllerComments{
+			Method:           "Accept",
+			Router:           `/:id/accept`,
+			AllowHTTPMethods: []string{"post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["easybook/controllers:WaitlistController"] = append(beego.GlobalControllerRouter["easybook/controllers:WaitlistController"],
 		beego.ControllerComments{
 			Method:           "Delete",
 			Router:           `/:id`,
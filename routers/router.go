@@ -26,24 +26,102 @@ func init() {
 			),
 		),
 
+		beego.NSNamespace("/exchange_rates",
+			beego.NSInclude(
+				&controllers.ExchangeRateController{},
+			),
+		),
+
+		beego.NSNamespace("/groups",
+			beego.NSInclude(
+				&controllers.GroupController{},
+			),
+		),
+
 		beego.NSNamespace("/guests",
 			beego.NSInclude(
 				&controllers.GuestController{},
 			),
 		),
 
+		beego.NSNamespace("/holds",
+			beego.NSInclude(
+				&controllers.HoldController{},
+			),
+		),
+
 		beego.NSNamespace("/hotels",
 			beego.NSInclude(
 				&controllers.HotelController{},
 			),
 		),
 
+		beego.NSNamespace("/inbox",
+			beego.NSInclude(
+				&controllers.InboxController{},
+			),
+		),
+
+		beego.NSNamespace("/invoice_templates",
+			beego.NSInclude(
+				&controllers.InvoiceTemplateController{},
+			),
+		),
+
+		beego.NSNamespace("/invoices",
+			beego.NSInclude(
+				&controllers.InvoiceController{},
+			),
+		),
+
+		beego.NSNamespace("/no_show_policies",
+			beego.NSInclude(
+				&controllers.NoShowPolicyController{},
+			),
+		),
+
 		beego.NSNamespace("/notifications",
 			beego.NSInclude(
 				&controllers.NotificationController{},
 			),
 		),
 
+		beego.NSNamespace("/notification_rules",
+			beego.NSInclude(
+				&controllers.NotificationRuleController{},
+			),
+		),
+
+		beego.NSNamespace("/notification_templates",
+			beego.NSInclude(
+				&controllers.NotificationTemplateController{},
+			),
+		),
+
+		beego.NSNamespace("/overbooking",
+			beego.NSInclude(
+				&controllers.OverbookingController{},
+			),
+		),
+
+		beego.NSNamespace("/payments",
+			beego.NSInclude(
+				&controllers.PaymentController{},
+			),
+		),
+
+		beego.NSNamespace("/promotions",
+			beego.NSInclude(
+				&controllers.PromotionController{},
+			),
+		),
+
+		beego.NSNamespace("/rate_plans",
+			beego.NSInclude(
+				&controllers.RatePlanController{},
+			),
+		),
+
 		beego.NSNamespace("/reservations",
 			beego.NSInclude(
 				&controllers.ReservationController{},
@@ -55,6 +133,18 @@ func init() {
 				&controllers.RoomController{},
 			),
 		),
+
+		beego.NSNamespace("/tax_rules",
+			beego.NSInclude(
+				&controllers.TaxRuleController{},
+			),
+		),
+
+		beego.NSNamespace("/waitlist",
+			beego.NSInclude(
+				&controllers.WaitlistController{},
+			),
+		),
 	)
 	beego.AddNamespace(ns)
 }
@@ -0,0 +1,86 @@
+// Package auth issues and verifies the tokens that identify API callers.
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/astaxie/beego"
+)
+
+// Errors returned by Verify.
+var (
+	ErrNoSecret     = errors.New("auth: authsecret is not configured")
+	ErrInvalidToken = errors.New("auth: invalid token")
+	ErrExpiredToken = errors.New("auth: token is expired")
+)
+
+// Claims identifies the caller of a request.
+type Claims struct {
+	GuestId   int   `json:"guestId"`
+	Role      int8  `json:"role"`
+	ExpiresAt int64 `json:"expiresAt"`
+}
+
+func secret() []byte {
+	return []byte(beego.AppConfig.String("authsecret"))
+}
+
+func ttl() time.Duration {
+	return time.Duration(beego.AppConfig.DefaultInt("authttl", 24)) * time.Hour
+}
+
+// Sign issues a token for the guest with role, valid for authttl hours.
+func Sign(guestID int, role int8) (string, error) {
+	if len(secret()) == 0 {
+		return "", ErrNoSecret
+	}
+
+	b, err := json.Marshal(&Claims{
+		GuestId:   guestID,
+		Role:      role,
+		ExpiresAt: time.Now().Add(ttl()).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	return payload + "." + sign(payload), nil
+}
+
+// Verify checks the signature and expiry of token and returns its claims.
+func Verify(token string) (*Claims, error) {
+	if len(secret()) == 0 {
+		return nil, ErrNoSecret
+	}
+
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 || !hmac.Equal([]byte(parts[1]), []byte(sign(parts[0]))) {
+		return nil, ErrInvalidToken
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	claims := &Claims{}
+	if err = json.Unmarshal(b, claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if time.Now().Unix() > claims.ExpiresAt {
+		return nil, ErrExpiredToken
+	}
+	return claims, nil
+}
+
+func sign(payload string) string {
+	mac := hmac.New(sha256.New, secret())
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,187 @@
+// Package availability tells which rooms are free for a stay, neither
+// reserved nor held. A reserved room is free still while its hotel may
+// overbook its service level, see models.OverbookingAllowance.
+package availability
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"easybook/models"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// ErrUnavailable is returned when a room is reserved or held for a night
+// of a stay.
+var ErrUnavailable = errors.New("availability: room is not available for these dates")
+
+// Unavailable returns the rooms of roomIDs reserved or held at now for a
+// night from from until the day before to. Reserved rooms whose service
+// level may be overbooked by one room more are free, and so are the rooms
+// held by hold exceptHoldID, for the guest converting it.
+func Unavailable(o orm.Ormer, roomIDs []int, from time.Time, to time.Time, now time.Time, exceptHoldID int) (map[int]bool, error) {
+	taken, _, err := unavailable(o, roomIDs, from, to, now, exceptHoldID, 0, true)
+	return taken, err
+}
+
+// Check returns ErrUnavailable if a room of roomIDs is not free for the
+// stay, see Unavailable. Rooms reserved already pass only if their service
+// level may be overbooked by all the rooms of roomIDs of the level. The
+// rooms are locked until the transaction of o ends, so two bookings of a
+// room cannot both pass.
+func Check(o orm.Ormer, roomIDs []int, from time.Time, to time.Time, now time.Time, exceptHoldID int) error {
+	return check(o, roomIDs, from, to, now, exceptHoldID, 0, true)
+}
+
+// CheckChange is Check for reservation reservationID moving to roomIDs and
+// the stay from from until the day before to. The rooms it reserves
+// already are free for it.
+func CheckChange(o orm.Ormer, reservationID int, roomIDs []int, from time.Time, to time.Time, now time.Time) error {
+	return check(o, roomIDs, from, to, now, 0, reservationID, true)
+}
+
+// CheckVacant is CheckChange without overbooking: the rooms must be
+// neither reserved by another reservation nor held, e.g. to relocate a
+// guest of an overbooked night.
+func CheckVacant(o orm.Ormer, reservationID int, roomIDs []int, from time.Time, to time.Time, now time.Time) error {
+	return check(o, roomIDs, from, to, now, 0, reservationID, false)
+}
+
+// unavailable returns the rooms of roomIDs taken for the stay and, with
+// overbook, the reserved rooms left free by the allowance of their service
+// level, by level.
+func unavailable(o orm.Ormer, roomIDs []int, from time.Time, to time.Time, now time.Time, exceptHoldID int, exceptReservationID int, overbook bool) (map[int]bool, map[int]int, error) {
+	taken := make(map[int]bool)
+	over := make(map[int]int)
+	if len(roomIDs) == 0 {
+		return taken, over, nil
+	}
+	reserved, err := models.GetReservedRoomIds(o, roomIDs, from, to, exceptReservationID)
+	if err != nil {
+		return nil, nil, err
+	}
+	held, err := models.GetHeldRoomIds(o, roomIDs, from, to, now, exceptHoldID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, id := range append(reserved, held...) {
+		taken[id] = true
+	}
+	if !overbook || len(reserved) == 0 {
+		return taken, over, nil
+	}
+
+	qs := o.QueryTable(new(models.Room)).Filter("Id__in", reserved)
+	if len(held) != 0 {
+		// a held room is never overbooked
+		qs = qs.Exclude("Id__in", held)
+	}
+	var rooms []models.Room
+	if _, err = qs.All(&rooms, "Id", "HotelId", "ServiceLevelId"); err != nil {
+		return nil, nil, err
+	}
+	free, err := headroom(o, rooms, from, to, exceptReservationID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, r := range rooms {
+		if free[r.ServiceLevelId.Id] > 0 {
+			delete(taken, r.Id)
+			over[r.Id] = r.ServiceLevelId.Id
+		}
+	}
+	return taken, over, nil
+}
+
+func check(o orm.Ormer, roomIDs []int, from time.Time, to time.Time, now time.Time, exceptHoldID int, exceptReservationID int, overbook bool) error {
+	if len(roomIDs) == 0 {
+		return nil
+	}
+	var rooms []models.Room
+	if _, err := o.QueryTable(new(models.Room)).Filter("Id__in", roomIDs).ForUpdate().All(&rooms, "Id", "HotelId", "ServiceLevelId"); err != nil {
+		return err
+	}
+	taken, over, err := unavailable(o, roomIDs, from, to, now, exceptHoldID, exceptReservationID, overbook)
+	if err != nil {
+		return err
+	}
+	if len(taken) != 0 {
+		return ErrUnavailable
+	}
+	if len(over) == 0 {
+		return nil
+	}
+
+	// every room asked of an overbooked level counts against its allowance,
+	// the other rooms of the level are locked so that two bookings cannot
+	// both use the last room of the allowance
+	levels := make(map[int]bool)
+	for _, level := range over {
+		levels[level] = true
+	}
+	var l orm.ParamsList
+	for level := range levels {
+		l = append(l, level)
+	}
+	if _, err = o.QueryTable(new(models.Room)).Filter("ServiceLevelId__in", l...).ForUpdate().All(&[]models.Room{}, "Id"); err != nil {
+		return err
+	}
+	var asked []models.Room
+	for _, r := range rooms {
+		if levels[r.ServiceLevelId.Id] {
+			asked = append(asked, r)
+		}
+	}
+	free, err := headroom(o, asked, from, to, exceptReservationID)
+	if err != nil {
+		return err
+	}
+	for _, r := range asked {
+		free[r.ServiceLevelId.Id]--
+	}
+	for level := range levels {
+		if free[level] < 0 {
+			return ErrUnavailable
+		}
+	}
+	return nil
+}
+
+// FreeHotelIds returns the ids of all hotels with a room free at now for
+// the nights from from until the day before to, in ascending order.
+func FreeHotelIds(o orm.Ormer, from time.Time, to time.Time, now time.Time) ([]int, error) {
+	ids, err := models.GetVacantHotelIds(o, from, to, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// only the rooms of the hotels without a vacant room but allowed to
+	// overbook are checked one by one
+	over, err := models.GetOverbookingHotelIds(o, ids)
+	if err != nil || len(over) == 0 {
+		return ids, err
+	}
+	var rooms []models.Room
+	if _, err = o.QueryTable(new(models.Room)).Filter("HotelId__Id__in", over).Limit(-1).All(&rooms, "Id", "HotelId"); err != nil {
+		return nil, err
+	}
+	roomIDs := make([]int, len(rooms))
+	for i, room := range rooms {
+		roomIDs[i] = room.Id
+	}
+	taken, err := Unavailable(o, roomIDs, from, to, now, 0)
+	if err != nil {
+		return nil, err
+	}
+	free := make(map[int]bool)
+	for _, room := range rooms {
+		if !taken[room.Id] && !free[room.HotelId.Id] {
+			free[room.HotelId.Id] = true
+			ids = append(ids, room.HotelId.Id)
+		}
+	}
+	sort.Ints(ids)
+	return ids, nil
+}
@@ -0,0 +1,206 @@
+package availability
+
+import (
+	"testing"
+	"time"
+
+	"easybook/models"
+	"easybook/services/servicetest"
+	"easybook/types"
+
+	"github.com/astaxie/beego/orm"
+)
+
+func TestMain(m *testing.M) {
+	servicetest.Main(m)
+}
+
+func TestAllowance(t *testing.T) {
+	hotel := models.OverbookingAllowance{Rooms: 2}
+	level := models.OverbookingAllowance{ServiceLevelId: &models.ServiceLevel{Id: 7}, Rooms: 1}
+	cases := []struct {
+		allowances []models.OverbookingAllowance
+		level      int
+		want       int
+	}{
+		{nil, 7, 0},
+		{[]models.OverbookingAllowance{hotel}, 7, 2},
+		{[]models.OverbookingAllowance{hotel, level}, 7, 1},
+		{[]models.OverbookingAllowance{level, hotel}, 7, 1},
+		{[]models.OverbookingAllowance{level, hotel}, 8, 2},
+		{[]models.OverbookingAllowance{level}, 8, 0},
+	}
+	for _, c := range cases {
+		if got := Allowance(c.allowances, c.level); got != c.want {
+			t.Errorf("Allowance(%v, %d) = %d, want %d", c.allowances, c.level, got, c.want)
+		}
+	}
+}
+
+func TestUnavailable(t *testing.T) {
+	o := servicetest.Ormer(t)
+	_, rooms := servicetest.Hotel(t, o, 3, types.NewMoney(1000000, types.DefaultCurrency))
+	reserved, held, free := rooms[0].Id, rooms[1].Id, rooms[2].Id
+	ids := []int{reserved, held, free}
+	g := servicetest.Guest(t, o)
+	from := servicetest.Date(2030, 8, 10)
+	to := from.AddDate(0, 0, 3)
+	now := time.Now().Truncate(time.Second)
+	servicetest.Reservation(t, o, g, rooms[:1], from, to, models.ReservationConfirmed)
+	servicetest.Reservation(t, o, g, rooms[2:], from, to, models.ReservationCancelled)
+	h := hold(t, o, g, held, from, to, now.Add(time.Minute))
+
+	cases := []struct {
+		name       string
+		from, to   time.Time
+		now        time.Time
+		exceptHold int
+		want       []int
+	}{
+		{"stay", from, to, now, 0, []int{reserved, held}},
+		{"last night", to.AddDate(0, 0, -1), to.AddDate(0, 0, 2), now, 0, []int{reserved, held}},
+		{"night before", from.AddDate(0, 0, -1), from, now, 0, nil},
+		{"night after", to, to.AddDate(0, 0, 1), now, 0, nil},
+		{"hold expired", from, to, now.Add(time.Minute), 0, []int{reserved}},
+		{"hold converted by its guest", from, to, now, h.Id, []int{reserved}},
+	}
+	for _, c := range cases {
+		taken, err := Unavailable(o, ids, c.from, c.to, c.now, c.exceptHold)
+		if err != nil {
+			t.Fatalf("%s: Unavailable error: %v", c.name, err)
+		}
+		if len(taken) != len(c.want) {
+			t.Errorf("%s: Unavailable = %v, want %v", c.name, taken, c.want)
+			continue
+		}
+		for _, id := range c.want {
+			if !taken[id] {
+				t.Errorf("%s: Unavailable = %v, want %v", c.name, taken, c.want)
+			}
+		}
+	}
+}
+
+func TestCheckOverbooking(t *testing.T) {
+	o := servicetest.Ormer(t)
+	h, rooms := servicetest.Hotel(t, o, 2, types.NewMoney(1000000, types.DefaultCurrency))
+	first, second := rooms[0].Id, rooms[1].Id
+	g := servicetest.Guest(t, o)
+	from := servicetest.Date(2030, 9, 1)
+	to := from.AddDate(0, 0, 2)
+	now := time.Now().Truncate(time.Second)
+	r := servicetest.Reservation(t, o, g, rooms, from, to, models.ReservationConfirmed)
+
+	if err := Check(o, []int{first}, from, to, now, 0); err != ErrUnavailable {
+		t.Errorf("Check of a reserved room without allowance error = %v, want ErrUnavailable", err)
+	}
+	if err := CheckChange(o, r.Id, []int{first, second}, from, to.AddDate(0, 0, 1), now); err != nil {
+		t.Errorf("CheckChange of the rooms of the reservation error: %v", err)
+	}
+
+	servicetest.Insert(t, o, &models.OverbookingAllowance{HotelId: h, Rooms: 1, CreatedAt: now, UpdatedAt: now})
+	if err := Check(o, []int{first}, from, to, now, 0); err != nil {
+		t.Errorf("Check of a reserved room within the allowance error: %v", err)
+	}
+	if err := Check(o, []int{first, second}, from, to, now, 0); err != ErrUnavailable {
+		t.Errorf("Check of two reserved rooms beyond the allowance error = %v, want ErrUnavailable", err)
+	}
+	if err := CheckVacant(o, 0, []int{first}, from, to, now); err != ErrUnavailable {
+		t.Errorf("CheckVacant of a reserved room error = %v, want ErrUnavailable", err)
+	}
+
+	// a level allowance overrides the one of the hotel
+	servicetest.Insert(t, o, &models.OverbookingAllowance{HotelId: h, ServiceLevelId: rooms[0].ServiceLevelId, Rooms: 0, CreatedAt: now, UpdatedAt: now})
+	if err := Check(o, []int{first}, from, to, now, 0); err != ErrUnavailable {
+		t.Errorf("Check of a reserved room of a level without allowance error = %v, want ErrUnavailable", err)
+	}
+}
+
+func TestLevels(t *testing.T) {
+	o := servicetest.Ormer(t)
+	h, rooms := servicetest.Hotel(t, o, 2, types.NewMoney(1000000, types.DefaultCurrency))
+	g := servicetest.Guest(t, o)
+	from := servicetest.Date(2030, 10, 1)
+	now := time.Now().Truncate(time.Second)
+	servicetest.Insert(t, o, &models.OverbookingAllowance{HotelId: h, Rooms: 1, CreatedAt: now, UpdatedAt: now})
+	servicetest.Reservation(t, o, g, rooms, from, from.AddDate(0, 0, 2), models.ReservationConfirmed)
+	servicetest.Reservation(t, o, g, rooms[:1], from.AddDate(0, 0, 1), from.AddDate(0, 0, 2), models.ReservationConfirmed)
+	servicetest.Reservation(t, o, g, rooms[:1], from, from.AddDate(0, 0, 1), models.ReservationCancelled)
+
+	dates, nights, err := Levels(o, h.Id, from, from.AddDate(0, 0, 3), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nights) != 5 {
+		t.Errorf("%d nights reserved, want 5", len(nights))
+	}
+	level := rooms[0].ServiceLevelId.Id
+	for i, reserved := range []int{2, 3, 0} {
+		date := from.AddDate(0, 0, i).Format(types.JSONDate)
+		want := Level{HotelId: h.Id, ServiceLevelId: level, Rooms: 2, Allowance: 1, Reserved: reserved}
+		if l := dates[date][level]; l == nil || *l != want {
+			t.Errorf("level on %s = %+v, want %+v", date, l, want)
+		}
+	}
+}
+
+func TestFreeHotelIds(t *testing.T) {
+	o := servicetest.Ormer(t)
+	price := types.NewMoney(1000000, types.DefaultCurrency)
+	vacant, _ := servicetest.Hotel(t, o, 1, price)
+	full, fullRooms := servicetest.Hotel(t, o, 1, price)
+	held, heldRooms := servicetest.Hotel(t, o, 1, price)
+	overbooked, overRooms := servicetest.Hotel(t, o, 1, price)
+	g := servicetest.Guest(t, o)
+	from := servicetest.Date(2030, 10, 20)
+	to := from.AddDate(0, 0, 2)
+	now := time.Now().Truncate(time.Second)
+	servicetest.Reservation(t, o, g, fullRooms, from.AddDate(0, 0, 1), to, models.ReservationConfirmed)
+	hold(t, o, g, heldRooms[0].Id, from, from.AddDate(0, 0, 1), now.Add(time.Minute))
+	servicetest.Reservation(t, o, g, overRooms, from, to, models.ReservationConfirmed)
+	servicetest.Insert(t, o, &models.OverbookingAllowance{HotelId: overbooked, Rooms: 1, CreatedAt: now, UpdatedAt: now})
+
+	ids, err := FreeHotelIds(o, from, to, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		name string
+		h    *models.Hotel
+		free bool
+	}{
+		{"vacant", vacant, true},
+		{"reserved", full, false},
+		{"held", held, false},
+		{"overbooked", overbooked, true},
+	} {
+		if intsContain(ids, c.h.Id) != c.free {
+			t.Errorf("%s hotel %d free = %v, want %v", c.name, c.h.Id, !c.free, c.free)
+		}
+	}
+	if ids, err = FreeHotelIds(o, from, to, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if !intsContain(ids, held.Id) {
+		t.Errorf("FreeHotelIds after the hold expired = %v, want hotel %d", ids, held.Id)
+	}
+}
+
+// intsContain reports whether l contains id.
+func intsContain(l []int, id int) bool {
+	for _, v := range l {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// hold inserts a hold of room for guest g until expires.
+func hold(t *testing.T, o orm.Ormer, g *models.Guest, room int, from time.Time, to time.Time, expires time.Time) *models.Hold {
+	t.Helper()
+	v := &models.Hold{GuestId: g, StartDate: from, EndDate: to, ExpiresAt: expires, CreatedAt: time.Now()}
+	servicetest.Insert(t, o, v)
+	servicetest.Insert(t, o, &models.HoldRoom{HoldId: v, RoomId: &models.Room{Id: room}})
+	return v
+}
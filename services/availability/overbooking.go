@@ -0,0 +1,126 @@
+package availability
+
+import (
+	"time"
+
+	"easybook/models"
+	"easybook/types"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// Level is the use of the rooms of a service level of a hotel for a
+// night: Reserved rooms of Rooms, which may be overbooked by Allowance.
+type Level struct {
+	HotelId        int `json:"hotelId"`
+	ServiceLevelId int `json:"serviceLevelId"`
+	Rooms          int `json:"rooms"`
+	Allowance      int `json:"allowance"`
+	Reserved       int `json:"reserved"`
+}
+
+// Allowance returns the overbooking allowance of service level levelID
+// among the allowances of its hotel: its own or else the one of the
+// hotel.
+func Allowance(allowances []models.OverbookingAllowance, levelID int) int {
+	rooms := 0
+	for _, v := range allowances {
+		switch {
+		case v.ServiceLevelId != nil && v.ServiceLevelId.Id == levelID:
+			return v.Rooms
+		case v.ServiceLevelId == nil:
+			rooms = v.Rooms
+		}
+	}
+	return rooms
+}
+
+// Levels returns the use of the rooms of hotel hotelID by service level for
+// each night from from until the day before to, by date, and the reserved
+// nights counted. The reservation exceptReservationID is not counted.
+func Levels(o orm.Ormer, hotelID int, from time.Time, to time.Time, exceptReservationID int) (map[string]map[int]*Level, []models.ReservedNight, error) {
+	allowances, err := models.GetHotelAllowances(o, hotelID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return levels(o, hotelID, allowances, from, to, exceptReservationID)
+}
+
+func levels(o orm.Ormer, hotelID int, allowances []models.OverbookingAllowance, from time.Time, to time.Time, exceptReservationID int) (map[string]map[int]*Level, []models.ReservedNight, error) {
+	rooms, err := models.CountLevelRooms(o, hotelID)
+	if err != nil {
+		return nil, nil, err
+	}
+	nights, err := models.GetReservedNights(o, hotelID, from, to, exceptReservationID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dates := make(map[string]map[int]*Level)
+	for night := from; night.Before(to); night = night.AddDate(0, 0, 1) {
+		date := make(map[int]*Level, len(rooms))
+		for id, n := range rooms {
+			date[id] = &Level{HotelId: hotelID, ServiceLevelId: id, Rooms: n, Allowance: Allowance(allowances, id)}
+		}
+		dates[night.Format(types.JSONDate)] = date
+	}
+	for _, n := range nights {
+		if l := dates[n.Date.Format(types.JSONDate)][n.ServiceLevelId]; l != nil {
+			l.Reserved++
+		}
+	}
+	return dates, nights, nil
+}
+
+// headroom returns how many rooms more than reserved may be reserved for
+// every night from from until the day before to, by service level of
+// rooms, the rooms of the level plus its allowance less the rooms reserved
+// on its busiest night. Levels without allowance are left out, they are
+// never overbooked.
+func headroom(o orm.Ormer, rooms []models.Room, from time.Time, to time.Time, exceptReservationID int) (map[int]int, error) {
+	free := make(map[int]int)
+	hotels := make(map[int]bool)
+	for _, r := range rooms {
+		if hotels[r.HotelId.Id] {
+			continue
+		}
+		hotels[r.HotelId.Id] = true
+
+		allowances, err := models.GetHotelAllowances(o, r.HotelId.Id)
+		if err != nil {
+			return nil, err
+		}
+		if !overbooks(allowances) {
+			continue
+		}
+		dates, _, err := levels(o, r.HotelId.Id, allowances, from, to, exceptReservationID)
+		if err != nil {
+			return nil, err
+		}
+		busiest := make(map[int]int)
+		for _, date := range dates {
+			for id, l := range date {
+				if l.Allowance <= 0 {
+					continue
+				}
+				if n, ok := busiest[id]; !ok || l.Rooms+l.Allowance-l.Reserved < n {
+					busiest[id] = l.Rooms + l.Allowance - l.Reserved
+				}
+			}
+		}
+		for id, n := range busiest {
+			free[id] = n
+		}
+	}
+	return free, nil
+}
+
+// overbooks reports whether a hotel of allowances may be overbooked.
+func overbooks(allowances []models.OverbookingAllowance) bool {
+	for _, v := range allowances {
+		if v.Rooms > 0 {
+			return true
+		}
+	}
+	return false
+}
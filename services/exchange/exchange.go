@@ -0,0 +1,202 @@
+// Package exchange converts money between currencies with the dated rates
+// of the exchange_rate table.
+package exchange
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"easybook/models"
+	"easybook/types"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// ErrNoRate is returned when no rate of a pair is in effect on a date.
+var ErrNoRate = errors.New("exchange: no rate for currency pair")
+
+// Currency validates an ISO 4217 code given by a client, e.g. in the
+// currency query parameter. An empty code is returned as is.
+func Currency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return "", nil
+	}
+	if _, ok := types.Digits(code); !ok {
+		return "", fmt.Errorf("exchange: unknown currency %q", code)
+	}
+	return code, nil
+}
+
+// Rate returns the value of one from in to on date. Pairs without a stored
+// rate are derived from the inverse rate, or crossed through
+// types.DefaultCurrency.
+func Rate(o orm.Ormer, from string, to string, date time.Time) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+	if r, err := pair(o, from, to, date); err != ErrNoRate {
+		return r, err
+	}
+
+	base := types.DefaultCurrency
+	if from == base || to == base {
+		return 0, ErrNoRate
+	}
+	r1, err := pair(o, from, base, date)
+	if err != nil {
+		return 0, err
+	}
+	r2, err := pair(o, base, to, date)
+	if err != nil {
+		return 0, err
+	}
+	return r1 * r2, nil
+}
+
+// pair returns the stored rate of from in to or the inverse of to in from.
+func pair(o orm.Ormer, from string, to string, date time.Time) (float64, error) {
+	v, err := models.GetExchangeRate(o, from, to, date)
+	if err == nil {
+		return v.Rate, nil
+	}
+	if err != orm.ErrNoRows {
+		return 0, err
+	}
+
+	v, err = models.GetExchangeRate(o, to, from, date)
+	if err == nil && v.Rate != 0 {
+		return 1 / v.Rate, nil
+	}
+	if err != nil && err != orm.ErrNoRows {
+		return 0, err
+	}
+	return 0, ErrNoRate
+}
+
+// Converter converts amounts to one currency at the rates of a date. Rates
+// are looked up once per currency, so a Converter serves one request.
+type Converter struct {
+	o     orm.Ormer
+	to    string
+	date  time.Time
+	rates map[string]float64
+}
+
+// NewConverter creates a Converter to currency to at the rates of date.
+func NewConverter(o orm.Ormer, to string, date time.Time) *Converter {
+	return &Converter{o: o, to: to, date: date, rates: make(map[string]float64)}
+}
+
+// Currency returns the currency amounts are converted to.
+func (c *Converter) Currency() string {
+	return c.to
+}
+
+// Convert returns m in the currency of c and the rate used.
+func (c *Converter) Convert(m types.Money) (types.Money, float64, error) {
+	from := m.Currency
+	if from == "" {
+		from = types.DefaultCurrency
+	}
+	rate, ok := c.rates[from]
+	if !ok {
+		var err error
+		if rate, err = Rate(c.o, from, c.to, c.date); err != nil {
+			return types.Money{}, 0, err
+		}
+		c.rates[from] = rate
+	}
+	return m.Convert(c.to, rate), rate, nil
+}
+
+// Import stores the rates read from r in CSV with the columns date, base,
+// currency and rate, e.g. "2020-09-18,VND,USD,0.0000431". A first line
+// starting with "date" is a header and lines starting with # are comments.
+// Rates already stored for a pair and date are replaced. Returns the number
+// of rates stored.
+func Import(r io.Reader) (n int, err error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = 4
+	cr.TrimLeadingSpace = true
+
+	o := orm.NewOrm()
+	_ = o.Begin()
+	for line := 1; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			_ = o.Rollback()
+			return 0, err
+		}
+		if line == 1 && strings.EqualFold(rec[0], "date") {
+			continue
+		}
+
+		v, err := parse(rec)
+		if err != nil {
+			_ = o.Rollback()
+			return 0, fmt.Errorf("exchange: line %d: %v", line, err)
+		}
+		if err = models.SetExchangeRate(o, v); err != nil {
+			_ = o.Rollback()
+			return 0, err
+		}
+		n++
+	}
+	return n, o.Commit()
+}
+
+// LoadFile imports the rates of the CSV file at path, see Import.
+func LoadFile(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return Import(f)
+}
+
+// Validate checks the currencies and rate of v.
+func Validate(v *models.ExchangeRate) error {
+	var err error
+	if v.Base, err = Currency(v.Base); err != nil {
+		return err
+	}
+	if v.Currency, err = Currency(v.Currency); err != nil {
+		return err
+	}
+	switch {
+	case v.Base == "" || v.Currency == "":
+		return errors.New("exchange: base and currency are required")
+	case v.Base == v.Currency:
+		return errors.New("exchange: base and currency must differ")
+	case v.Rate <= 0:
+		return errors.New("exchange: rate must be positive")
+	case v.Date.IsZero():
+		return errors.New("exchange: date is required")
+	}
+	return nil
+}
+
+func parse(rec []string) (*models.ExchangeRate, error) {
+	date, err := types.DateString(rec[0])
+	if err != nil {
+		return nil, err
+	}
+	rate, err := strconv.ParseFloat(rec[3], 64)
+	if err != nil {
+		return nil, err
+	}
+	v := &models.ExchangeRate{Base: rec[1], Currency: rec[2], Rate: rate, Date: date.Time}
+	return v, Validate(v)
+}
@@ -0,0 +1,283 @@
+// Package group reserves blocks of rooms for organizers and gives the
+// rooms to the guests of the group until the block is released.
+package group
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"easybook/models"
+	"easybook/services/availability"
+	"easybook/services/invoice"
+	"easybook/services/notification"
+	"easybook/services/periodic"
+	"easybook/services/pricing"
+	"easybook/services/waitlist"
+	"easybook/types"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/logs"
+	"github.com/astaxie/beego/orm"
+)
+
+// Errors of groups which cannot be changed.
+var (
+	ErrReleased      = errors.New("group: block is released")
+	ErrNotInBlock    = errors.New("group: room is not in the block")
+	ErrNoMaster      = errors.New("group: group is billed per guest")
+	ErrMixedHotels   = errors.New("group: rooms must be of the hotel of the group")
+	ErrReleaseBefore = errors.New("group: release date must not follow the start date")
+)
+
+// IsError reports whether err is caused by the group or the rooms asked
+// rather than by the database.
+func IsError(err error) bool {
+	switch err {
+	case availability.ErrUnavailable, pricing.ErrMinStay:
+		return true
+	}
+	return strings.HasPrefix(err.Error(), "group: ")
+}
+
+// Create reserves roomIDs for group g within the transaction of o: the
+// block reservation of the organizer is pending, it reserves the rooms
+// without being invoiced.
+func Create(o orm.Ormer, g *models.GroupBooking, roomIDs []int, now time.Time) error {
+	switch {
+	case len(roomIDs) == 0:
+		return errors.New("group: rooms are required")
+	case !g.StartDate.Before(g.EndDate):
+		return errors.New("group: end date must follow start date")
+	case g.ReleaseDate.IsZero():
+		g.ReleaseDate = g.StartDate
+	case g.ReleaseDate.After(g.StartDate):
+		return ErrReleaseBefore
+	}
+	if err := availability.Check(o, roomIDs, g.StartDate, g.EndDate, now, 0); err != nil {
+		return err
+	}
+
+	g.CreatedAt, g.UpdatedAt = now, now
+	if _, err := o.Insert(g); err != nil {
+		return err
+	}
+	block := &models.Reservation{
+		GuestId:    g.OrganizerId,
+		StartDate:  g.StartDate,
+		EndDate:    g.EndDate,
+		TotalPrice: types.NewMoney(0, types.DefaultCurrency),
+		Status:     models.ReservationPending,
+		GroupId:    g,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if _, err := o.Insert(block); err != nil {
+		return err
+	}
+	nights := pricing.Nights(block)
+	for _, id := range roomIDs {
+		room := &models.Room{Id: id}
+		if err := o.Read(room); err == orm.ErrNoRows {
+			return fmt.Errorf("group: room %d does not exist", id)
+		} else if err != nil {
+			return err
+		}
+		if room.HotelId == nil || room.HotelId.Id != g.HotelId.Id {
+			return ErrMixedHotels
+		}
+		if err := pricing.ReserveRoom(o, &models.RoomReserved{ReservationId: block, RoomId: room, CreatedAt: now, UpdatedAt: now}, nights); err != nil {
+			return err
+		}
+	}
+	if err := reprice(o, block, g.HotelId.Id); err != nil {
+		return err
+	}
+
+	g.BlockId = block
+	_, err := o.Update(g, "BlockId")
+	return err
+}
+
+// Assign gives room roomID of the block of group g, locked, to guest
+// guestID within the transaction of o. The room moves from the block to a
+// confirmed reservation of the guest at the price of the block, invoiced
+// to the guest unless the group has a master invoice.
+func Assign(o orm.Ormer, g *models.GroupBooking, roomID int, guestID int, guests uint8, now time.Time) (*models.Reservation, error) {
+	if !g.ReleasedAt.IsZero() || g.BlockId == nil {
+		return nil, ErrReleased
+	}
+	rr := &models.RoomReserved{}
+	err := o.QueryTable(new(models.RoomReserved)).
+		Filter("ReservationId", g.BlockId.Id).Filter("RoomId", roomID).
+		ForUpdate().One(rr)
+	if err == orm.ErrNoRows {
+		return nil, ErrNotInBlock
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	block := &models.Reservation{Id: g.BlockId.Id}
+	if err = o.Read(block); err != nil {
+		return nil, err
+	}
+	r := &models.Reservation{
+		GuestId:         &models.Guest{Id: guestID},
+		StartDate:       g.StartDate,
+		EndDate:         g.EndDate,
+		Guests:          guests,
+		TotalPrice:      types.NewMoney(0, block.TotalPrice.Currency),
+		DisplayCurrency: block.DisplayCurrency,
+		ExchangeRate:    block.ExchangeRate,
+		Status:          models.ReservationConfirmed,
+		GroupId:         g,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if _, err = o.Insert(r); err != nil {
+		return nil, err
+	}
+	// the nights of the room keep the price of the block
+	rr.ReservationId = r
+	if _, err = o.Update(rr, "ReservationId"); err != nil {
+		return nil, err
+	}
+	if err = reprice(o, block, g.HotelId.Id); err != nil {
+		return nil, err
+	}
+	if err = reprice(o, r, g.HotelId.Id); err != nil {
+		return nil, err
+	}
+	if err = notification.Confirmed(o, r, g.HotelId.Id, now); err != nil {
+		return nil, err
+	}
+	if g.MasterInvoice == 0 {
+		if _, err = invoice.Generate(o, r, g.HotelId.Id, now); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Release returns the rooms of the block of group g, locked, not given to
+// a guest to inventory within the transaction of o and offers them to the
+// waitlist of the hotel. The block reservation is canceled.
+func Release(o orm.Ormer, g *models.GroupBooking, now time.Time) error {
+	if !g.ReleasedAt.IsZero() {
+		return ErrReleased
+	}
+	if g.BlockId != nil {
+		_, err := o.QueryTable(new(models.RoomReserved)).Filter("ReservationId", g.BlockId.Id).Delete()
+		if err != nil {
+			return err
+		}
+		block := &models.Reservation{Id: g.BlockId.Id, Status: models.ReservationCancelled}
+		if _, err = o.Update(block, "Status"); err != nil {
+			return err
+		}
+	}
+	g.ReleasedAt = now
+	if _, err := o.Update(g, "ReleasedAt"); err != nil {
+		return err
+	}
+	_, err := waitlist.Match(o, g.HotelId.Id, now)
+	return err
+}
+
+// MasterInvoice issues or updates the master invoice of group g within
+// the transaction of o, over the reservations of its guests.
+func MasterInvoice(o orm.Ormer, g *models.GroupBooking, now time.Time) (*models.Invoice, error) {
+	switch {
+	case g.MasterInvoice == 0:
+		return nil, ErrNoMaster
+	case g.BlockId == nil:
+		return nil, errors.New("group: block reservation is deleted")
+	}
+	block := &models.Reservation{Id: g.BlockId.Id}
+	if err := o.Read(block); err != nil {
+		return nil, err
+	}
+	members, err := models.GetGroupMembers(o, g)
+	if err != nil {
+		return nil, err
+	}
+	return invoice.Master(o, block, g.OrganizerId, members, g.HotelId.Id, now)
+}
+
+// Releaser releases the blocks of groups whose release date came in
+// background.
+type Releaser struct {
+	// Interval is the time between two runs.
+	Interval time.Duration
+
+	runner periodic.Runner
+}
+
+// NewReleaser creates a Releaser configured by the group* keys of
+// app.conf.
+func NewReleaser() *Releaser {
+	return &Releaser{
+		Interval: time.Duration(beego.AppConfig.DefaultInt("groupreleaseinterval", 3600)) * time.Second,
+	}
+}
+
+// Start runs the releaser in background until Stop is called.
+func (s *Releaser) Start() {
+	s.runner.Start("group: release", s.Interval, func(now time.Time) error {
+		_, err := s.RunOnce(now)
+		return err
+	})
+}
+
+// Stop stops the releaser and waits for the current run to finish.
+func (s *Releaser) Stop() {
+	s.runner.Stop()
+}
+
+// RunOnce releases the blocks whose release date passed before the day of
+// now and returns their number. A block which fails is logged and skipped.
+func (s *Releaser) RunOnce(now time.Time) (int, error) {
+	o := orm.NewOrm()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	l, err := models.GetGroupsToRelease(o, today)
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	for _, g := range l {
+		_ = o.Begin()
+		// the group is locked against rooms being given meanwhile
+		if err = o.ReadForUpdate(&g); err == nil {
+			err = Release(o, &g, now)
+		}
+		if err == ErrReleased {
+			_ = o.Rollback()
+			continue
+		}
+		if err != nil {
+			// the other blocks are released still
+			_ = o.Rollback()
+			logs.Error("group: releasing group %d: %v", g.Id, err)
+			continue
+		}
+		if err = o.Commit(); err != nil {
+			logs.Error("group: releasing group %d: %v", g.Id, err)
+			continue
+		}
+		n++
+	}
+	return n, nil
+}
+
+// reprice sets the total of reservation r from its rooms.
+func reprice(o orm.Ormer, r *models.Reservation, hotelID int) error {
+	b, err := pricing.Quote(o, r, hotelID)
+	if err != nil {
+		return err
+	}
+	r.TotalPrice = b.Total
+	_, err = o.Update(r, "TotalPrice")
+	return err
+}
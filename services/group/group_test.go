@@ -0,0 +1,204 @@
+package group
+
+import (
+	"testing"
+	"time"
+
+	"easybook/models"
+	"easybook/services/availability"
+	"easybook/services/servicetest"
+	"easybook/types"
+)
+
+func TestMain(m *testing.M) {
+	servicetest.Main(m)
+}
+
+func TestCreate(t *testing.T) {
+	o := servicetest.Ormer(t)
+	h, rooms := servicetest.Hotel(t, o, 3, types.NewMoney(1000000, types.DefaultCurrency))
+	_, elsewhere := servicetest.Hotel(t, o, 1, types.NewMoney(1000000, types.DefaultCurrency))
+	organizer := servicetest.Guest(t, o)
+	from := servicetest.Date(2031, 5, 1)
+	to := from.AddDate(0, 0, 2)
+	now := time.Now().Truncate(time.Second)
+	servicetest.Reservation(t, o, servicetest.Guest(t, o), rooms[2:], from, to, models.ReservationConfirmed)
+	newGroup := func(from time.Time, to time.Time, release time.Time) *models.GroupBooking {
+		return &models.GroupBooking{Name: servicetest.Name("Group"), OrganizerId: organizer, HotelId: h, StartDate: from, EndDate: to, ReleaseDate: release}
+	}
+
+	cases := []struct {
+		name  string
+		g     *models.GroupBooking
+		rooms []int
+		err   error
+	}{
+		{"release after start", newGroup(from, to, from.AddDate(0, 0, 1)), []int{rooms[0].Id}, ErrReleaseBefore},
+		{"room reserved", newGroup(from, to, time.Time{}), []int{rooms[0].Id, rooms[2].Id}, availability.ErrUnavailable},
+		{"room of another hotel", newGroup(from, to, time.Time{}), []int{rooms[0].Id, elsewhere[0].Id}, ErrMixedHotels},
+	}
+	// the rooms reserved before an error are rolled back, as by callers
+	create := func(g *models.GroupBooking, rooms []int) error {
+		_ = o.Begin()
+		defer o.Rollback()
+		return Create(o, g, rooms, now)
+	}
+	for _, c := range cases {
+		if err := create(c.g, c.rooms); err != c.err {
+			t.Errorf("%s: Create error = %v, want %v", c.name, err, c.err)
+		}
+	}
+	for _, c := range []struct {
+		name  string
+		g     *models.GroupBooking
+		rooms []int
+	}{
+		{"no rooms", newGroup(from, to, time.Time{}), nil},
+		{"empty stay", newGroup(from, from, time.Time{}), []int{rooms[0].Id}},
+	} {
+		if err := create(c.g, c.rooms); err == nil || !IsError(err) {
+			t.Errorf("%s: Create error = %v, want a group error", c.name, err)
+		}
+	}
+
+	g := newGroup(from, to, time.Time{})
+	if err := Create(o, g, []int{rooms[0].Id, rooms[1].Id}, now); err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+	if !g.ReleaseDate.Equal(from) || g.BlockId == nil {
+		t.Errorf("group = %+v, want released on its start date with a block", g)
+	}
+	block := &models.Reservation{Id: g.BlockId.Id}
+	if err := o.Read(block); err != nil || block.Status != models.ReservationPending || block.TotalPrice.Amount <= 0 {
+		t.Errorf("block = %+v, %v, want a pending reservation with a total", block, err)
+	}
+	if rrs, err := models.GetRoomReservedByReservation(o, block.Id); err != nil || len(rrs) != 2 {
+		t.Errorf("block reserves %d rooms, %v, want 2", len(rrs), err)
+	}
+	if err := availability.Check(o, []int{rooms[0].Id}, from, to, now, 0); err != availability.ErrUnavailable {
+		t.Errorf("Check of a room of the block error = %v, want ErrUnavailable", err)
+	}
+}
+
+func TestAssign(t *testing.T) {
+	o := servicetest.Ormer(t)
+	h, rooms := servicetest.Hotel(t, o, 3, types.NewMoney(1000000, types.DefaultCurrency))
+	from := servicetest.Date(2031, 6, 1)
+	to := from.AddDate(0, 0, 2)
+	now := time.Now().Truncate(time.Second)
+	g := &models.GroupBooking{Name: servicetest.Name("Group"), OrganizerId: servicetest.Guest(t, o), HotelId: h, StartDate: from, EndDate: to}
+	if err := Create(o, g, []int{rooms[0].Id, rooms[1].Id}, now); err != nil {
+		t.Fatal(err)
+	}
+	block := &models.Reservation{Id: g.BlockId.Id}
+	if err := o.Read(block); err != nil {
+		t.Fatal(err)
+	}
+	total := block.TotalPrice
+
+	guest := servicetest.Guest(t, o)
+	if _, err := Assign(o, g, rooms[2].Id, guest.Id, 1, now); err != ErrNotInBlock {
+		t.Errorf("Assign of a room outside the block error = %v, want ErrNotInBlock", err)
+	}
+	r, err := Assign(o, g, rooms[0].Id, guest.Id, 2, now)
+	if err != nil {
+		t.Fatalf("Assign error: %v", err)
+	}
+	if r.Status != models.ReservationConfirmed || r.GroupId.Id != g.Id || r.TotalPrice.Amount <= 0 {
+		t.Errorf("reservation of the guest = %+v, want confirmed in the group with a total", r)
+	}
+	if rrs, err := models.GetRoomReservedByReservation(o, r.Id); err != nil || len(rrs) != 1 || rrs[0].RoomId.Id != rooms[0].Id {
+		t.Errorf("rooms of the guest = %+v, %v, want room %d", rrs, err, rooms[0].Id)
+	}
+	if err = o.Read(block); err != nil || block.TotalPrice.Amount >= total.Amount {
+		t.Errorf("block total after assignment = %v, %v, want less than %v", block.TotalPrice, err, total)
+	}
+	if n, err := o.QueryTable(new(models.Invoice)).Filter("ReservationId", r.Id).Count(); err != nil || n != 1 {
+		t.Errorf("%d invoices of the guest, %v, want 1", n, err)
+	}
+	if _, err = MasterInvoice(o, g, now); err != ErrNoMaster {
+		t.Errorf("MasterInvoice of a group billed per guest error = %v, want ErrNoMaster", err)
+	}
+	if _, err = Assign(o, g, rooms[0].Id, guest.Id, 1, now); err != ErrNotInBlock {
+		t.Errorf("Assign of a room given already error = %v, want ErrNotInBlock", err)
+	}
+}
+
+func TestMasterInvoice(t *testing.T) {
+	o := servicetest.Ormer(t)
+	h, rooms := servicetest.Hotel(t, o, 2, types.NewMoney(1000000, types.DefaultCurrency))
+	from := servicetest.Date(2031, 7, 1)
+	now := time.Now().Truncate(time.Second)
+	organizer := servicetest.Guest(t, o)
+	g := &models.GroupBooking{Name: servicetest.Name("Group"), OrganizerId: organizer, HotelId: h, StartDate: from, EndDate: from.AddDate(0, 0, 1), MasterInvoice: 1}
+	if err := Create(o, g, []int{rooms[0].Id, rooms[1].Id}, now); err != nil {
+		t.Fatal(err)
+	}
+	var members []*models.Reservation
+	for _, room := range rooms {
+		r, err := Assign(o, g, room.Id, servicetest.Guest(t, o).Id, 1, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		members = append(members, r)
+	}
+	if n, err := o.QueryTable(new(models.Invoice)).Filter("ReservationId", members[0].Id).Count(); err != nil || n != 0 {
+		t.Errorf("%d invoices of a guest of a master invoice group, %v, want none", n, err)
+	}
+
+	v, err := MasterInvoice(o, g, now)
+	if err != nil {
+		t.Fatalf("MasterInvoice error: %v", err)
+	}
+	want, err := members[0].TotalPrice.Add(members[1].TotalPrice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.GuestId.Id != organizer.Id || v.ReservationId.Id != g.BlockId.Id || v.Amount != want {
+		t.Errorf("master invoice = %+v, want %v billed to %d on the block", v, want, organizer.Id)
+	}
+}
+
+func TestReleaser(t *testing.T) {
+	o := servicetest.Ormer(t)
+	h, rooms := servicetest.Hotel(t, o, 2, types.NewMoney(1000000, types.DefaultCurrency))
+	now := time.Now().Truncate(time.Second)
+	from := servicetest.Date(now.Year()+1, now.Month(), 1)
+	to := from.AddDate(0, 0, 1)
+	g := &models.GroupBooking{Name: servicetest.Name("Group"), OrganizerId: servicetest.Guest(t, o), HotelId: h, StartDate: from, EndDate: to,
+		ReleaseDate: servicetest.Date(now.Year(), now.Month(), now.Day()).AddDate(0, 0, -1)}
+	if err := Create(o, g, []int{rooms[0].Id, rooms[1].Id}, now); err != nil {
+		t.Fatal(err)
+	}
+	member, err := Assign(o, g, rooms[0].Id, servicetest.Guest(t, o).Id, 1, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waiting := &models.WaitlistEntry{GuestId: servicetest.Guest(t, o), HotelId: h, StartDate: from, EndDate: to, Rooms: 1, Guests: 1,
+		Status: models.WaitlistWaiting, CreatedAt: now, UpdatedAt: now}
+	servicetest.Insert(t, o, waiting)
+
+	if _, err = NewReleaser().RunOnce(now); err != nil {
+		t.Fatalf("RunOnce error: %v", err)
+	}
+	released, err := models.GetGroupBookingById(o, g.Id)
+	if err != nil || released.ReleasedAt.IsZero() {
+		t.Fatalf("group = %+v, %v, want released", released, err)
+	}
+	block := &models.Reservation{Id: g.BlockId.Id}
+	if err = o.Read(block); err != nil || block.Status != models.ReservationCancelled {
+		t.Errorf("block = %+v, %v, want canceled", block, err)
+	}
+	if rrs, err := models.GetRoomReservedByReservation(o, member.Id); err != nil || len(rrs) != 1 {
+		t.Errorf("rooms of the guest after release = %+v, %v, want kept", rrs, err)
+	}
+	if e, err := models.GetWaitlistEntryById(o, waiting.Id); err != nil || e.Status != models.WaitlistOffered {
+		t.Errorf("waiting entry = %+v, %v, want offered the released room", e, err)
+	}
+	if err = Release(o, released, now); err != ErrReleased {
+		t.Errorf("Release of a released group error = %v, want ErrReleased", err)
+	}
+	if _, err = Assign(o, released, rooms[1].Id, servicetest.Guest(t, o).Id, 1, now); err != ErrReleased {
+		t.Errorf("Assign of a released group error = %v, want ErrReleased", err)
+	}
+}
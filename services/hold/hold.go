@@ -0,0 +1,152 @@
+// Package hold keeps rooms for a guest for a short time while they check
+// out, until the hold is converted into a reservation or expires.
+package hold
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"easybook/models"
+	"easybook/services/availability"
+	"easybook/services/periodic"
+	"easybook/types"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/orm"
+)
+
+// Errors of holds which cannot be converted.
+var (
+	ErrExpired   = errors.New("hold: hold has expired")
+	ErrConverted = errors.New("hold: hold is already converted")
+	ErrMismatch  = errors.New("hold: guest, dates or rooms differ from the hold")
+)
+
+// IsError reports whether err is caused by the hold or the rooms asked
+// rather than by the database.
+func IsError(err error) bool {
+	return err == availability.ErrUnavailable || strings.HasPrefix(err.Error(), "hold: ")
+}
+
+// TTL returns how long a hold keeps its rooms, the holdttl key of app.conf
+// in seconds.
+func TTL() time.Duration {
+	return time.Duration(beego.AppConfig.DefaultInt("holdttl", 900)) * time.Second
+}
+
+// Place holds roomIDs for guest guestID for the nights from from until the
+// day before to, until now plus TTL, within the transaction of o. It fails
+// with availability.ErrUnavailable if a room is reserved or held.
+func Place(o orm.Ormer, guestID int, roomIDs []int, from time.Time, to time.Time, now time.Time) (*models.Hold, error) {
+	switch {
+	case len(roomIDs) == 0:
+		return nil, errors.New("hold: rooms are required")
+	case !from.Before(to):
+		return nil, errors.New("hold: end date must follow start date")
+	}
+	if err := availability.Check(o, roomIDs, from, to, now, 0); err != nil {
+		return nil, err
+	}
+
+	v := &models.Hold{
+		GuestId:   &models.Guest{Id: guestID},
+		StartDate: from,
+		EndDate:   to,
+		ExpiresAt: now.Add(TTL()),
+		CreatedAt: now,
+		Rooms:     roomIDs,
+	}
+	if _, err := o.Insert(v); err != nil {
+		return nil, err
+	}
+	for _, id := range roomIDs {
+		if _, err := o.Insert(&models.HoldRoom{HoldId: v, RoomId: &models.Room{Id: id}}); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// Convert converts hold holdID into reservation r of roomIDs within the
+// transaction of o. The reservation must be of the guest, dates and rooms
+// of the hold.
+func Convert(o orm.Ormer, holdID int, r *models.Reservation, roomIDs []int, now time.Time) error {
+	v := &models.Hold{Id: holdID}
+	if err := o.ReadForUpdate(v); err == orm.ErrNoRows {
+		return ErrMismatch
+	} else if err != nil {
+		return err
+	}
+	switch {
+	case v.ReservationId != nil:
+		return ErrConverted
+	case !now.Before(v.ExpiresAt):
+		return ErrExpired
+	case v.GuestId.Id != r.GuestId.Id || !types.SameDay(v.StartDate, r.StartDate) || !types.SameDay(v.EndDate, r.EndDate):
+		return ErrMismatch
+	}
+	held, err := models.GetHoldRoomIds(o, holdID)
+	if err != nil {
+		return err
+	}
+	if !sameIds(held, roomIDs) {
+		return ErrMismatch
+	}
+
+	v.ReservationId = r
+	_, err = o.Update(v, "ReservationId")
+	return err
+}
+
+// Sweeper deletes expired holds in background. Availability ignores
+// expired holds already, sweeping keeps the table small.
+type Sweeper struct {
+	// Interval is the time between two sweeps.
+	Interval time.Duration
+
+	runner periodic.Runner
+}
+
+// NewSweeper creates a Sweeper configured by the hold* keys of app.conf.
+func NewSweeper() *Sweeper {
+	return &Sweeper{
+		Interval: time.Duration(beego.AppConfig.DefaultInt("holdsweepinterval", 60)) * time.Second,
+	}
+}
+
+// Start runs the sweeper in background until Stop is called.
+func (s *Sweeper) Start() {
+	s.runner.Start("hold: sweep", s.Interval, func(now time.Time) error {
+		_, err := s.RunOnce(now)
+		return err
+	})
+}
+
+// Stop stops the sweeper and waits for the current sweep to finish.
+func (s *Sweeper) Stop() {
+	s.runner.Stop()
+}
+
+// RunOnce deletes the holds expired at now and returns their number.
+func (s *Sweeper) RunOnce(now time.Time) (int64, error) {
+	return models.DeleteExpiredHolds(orm.NewOrm(), now)
+}
+
+// sameIds reports whether a and b hold the same ids.
+func sameIds(a []int, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]int(nil), a...)
+	b = append([]int(nil), b...)
+	sort.Ints(a)
+	sort.Ints(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
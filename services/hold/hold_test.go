@@ -0,0 +1,165 @@
+package hold
+
+import (
+	"testing"
+	"time"
+
+	"easybook/models"
+	"easybook/services/availability"
+	"easybook/services/servicetest"
+	"easybook/types"
+)
+
+func TestMain(m *testing.M) {
+	servicetest.Main(m)
+}
+
+func TestPlace(t *testing.T) {
+	o := servicetest.Ormer(t)
+	_, rooms := servicetest.Hotel(t, o, 2, types.NewMoney(1000000, types.DefaultCurrency))
+	first, second := rooms[0].Id, rooms[1].Id
+	g1, g2 := servicetest.Guest(t, o), servicetest.Guest(t, o)
+	from := servicetest.Date(2030, 5, 10)
+	to := from.AddDate(0, 0, 3)
+	servicetest.Reservation(t, o, g2, rooms[1:], from.AddDate(0, 0, 2), to, models.ReservationConfirmed)
+	now := time.Now().Truncate(time.Second)
+
+	v, err := Place(o, g1.Id, []int{first}, from, to, now)
+	if err != nil {
+		t.Fatalf("Place error: %v", err)
+	}
+	if !v.ExpiresAt.Equal(now.Add(TTL())) {
+		t.Errorf("hold expires at %v, want %v", v.ExpiresAt, now.Add(TTL()))
+	}
+	if held, err := models.GetHoldRoomIds(o, v.Id); err != nil || len(held) != 1 || held[0] != first {
+		t.Errorf("rooms held = %v, %v, want [%d]", held, err, first)
+	}
+
+	cases := []struct {
+		name     string
+		rooms    []int
+		from, to time.Time
+		now      time.Time
+		err      error
+	}{
+		{"held night", []int{first}, from.AddDate(0, 0, 2), to.AddDate(0, 0, 2), now, availability.ErrUnavailable},
+		{"reserved night", []int{second}, from, from.AddDate(0, 0, 3), now, availability.ErrUnavailable},
+		{"free night", []int{second}, from, from.AddDate(0, 0, 2), now, nil},
+		{"after the hold", []int{first}, to, to.AddDate(0, 0, 1), now, nil},
+		{"hold expired", []int{first}, from, to, now.Add(TTL()), nil},
+	}
+	for _, c := range cases {
+		_, err := Place(o, g2.Id, c.rooms, c.from, c.to, c.now)
+		if err != c.err {
+			t.Errorf("%s: Place error = %v, want %v", c.name, err, c.err)
+		}
+		if err != nil && !IsError(err) {
+			t.Errorf("%s: IsError(%v) = false", c.name, err)
+		}
+	}
+
+	for _, c := range []struct {
+		name     string
+		rooms    []int
+		from, to time.Time
+	}{
+		{"no rooms", nil, from, to},
+		{"empty stay", []int{second}, to, to},
+		{"reversed stay", []int{second}, to, from},
+	} {
+		if _, err := Place(o, g1.Id, c.rooms, c.from, c.to, now); err == nil || !IsError(err) {
+			t.Errorf("%s: Place error = %v, want a hold error", c.name, err)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	o := servicetest.Ormer(t)
+	_, rooms := servicetest.Hotel(t, o, 2, types.NewMoney(1000000, types.DefaultCurrency))
+	g, other := servicetest.Guest(t, o), servicetest.Guest(t, o)
+	from := servicetest.Date(2030, 6, 1)
+	to := from.AddDate(0, 0, 2)
+	now := time.Now().Truncate(time.Second)
+	ids := []int{rooms[0].Id, rooms[1].Id}
+
+	v, err := Place(o, g.Id, ids, from, to, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := servicetest.Reservation(t, o, g, rooms, from, to, models.ReservationPending)
+
+	cases := []struct {
+		name  string
+		id    int
+		r     *models.Reservation
+		rooms []int
+		now   time.Time
+		err   error
+	}{
+		{"unknown hold", -1, r, ids, now, ErrMismatch},
+		{"other guest", v.Id, &models.Reservation{Id: r.Id, GuestId: other, StartDate: from, EndDate: to}, ids, now, ErrMismatch},
+		{"other dates", v.Id, &models.Reservation{Id: r.Id, GuestId: g, StartDate: from, EndDate: to.AddDate(0, 0, 1)}, ids, now, ErrMismatch},
+		{"fewer rooms", v.Id, r, ids[:1], now, ErrMismatch},
+		{"other rooms", v.Id, r, []int{ids[0], ids[0]}, now, ErrMismatch},
+		{"expired", v.Id, r, ids, v.ExpiresAt, ErrExpired},
+		{"rooms in another order", v.Id, r, []int{ids[1], ids[0]}, now, nil},
+		{"converted", v.Id, r, ids, now, ErrConverted},
+	}
+	for _, c := range cases {
+		if err := Convert(o, c.id, c.r, c.rooms, c.now); err != c.err {
+			t.Errorf("%s: Convert error = %v, want %v", c.name, err, c.err)
+		}
+	}
+
+	got, err := models.GetHoldById(o, v.Id)
+	if err != nil || got.ReservationId == nil || got.ReservationId.Id != r.Id {
+		t.Errorf("converted hold = %+v, %v, want reservation %d", got, err, r.Id)
+	}
+	// the rooms of a converted hold are reserved, not held
+	if held, err := models.GetHeldRoomIds(o, ids, from, to, now, 0); err != nil || len(held) != 0 {
+		t.Errorf("rooms held after conversion = %v, %v, want none", held, err)
+	}
+}
+
+func TestSweeper(t *testing.T) {
+	o := servicetest.Ormer(t)
+	_, rooms := servicetest.Hotel(t, o, 3, types.NewMoney(1000000, types.DefaultCurrency))
+	g := servicetest.Guest(t, o)
+	from := servicetest.Date(2030, 7, 1)
+	to := from.AddDate(0, 0, 1)
+	now := time.Now().Truncate(time.Second)
+
+	expired, err := Place(o, g.Id, []int{rooms[0].Id}, from, to, now.Add(-TTL()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	converted, err := Place(o, g.Id, []int{rooms[1].Id}, from, to, now.Add(-TTL()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := servicetest.Reservation(t, o, g, rooms[1:2], from, to, models.ReservationPending)
+	if err = Convert(o, converted.Id, r, []int{rooms[1].Id}, now.Add(-TTL())); err != nil {
+		t.Fatal(err)
+	}
+	current, err := Place(o, g.Id, []int{rooms[2].Id}, from, to, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = NewSweeper().RunOnce(now); err != nil {
+		t.Fatalf("RunOnce error: %v", err)
+	}
+	for _, c := range []struct {
+		name string
+		v    *models.Hold
+		kept bool
+	}{
+		{"expired", expired, false},
+		{"converted", converted, true},
+		{"current", current, true},
+	} {
+		if _, err := models.GetHoldById(o, c.v.Id); (err == nil) != c.kept {
+			t.Errorf("%s hold: reading after the sweep error = %v, want kept %v", c.name, err, c.kept)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Package idempotency stores the responses of requests made under an
+// Idempotency-Key header, so that a retried request gets the original
+// response instead of being applied again.
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+
+	"easybook/models"
+	"easybook/services/periodic"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/orm"
+)
+
+// Errors of keys which cannot be used for a request.
+var (
+	ErrConflict   = errors.New("idempotency: key was used for a different request")
+	ErrInProgress = errors.New("idempotency: a request with this key is in progress")
+)
+
+// TTL returns how long the response of a key is kept, the idempotencyttl
+// key of app.conf in hours.
+func TTL() time.Duration {
+	return time.Duration(beego.AppConfig.DefaultInt("idempotencyttl", 24)) * time.Hour
+}
+
+// Lock returns how long a request in progress holds its key, the
+// idempotencylock key of app.conf in seconds. A key held longer is taken
+// over by a retry, as its request is assumed to have died.
+func Lock() time.Duration {
+	return time.Duration(beego.AppConfig.DefaultInt("idempotencylock", 60)) * time.Second
+}
+
+// Fingerprint identifies a request by its method, path, raw query and
+// body.
+func Fingerprint(method string, path string, query string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "?" + query + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Begin starts the request of fingerprint under key in scope. The key
+// returned has a non zero Status when the request was completed already,
+// its Response is to be replayed. Otherwise the request goes on and its
+// response is stored by Finish. A key used for another request fails with
+// ErrConflict, a key of a request in progress with ErrInProgress. A key
+// older than TTL which is not swept yet is used anew. Begin runs outside of
+// any transaction of the request, so concurrent retries see the key.
+func Begin(scope string, key string, fingerprint string, now time.Time) (*models.IdempotencyKey, error) {
+	o := orm.NewOrm()
+	v := &models.IdempotencyKey{
+		Scope:       scope,
+		Key:         key,
+		Fingerprint: fingerprint,
+		CreatedAt:   now,
+	}
+	created, _, err := o.ReadOrCreate(v, "Scope", "Key")
+	if err != nil {
+		// a concurrent request created the key first
+		if o.Read(v, "Scope", "Key") != nil {
+			return nil, err
+		}
+	}
+	if created {
+		return v, nil
+	}
+
+	if now.Sub(v.CreatedAt) >= TTL() {
+		return renew(o, v, fingerprint, now)
+	}
+	switch {
+	case v.Fingerprint != fingerprint:
+		return nil, ErrConflict
+	case v.Status != 0:
+		return v, nil
+	case now.Sub(v.CreatedAt) < Lock():
+		return nil, ErrInProgress
+	}
+	// take over the key of a request which died, unless another retry did
+	n, err := o.QueryTable(new(models.IdempotencyKey)).
+		Filter("Id", v.Id).Filter("Status", 0).Filter("CreatedAt", v.CreatedAt).
+		Update(orm.Params{"CreatedAt": now})
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrInProgress
+	}
+	v.CreatedAt = now
+	return v, nil
+}
+
+// renew uses the expired key v for the request of fingerprint, unless a
+// concurrent request did.
+func renew(o orm.Ormer, v *models.IdempotencyKey, fingerprint string, now time.Time) (*models.IdempotencyKey, error) {
+	n, err := o.QueryTable(new(models.IdempotencyKey)).
+		Filter("Id", v.Id).Filter("CreatedAt", v.CreatedAt).
+		Update(orm.Params{"Fingerprint": fingerprint, "Status": 0, "Response": "", "CreatedAt": now})
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrInProgress
+	}
+	v.Fingerprint, v.Status, v.Response, v.CreatedAt = fingerprint, 0, "", now
+	return v, nil
+}
+
+// Finish stores the response of the request of v. Server errors are not
+// stored, the key is released so the request can be retried.
+func Finish(v *models.IdempotencyKey, status int, body []byte) error {
+	if status == 0 {
+		status = http.StatusOK
+	}
+	o := orm.NewOrm()
+	if status >= http.StatusInternalServerError {
+		_, err := o.Delete(v)
+		return err
+	}
+	v.Status = status
+	v.Response = string(body)
+	_, err := o.Update(v, "Status", "Response")
+	return err
+}
+
+// Sweeper deletes the keys older than TTL in background, so that requests
+// do not pay for it.
+type Sweeper struct {
+	// Interval is the time between two sweeps.
+	Interval time.Duration
+
+	runner periodic.Runner
+}
+
+// NewSweeper creates a Sweeper configured by the idempotency* keys of
+// app.conf.
+func NewSweeper() *Sweeper {
+	return &Sweeper{
+		Interval: time.Duration(beego.AppConfig.DefaultInt("idempotencysweepinterval", 3600)) * time.Second,
+	}
+}
+
+// Start runs the sweeper in background until Stop is called.
+func (s *Sweeper) Start() {
+	s.runner.Start("idempotency: sweep", s.Interval, func(now time.Time) error {
+		_, err := s.RunOnce(now)
+		return err
+	})
+}
+
+// Stop stops the sweeper and waits for the current sweep to finish.
+func (s *Sweeper) Stop() {
+	s.runner.Stop()
+}
+
+// RunOnce deletes the keys older than TTL at now and returns their number.
+func (s *Sweeper) RunOnce(now time.Time) (int64, error) {
+	return models.DeleteIdempotencyKeysBefore(orm.NewOrm(), now.Add(-TTL()))
+}
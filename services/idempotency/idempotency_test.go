@@ -0,0 +1,127 @@
+package idempotency
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"easybook/models"
+	"easybook/services/servicetest"
+)
+
+func TestMain(m *testing.M) {
+	servicetest.Main(m)
+}
+
+func TestFingerprint(t *testing.T) {
+	base := Fingerprint("POST", "/v1/booking/reserve", "currency=USD", []byte(`{"rooms":[1]}`))
+	cases := []struct {
+		name        string
+		fingerprint string
+	}{
+		{"method", Fingerprint("PUT", "/v1/booking/reserve", "currency=USD", []byte(`{"rooms":[1]}`))},
+		{"path", Fingerprint("POST", "/v1/booking/hold", "currency=USD", []byte(`{"rooms":[1]}`))},
+		{"query", Fingerprint("POST", "/v1/booking/reserve", "currency=EUR", []byte(`{"rooms":[1]}`))},
+		{"no query", Fingerprint("POST", "/v1/booking/reserve", "", []byte(`{"rooms":[1]}`))},
+		{"body", Fingerprint("POST", "/v1/booking/reserve", "currency=USD", []byte(`{"rooms":[2]}`))},
+	}
+	for _, c := range cases {
+		if c.fingerprint == base {
+			t.Errorf("requests differing by %s have the same fingerprint", c.name)
+		}
+	}
+	if again := Fingerprint("POST", "/v1/booking/reserve", "currency=USD", []byte(`{"rooms":[1]}`)); again != base {
+		t.Errorf("fingerprint of the same request = %s, want %s", again, base)
+	}
+}
+
+func TestBegin(t *testing.T) {
+	servicetest.Ormer(t)
+	scope := servicetest.Name("guest")
+	first := Fingerprint("POST", "/v1/booking/reserve", "", []byte("first"))
+	other := Fingerprint("POST", "/v1/booking/reserve", "", []byte("other"))
+	now := time.Now().Truncate(time.Second)
+
+	v, err := Begin(scope, "key", first, now)
+	if err != nil || v.Status != 0 {
+		t.Fatalf("Begin of a new key = %+v, %v, want a key in progress", v, err)
+	}
+	if _, err = Begin(scope, "key", first, now.Add(time.Second)); err != ErrInProgress {
+		t.Errorf("Begin of a key in progress error = %v, want ErrInProgress", err)
+	}
+	if _, err = Begin(scope, "key", other, now.Add(time.Second)); err != ErrConflict {
+		t.Errorf("Begin of a key of another request error = %v, want ErrConflict", err)
+	}
+	if v, err = Begin(servicetest.Name("guest"), "key", other, now); err != nil || v.Status != 0 {
+		t.Errorf("Begin of the key in another scope = %+v, %v, want a key in progress", v, err)
+	}
+
+	// a retry takes over the key once the request held it Lock
+	retry, err := Begin(scope, "key", first, now.Add(Lock()))
+	if err != nil || !retry.CreatedAt.Equal(now.Add(Lock())) {
+		t.Fatalf("Begin after Lock = %+v, %v, want the key taken over", retry, err)
+	}
+	if err = Finish(retry, http.StatusCreated, []byte(`{"Id":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	v, err = Begin(scope, "key", first, now.Add(2*Lock()))
+	if err != nil || v.Status != http.StatusCreated || v.Response != `{"Id":1}` {
+		t.Errorf("Begin of a finished key = %+v, %v, want the response replayed", v, err)
+	}
+	if _, err = Begin(scope, "key", other, now.Add(2*Lock())); err != ErrConflict {
+		t.Errorf("Begin of a finished key of another request error = %v, want ErrConflict", err)
+	}
+
+	// an expired key is used anew, even by another request
+	expired := now.Add(Lock() + TTL())
+	v, err = Begin(scope, "key", other, expired)
+	if err != nil || v.Status != 0 || v.Fingerprint != other || !v.CreatedAt.Equal(expired) {
+		t.Errorf("Begin of an expired key = %+v, %v, want it renewed", v, err)
+	}
+}
+
+func TestFinishServerError(t *testing.T) {
+	servicetest.Ormer(t)
+	scope := servicetest.Name("guest")
+	fingerprint := Fingerprint("POST", "/v1/payment", "", nil)
+	now := time.Now().Truncate(time.Second)
+
+	v, err := Begin(scope, "key", fingerprint, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = Finish(v, http.StatusBadGateway, []byte("provider down")); err != nil {
+		t.Fatal(err)
+	}
+	// the key was released, the retry goes on at once
+	if v, err = Begin(scope, "key", fingerprint, now.Add(time.Second)); err != nil || v.Status != 0 {
+		t.Errorf("Begin after a server error = %+v, %v, want a key in progress", v, err)
+	}
+}
+
+func TestSweeper(t *testing.T) {
+	o := servicetest.Ormer(t)
+	scope := servicetest.Name("guest")
+	fingerprint := Fingerprint("POST", "/v1/booking/reserve", "", nil)
+	now := time.Now().Truncate(time.Second)
+
+	old, err := Begin(scope, "old", fingerprint, now.Add(-TTL()-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recent, err := Begin(scope, "recent", fingerprint, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := NewSweeper().RunOnce(now)
+	if err != nil || n < 1 {
+		t.Fatalf("RunOnce = %d, %v, want the old key deleted", n, err)
+	}
+	if err = o.Read(&models.IdempotencyKey{Id: old.Id}); err == nil {
+		t.Errorf("key older than TTL was not deleted")
+	}
+	if err = o.Read(&models.IdempotencyKey{Id: recent.Id}); err != nil {
+		t.Errorf("reading the key younger than TTL: %v", err)
+	}
+}
@@ -0,0 +1,247 @@
+// Package invoice bills reservations.
+package invoice
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"easybook/models"
+	"easybook/services/pricing"
+	"easybook/types"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/orm"
+)
+
+// ErrPaid is returned when repricing an invoice which is paid already.
+var ErrPaid = errors.New("invoice: invoice is paid")
+
+// Generate issues the invoice of a confirmed reservation within the
+// transaction of o: a line per night of every reserved room, the discounts
+// of the reservation and the taxes and fees of the tax rules, as priced by
+// pricing.Quote. An invoice already issued for the reservation and not
+// canceled is returned instead.
+func Generate(o orm.Ormer, r *models.Reservation, hotelID int, now time.Time) (*models.Invoice, error) {
+	if v, err := models.GetActiveInvoice(o, r.Id); err != orm.ErrNoRows {
+		return v, err
+	}
+
+	v := &models.Invoice{
+		GuestId:         r.GuestId,
+		ReservationId:   r,
+		DisplayCurrency: r.DisplayCurrency,
+		ExchangeRate:    r.ExchangeRate,
+		IssuedAt:        now,
+	}
+	lines, err := price(o, v, r, hotelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if hotelID != 0 {
+		seq, err := models.NextInvoiceSequence(o, hotelID)
+		if err != nil {
+			return nil, err
+		}
+		v.HotelId = &models.Hotel{Id: hotelID}
+		v.Number = Number(hotelID, seq)
+	}
+
+	if _, err = o.Insert(v); err != nil {
+		return nil, err
+	}
+	return v, insertLines(o, v, lines)
+}
+
+// Reprice replaces the lines and amounts of the unpaid invoice v of
+// reservation r within the transaction of o, after the dates or rooms of r
+// changed. The invoice keeps its number.
+func Reprice(o orm.Ormer, v *models.Invoice, r *models.Reservation, hotelID int) error {
+	if !v.PaidAt.IsZero() {
+		return ErrPaid
+	}
+	if _, err := models.DeleteInvoiceLines(o, v.Id); err != nil {
+		return err
+	}
+	lines, err := price(o, v, r, hotelID)
+	if err != nil {
+		return err
+	}
+	if _, err = o.Update(v, "Subtotal", "DiscountAmount", "TaxAmount", "FeeAmount", "Amount"); err != nil {
+		return err
+	}
+	return insertLines(o, v, lines)
+}
+
+// Master issues the master invoice of a group within the transaction of o,
+// billed to organizer on the block reservation of the group: the lines of
+// the reservations of members, described with their guest, and the sum of
+// their amounts. An unpaid master invoice is updated with the current
+// members and keeps its number, a paid one is returned as is.
+func Master(o orm.Ormer, block *models.Reservation, organizer *models.Guest, members []models.Reservation, hotelID int, now time.Time) (*models.Invoice, error) {
+	v, err := models.GetActiveInvoice(o, block.Id)
+	switch {
+	case err == orm.ErrNoRows:
+		v = &models.Invoice{
+			GuestId:         organizer,
+			ReservationId:   block,
+			DisplayCurrency: block.DisplayCurrency,
+			ExchangeRate:    block.ExchangeRate,
+			IssuedAt:        now,
+		}
+	case err != nil:
+		return nil, err
+	case !v.PaidAt.IsZero():
+		return v, nil
+	default:
+		if _, err = models.DeleteInvoiceLines(o, v.Id); err != nil {
+			return nil, err
+		}
+	}
+
+	currency := block.TotalPrice.Currency
+	if currency == "" {
+		currency = types.DefaultCurrency
+	}
+	zero := types.NewMoney(0, currency)
+	v.Subtotal, v.DiscountAmount, v.TaxAmount, v.FeeAmount, v.Amount = zero, zero, zero, zero, zero
+	var lines []*models.InvoiceLine
+	for i := range members {
+		r := &members[i]
+		guest := &models.Guest{Id: r.GuestId.Id}
+		if err = o.Read(guest); err != nil {
+			return nil, err
+		}
+		var part models.Invoice
+		l, err := price(o, &part, r, hotelID)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range l {
+			line.Description = fmt.Sprintf("%s %s: %s", guest.FirstName, guest.LastName, line.Description)
+		}
+		lines = append(lines, l...)
+		if err = addTotals(v, &part); err != nil {
+			return nil, err
+		}
+	}
+
+	if v.Id != 0 {
+		_, err = o.Update(v, "Subtotal", "DiscountAmount", "TaxAmount", "FeeAmount", "Amount")
+	} else {
+		if hotelID != 0 {
+			seq, err := models.NextInvoiceSequence(o, hotelID)
+			if err != nil {
+				return nil, err
+			}
+			v.HotelId = &models.Hotel{Id: hotelID}
+			v.Number = Number(hotelID, seq)
+		}
+		_, err = o.Insert(v)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return v, insertLines(o, v, lines)
+}
+
+// price sets the amounts of invoice v from the quote of r and returns its
+// lines.
+func price(o orm.Ormer, v *models.Invoice, r *models.Reservation, hotelID int) ([]*models.InvoiceLine, error) {
+	b, err := pricing.Quote(o, r, hotelID)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []*models.InvoiceLine
+	for _, night := range b.Nights {
+		rr := night.RoomReserved
+		lines = append(lines, &models.InvoiceLine{
+			Kind:           models.LineRoomNight,
+			Description:    fmt.Sprintf("Room %d %s, night of %s", rr.RoomId.Number, rr.RoomId.Name, night.Date.Format("2006-01-02")),
+			RoomReservedId: rr,
+			Date:           night.Date.Time,
+			Quantity:       1,
+			UnitPrice:      night.Price,
+			Amount:         night.Price,
+		})
+	}
+
+	v.Subtotal = b.Rooms
+	v.DiscountAmount = b.Discount
+	v.TaxAmount = b.Tax
+	v.FeeAmount = b.Fees
+	v.Amount = b.Total
+
+	for _, d := range b.Discounts {
+		lines = append(lines, &models.InvoiceLine{
+			Kind:        models.LineDiscount,
+			Description: d.Name,
+			Quantity:    1,
+			UnitPrice:   d.Amount.Neg(),
+			Amount:      d.Amount.Neg(),
+		})
+	}
+
+	for _, c := range b.Charges {
+		kind := models.LineTax
+		if c.Category == models.CategoryFee {
+			kind = models.LineFee
+		}
+		lines = append(lines, &models.InvoiceLine{
+			Kind:        kind,
+			Description: c.Name,
+			Quantity:    c.Quantity,
+			UnitPrice:   c.UnitPrice,
+			Amount:      c.Amount,
+		})
+	}
+	return lines, nil
+}
+
+func insertLines(o orm.Ormer, v *models.Invoice, lines []*models.InvoiceLine) error {
+	for _, line := range lines {
+		line.InvoiceId = v
+		if _, err := o.Insert(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Cancel cancels the unpaid invoice of a reservation within the transaction
+// of o. Paid invoices are kept, they are refunded instead.
+func Cancel(o orm.Ormer, reservationID int, now time.Time) error {
+	_, err := o.QueryTable(new(models.Invoice)).
+		Filter("ReservationId", reservationID).
+		Filter("PaidAt__isnull", true).
+		Filter("CanceledAt__isnull", true).
+		Update(orm.Params{
+			"CanceledAt": now,
+		})
+	return err
+}
+
+// Number formats the seq-th invoice number of a hotel, e.g. INV-3-000042.
+func Number(hotelID int, seq int64) string {
+	return fmt.Sprintf("%s-%d-%06d", beego.AppConfig.DefaultString("invoiceprefix", "INV"), hotelID, seq)
+}
+
+// addTotals adds the totals of part to the totals of v.
+func addTotals(v *models.Invoice, part *models.Invoice) (err error) {
+	if v.Subtotal, err = v.Subtotal.Add(part.Subtotal); err != nil {
+		return err
+	}
+	if v.DiscountAmount, err = v.DiscountAmount.Add(part.DiscountAmount); err != nil {
+		return err
+	}
+	if v.TaxAmount, err = v.TaxAmount.Add(part.TaxAmount); err != nil {
+		return err
+	}
+	if v.FeeAmount, err = v.FeeAmount.Add(part.FeeAmount); err != nil {
+		return err
+	}
+	v.Amount, err = v.Amount.Add(part.Amount)
+	return err
+}
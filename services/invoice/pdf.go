@@ -0,0 +1,245 @@
+package invoice
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"easybook/models"
+	"easybook/services/pdf"
+	"easybook/types"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/logs"
+	"github.com/astaxie/beego/orm"
+)
+
+const (
+	margin   = 50.0
+	rowSize  = 9.0
+	rowSpace = 16.0
+)
+
+// PDF returns the PDF of invoice v. When invoicepdfcache is on, documents
+// are cached in invoicepdfdir under a name derived from everything they
+// show that may change, so a stale document is never served.
+func PDF(v *models.Invoice) ([]byte, error) {
+	o := orm.NewOrm()
+	var t *models.InvoiceTemplate
+	if v.HotelId != nil {
+		t, _ = models.GetInvoiceTemplateByHotel(v.HotelId.Id)
+	}
+	paid, err := models.CapturedAmount(o, v.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !beego.AppConfig.DefaultBool("invoicepdfcache", true) {
+		return render(o, v, t, paid)
+	}
+
+	key := fmt.Sprintf("%d-%d-%d-%d-%d-%d", v.Id, v.UpdatedAt.Unix(), v.Amount.Amount,
+		v.PaidAt.Unix(), v.CanceledAt.Unix(), paid.Amount)
+	if t != nil {
+		key += fmt.Sprintf("-%d-%d", t.Id, t.UpdatedAt.Unix())
+	}
+	dir := beego.AppConfig.DefaultString("invoicepdfdir", filepath.Join("cache", "invoices"))
+	path := filepath.Join(dir, key+".pdf")
+	if b, err := ioutil.ReadFile(path); err == nil {
+		return b, nil
+	}
+
+	b, err := render(o, v, t, paid)
+	if err != nil {
+		return nil, err
+	}
+	// caching is best effort, the document is served anyway
+	if err = os.MkdirAll(dir, 0755); err == nil {
+		tmp := path + ".tmp"
+		if err = ioutil.WriteFile(tmp, b, 0644); err == nil {
+			err = os.Rename(tmp, path)
+		}
+	}
+	if err != nil {
+		logs.Warn("invoice %d: caching pdf failed: %v", v.Id, err)
+	}
+	return b, nil
+}
+
+// render draws invoice v on the letterhead t, which may be nil. paid is
+// the amount captured by its payments.
+func render(o orm.Ormer, v *models.Invoice, t *models.InvoiceTemplate, paid types.Money) ([]byte, error) {
+	guest := &models.Guest{Id: v.GuestId.Id}
+	if err := o.Read(guest); err != nil {
+		return nil, err
+	}
+	var hotel *models.Hotel
+	if v.HotelId != nil {
+		hotel = &models.Hotel{Id: v.HotelId.Id}
+		if err := o.Read(hotel); err != nil {
+			return nil, err
+		}
+		if hotel.CityId != nil {
+			_ = o.Read(hotel.CityId)
+		}
+	}
+	lines, err := models.GetInvoiceLines(o, v.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := pdf.New()
+	width, height := doc.Size()
+	right := width - margin
+	doc.AddPage()
+
+	// letterhead: logo and hotel on the left, invoice details on the right
+	y := margin
+	if t != nil && t.LogoPath != "" {
+		f, err := os.Open(t.LogoPath)
+		if err == nil {
+			err = doc.Image(f, margin, y, 120, 50)
+			f.Close()
+		}
+		if err != nil {
+			logs.Warn("invoice %d: logo %s: %v", v.Id, t.LogoPath, err)
+		} else {
+			y += 60
+		}
+	}
+	top := y
+	if hotel != nil {
+		y += 12
+		doc.Text(margin, y, 12, true, pdf.Left, hotel.Name)
+		for _, s := range pdf.Wrap(hotel.Address, rowSize, false, 250) {
+			y += 12
+			doc.Text(margin, y, rowSize, false, pdf.Left, s)
+		}
+		if hotel.CityId != nil && hotel.CityId.Name != "" {
+			y += 12
+			doc.Text(margin, y, rowSize, false, pdf.Left, fmt.Sprintf("%s %d", hotel.CityId.Name, hotel.CityId.PostCode))
+		}
+	}
+	if t != nil && t.Header != "" {
+		for _, s := range pdf.Wrap(t.Header, rowSize, false, 250) {
+			y += 12
+			doc.Text(margin, y, rowSize, false, pdf.Left, s)
+		}
+	}
+
+	ry := top + 16
+	doc.Text(right, ry, 18, true, pdf.Right, "INVOICE")
+	number := v.Number
+	if number == "" {
+		number = fmt.Sprintf("#%d", v.Id)
+	}
+	ry += 18
+	doc.Text(right, ry, 10, true, pdf.Right, number)
+	ry += 14
+	doc.Text(right, ry, rowSize, false, pdf.Right, "Issued "+v.IssuedAt.Format("2006-01-02"))
+	ry += 12
+	doc.Text(right, ry, rowSize, false, pdf.Right, fmt.Sprintf("Reservation #%d", v.ReservationId.Id))
+	if ry > y {
+		y = ry
+	}
+
+	// billing address
+	y += 30
+	doc.Text(margin, y, rowSize, true, pdf.Left, "BILL TO")
+	y += 14
+	doc.Text(margin, y, 10, false, pdf.Left, strings.TrimSpace(guest.FirstName+" "+guest.LastName))
+	for _, s := range pdf.Wrap(guest.Address, rowSize, false, 250) {
+		if s == "" {
+			continue
+		}
+		y += 12
+		doc.Text(margin, y, rowSize, false, pdf.Left, s)
+	}
+	y += 12
+	doc.Text(margin, y, rowSize, false, pdf.Left, guest.Email)
+
+	// line items
+	cols := []float64{margin + 6, right - 170, right - 90, right - 6}
+	y += 30
+	doc.Rect(margin, y-12, right-margin, 18, 0.9)
+	doc.Text(cols[0], y, rowSize, true, pdf.Left, "Description")
+	doc.Text(cols[1], y, rowSize, true, pdf.Right, "Qty")
+	doc.Text(cols[2], y, rowSize, true, pdf.Right, "Unit price")
+	doc.Text(cols[3], y, rowSize, true, pdf.Right, "Amount")
+	y += 8
+	for _, line := range lines {
+		if line.Kind == models.LineTax {
+			continue
+		}
+		if y > height-margin-140 {
+			doc.AddPage()
+			y = margin
+		}
+		y += rowSpace
+		doc.Text(cols[0], y, rowSize, false, pdf.Left, line.Description)
+		doc.Text(cols[1], y, rowSize, false, pdf.Right, fmt.Sprintf("%d", line.Quantity))
+		doc.Text(cols[2], y, rowSize, false, pdf.Right, line.UnitPrice.Decimal())
+		doc.Text(cols[3], y, rowSize, false, pdf.Right, line.Amount.Decimal())
+		doc.Line(margin, y+5, right, y+5, 0.3, 0.8)
+	}
+
+	// totals
+	y += 10
+	total := func(label string, value string, bold bool) {
+		y += rowSpace
+		doc.Text(cols[2], y, rowSize, bold, pdf.Right, label)
+		doc.Text(cols[3], y, rowSize, bold, pdf.Right, value)
+	}
+	total("Subtotal", v.Subtotal.String(), false)
+	if !v.DiscountAmount.IsZero() {
+		total("Discount", v.DiscountAmount.Neg().String(), false)
+	}
+	if !v.FeeAmount.IsZero() {
+		total("Fees", v.FeeAmount.String(), false)
+	}
+	for _, line := range lines {
+		if line.Kind == models.LineTax {
+			total(line.Description, line.Amount.String(), false)
+		}
+	}
+	doc.Line(cols[1], y+6, right, y+6, 0.8, 0)
+	y += 4
+	total("Total", v.Amount.String(), true)
+	if v.DisplayCurrency != "" && v.ExchangeRate > 0 {
+		total(fmt.Sprintf("In %s at %g", v.DisplayCurrency, v.ExchangeRate), v.Amount.Convert(v.DisplayCurrency, v.ExchangeRate).String(), false)
+	}
+
+	// payment status
+	y += 30
+	due, err := v.Amount.Sub(paid)
+	if err != nil {
+		return nil, err
+	}
+	var status string
+	switch {
+	case !v.CanceledAt.IsZero():
+		status = "CANCELED on " + v.CanceledAt.Format("2006-01-02")
+	case !v.PaidAt.IsZero():
+		status = "PAID on " + v.PaidAt.Format("2006-01-02")
+	case paid.Sign() > 0:
+		status = "PARTIALLY PAID, " + due.String() + " due"
+	default:
+		status = "UNPAID, " + v.Amount.String() + " due"
+	}
+	doc.Text(margin, y, 11, true, pdf.Left, status)
+
+	// legal footer
+	if t != nil && t.Footer != "" {
+		footer := pdf.Wrap(t.Footer, 7.5, false, right-margin)
+		fy := height - margin - float64(len(footer)-1)*10
+		doc.Line(margin, fy-14, right, fy-14, 0.5, 0.6)
+		for _, s := range footer {
+			doc.Text(width/2, fy, 7.5, false, pdf.Center, s)
+			fy += 10
+		}
+	}
+
+	return doc.Bytes(), nil
+}
@@ -0,0 +1,234 @@
+// Package modification changes the dates and rooms of reservations.
+package modification
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"easybook/models"
+	"easybook/services/availability"
+	"easybook/services/invoice"
+	"easybook/services/notification"
+	"easybook/services/pricing"
+	"easybook/types"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// Errors of reservations which cannot be modified.
+var (
+	ErrCanceled  = errors.New("modification: reservation is canceled")
+	ErrCheckedIn = errors.New("modification: a room of the reservation is checked in")
+	ErrNoChange  = errors.New("modification: dates and rooms are unchanged")
+	ErrHotel     = errors.New("modification: rooms must be of the hotel of the reservation")
+)
+
+// IsError reports whether err is caused by the change asked rather than by
+// the database.
+func IsError(err error) bool {
+	switch err {
+	case availability.ErrUnavailable, pricing.ErrMinStay, invoice.ErrPaid:
+		return true
+	}
+	return strings.HasPrefix(err.Error(), "modification: ")
+}
+
+// Change is a modification of a reservation. Zero dates and no rooms keep
+// those of the reservation.
+type Change struct {
+	StartDate time.Time
+	EndDate   time.Time
+	Rooms     []int
+	Reason    string
+	// ChangedBy is the id of the guest or staff member changing, zero if
+	// unknown.
+	ChangedBy int
+}
+
+// Modify applies ch to reservation r within the transaction of o, r must
+// be locked. The rooms are checked free for the new stay, the nights kept
+// keep their price while the nights added are priced from the rate plans
+// in effect, then the total, the unpaid invoice and the notifications of r
+// follow. The change is recorded with the difference of the totals.
+func Modify(o orm.Ormer, r *models.Reservation, ch *Change, now time.Time) (*models.ReservationChange, *pricing.Breakdown, error) {
+	if r.Status == models.ReservationCancelled {
+		return nil, nil, ErrCanceled
+	}
+	old := *r
+
+	reserved, err := models.GetRoomReservedByReservation(o, r.Id)
+	if err != nil {
+		return nil, nil, err
+	}
+	oldRooms := make([]int, len(reserved))
+	for i, rr := range reserved {
+		if !rr.CheckIn.IsZero() {
+			return nil, nil, ErrCheckedIn
+		}
+		oldRooms[i] = rr.RoomId.Id
+	}
+
+	if !ch.StartDate.IsZero() {
+		r.StartDate = ch.StartDate
+	}
+	if !ch.EndDate.IsZero() {
+		r.EndDate = ch.EndDate
+	}
+	rooms := ch.Rooms
+	if len(rooms) == 0 {
+		rooms = oldRooms
+	}
+	if err = validate(&old, r, oldRooms, rooms); err != nil {
+		return nil, nil, err
+	}
+
+	inv, err := models.GetActiveInvoice(o, r.Id)
+	switch {
+	case err == orm.ErrNoRows:
+		inv = nil
+	case err != nil:
+		return nil, nil, err
+	case !inv.PaidAt.IsZero():
+		return nil, nil, invoice.ErrPaid
+	default:
+		// the lines refer to the rooms reserved, they are priced again
+		if _, err = models.DeleteInvoiceLines(o, inv.Id); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err = availability.CheckChange(o, r.Id, rooms, r.StartDate, r.EndDate, now); err != nil {
+		return nil, nil, err
+	}
+	hotelID, err := moveRooms(o, r, reserved, rooms)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err = o.Update(r, "StartDate", "EndDate"); err != nil {
+		return nil, nil, err
+	}
+
+	b, err := pricing.Quote(o, r, hotelID)
+	if err != nil {
+		return nil, nil, err
+	}
+	r.TotalPrice = b.Total
+	if _, err = o.Update(r, "TotalPrice"); err != nil {
+		return nil, nil, err
+	}
+	if inv != nil {
+		if err = invoice.Reprice(o, inv, r, hotelID); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err = notification.Changed(o, &old, r, now); err != nil {
+		return nil, nil, err
+	}
+
+	v := &models.ReservationChange{
+		ReservationId: r,
+		OldStartDate:  old.StartDate,
+		OldEndDate:    old.EndDate,
+		OldRooms:      models.RoomsCSV(oldRooms),
+		NewStartDate:  r.StartDate,
+		NewEndDate:    r.EndDate,
+		NewRooms:      models.RoomsCSV(rooms),
+		OldTotal:      old.TotalPrice,
+		NewTotal:      r.TotalPrice,
+		Reason:        ch.Reason,
+		CreatedAt:     now,
+	}
+	// totals in different currencies have no difference
+	if d, err := r.TotalPrice.Sub(old.TotalPrice); err == nil {
+		v.Difference = d
+	}
+	if ch.ChangedBy != 0 {
+		v.ChangedBy = &models.Guest{Id: ch.ChangedBy}
+	}
+	if _, err = o.Insert(v); err != nil {
+		return nil, nil, err
+	}
+	return v, b, nil
+}
+
+// validate checks the new stay and rooms of r, modified from old.
+func validate(old *models.Reservation, r *models.Reservation, oldRooms []int, rooms []int) error {
+	if !r.StartDate.Before(r.EndDate) {
+		return errors.New("modification: end date must follow start date")
+	}
+	seen := make(map[int]bool, len(rooms))
+	for _, id := range rooms {
+		if seen[id] {
+			return fmt.Errorf("modification: room %d is given twice", id)
+		}
+		seen[id] = true
+	}
+	if types.SameDay(old.StartDate, r.StartDate) && types.SameDay(old.EndDate, r.EndDate) && len(rooms) == len(oldRooms) {
+		for _, id := range oldRooms {
+			if !seen[id] {
+				return nil
+			}
+		}
+		return ErrNoChange
+	}
+	return nil
+}
+
+// moveRooms updates the rooms reserved by r to rooms for its stay and
+// returns their hotel: rooms dropped are deleted, rooms kept are repriced
+// and rooms added are reserved.
+func moveRooms(o orm.Ormer, r *models.Reservation, reserved []models.RoomReserved, rooms []int) (hotelID int, err error) {
+	for _, rr := range reserved {
+		if rr.RoomId.HotelId != nil {
+			hotelID = rr.RoomId.HotelId.Id
+		}
+	}
+
+	nights := pricing.Nights(r)
+	kept := make(map[int]bool, len(reserved))
+	for i := range reserved {
+		rr := &reserved[i]
+		if !contains(rooms, rr.RoomId.Id) {
+			if _, err = o.Delete(rr); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		kept[rr.RoomId.Id] = true
+		if err = pricing.RepriceRoom(o, rr, nights); err != nil {
+			return 0, err
+		}
+	}
+
+	for _, id := range rooms {
+		if kept[id] {
+			continue
+		}
+		room := &models.Room{Id: id}
+		if err = o.Read(room); err == orm.ErrNoRows {
+			return 0, fmt.Errorf("modification: room %d does not exist", id)
+		} else if err != nil {
+			return 0, err
+		}
+		if room.HotelId == nil || hotelID != 0 && room.HotelId.Id != hotelID {
+			return 0, ErrHotel
+		}
+		hotelID = room.HotelId.Id
+		rr := &models.RoomReserved{ReservationId: r, RoomId: room}
+		if err = pricing.ReserveRoom(o, rr, nights); err != nil {
+			return 0, err
+		}
+	}
+	return hotelID, nil
+}
+
+func contains(ids []int, id int) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,259 @@
+// Package noshow marks the reservations whose guests did not arrive by the
+// cut-off of their hotel as no-show, charges the penalty nights and
+// releases the others.
+package noshow
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"easybook/models"
+	"easybook/services/invoice"
+	"easybook/services/notification"
+	"easybook/services/periodic"
+	"easybook/services/pricing"
+	"easybook/services/waitlist"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/logs"
+	"github.com/astaxie/beego/orm"
+)
+
+// Errors of reservations which cannot be marked no-show.
+var (
+	ErrNotConfirmed = errors.New("noshow: reservation is not confirmed")
+	ErrArrived      = errors.New("noshow: a room is checked in")
+)
+
+// IsError reports whether err is caused by the policy or reservation
+// rather than by the database.
+func IsError(err error) bool {
+	return strings.HasPrefix(err.Error(), "noshow: ")
+}
+
+// Default returns the policy of the hotels without one of their own,
+// configured by the noshow* keys of app.conf.
+func Default() *models.NoShowPolicy {
+	return &models.NoShowPolicy{
+		Timezone:      beego.AppConfig.DefaultString("noshowtimezone", "Local"),
+		CutoffDays:    beego.AppConfig.DefaultInt("noshowcutoffdays", 1),
+		CutoffHour:    beego.AppConfig.DefaultInt("noshowcutoffhour", 6),
+		PenaltyNights: beego.AppConfig.DefaultInt("noshowpenaltynights", 1),
+		IsActive:      1,
+	}
+}
+
+// Validate checks the hotel, timezone, cut-off and penalty of policy v.
+func Validate(v *models.NoShowPolicy) error {
+	if v.HotelId == nil || v.HotelId.Id == 0 {
+		return errors.New("noshow: hotel is required")
+	}
+	if _, err := time.LoadLocation(v.Timezone); err != nil {
+		return errors.New("noshow: unknown timezone " + strconv.Quote(v.Timezone))
+	}
+	switch {
+	case v.CutoffDays < 0:
+		return errors.New("noshow: cut-off days must not be negative")
+	case v.CutoffHour < 0 || v.CutoffHour > 23:
+		return errors.New("noshow: cut-off hour must be within 0 and 23")
+	case v.PenaltyNights < 0:
+		return errors.New("noshow: penalty nights must not be negative")
+	}
+	return nil
+}
+
+// Cutoff returns when reservation r becomes no-show under policy p, at
+// CutoffHour o'clock CutoffDays days after its start date in the timezone
+// of the hotel.
+func Cutoff(p *models.NoShowPolicy, r *models.Reservation) (time.Time, error) {
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	y, m, d := r.StartDate.Date()
+	return time.Date(y, m, d+p.CutoffDays, p.CutoffHour, 0, 0, 0, loc), nil
+}
+
+// Mark marks reservation r of hotel hotelID, locked, no-show under policy
+// p within the transaction of o. The first PenaltyNights nights are kept
+// at the price booked and the unpaid invoice is billed for them only, the
+// other nights are released and offered to the waitlist. The change is
+// recorded and the staff of the hotel notified.
+func Mark(o orm.Ormer, r *models.Reservation, hotelID int, p *models.NoShowPolicy, now time.Time) (*models.ReservationChange, error) {
+	if r.Status != models.ReservationConfirmed {
+		return nil, ErrNotConfirmed
+	}
+	reserved, err := models.GetRoomReservedByReservation(o, r.Id)
+	if err != nil {
+		return nil, err
+	}
+	rooms := make([]int, len(reserved))
+	for i, rr := range reserved {
+		if !rr.CheckIn.IsZero() {
+			return nil, ErrArrived
+		}
+		rooms[i] = rr.RoomId.Id
+	}
+	old := *r
+
+	if keep := p.PenaltyNights; keep < len(pricing.Nights(r)) {
+		r.EndDate = r.StartDate.AddDate(0, 0, keep)
+		if _, err = models.DeleteRoomNightsFrom(o, r.Id, r.EndDate); err != nil {
+			return nil, err
+		}
+	}
+	r.Status = models.ReservationNoShow
+	b, err := pricing.Quote(o, r, hotelID)
+	if err != nil {
+		return nil, err
+	}
+	r.TotalPrice = b.Total
+	if _, err = o.Update(r, "EndDate", "TotalPrice", "Status"); err != nil {
+		return nil, err
+	}
+
+	// a paid invoice is kept, the refund of the nights released is up to
+	// the hotel
+	inv, err := models.GetActiveInvoice(o, r.Id)
+	switch {
+	case err == orm.ErrNoRows:
+	case err != nil:
+		return nil, err
+	case inv.PaidAt.IsZero():
+		if err = invoice.Reprice(o, inv, r, hotelID); err != nil {
+			return nil, err
+		}
+	}
+	if err = notification.NoShow(o, r, hotelID, now); err != nil {
+		return nil, err
+	}
+
+	v := &models.ReservationChange{
+		ReservationId: r,
+		OldStartDate:  old.StartDate,
+		OldEndDate:    old.EndDate,
+		OldRooms:      models.RoomsCSV(rooms),
+		NewStartDate:  r.StartDate,
+		NewEndDate:    r.EndDate,
+		NewRooms:      models.RoomsCSV(rooms),
+		OldTotal:      old.TotalPrice,
+		NewTotal:      r.TotalPrice,
+		Reason:        "no-show",
+		CreatedAt:     now,
+	}
+	// totals in different currencies have no difference
+	if d, err := r.TotalPrice.Sub(old.TotalPrice); err == nil {
+		v.Difference = d
+	}
+	if _, err = o.Insert(v); err != nil {
+		return nil, err
+	}
+
+	if hotelID != 0 {
+		if _, err = waitlist.Match(o, hotelID, now); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// Detector marks the reservations past the no-show cut-off of their hotel
+// in background.
+type Detector struct {
+	// Interval is the time between two runs.
+	Interval time.Duration
+	// Lookback is how far before now the start dates of the reservations
+	// checked go, older reservations are left as they are.
+	Lookback time.Duration
+
+	runner periodic.Runner
+}
+
+// NewDetector creates a Detector configured by the noshow* keys of
+// app.conf.
+func NewDetector() *Detector {
+	return &Detector{
+		Interval: time.Duration(beego.AppConfig.DefaultInt("noshowinterval", 900)) * time.Second,
+		Lookback: time.Duration(beego.AppConfig.DefaultInt("noshowlookbackdays", 7)) * 24 * time.Hour,
+	}
+}
+
+// Start runs the detector in background until Stop is called.
+func (s *Detector) Start() {
+	s.runner.Start("noshow: detection", s.Interval, func(now time.Time) error {
+		_, err := s.RunOnce(now)
+		return err
+	})
+}
+
+// Stop stops the detector and waits for the current run to finish.
+func (s *Detector) Stop() {
+	s.runner.Stop()
+}
+
+// RunOnce marks the confirmed reservations past their cut-off at now
+// without any room checked in no-show and returns their number. A
+// reservation which fails is logged and skipped.
+func (s *Detector) RunOnce(now time.Time) (int, error) {
+	o := orm.NewOrm()
+	// a day more on both sides covers the timezones of every hotel
+	l, err := models.GetArrivingReservations(o, now.Add(-s.Lookback).AddDate(0, 0, -1), now.AddDate(0, 0, 1))
+	if err != nil {
+		return 0, err
+	}
+
+	policies := make(map[int]*models.NoShowPolicy)
+	var n int
+	for i := range l {
+		r := &l[i]
+		hotelID, err := models.GetReservationHotelId(o, r.Id)
+		if err == orm.ErrNoRows {
+			// no room reserved, nothing to release
+			continue
+		}
+		if err != nil {
+			return n, err
+		}
+		p, ok := policies[hotelID]
+		if !ok {
+			if p, err = models.GetHotelNoShowPolicy(o, hotelID); err == orm.ErrNoRows {
+				p = Default()
+			} else if err != nil {
+				return n, err
+			}
+			policies[hotelID] = p
+		}
+		cutoff, err := Cutoff(p, r)
+		if err != nil {
+			logs.Error("noshow: policy of hotel %d: %v", hotelID, err)
+			continue
+		}
+		if now.Before(cutoff) {
+			continue
+		}
+
+		_ = o.Begin()
+		// the reservation is locked against a check-in meanwhile
+		if err = o.ReadForUpdate(r); err == nil {
+			_, err = Mark(o, r, hotelID, p, now)
+		}
+		if err == ErrNotConfirmed || err == ErrArrived {
+			_ = o.Rollback()
+			continue
+		}
+		if err != nil {
+			// the other reservations are marked still
+			_ = o.Rollback()
+			logs.Error("noshow: marking reservation %d: %v", r.Id, err)
+			continue
+		}
+		if err = o.Commit(); err != nil {
+			logs.Error("noshow: marking reservation %d: %v", r.Id, err)
+			continue
+		}
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,155 @@
+package noshow
+
+import (
+	"testing"
+	"time"
+
+	"easybook/models"
+	"easybook/services/invoice"
+	"easybook/services/servicetest"
+	"easybook/types"
+
+	"github.com/astaxie/beego/orm"
+)
+
+func TestMain(m *testing.M) {
+	servicetest.Main(m)
+}
+
+func TestValidate(t *testing.T) {
+	hotel := &models.Hotel{Id: 1}
+	cases := []struct {
+		name string
+		v    models.NoShowPolicy
+		ok   bool
+	}{
+		{"valid", models.NoShowPolicy{HotelId: hotel, Timezone: "Europe/Paris", CutoffDays: 1, CutoffHour: 6, PenaltyNights: 1}, true},
+		{"no hotel", models.NoShowPolicy{Timezone: "UTC"}, false},
+		{"unknown timezone", models.NoShowPolicy{HotelId: hotel, Timezone: "Mars/Olympus"}, false},
+		{"negative days", models.NoShowPolicy{HotelId: hotel, Timezone: "UTC", CutoffDays: -1}, false},
+		{"hour past 23", models.NoShowPolicy{HotelId: hotel, Timezone: "UTC", CutoffHour: 24}, false},
+		{"negative penalty", models.NoShowPolicy{HotelId: hotel, Timezone: "UTC", PenaltyNights: -1}, false},
+	}
+	for _, c := range cases {
+		if err := Validate(&c.v); (err == nil) != c.ok || err != nil && !IsError(err) {
+			t.Errorf("%s: Validate error = %v, want ok %v", c.name, err, c.ok)
+		}
+	}
+}
+
+func TestCutoff(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip(err)
+	}
+	r := &models.Reservation{StartDate: servicetest.Date(2032, 1, 31)}
+	cases := []struct {
+		p    models.NoShowPolicy
+		want time.Time
+	}{
+		{models.NoShowPolicy{Timezone: "UTC", CutoffHour: 18}, time.Date(2032, 1, 31, 18, 0, 0, 0, time.UTC)},
+		{models.NoShowPolicy{Timezone: "Europe/Paris", CutoffDays: 1, CutoffHour: 6}, time.Date(2032, 2, 1, 6, 0, 0, 0, paris)},
+	}
+	for _, c := range cases {
+		if got, err := Cutoff(&c.p, r); err != nil || !got.Equal(c.want) {
+			t.Errorf("Cutoff(%+v) = %v, %v, want %v", c.p, got, err, c.want)
+		}
+	}
+	if _, err := Cutoff(&models.NoShowPolicy{Timezone: "Mars/Olympus"}, r); err == nil {
+		t.Error("Cutoff of an unknown timezone error = nil")
+	}
+}
+
+func TestMark(t *testing.T) {
+	o := servicetest.Ormer(t)
+	h, rooms := servicetest.Hotel(t, o, 2, types.NewMoney(1000000, types.DefaultCurrency))
+	from := servicetest.Date(2032, 2, 1)
+	to := from.AddDate(0, 0, 3)
+	now := time.Now().Truncate(time.Second)
+	p := &models.NoShowPolicy{Timezone: "UTC", CutoffDays: 1, PenaltyNights: 1, IsActive: 1}
+	r := servicetest.Reservation(t, o, servicetest.Guest(t, o), rooms, from, to, models.ReservationConfirmed)
+	inv, err := invoice.Generate(o, r, h.Id, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	full := inv.Amount
+
+	v, err := Mark(o, r, h.Id, p, now)
+	if err != nil {
+		t.Fatalf("Mark error: %v", err)
+	}
+	if r.Status != models.ReservationNoShow || !r.EndDate.Equal(from.AddDate(0, 0, 1)) || r.TotalPrice.Amount <= 0 {
+		t.Errorf("reservation = %+v, want no-show for its first night with a total", r)
+	}
+	if v.Reason != "no-show" || !v.OldEndDate.Equal(to) || !v.NewEndDate.Equal(r.EndDate) || v.NewTotal != r.TotalPrice {
+		t.Errorf("change = %+v, want the stay cut from %v to %v", v, to, r.EndDate)
+	}
+	if nights, err := models.GetRoomNightsByReservation(o, r.Id); err != nil || len(nights) != 2 {
+		t.Errorf("%d nights kept, %v, want one per room", len(nights), err)
+	}
+	if inv, err = models.GetActiveInvoice(o, r.Id); err != nil || inv.Amount.Amount >= full.Amount {
+		t.Errorf("invoice after no-show = %+v, %v, want less than %v", inv, err, full)
+	}
+	if _, err = Mark(o, r, h.Id, p, now); err != ErrNotConfirmed {
+		t.Errorf("Mark of a no-show reservation error = %v, want ErrNotConfirmed", err)
+	}
+
+	arrived := servicetest.Reservation(t, o, servicetest.Guest(t, o), rooms[:1], to, to.AddDate(0, 0, 2), models.ReservationConfirmed)
+	checkIn(t, o, arrived, now)
+	if _, err = Mark(o, arrived, h.Id, p, now); err != ErrArrived {
+		t.Errorf("Mark of a checked in reservation error = %v, want ErrArrived", err)
+	}
+}
+
+func TestDetectorRunOnce(t *testing.T) {
+	o := servicetest.Ormer(t)
+	h, rooms := servicetest.Hotel(t, o, 2, types.NewMoney(1000000, types.DefaultCurrency))
+	_, elsewhere := servicetest.Hotel(t, o, 1, types.NewMoney(1000000, types.DefaultCurrency))
+	from := servicetest.Date(2032, 3, 1)
+	to := from.AddDate(0, 0, 2)
+	now := time.Now().Truncate(time.Second)
+	servicetest.Insert(t, o, &models.NoShowPolicy{HotelId: h, Timezone: "UTC", CutoffHour: 12, PenaltyNights: 1, IsActive: 1,
+		CreatedAt: now, UpdatedAt: now})
+	due := servicetest.Reservation(t, o, servicetest.Guest(t, o), rooms[:1], from, to, models.ReservationConfirmed)
+	arrived := servicetest.Reservation(t, o, servicetest.Guest(t, o), rooms[1:], from, to, models.ReservationConfirmed)
+	checkIn(t, o, arrived, now)
+	// the default policy waits until the next day
+	early := servicetest.Reservation(t, o, servicetest.Guest(t, o), elsewhere, from, to, models.ReservationConfirmed)
+
+	d := NewDetector()
+	if n, err := d.RunOnce(time.Date(2032, 3, 1, 11, 0, 0, 0, time.UTC)); err != nil || n != 0 {
+		t.Errorf("RunOnce before the cut-off = %d, %v, want none marked", n, err)
+	}
+	if n, err := d.RunOnce(time.Date(2032, 3, 1, 13, 0, 0, 0, time.UTC)); err != nil || n != 1 {
+		t.Errorf("RunOnce after the cut-off = %d, %v, want one marked", n, err)
+	}
+	for _, c := range []struct {
+		name   string
+		r      *models.Reservation
+		status uint8
+	}{
+		{"due", due, models.ReservationNoShow},
+		{"arrived", arrived, models.ReservationConfirmed},
+		{"early", early, models.ReservationConfirmed},
+	} {
+		r := &models.Reservation{Id: c.r.Id}
+		if err := o.Read(r); err != nil || r.Status != c.status {
+			t.Errorf("%s reservation = %+v, %v, want status %d", c.name, r, err, c.status)
+		}
+	}
+}
+
+// checkIn checks in the rooms of r at now.
+func checkIn(t *testing.T, o orm.Ormer, r *models.Reservation, now time.Time) {
+	t.Helper()
+	rrs, err := models.GetRoomReservedByReservation(o, r.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range rrs {
+		rrs[i].CheckIn = now
+		if _, err = o.Update(&rrs[i], "CheckIn"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
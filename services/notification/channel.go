@@ -0,0 +1,160 @@
+package notification
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"easybook/models"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/orm"
+)
+
+// Notification types, they are the values of models.Notification.Type.
+const (
+	TypeEmail   = "EML"
+	TypeSMS     = "SMS"
+	TypeWebhook = "WHK"
+	TypeInApp   = "APP"
+)
+
+// Message is a notification ready to be delivered to its guest.
+type Message struct {
+	Notification *models.Notification
+	Reservation  *models.Reservation
+	Guest        *models.Guest
+	Subject      string
+	Body         string
+	// HTML reports whether Body is HTML rather than plain text.
+	HTML bool
+}
+
+// Sender delivers messages through a channel.
+type Sender interface {
+	Send(m *Message) error
+}
+
+// Config holds the keys of a channel section in app.conf.
+type Config map[string]string
+
+// Get returns the value of key, or def if it is empty.
+func (c Config) Get(key string, def string) string {
+	if v := c[key]; v != "" {
+		return v
+	}
+	return def
+}
+
+// Driver creates a Sender from its configuration.
+type Driver func(cfg Config) (Sender, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = map[string]Driver{}
+
+	sendersMu sync.RWMutex
+	senders   = map[string]Sender{}
+)
+
+// RegisterDriver makes a channel implementation available by name in the
+// driver key of channel sections.
+func RegisterDriver(name string, driver Driver) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	drivers[name] = driver
+}
+
+// Register makes sender deliver notifications of type typ, e.g. "EML".
+func Register(typ string, sender Sender) {
+	sendersMu.Lock()
+	defer sendersMu.Unlock()
+	senders[typ] = sender
+}
+
+// Lookup returns the sender registered for type typ.
+func Lookup(typ string) (Sender, bool) {
+	sendersMu.RLock()
+	defer sendersMu.RUnlock()
+	s, ok := senders[typ]
+	return s, ok
+}
+
+// Setup registers a sender for every [notify.<type>] section of app.conf,
+// the driver key of the section selects the implementation:
+//
+//	[notify.eml]
+//	driver = smtp
+//	host = 127.0.0.1
+//	port = 25
+//	from = no-reply@easybook.local
+func Setup() error {
+	for _, typ := range []string{TypeEmail, TypeSMS, TypeWebhook, TypeInApp} {
+		section, err := beego.AppConfig.GetSection("notify." + strings.ToLower(typ))
+		if err != nil || section["driver"] == "" {
+			continue
+		}
+
+		driversMu.RLock()
+		driver, ok := drivers[section["driver"]]
+		driversMu.RUnlock()
+		if !ok {
+			return fmt.Errorf("notification: unknown driver %q for %s, available: %s",
+				section["driver"], typ, strings.Join(driverNames(), ", "))
+		}
+
+		sender, err := driver(Config(section))
+		if err != nil {
+			return fmt.Errorf("notification: %s: %v", typ, err)
+		}
+		Register(typ, sender)
+	}
+	return nil
+}
+
+func driverNames() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	var names []string
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newMessage composes the message of n from the latest template named
+// after its event, in the language of the guest. Notifications without a
+// template are sent with their description as body.
+func newMessage(n *models.Notification) (*Message, error) {
+	data, err := LoadTemplateData(n.ReservationId.Id)
+	if err != nil {
+		return nil, err
+	}
+	data.Notification = n
+
+	m := &Message{
+		Notification: n,
+		Reservation:  data.Reservation,
+		Guest:        data.Guest,
+		Subject:      fmt.Sprintf("Easybook reservation #%d", data.Reservation.Id),
+		Body:         n.Description,
+	}
+	if n.Event == "" {
+		return m, nil
+	}
+
+	t, err := FindTemplate(n.Event, n.Type, data.Guest.Language)
+	if err == orm.ErrNoRows {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if m.Subject, m.Body, err = Render(t, data); err != nil {
+		return nil, err
+	}
+	m.HTML = t.Format == models.FormatHTML
+	return m, nil
+}
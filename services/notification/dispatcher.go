@@ -0,0 +1,131 @@
+// Package notification delivers due notifications through the channel
+// registered for their type.
+package notification
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"easybook/models"
+	"easybook/services/periodic"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/logs"
+)
+
+// Dispatcher polls due notifications and sends them. Notifications are
+// claimed with a lease before sending, so several app instances can run a
+// Dispatcher against the same database without sending twice.
+type Dispatcher struct {
+	// Owner identifies the claims of this dispatcher.
+	Owner string
+	// Interval is the time between two polls.
+	Interval time.Duration
+	// BatchSize is the maximum number of notifications sent per poll.
+	BatchSize int
+	// Lease is how long a claimed notification is locked while sending.
+	Lease time.Duration
+	// MaxAttempts is the number of failed sends after which a
+	// notification is given up.
+	MaxAttempts int
+	// RetryBackoff is the delay before the first retry, it doubles on
+	// every further failure up to MaxRetryBackoff.
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+
+	runner periodic.Runner
+}
+
+// NewDispatcher creates a Dispatcher configured by the notify* keys of
+// app.conf.
+func NewDispatcher() *Dispatcher {
+	host, _ := os.Hostname()
+	return &Dispatcher{
+		Owner:           fmt.Sprintf("%s-%d", host, os.Getpid()),
+		Interval:        time.Duration(beego.AppConfig.DefaultInt("notifyinterval", 10)) * time.Second,
+		BatchSize:       beego.AppConfig.DefaultInt("notifybatchsize", 50),
+		Lease:           time.Duration(beego.AppConfig.DefaultInt("notifylease", 60)) * time.Second,
+		MaxAttempts:     beego.AppConfig.DefaultInt("notifymaxattempts", 5),
+		RetryBackoff:    time.Duration(beego.AppConfig.DefaultInt("notifyretrybackoff", 60)) * time.Second,
+		MaxRetryBackoff: time.Duration(beego.AppConfig.DefaultInt("notifymaxretrybackoff", 3600)) * time.Second,
+	}
+}
+
+// Start runs the dispatcher in background until Stop is called.
+func (d *Dispatcher) Start() {
+	d.runner.Start("notification: dispatch", d.Interval, func(now time.Time) error {
+		_, err := d.RunOnce(now)
+		return err
+	})
+}
+
+// Stop stops the dispatcher and waits for the current poll to finish.
+func (d *Dispatcher) Stop() {
+	d.runner.Stop()
+}
+
+// RunOnce sends the notifications which are due at now and returns the
+// number of notifications sent.
+func (d *Dispatcher) RunOnce(now time.Time) (sent int, err error) {
+	l, err := models.GetDueNotifications(now, d.MaxAttempts, d.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range l {
+		n := &l[i]
+		claimed, err := models.ClaimNotification(n.Id, d.Owner, now, now.Add(d.Lease))
+		if err != nil {
+			return sent, err
+		}
+		if !claimed {
+			// another dispatcher got it first
+			continue
+		}
+
+		m, err := d.send(n)
+		if err != nil {
+			logs.Warn("notification: sending %d failed: %v", n.Id, err)
+			if err = models.FailNotification(n.Id, d.Owner, err, now.Add(d.backoff(n.Attempts+1))); err != nil {
+				return sent, err
+			}
+			continue
+		}
+		if err = models.CompleteNotification(n.Id, d.Owner); err != nil {
+			return sent, err
+		}
+		sent++
+		if n.Type == TypeInApp {
+			n.IsCompleted = models.NotificationSent
+			n.LockedBy, n.LockedUntil, n.LastError = "", time.Time{}, ""
+			Inbox.Publish(m.Guest.Id, n)
+		}
+	}
+	return sent, nil
+}
+
+func (d *Dispatcher) send(n *models.Notification) (*Message, error) {
+	sender, ok := Lookup(n.Type)
+	if !ok {
+		return nil, fmt.Errorf("no channel for type %q", n.Type)
+	}
+	m, err := newMessage(n)
+	if err != nil {
+		return nil, err
+	}
+	return m, sender.Send(m)
+}
+
+// backoff returns the delay before retrying a notification which failed
+// attempts times.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.RetryBackoff
+	for i := 1; i < attempts && delay < d.MaxRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > d.MaxRetryBackoff {
+		delay = d.MaxRetryBackoff
+	}
+	return delay
+}
@@ -0,0 +1,219 @@
+package notification
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"easybook/models"
+	"easybook/services/servicetest"
+	"easybook/types"
+)
+
+func TestMain(m *testing.M) {
+	servicetest.Main(m)
+}
+
+func TestDispatcherRunOnce(t *testing.T) {
+	o := servicetest.Ormer(t)
+
+	server, err := NewMemSMTPServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	Register(TypeEmail, &SMTPSender{Addr: server.Addr(), From: "no-reply@easybook.test"})
+	dir, err := ioutil.TempDir("", "notification")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	Register(TypeSMS, &FileSender{Path: filepath.Join(dir, "sms.log")})
+
+	_, rooms := servicetest.Hotel(t, o, 1, types.NewMoney(1000000, types.DefaultCurrency))
+	from := servicetest.Date(2030, 3, 1)
+	reachable := servicetest.Guest(t, o)
+	r1 := servicetest.Reservation(t, o, reachable, rooms, from, from.AddDate(0, 0, 2), models.ReservationConfirmed)
+	unreachable := servicetest.Guest(t, o)
+	unreachable.Email = ""
+	if _, err = o.Update(unreachable, "Email"); err != nil {
+		t.Fatal(err)
+	}
+	r2 := servicetest.Reservation(t, o, unreachable, rooms, from.AddDate(0, 0, 2), from.AddDate(0, 0, 4), models.ReservationConfirmed)
+
+	now := time.Now().Truncate(time.Second)
+	queue := func(typ string, r *models.Reservation) *models.Notification {
+		n := &models.Notification{
+			Type:          typ,
+			TriggerAt:     now.Add(-time.Minute),
+			Description:   servicetest.Name("Reminder"),
+			ReservationId: r,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		if _, err := models.AddNotification(n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	mail := queue(TypeEmail, r1)
+	sms := queue(TypeSMS, r1)
+	retried := queue(TypeEmail, r2)
+
+	d := &Dispatcher{
+		Owner:           servicetest.Name("dispatcher"),
+		BatchSize:       1000,
+		Lease:           time.Minute,
+		MaxAttempts:     2,
+		RetryBackoff:    time.Minute,
+		MaxRetryBackoff: time.Hour,
+	}
+	if _, err = d.RunOnce(now); err != nil {
+		t.Fatalf("RunOnce error: %v", err)
+	}
+
+	check := func(n *models.Notification, completed int8, attempts int) *models.Notification {
+		t.Helper()
+		v, err := models.GetNotificationById(n.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.IsCompleted != completed || v.Attempts != attempts {
+			t.Errorf("notification %s: completed %d after %d attempts, want %d after %d (last error %q)",
+				n.Type, v.IsCompleted, v.Attempts, completed, attempts, v.LastError)
+		}
+		return v
+	}
+	check(mail, models.NotificationSent, 0)
+	check(sms, models.NotificationSent, 0)
+	if v := check(retried, models.NotificationPending, 1); !strings.Contains(v.LastError, "no email") || !v.LockedUntil.After(now) {
+		t.Errorf("failed notification: last error %q locked until %v, want no email until after %v", v.LastError, v.LockedUntil, now)
+	}
+
+	delivered := false
+	for _, m := range server.Mails() {
+		if len(m.To) == 1 && m.To[0] == reachable.Email && strings.Contains(m.Data, mail.Description) {
+			delivered = true
+		}
+	}
+	if !delivered {
+		t.Errorf("no mail to %s with %q among %d mails", reachable.Email, mail.Description, len(server.Mails()))
+	}
+	if records := readRecords(t, filepath.Join(dir, "sms.log")); len(records) != 1 || records[0].NotificationId != sms.Id || records[0].Body != sms.Description {
+		t.Errorf("sms records = %+v, want notification %d", records, sms.Id)
+	}
+
+	// the failed notification waits for its backoff, then is retried
+	if _, err = d.RunOnce(now.Add(30 * time.Second)); err != nil {
+		t.Fatalf("RunOnce error: %v", err)
+	}
+	check(retried, models.NotificationPending, 1)
+	unreachable.Email = fmt.Sprintf("guest%d@easybook.test", unreachable.Id)
+	if _, err = o.Update(unreachable, "Email"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = d.RunOnce(now.Add(2 * time.Minute)); err != nil {
+		t.Fatalf("RunOnce error: %v", err)
+	}
+	check(retried, models.NotificationSent, 1)
+	check(mail, models.NotificationSent, 0)
+	if n := len(readRecords(t, filepath.Join(dir, "sms.log"))); n != 1 {
+		t.Errorf("%d sms records after retry, want the first one only", n)
+	}
+}
+
+func TestDispatcherGivesUp(t *testing.T) {
+	o := servicetest.Ormer(t)
+
+	Register(TypeWebhook, failingSender{})
+	_, rooms := servicetest.Hotel(t, o, 1, types.NewMoney(1000000, types.DefaultCurrency))
+	from := servicetest.Date(2030, 4, 1)
+	r := servicetest.Reservation(t, o, servicetest.Guest(t, o), rooms, from, from.AddDate(0, 0, 1), models.ReservationConfirmed)
+
+	now := time.Now().Truncate(time.Second)
+	n := &models.Notification{
+		Type:          TypeWebhook,
+		TriggerAt:     now.Add(-time.Minute),
+		ReservationId: r,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if _, err := models.AddNotification(n); err != nil {
+		t.Fatal(err)
+	}
+
+	d := &Dispatcher{
+		Owner:           servicetest.Name("dispatcher"),
+		BatchSize:       1000,
+		Lease:           time.Minute,
+		MaxAttempts:     2,
+		RetryBackoff:    time.Minute,
+		MaxRetryBackoff: 90 * time.Second,
+	}
+	for _, at := range []time.Duration{0, 2 * time.Minute, 10 * time.Minute} {
+		if _, err := d.RunOnce(now.Add(at)); err != nil {
+			t.Fatalf("RunOnce error: %v", err)
+		}
+	}
+	v, err := models.GetNotificationById(n.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.IsCompleted != models.NotificationPending || v.Attempts != d.MaxAttempts || v.LastError != errFailing.Error() {
+		t.Errorf("notification completed %d after %d attempts with %q, want given up after %d", v.IsCompleted, v.Attempts, v.LastError, d.MaxAttempts)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := &Dispatcher{RetryBackoff: time.Minute, MaxRetryBackoff: 5 * time.Minute}
+	cases := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{4, 5 * time.Minute},
+		{10, 5 * time.Minute},
+	}
+	for _, c := range cases {
+		if got := d.backoff(c.attempts); got != c.want {
+			t.Errorf("backoff(%d) = %v, want %v", c.attempts, got, c.want)
+		}
+	}
+}
+
+var errFailing = errors.New("webhook: endpoint is down")
+
+// failingSender fails every message.
+type failingSender struct{}
+
+func (failingSender) Send(m *Message) error {
+	return errFailing
+}
+
+func readRecords(t *testing.T, path string) []FileRecord {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var l []FileRecord
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		var rec FileRecord
+		if err := json.Unmarshal(s.Bytes(), &rec); err != nil {
+			t.Fatal(err)
+		}
+		l = append(l, rec)
+	}
+	return l
+}
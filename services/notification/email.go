@@ -0,0 +1,82 @@
+package notification
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+func init() {
+	RegisterDriver("smtp", newSMTPSender)
+	RegisterDriver("memsmtp", newMemSMTPSender)
+}
+
+// SMTPSender sends EML notifications to the guest email through an SMTP
+// server.
+type SMTPSender struct {
+	Addr     string
+	From     string
+	Username string
+	Password string
+}
+
+func newSMTPSender(cfg Config) (Sender, error) {
+	if cfg["host"] == "" {
+		return nil, errors.New("smtp: host is required")
+	}
+	return &SMTPSender{
+		Addr:     net.JoinHostPort(cfg["host"], cfg.Get("port", "25")),
+		From:     cfg.Get("from", "no-reply@easybook.local"),
+		Username: cfg["username"],
+		Password: cfg["password"],
+	}, nil
+}
+
+// newMemSMTPSender starts an in-memory SMTP server on a local port and
+// sends to it.
+func newMemSMTPSender(cfg Config) (Sender, error) {
+	server, err := NewMemSMTPServer()
+	if err != nil {
+		return nil, err
+	}
+	host, _, _ := net.SplitHostPort(server.Addr())
+	return &SMTPSender{
+		Addr: server.Addr(),
+		From: cfg.Get("from", "no-reply@"+host),
+	}, nil
+}
+
+// Send implements Sender.
+func (s *SMTPSender) Send(m *Message) error {
+	if m.Guest.Email == "" {
+		return errors.New("smtp: guest has no email")
+	}
+
+	var auth smtp.Auth
+	if s.Username != "" {
+		host, _, _ := net.SplitHostPort(s.Addr)
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+	return smtp.SendMail(s.Addr, auth, s.From, []string{m.Guest.Email}, s.compose(m))
+}
+
+func (s *SMTPSender) compose(m *Message) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", s.From)
+	fmt.Fprintf(&b, "To: %s\r\n", m.Guest.Email)
+	fmt.Fprintf(&b, "Subject: %s\r\n", m.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	if m.HTML {
+		b.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+	} else {
+		b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	}
+	b.WriteString("\r\n")
+	b.WriteString(m.Body)
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
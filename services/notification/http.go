@@ -0,0 +1,129 @@
+package notification
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func init() {
+	RegisterDriver("smsgateway", newSMSGatewaySender)
+	RegisterDriver("webhook", newWebhookSender)
+}
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// SMSGatewaySender sends SMS notifications to the guest phone by posting
+// {"to": ..., "text": ...} to an HTTP SMS gateway.
+type SMSGatewaySender struct {
+	URL    string
+	APIKey string
+	From   string
+}
+
+func newSMSGatewaySender(cfg Config) (Sender, error) {
+	if cfg["url"] == "" {
+		return nil, errors.New("smsgateway: url is required")
+	}
+	return &SMSGatewaySender{
+		URL:    cfg["url"],
+		APIKey: cfg["apikey"],
+		From:   cfg.Get("from", "Easybook"),
+	}, nil
+}
+
+// Send implements Sender.
+func (s *SMSGatewaySender) Send(m *Message) error {
+	if m.Guest.Phone == "" {
+		return errors.New("smsgateway: guest has no phone")
+	}
+
+	body, _ := json.Marshal(map[string]string{
+		"from": s.From,
+		"to":   m.Guest.Phone,
+		"text": m.Body,
+	})
+	req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.APIKey)
+	}
+	return do(req)
+}
+
+// WebhookSender posts WHK notifications as JSON to a URL. The body is
+// signed with HMAC-SHA256 of the secret in the X-Easybook-Signature header.
+type WebhookSender struct {
+	URL    string
+	Secret string
+}
+
+// WebhookPayload is the body posted by WebhookSender.
+type WebhookPayload struct {
+	NotificationId int       `json:"notification_id"`
+	ReservationId  int       `json:"reservation_id"`
+	GuestId        int       `json:"guest_id"`
+	TriggerAt      time.Time `json:"trigger_at"`
+	Subject        string    `json:"subject"`
+	Body           string    `json:"body"`
+}
+
+func newWebhookSender(cfg Config) (Sender, error) {
+	if cfg["url"] == "" {
+		return nil, errors.New("webhook: url is required")
+	}
+	return &WebhookSender{
+		URL:    cfg["url"],
+		Secret: cfg["secret"],
+	}, nil
+}
+
+// Send implements Sender.
+func (s *WebhookSender) Send(m *Message) error {
+	body, err := json.Marshal(WebhookPayload{
+		NotificationId: m.Notification.Id,
+		ReservationId:  m.Reservation.Id,
+		GuestId:        m.Guest.Id,
+		TriggerAt:      m.Notification.TriggerAt,
+		Subject:        m.Subject,
+		Body:           m.Body,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Easybook-Delivery", strconv.Itoa(m.Notification.Id))
+	if s.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(s.Secret))
+		mac.Write(body)
+		req.Header.Set("X-Easybook-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	return do(req)
+}
+
+// do sends req and fails on a non-2xx response.
+func do(req *http.Request) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s responded %s", req.URL.Host, resp.Status)
+	}
+	return nil
+}
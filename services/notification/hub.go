@@ -0,0 +1,66 @@
+package notification
+
+import (
+	"sync"
+
+	"easybook/models"
+)
+
+// Inbox is the hub the dispatcher publishes completed in-app notifications
+// to. It only reaches clients connected to the same app instance.
+var Inbox = NewHub(16)
+
+// Hub fans out notifications to the subscribers of their guest.
+type Hub struct {
+	size int
+
+	mu   sync.Mutex
+	subs map[int]map[chan *models.Notification]struct{}
+}
+
+// NewHub creates a Hub whose subscriptions buffer size notifications.
+func NewHub(size int) *Hub {
+	return &Hub{
+		size: size,
+		subs: make(map[int]map[chan *models.Notification]struct{}),
+	}
+}
+
+// Subscribe returns a channel receiving the notifications published to
+// guestID and a function to end the subscription.
+func (h *Hub) Subscribe(guestID int) (<-chan *models.Notification, func()) {
+	ch := make(chan *models.Notification, h.size)
+
+	h.mu.Lock()
+	if h.subs[guestID] == nil {
+		h.subs[guestID] = make(map[chan *models.Notification]struct{})
+	}
+	h.subs[guestID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			delete(h.subs[guestID], ch)
+			if len(h.subs[guestID]) == 0 {
+				delete(h.subs, guestID)
+			}
+			close(ch)
+		})
+	}
+}
+
+// Publish sends n to the subscribers of guestID. Subscribers that are too
+// slow to keep up miss it rather than blocking the dispatcher.
+func (h *Hub) Publish(guestID int, n *models.Notification) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[guestID] {
+		select {
+		case ch <- n:
+		default:
+		}
+	}
+}
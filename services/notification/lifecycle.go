@@ -0,0 +1,274 @@
+package notification
+
+import (
+	"time"
+
+	"easybook/models"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// DefaultRules are used for the events a hotel has no active notification
+// rule for.
+var DefaultRules = []models.NotificationRule{
+	{Event: models.EventConfirmed, Type: TypeEmail},
+	{Event: models.EventBeforeStart, Type: TypeEmail, OffsetDays: 3, Hour: 9},
+	{Event: models.EventCheckOut, Type: TypeEmail, OffsetDays: 0, Hour: 8},
+	{Event: models.EventCancelled, Type: TypeEmail},
+	{Event: models.EventOffered, Type: TypeEmail},
+	{Event: models.EventRelocated, Type: TypeEmail},
+	{Event: models.EventNoShow, Type: TypeWebhook},
+}
+
+var descriptions = map[string]string{
+	models.EventConfirmed:   "Your reservation is confirmed.",
+	models.EventBeforeStart: "Your stay starts soon.",
+	models.EventCheckOut:    "Today is your check-out day.",
+	models.EventCancelled:   "Your reservation is cancelled.",
+	models.EventOffered:     "Rooms are available for your waitlisted stay, accept the offer before it expires.",
+	models.EventRelocated:   "Your room has changed, the hotel is fully booked for your stay.",
+	models.EventNoShow:      "The guest did not arrive, the reservation is marked no-show.",
+}
+
+// dateEvents are the events scheduled relative to the reservation dates,
+// they follow date changes.
+var dateEvents = []string{models.EventBeforeStart, models.EventCheckOut}
+
+// Confirmed schedules the notifications of a confirmed reservation: the
+// confirmation itself and the reminders before its dates. o is the ormer of
+// the transaction confirming the reservation.
+func Confirmed(o orm.Ormer, r *models.Reservation, hotelID int, now time.Time) error {
+	rules, err := hotelRules(o, hotelID)
+	if err != nil {
+		return err
+	}
+
+	for i := range rules {
+		rule := &rules[i]
+		switch rule.Event {
+		case models.EventConfirmed:
+			err = schedule(o, r, rule, now, now)
+		case models.EventBeforeStart, models.EventCheckOut:
+			// reminders already past when booking late are not sent
+			if at := triggerAt(r, rule); at.After(now) {
+				err = schedule(o, r, rule, at, now)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rescheduled moves the pending date based notifications of a reservation
+// whose dates changed. Reminders that are not pending anymore, e.g. because
+// they were already sent for the old dates, are scheduled again.
+func Rescheduled(o orm.Ormer, r *models.Reservation, hotelID int, now time.Time) error {
+	rules, err := hotelRules(o, hotelID)
+	if err != nil {
+		return err
+	}
+	pending, err := models.GetPendingNotifications(o, r.Id, now, dateEvents...)
+	if err != nil {
+		return err
+	}
+
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Event != models.EventBeforeStart && rule.Event != models.EventCheckOut {
+			continue
+		}
+
+		at := triggerAt(r, rule)
+		var found bool
+		for _, n := range pending {
+			if n.Event != rule.Event || n.Type != rule.Type {
+				continue
+			}
+			found = true
+			if !at.After(now) {
+				err = models.CancelNotification(o, n.Id)
+			} else if !at.Equal(n.TriggerAt) {
+				err = models.RescheduleNotification(o, n.Id, at)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if !found && at.After(now) {
+			if err = schedule(o, r, rule, at, now); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Cancelled cancels the pending notifications of a cancelled reservation
+// and schedules the cancellation notice.
+func Cancelled(o orm.Ormer, r *models.Reservation, hotelID int, now time.Time) error {
+	if err := Discarded(o, r, now); err != nil {
+		return err
+	}
+
+	rules, err := hotelRules(o, hotelID)
+	if err != nil {
+		return err
+	}
+	for i := range rules {
+		if rules[i].Event != models.EventCancelled {
+			continue
+		}
+		if err = schedule(o, r, &rules[i], now, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Offered schedules the notice of a waitlist offer, the pending
+// reservation r.
+func Offered(o orm.Ormer, r *models.Reservation, hotelID int, now time.Time) error {
+	rules, err := hotelRules(o, hotelID)
+	if err != nil {
+		return err
+	}
+	for i := range rules {
+		if rules[i].Event != models.EventOffered {
+			continue
+		}
+		if err = schedule(o, r, &rules[i], now, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Relocated schedules the notice of a room of reservation r moved to
+// another room.
+func Relocated(o orm.Ormer, r *models.Reservation, hotelID int, now time.Time) error {
+	rules, err := hotelRules(o, hotelID)
+	if err != nil {
+		return err
+	}
+	for i := range rules {
+		if rules[i].Event != models.EventRelocated {
+			continue
+		}
+		if err = schedule(o, r, &rules[i], now, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NoShow cancels the pending notifications of reservation r, marked
+// no-show, and schedules the notice to the staff of the hotel.
+func NoShow(o orm.Ormer, r *models.Reservation, hotelID int, now time.Time) error {
+	if err := Discarded(o, r, now); err != nil {
+		return err
+	}
+
+	rules, err := hotelRules(o, hotelID)
+	if err != nil {
+		return err
+	}
+	for i := range rules {
+		if rules[i].Event != models.EventNoShow {
+			continue
+		}
+		if err = schedule(o, r, &rules[i], now, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Discarded cancels the pending notifications of a reservation cancelled
+// without notice, e.g. a lapsed waitlist offer.
+func Discarded(o orm.Ormer, r *models.Reservation, now time.Time) error {
+	pending, err := models.GetPendingNotifications(o, r.Id, now)
+	if err != nil {
+		return err
+	}
+	for _, n := range pending {
+		if err = models.CancelNotification(o, n.Id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Changed schedules the notifications following a reservation update from
+// old to r: confirmation, cancellation or date change.
+func Changed(o orm.Ormer, old *models.Reservation, r *models.Reservation, now time.Time) error {
+	if old.Status == r.Status && old.StartDate.Equal(r.StartDate) && old.EndDate.Equal(r.EndDate) {
+		return nil
+	}
+
+	hotelID, err := models.GetReservationHotelId(o, r.Id)
+	if err == orm.ErrNoRows {
+		// no room reserved, the hotel and its rules are unknown
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case r.Status == models.ReservationCancelled && old.Status != models.ReservationCancelled:
+		return Cancelled(o, r, hotelID, now)
+	case r.Status == models.ReservationConfirmed && old.Status != models.ReservationConfirmed:
+		return Confirmed(o, r, hotelID, now)
+	case r.Status == models.ReservationConfirmed:
+		return Rescheduled(o, r, hotelID, now)
+	}
+	return nil
+}
+
+// hotelRules returns the active rules of the hotel and the DefaultRules of
+// the events it has no active rule for.
+func hotelRules(o orm.Ormer, hotelID int) ([]models.NotificationRule, error) {
+	rules, err := models.GetActiveNotificationRules(o, hotelID)
+	if err != nil {
+		return nil, err
+	}
+	events := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		events[rule.Event] = true
+	}
+	for _, rule := range DefaultRules {
+		if !events[rule.Event] {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+// triggerAt returns when a date based rule fires for r, OffsetDays days
+// before the date at Hour o'clock.
+func triggerAt(r *models.Reservation, rule *models.NotificationRule) time.Time {
+	date := r.StartDate
+	if rule.Event == models.EventCheckOut {
+		date = r.EndDate
+	}
+	y, m, d := date.Date()
+	return time.Date(y, m, d-rule.OffsetDays, rule.Hour, 0, 0, 0, time.Local)
+}
+
+// schedule inserts the notification of rule for r, due at at and created
+// at now.
+func schedule(o orm.Ormer, r *models.Reservation, rule *models.NotificationRule, at time.Time, now time.Time) error {
+	_, err := o.Insert(&models.Notification{
+		Type:          rule.Type,
+		TriggerAt:     at,
+		IsCompleted:   models.NotificationPending,
+		Description:   descriptions[rule.Event],
+		ReservationId: r,
+		Event:         rule.Event,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	})
+	return err
+}
@@ -0,0 +1,85 @@
+package notification
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+func init() {
+	RegisterDriver("file", newFileSender)
+	RegisterDriver("inbox", newInboxSender)
+}
+
+// FileSender appends every message as a JSON line to a file, it stands in
+// for any channel in development and tests.
+type FileSender struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+// FileRecord is a line written by FileSender.
+type FileRecord struct {
+	SentAt         time.Time `json:"sent_at"`
+	Type           string    `json:"type"`
+	NotificationId int       `json:"notification_id"`
+	ReservationId  int       `json:"reservation_id"`
+	GuestId        int       `json:"guest_id"`
+	Email          string    `json:"email,omitempty"`
+	Phone          string    `json:"phone,omitempty"`
+	Subject        string    `json:"subject"`
+	Body           string    `json:"body"`
+}
+
+func newFileSender(cfg Config) (Sender, error) {
+	path := cfg.Get("path", filepath.Join("logs", "notifications.log"))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return &FileSender{Path: path}, nil
+}
+
+// Send implements Sender.
+func (s *FileSender) Send(m *Message) error {
+	line, err := json.Marshal(FileRecord{
+		SentAt:         time.Now(),
+		Type:           m.Notification.Type,
+		NotificationId: m.Notification.Id,
+		ReservationId:  m.Reservation.Id,
+		GuestId:        m.Guest.Id,
+		Email:          m.Guest.Email,
+		Phone:          m.Guest.Phone,
+		Subject:        m.Subject,
+		Body:           m.Body,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// InboxSender delivers APP notifications to the in-app inbox. The
+// notification row itself is the inbox entry, so there is nothing to send;
+// the dispatcher pushes it to the Inbox hub once it is completed.
+type InboxSender struct{}
+
+func newInboxSender(cfg Config) (Sender, error) {
+	return InboxSender{}, nil
+}
+
+// Send implements Sender.
+func (InboxSender) Send(m *Message) error {
+	return nil
+}
@@ -0,0 +1,134 @@
+package notification
+
+import (
+	"bufio"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+)
+
+// MemMail is a mail received by a MemSMTPServer.
+type MemMail struct {
+	From string
+	To   []string
+	Data string
+}
+
+// MemSMTPServer is a minimal SMTP server keeping received mails in memory,
+// it stands in for a real mail server in development and tests.
+type MemSMTPServer struct {
+	ln net.Listener
+
+	mu    sync.Mutex
+	mails []MemMail
+}
+
+// NewMemSMTPServer starts a MemSMTPServer on a random local port.
+func NewMemSMTPServer() (*MemSMTPServer, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &MemSMTPServer{ln: ln}
+	go s.serve()
+	return s, nil
+}
+
+// Addr returns the host:port the server listens on.
+func (s *MemSMTPServer) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// Mails returns the mails received so far.
+func (s *MemSMTPServer) Mails() []MemMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]MemMail(nil), s.mails...)
+}
+
+// Close stops the server.
+func (s *MemSMTPServer) Close() error {
+	return s.ln.Close()
+}
+
+func (s *MemSMTPServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *MemSMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	tp := textproto.NewConn(conn)
+	_ = tp.PrintfLine("220 easybook memsmtp ready")
+
+	var mail MemMail
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			_ = tp.PrintfLine("250 memsmtp")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			mail = MemMail{From: trimAddress(line[len("MAIL FROM:"):])}
+			_ = tp.PrintfLine("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			mail.To = append(mail.To, trimAddress(line[len("RCPT TO:"):]))
+			_ = tp.PrintfLine("250 OK")
+		case cmd == "DATA":
+			_ = tp.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := readData(tp.R)
+			if err != nil {
+				return
+			}
+			mail.Data = data
+			s.mu.Lock()
+			s.mails = append(s.mails, mail)
+			s.mu.Unlock()
+			mail = MemMail{}
+			_ = tp.PrintfLine("250 OK")
+		case cmd == "RSET":
+			mail = MemMail{}
+			_ = tp.PrintfLine("250 OK")
+		case cmd == "NOOP":
+			_ = tp.PrintfLine("250 OK")
+		case cmd == "QUIT":
+			_ = tp.PrintfLine("221 Bye")
+			return
+		default:
+			_ = tp.PrintfLine("502 Command not implemented")
+		}
+	}
+}
+
+func readData(r *bufio.Reader) (string, error) {
+	var b strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		if line == ".\r\n" || line == ".\n" {
+			return b.String(), nil
+		}
+		// undo dot-stuffing
+		b.WriteString(strings.TrimPrefix(line, "."))
+	}
+}
+
+func trimAddress(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		s = s[:i]
+	}
+	return strings.Trim(s, "<>")
+}
@@ -0,0 +1,131 @@
+package notification
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"easybook/models"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/orm"
+)
+
+// TemplateData is the data notification templates are executed with, e.g.
+// {{.Guest.FirstName}} or {{range .Rooms}}{{.Name}}{{end}}.
+type TemplateData struct {
+	Notification *models.Notification
+	Reservation  *models.Reservation
+	Guest        *models.Guest
+	Hotel        *models.Hotel
+	Rooms        []*models.Room
+}
+
+var templateFuncs = map[string]interface{}{
+	"date": func(t time.Time) string {
+		return t.Format("2006-01-02")
+	},
+	"datetime": func(t time.Time) string {
+		return t.Format("2006-01-02 15:04")
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// DefaultLanguage is the language of templates used when none exists in
+// the language of the guest.
+func DefaultLanguage() string {
+	return beego.AppConfig.DefaultString("notifylanguage", "en")
+}
+
+// LoadTemplateData loads the reservation by id with its guest, hotel and
+// rooms.
+func LoadTemplateData(reservationID int) (*TemplateData, error) {
+	o := orm.NewOrm()
+	reservation := &models.Reservation{Id: reservationID}
+	if err := o.Read(reservation); err != nil {
+		return nil, err
+	}
+	guest := &models.Guest{Id: reservation.GuestId.Id}
+	if err := o.Read(guest); err != nil {
+		return nil, err
+	}
+	guest.Password = ""
+
+	data := &TemplateData{
+		Reservation: reservation,
+		Guest:       guest,
+	}
+	l, err := models.GetRoomReservedByReservation(o, reservationID)
+	if err != nil {
+		return nil, err
+	}
+	for _, rr := range l {
+		data.Rooms = append(data.Rooms, rr.RoomId)
+	}
+	if len(data.Rooms) != 0 {
+		data.Hotel = &models.Hotel{Id: data.Rooms[0].HotelId.Id}
+		if err = o.Read(data.Hotel); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// FindTemplate returns the latest version of the template named name for
+// type typ in language, falling back to the default language.
+func FindTemplate(name string, typ string, language string) (*models.NotificationTemplate, error) {
+	if language != "" {
+		t, err := models.GetLatestNotificationTemplate(name, typ, language)
+		if err != orm.ErrNoRows {
+			return t, err
+		}
+	}
+	return models.GetLatestNotificationTemplate(name, typ, DefaultLanguage())
+}
+
+// CheckTemplate reports syntax errors in the subject and body of t.
+func CheckTemplate(t *models.NotificationTemplate) error {
+	if _, err := texttemplate.New("subject").Funcs(templateFuncs).Parse(t.Subject); err != nil {
+		return err
+	}
+	if t.Format == models.FormatHTML {
+		_, err := htmltemplate.New("body").Funcs(templateFuncs).Parse(t.Body)
+		return err
+	}
+	_, err := texttemplate.New("body").Funcs(templateFuncs).Parse(t.Body)
+	return err
+}
+
+// Render executes the subject and body of t with data. Bodies in the html
+// format are escaped by html/template.
+func Render(t *models.NotificationTemplate, data *TemplateData) (subject string, body string, err error) {
+	var b bytes.Buffer
+	st, err := texttemplate.New("subject").Funcs(templateFuncs).Parse(t.Subject)
+	if err != nil {
+		return "", "", err
+	}
+	if err = st.Execute(&b, data); err != nil {
+		return "", "", err
+	}
+	subject = strings.TrimSpace(b.String())
+
+	b.Reset()
+	if t.Format == models.FormatHTML {
+		var bt *htmltemplate.Template
+		if bt, err = htmltemplate.New("body").Funcs(templateFuncs).Parse(t.Body); err == nil {
+			err = bt.Execute(&b, data)
+		}
+	} else {
+		var bt *texttemplate.Template
+		if bt, err = texttemplate.New("body").Funcs(templateFuncs).Parse(t.Body); err == nil {
+			err = bt.Execute(&b, data)
+		}
+	}
+	if err != nil {
+		return "", "", err
+	}
+	return subject, b.String(), nil
+}
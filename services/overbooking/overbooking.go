@@ -0,0 +1,201 @@
+// Package overbooking lets hotels reserve more rooms than they have,
+// reports the nights overbooked and relocates guests when more arrive
+// than the hotel has rooms.
+package overbooking
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"easybook/models"
+	"easybook/services/availability"
+	"easybook/services/notification"
+	"easybook/types"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// Errors of reserved rooms which cannot be relocated.
+var (
+	ErrCanceled   = errors.New("overbooking: reservation is canceled")
+	ErrCheckedIn  = errors.New("overbooking: room is checked in")
+	ErrSameRoom   = errors.New("overbooking: room is the room reserved")
+	ErrOtherHotel = errors.New("overbooking: room is in another hotel than the room reserved")
+)
+
+// ErrDuplicate is returned for a second allowance of a hotel or service
+// level.
+var ErrDuplicate = errors.New("overbooking: allowance exists for this hotel and service level")
+
+// IsError reports whether err is caused by the allowance or relocation
+// asked rather than by the database.
+func IsError(err error) bool {
+	return err == availability.ErrUnavailable || strings.HasPrefix(err.Error(), "overbooking: ")
+}
+
+// Night is a night a service level of a hotel has more rooms reserved than
+// rooms. RoomReserved are the reserved rooms sharing a room that night,
+// the guests to relocate.
+type Night struct {
+	Date types.Date `json:"date"`
+	availability.Level
+	Overbooked   int   `json:"overbooked"`
+	RoomReserved []int `json:"roomReserved"`
+}
+
+// Validate checks allowance v within the transaction of o: the service
+// level, if any, is of the hotel and no other allowance exists for them.
+func Validate(o orm.Ormer, v *models.OverbookingAllowance) error {
+	switch {
+	case v.HotelId == nil || v.HotelId.Id == 0:
+		return errors.New("overbooking: hotel is required")
+	case v.Rooms < 0:
+		return errors.New("overbooking: rooms must not be negative")
+	}
+	qs := o.QueryTable(new(models.OverbookingAllowance)).Filter("HotelId", v.HotelId.Id).Exclude("Id", v.Id)
+	if v.ServiceLevelId != nil && v.ServiceLevelId.Id != 0 {
+		level := &models.ServiceLevel{Id: v.ServiceLevelId.Id}
+		if err := o.Read(level); err == orm.ErrNoRows || err == nil && level.HotelId.Id != v.HotelId.Id {
+			return errors.New("overbooking: service level is not of the hotel")
+		} else if err != nil {
+			return err
+		}
+		qs = qs.Filter("ServiceLevelId", v.ServiceLevelId.Id)
+	} else {
+		v.ServiceLevelId = nil
+		qs = qs.Filter("ServiceLevelId__isnull", true)
+	}
+	if qs.Exist() {
+		return ErrDuplicate
+	}
+	return nil
+}
+
+// Report returns the nights from from until the day before to that a
+// service level of hotel hotelID is overbooked, by date and level.
+func Report(o orm.Ormer, hotelID int, from time.Time, to time.Time) ([]Night, error) {
+	dates, nights, err := availability.Levels(o, hotelID, from, to, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// the reserved rooms of each room for each night
+	shared := make(map[string]map[int][]int)
+	for _, n := range nights {
+		date := n.Date.Format(types.JSONDate)
+		if shared[date] == nil {
+			shared[date] = make(map[int][]int)
+		}
+		shared[date][n.RoomId] = append(shared[date][n.RoomId], n.RoomReservedId)
+	}
+
+	l := make([]Night, 0)
+	for date, levels := range dates {
+		for _, level := range levels {
+			if level.Reserved <= level.Rooms {
+				continue
+			}
+			d, _ := types.DateString(date)
+			l = append(l, Night{Date: d, Level: *level, Overbooked: level.Reserved - level.Rooms, RoomReserved: []int{}})
+		}
+	}
+	sort.Slice(l, func(i, j int) bool {
+		if !l[i].Date.Time.Equal(l[j].Date.Time) {
+			return l[i].Date.Time.Before(l[j].Date.Time)
+		}
+		return l[i].ServiceLevelId < l[j].ServiceLevelId
+	})
+	levelOf := make(map[int]int)
+	for _, n := range nights {
+		levelOf[n.RoomId] = n.ServiceLevelId
+	}
+	for i := range l {
+		for room, rrs := range shared[l[i].Date.Time.Format(types.JSONDate)] {
+			if len(rrs) > 1 && levelOf[room] == l[i].ServiceLevelId {
+				l[i].RoomReserved = append(l[i].RoomReserved, rrs...)
+			}
+		}
+		sort.Ints(l[i].RoomReserved)
+	}
+	return l, nil
+}
+
+// Relocate moves reserved room rrID to the vacant room roomID of the same
+// hotel for its whole stay within the transaction of o, at the price of the nights booked, and
+// notifies the guest. relocatedBy is the staff member relocating.
+func Relocate(o orm.Ormer, rrID int, roomID int, reason string, relocatedBy int, now time.Time) (*models.Relocation, error) {
+	rr := &models.RoomReserved{Id: rrID}
+	if err := o.ReadForUpdate(rr); err != nil {
+		return nil, err
+	}
+	r := &models.Reservation{Id: rr.ReservationId.Id}
+	if err := o.ReadForUpdate(r); err != nil {
+		return nil, err
+	}
+	switch {
+	case r.Status == models.ReservationCancelled:
+		return nil, ErrCanceled
+	case !rr.CheckIn.IsZero():
+		return nil, ErrCheckedIn
+	case rr.RoomId.Id == roomID:
+		return nil, ErrSameRoom
+	}
+	to := &models.Room{Id: roomID}
+	if err := o.Read(to); err == orm.ErrNoRows {
+		return nil, fmt.Errorf("overbooking: room %d does not exist", roomID)
+	} else if err != nil {
+		return nil, err
+	}
+	from := &models.Room{Id: rr.RoomId.Id}
+	if err := o.Read(from); err != nil {
+		return nil, err
+	}
+	if to.HotelId == nil || from.HotelId == nil || to.HotelId.Id != from.HotelId.Id {
+		return nil, ErrOtherHotel
+	}
+
+	// the other rooms of the reservation are not vacant for it
+	rrs, err := models.GetRoomReservedByReservation(o, r.Id)
+	if err != nil {
+		return nil, err
+	}
+	for _, other := range rrs {
+		if other.RoomId.Id == roomID {
+			return nil, availability.ErrUnavailable
+		}
+	}
+	if err = availability.CheckVacant(o, r.Id, []int{roomID}, r.StartDate, r.EndDate, now); err != nil {
+		return nil, err
+	}
+
+	v := &models.Relocation{
+		ReservationId:  r,
+		RoomReservedId: rr,
+		FromRoomId:     rr.RoomId,
+		ToRoomId:       &models.Room{Id: roomID},
+		Reason:         reason,
+		CreatedAt:      now,
+	}
+	if relocatedBy != 0 {
+		v.RelocatedBy = &models.Guest{Id: relocatedBy}
+	}
+	rr.RoomId = v.ToRoomId
+	if _, err = o.Update(rr, "RoomId"); err != nil {
+		return nil, err
+	}
+	if _, err = o.Insert(v); err != nil {
+		return nil, err
+	}
+
+	hotelID, err := models.GetReservationHotelId(o, r.Id)
+	if err != nil && err != orm.ErrNoRows {
+		return nil, err
+	}
+	if err = notification.Relocated(o, r, hotelID, now); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
@@ -0,0 +1,143 @@
+package overbooking
+
+import (
+	"testing"
+	"time"
+
+	"easybook/models"
+	"easybook/services/availability"
+	"easybook/services/servicetest"
+	"easybook/types"
+)
+
+func TestMain(m *testing.M) {
+	servicetest.Main(m)
+}
+
+func TestValidate(t *testing.T) {
+	o := servicetest.Ormer(t)
+	h, rooms := servicetest.Hotel(t, o, 1, types.NewMoney(1000000, types.DefaultCurrency))
+	other, otherRooms := servicetest.Hotel(t, o, 1, types.NewMoney(1000000, types.DefaultCurrency))
+	level := rooms[0].ServiceLevelId
+	now := time.Now()
+	servicetest.Insert(t, o, &models.OverbookingAllowance{HotelId: h, Rooms: 1, CreatedAt: now, UpdatedAt: now})
+
+	cases := []struct {
+		name string
+		v    models.OverbookingAllowance
+		err  bool
+	}{
+		{"no hotel", models.OverbookingAllowance{Rooms: 1}, true},
+		{"negative rooms", models.OverbookingAllowance{HotelId: h, Rooms: -1}, true},
+		{"second of the hotel", models.OverbookingAllowance{HotelId: h, Rooms: 2}, true},
+		{"level of the hotel", models.OverbookingAllowance{HotelId: h, ServiceLevelId: level, Rooms: 2}, false},
+		{"level of another hotel", models.OverbookingAllowance{HotelId: h, ServiceLevelId: otherRooms[0].ServiceLevelId, Rooms: 2}, true},
+		{"empty level of another hotel", models.OverbookingAllowance{HotelId: other, ServiceLevelId: &models.ServiceLevel{}, Rooms: 2}, false},
+	}
+	for _, c := range cases {
+		err := Validate(o, &c.v)
+		if (err != nil) != c.err || err != nil && !IsError(err) {
+			t.Errorf("%s: Validate error = %v, want an overbooking error %v", c.name, err, c.err)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	o := servicetest.Ormer(t)
+	h, rooms := servicetest.Hotel(t, o, 2, types.NewMoney(1000000, types.DefaultCurrency))
+	g := servicetest.Guest(t, o)
+	from := servicetest.Date(2030, 11, 1)
+	to := from.AddDate(0, 0, 3)
+	r1 := servicetest.Reservation(t, o, g, rooms, from, to, models.ReservationConfirmed)
+	r2 := servicetest.Reservation(t, o, g, rooms[:1], from.AddDate(0, 0, 1), from.AddDate(0, 0, 2), models.ReservationConfirmed)
+	servicetest.Reservation(t, o, g, rooms[1:], from.AddDate(0, 0, 2), to, models.ReservationCancelled)
+
+	l, err := Report(o, h.Id, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l) != 1 {
+		t.Fatalf("Report = %+v, want the second night only", l)
+	}
+	first := roomReserved(t, r1, rooms[0].Id)
+	second := roomReserved(t, r2, rooms[0].Id)
+	n := l[0]
+	if !n.Date.Time.Equal(from.AddDate(0, 0, 1)) || n.Overbooked != 1 || n.Reserved != 3 || n.Rooms != 2 ||
+		len(n.RoomReserved) != 2 || n.RoomReserved[0] != first || n.RoomReserved[1] != second {
+		t.Errorf("overbooked night = %+v, want %v overbooked by 1 with reserved rooms %d and %d",
+			n, from.AddDate(0, 0, 1), first, second)
+	}
+}
+
+func TestRelocate(t *testing.T) {
+	o := servicetest.Ormer(t)
+	_, rooms := servicetest.Hotel(t, o, 3, types.NewMoney(1000000, types.DefaultCurrency))
+	_, elsewhere := servicetest.Hotel(t, o, 1, types.NewMoney(1000000, types.DefaultCurrency))
+	g, staff := servicetest.Guest(t, o), servicetest.Guest(t, o)
+	from := servicetest.Date(2030, 12, 1)
+	to := from.AddDate(0, 0, 2)
+	now := time.Now().Truncate(time.Second)
+	r := servicetest.Reservation(t, o, g, rooms[:2], from, to, models.ReservationConfirmed)
+	busy := servicetest.Reservation(t, o, g, rooms[2:], from.AddDate(0, 0, 1), to, models.ReservationConfirmed)
+	canceled := servicetest.Reservation(t, o, g, rooms[:1], from, to, models.ReservationCancelled)
+	rr := roomReserved(t, r, rooms[0].Id)
+
+	cases := []struct {
+		name string
+		rr   int
+		room int
+		err  error
+	}{
+		{"same room", rr, rooms[0].Id, ErrSameRoom},
+		{"room of the reservation", rr, rooms[1].Id, availability.ErrUnavailable},
+		{"room reserved", rr, rooms[2].Id, availability.ErrUnavailable},
+		{"other hotel", rr, elsewhere[0].Id, ErrOtherHotel},
+		{"canceled", roomReserved(t, canceled, rooms[0].Id), rooms[2].Id, ErrCanceled},
+	}
+	for _, c := range cases {
+		if _, err := Relocate(o, c.rr, c.room, "overbooked", staff.Id, now); err != c.err {
+			t.Errorf("%s: Relocate error = %v, want %v", c.name, err, c.err)
+		}
+	}
+	if _, err := Relocate(o, rr, -1, "overbooked", staff.Id, now); err == nil || !IsError(err) {
+		t.Errorf("Relocate to an unknown room error = %v, want an overbooking error", err)
+	}
+
+	// the busy room is vacant once its reservation is canceled
+	busy.Status = models.ReservationCancelled
+	if _, err := o.Update(busy, "Status"); err != nil {
+		t.Fatal(err)
+	}
+	v, err := Relocate(o, rr, rooms[2].Id, "overbooked", staff.Id, now)
+	if err != nil {
+		t.Fatalf("Relocate error: %v", err)
+	}
+	if v.FromRoomId.Id != rooms[0].Id || v.ToRoomId.Id != rooms[2].Id || v.RelocatedBy.Id != staff.Id {
+		t.Errorf("relocation = %+v, want from room %d to %d by %d", v, rooms[0].Id, rooms[2].Id, staff.Id)
+	}
+	moved := &models.RoomReserved{Id: rr}
+	if err = o.Read(moved); err != nil || moved.RoomId.Id != rooms[2].Id {
+		t.Errorf("reserved room after relocation = %+v, %v, want room %d", moved, err, rooms[2].Id)
+	}
+	n, err := o.QueryTable(new(models.Notification)).
+		Filter("ReservationId", r.Id).Filter("Event", models.EventRelocated).Count()
+	if err != nil || n == 0 {
+		t.Errorf("%d relocation notices, %v, want the guest notified", n, err)
+	}
+}
+
+// roomReserved returns the id of the room reserved of room by r.
+func roomReserved(t *testing.T, r *models.Reservation, room int) int {
+	t.Helper()
+	rrs, err := models.GetRoomReservedByReservation(servicetest.Ormer(t), r.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rr := range rrs {
+		if rr.RoomId.Id == room {
+			return rr.Id
+		}
+	}
+	t.Fatalf("room %d is not reserved by reservation %d", room, r.Id)
+	return 0
+}
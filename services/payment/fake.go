@@ -0,0 +1,125 @@
+package payment
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"easybook/types"
+
+	"github.com/astaxie/beego"
+)
+
+// Tokens understood by FakeProvider. Any other token is authorized.
+const (
+	FakeTokenDecline = "tok_decline"
+	// FakeTokenAsync authorizes pending, the outcome is expected by webhook.
+	FakeTokenAsync = "tok_async"
+	// FakeTokenRefundFail makes refunds of the payment fail.
+	FakeTokenRefundFail = "tok_refund_fail"
+)
+
+// FakeSignatureHeader carries the HMAC-SHA256 of fake webhook bodies.
+const FakeSignatureHeader = "X-Fake-Signature"
+
+// init registers the fake provider in the dev and test run modes only, it
+// authorizes any token.
+func init() {
+	if beego.BConfig.RunMode != beego.DEV && beego.BConfig.RunMode != "test" {
+		return
+	}
+	Register(&FakeProvider{Secret: beego.AppConfig.DefaultString("paymentfakesecret", "fake-secret")})
+}
+
+// FakeProvider is a provider for development and tests: it never calls
+// out, references are derived from the request and its idempotency key, a
+// random nonce without one, and outcomes from the token.
+type FakeProvider struct {
+	Secret string
+}
+
+// Name implements Provider.
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+// Authorize implements Provider.
+func (p *FakeProvider) Authorize(req *AuthorizeRequest) (*Result, error) {
+	if req.Token == FakeTokenDecline {
+		return nil, ErrDeclined
+	}
+	nonce := req.Key
+	if nonce == "" {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		nonce = hex.EncodeToString(b)
+	}
+	ref := "fake_pay_" + digest(fmt.Sprintf("%d:%s:%s:%s", req.InvoiceId, req.Amount, req.Token, nonce))
+	if req.Token == FakeTokenRefundFail {
+		ref += "_rf"
+	}
+	return &Result{Ref: ref, Pending: req.Token == FakeTokenAsync}, nil
+}
+
+// Capture implements Provider.
+func (p *FakeProvider) Capture(ref string, amount types.Money) (*Result, error) {
+	if !strings.HasPrefix(ref, "fake_pay_") {
+		return nil, errors.New("fake: unknown payment")
+	}
+	return &Result{Ref: ref}, nil
+}
+
+// Refund implements Provider.
+func (p *FakeProvider) Refund(ref string, amount types.Money) (*Result, error) {
+	if !strings.HasPrefix(ref, "fake_pay_") {
+		return nil, errors.New("fake: unknown payment")
+	}
+	if strings.HasSuffix(ref, "_rf") {
+		return nil, ErrDeclined
+	}
+	return &Result{Ref: "fake_ref_" + digest(fmt.Sprintf("%s:%s", ref, amount))}, nil
+}
+
+// Void implements Provider.
+func (p *FakeProvider) Void(ref string) (*Result, error) {
+	if !strings.HasPrefix(ref, "fake_pay_") {
+		return nil, errors.New("fake: unknown payment")
+	}
+	return &Result{Ref: ref}, nil
+}
+
+// ParseWebhook implements Provider. The body is an Event in JSON signed by
+// Sign in the X-Fake-Signature header.
+func (p *FakeProvider) ParseWebhook(header http.Header, body []byte) (*Event, error) {
+	if !hmac.Equal([]byte(header.Get(FakeSignatureHeader)), []byte(p.Sign(body))) {
+		return nil, ErrInvalidSignature
+	}
+	e := &Event{}
+	if err := json.Unmarshal(body, e); err != nil {
+		return nil, err
+	}
+	if e.Id == "" || e.Type == "" || e.PaymentRef == "" {
+		return nil, errors.New("fake: id, type and payment_ref are required")
+	}
+	return e, nil
+}
+
+// Sign returns the signature of a webhook body, to simulate provider calls.
+func (p *FakeProvider) Sign(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(p.Secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func digest(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:8])
+}
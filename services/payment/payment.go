@@ -0,0 +1,323 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"easybook/models"
+	"easybook/types"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// ErrInvalidState is returned for operations not allowed in the current
+// status of a payment or invoice.
+var ErrInvalidState = errors.New("payment: operation not allowed in current state")
+
+// Authorize reserves amount of invoice invoiceID through provider p, and
+// captures it right away when capture is set. A declined authorization is
+// recorded as a failed payment and returned with ErrDeclined.
+func Authorize(p Provider, invoiceID int, amount types.Money, token string, key string, capture bool, now time.Time) (*models.Payment, error) {
+	o := orm.NewOrm()
+	inv := &models.Invoice{Id: invoiceID}
+	if err := o.Read(inv); err != nil {
+		return nil, err
+	}
+	if !inv.CanceledAt.IsZero() || !inv.PaidAt.IsZero() {
+		return nil, ErrInvalidState
+	}
+	if amount.Sign() <= 0 {
+		amount = inv.Amount
+	}
+	if amount.Currency != inv.Amount.Currency {
+		return nil, types.ErrCurrencyMismatch
+	}
+
+	v := &models.Payment{
+		InvoiceId:      inv,
+		Provider:       p.Name(),
+		Amount:         amount,
+		RefundedAmount: types.NewMoney(0, amount.Currency),
+		Status:         models.PaymentPending,
+	}
+	res, err := p.Authorize(&AuthorizeRequest{
+		InvoiceId: invoiceID,
+		Amount:    amount,
+		Token:     token,
+		Key:       key,
+	})
+	switch {
+	case err != nil:
+		v.Status = models.PaymentFailed
+		v.FailureReason = err.Error()
+	case res.Pending:
+		v.ProviderRef = res.Ref
+	default:
+		v.ProviderRef = res.Ref
+		v.Status = models.PaymentAuthorized
+		v.AuthorizedAt = now
+	}
+	if _, ierr := o.Insert(v); ierr != nil {
+		return nil, ierr
+	}
+	if err != nil {
+		return v, err
+	}
+
+	if capture && v.Status == models.PaymentAuthorized {
+		return Capture(p, v.Id, now)
+	}
+	return v, nil
+}
+
+// Capture collects the authorized payment by id and marks its invoice paid
+// once the captured payments cover the invoice amount.
+func Capture(p Provider, id int, now time.Time) (*models.Payment, error) {
+	return update(id, func(o orm.Ormer, v *models.Payment) error {
+		if v.Status != models.PaymentAuthorized {
+			return ErrInvalidState
+		}
+		if _, err := p.Capture(v.ProviderRef, v.Amount); err != nil {
+			return err
+		}
+		return captured(o, v, now)
+	})
+}
+
+// Void releases the authorized payment by id.
+func Void(p Provider, id int, now time.Time) (*models.Payment, error) {
+	return update(id, func(o orm.Ormer, v *models.Payment) error {
+		if v.Status != models.PaymentAuthorized && v.Status != models.PaymentPending {
+			return ErrInvalidState
+		}
+		if _, err := p.Void(v.ProviderRef); err != nil {
+			return err
+		}
+		v.Status = models.PaymentVoided
+		v.VoidedAt = now
+		_, err := o.Update(v, "Status", "VoidedAt")
+		return err
+	})
+}
+
+// Refund gives back amount of the captured payment by id, the whole
+// remaining amount if amount is 0. A refund declined by the provider is
+// recorded as failed and returned with the error.
+func Refund(p Provider, id int, amount types.Money, reason string, now time.Time) (r *models.Refund, err error) {
+	var declined error
+	_, err = update(id, func(o orm.Ormer, v *models.Payment) error {
+		remaining, err := v.Amount.Sub(v.RefundedAmount)
+		if err != nil {
+			return err
+		}
+		if amount.Sign() <= 0 {
+			amount = remaining
+		}
+		if amount.Currency != remaining.Currency {
+			return types.ErrCurrencyMismatch
+		}
+		cmp, err := amount.Cmp(remaining)
+		if err != nil {
+			return err
+		}
+		if v.Status != models.PaymentCaptured || cmp > 0 {
+			return ErrInvalidState
+		}
+
+		r = &models.Refund{
+			PaymentId: v,
+			Amount:    amount,
+			Reason:    reason,
+			Status:    models.RefundPending,
+		}
+		res, perr := p.Refund(v.ProviderRef, amount)
+		switch {
+		case perr != nil:
+			declined = perr
+			r.Status = models.RefundFailed
+			r.FailureReason = perr.Error()
+		case res.Pending:
+			r.ProviderRef = res.Ref
+		default:
+			r.ProviderRef = res.Ref
+			r.Status = models.RefundSucceeded
+		}
+		if _, err := o.Insert(r); err != nil {
+			return err
+		}
+		if r.Status == models.RefundPending || r.Status == models.RefundSucceeded {
+			// pending refunds hold their amount until they fail
+			if err := refunded(o, v, amount); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		err = declined
+	}
+	return r, err
+}
+
+// HandleWebhook verifies and applies a webhook call of provider p. Events
+// already handled are acknowledged without being applied again, so
+// duplicate returns true.
+func HandleWebhook(p Provider, header http.Header, body []byte, now time.Time) (duplicate bool, err error) {
+	e, err := p.ParseWebhook(header, body)
+	if err != nil {
+		return false, err
+	}
+
+	o := orm.NewOrm()
+	_ = o.Begin()
+	created, err := models.AddPaymentEvent(o, &models.PaymentEvent{
+		Provider:    p.Name(),
+		EventId:     e.Id,
+		Type:        e.Type,
+		Payload:     string(body),
+		ProcessedAt: now,
+	})
+	if err != nil {
+		_ = o.Rollback()
+		return false, err
+	}
+	if !created {
+		_ = o.Rollback()
+		return true, nil
+	}
+
+	if err = apply(o, p, e, now); err != nil {
+		_ = o.Rollback()
+		return false, err
+	}
+	return false, o.Commit()
+}
+
+func apply(o orm.Ormer, p Provider, e *Event, now time.Time) error {
+	v, err := models.GetPaymentByRef(o, p.Name(), e.PaymentRef)
+	if err != nil {
+		return err
+	}
+
+	switch e.Type {
+	case EventAuthorized:
+		if v.Status != models.PaymentPending {
+			return nil
+		}
+		v.Status = models.PaymentAuthorized
+		v.AuthorizedAt = now
+		_, err = o.Update(v, "Status", "AuthorizedAt")
+	case EventCaptured:
+		if v.Status != models.PaymentPending && v.Status != models.PaymentAuthorized {
+			return nil
+		}
+		if v.AuthorizedAt.IsZero() {
+			v.AuthorizedAt = now
+		}
+		err = captured(o, v, now)
+	case EventFailed:
+		if v.Status != models.PaymentPending && v.Status != models.PaymentAuthorized {
+			return nil
+		}
+		v.Status = models.PaymentFailed
+		v.FailureReason = e.Reason
+		_, err = o.Update(v, "Status", "FailureReason")
+	case EventVoided:
+		if v.Status != models.PaymentPending && v.Status != models.PaymentAuthorized {
+			return nil
+		}
+		v.Status = models.PaymentVoided
+		v.VoidedAt = now
+		_, err = o.Update(v, "Status", "VoidedAt")
+	case EventRefundSucceeded, EventRefundFailed:
+		var r *models.Refund
+		if r, err = models.GetRefundByRef(o, v.Id, e.RefundRef); err != nil {
+			return err
+		}
+		if r.Status != models.RefundPending {
+			return nil
+		}
+		if e.Type == EventRefundSucceeded {
+			r.Status = models.RefundSucceeded
+		} else {
+			r.Status = models.RefundFailed
+			r.FailureReason = e.Reason
+			if err = refunded(o, v, r.Amount.Neg()); err != nil {
+				return err
+			}
+		}
+		_, err = o.Update(r, "Status", "FailureReason")
+	default:
+		return fmt.Errorf("payment: unknown event type %q", e.Type)
+	}
+	return err
+}
+
+// update runs fn on the payment by id locked within a transaction.
+func update(id int, fn func(o orm.Ormer, v *models.Payment) error) (*models.Payment, error) {
+	o := orm.NewOrm()
+	_ = o.Begin()
+	v := &models.Payment{Id: id}
+	if err := o.ReadForUpdate(v); err != nil {
+		_ = o.Rollback()
+		return nil, err
+	}
+	if err := fn(o, v); err != nil {
+		_ = o.Rollback()
+		return nil, err
+	}
+	return v, o.Commit()
+}
+
+// captured marks v captured and its invoice paid when fully covered.
+func captured(o orm.Ormer, v *models.Payment, now time.Time) error {
+	v.Status = models.PaymentCaptured
+	v.CapturedAt = now
+	if _, err := o.Update(v, "Status", "AuthorizedAt", "CapturedAt"); err != nil {
+		return err
+	}
+	return settle(o, v.InvoiceId.Id, now)
+}
+
+// refunded adds amount to the refunded amount of v, and reopens its invoice
+// when the payments do not cover it anymore.
+func refunded(o orm.Ormer, v *models.Payment, amount types.Money) error {
+	refunded, err := v.RefundedAmount.Add(amount)
+	if err != nil {
+		return err
+	}
+	v.RefundedAmount = refunded
+	if _, err := o.Update(v, "RefundedAmount"); err != nil {
+		return err
+	}
+	return settle(o, v.InvoiceId.Id, time.Now())
+}
+
+// settle sets or clears PaidAt of the invoice by id according to its
+// captured payments.
+func settle(o orm.Ormer, invoiceID int, now time.Time) error {
+	inv := &models.Invoice{Id: invoiceID}
+	if err := o.ReadForUpdate(inv); err != nil {
+		return err
+	}
+	paid, err := models.CapturedAmount(o, invoiceID)
+	if err != nil {
+		return err
+	}
+
+	cmp, err := paid.Cmp(inv.Amount)
+	if err != nil {
+		return err
+	}
+	switch {
+	case cmp >= 0 && inv.PaidAt.IsZero():
+		_, err = o.QueryTable(new(models.Invoice)).Filter("Id", invoiceID).
+			Update(orm.Params{"PaidAt": now})
+	case cmp < 0 && !inv.PaidAt.IsZero():
+		_, err = o.QueryTable(new(models.Invoice)).Filter("Id", invoiceID).
+			Update(orm.Params{"PaidAt": nil})
+	}
+	return err
+}
@@ -0,0 +1,110 @@
+// Package payment charges invoices through pluggable payment providers.
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"easybook/types"
+
+	"github.com/astaxie/beego"
+)
+
+// ErrDeclined is returned by providers when the payment method is refused.
+var ErrDeclined = errors.New("payment: declined")
+
+// ErrInvalidSignature is returned by providers for webhook calls they did
+// not sign.
+var ErrInvalidSignature = errors.New("payment: invalid webhook signature")
+
+// AuthorizeRequest asks a provider to reserve Amount on a payment method.
+type AuthorizeRequest struct {
+	InvoiceId int
+	Amount    types.Money
+	// Token identifies the payment method at the provider.
+	Token string
+	// Key makes retried requests authorize once.
+	Key string
+}
+
+// Result is the outcome of a provider operation.
+type Result struct {
+	// Ref is the id of the payment or refund at the provider.
+	Ref string
+	// Pending reports whether the outcome is sent later by webhook.
+	Pending bool
+}
+
+// Webhook event types.
+const (
+	EventAuthorized      = "payment.authorized"
+	EventCaptured        = "payment.captured"
+	EventFailed          = "payment.failed"
+	EventVoided          = "payment.voided"
+	EventRefundSucceeded = "refund.succeeded"
+	EventRefundFailed    = "refund.failed"
+)
+
+// Event is an asynchronous outcome sent by a provider.
+type Event struct {
+	// Id is unique per provider, redelivered events have the same id.
+	Id   string `json:"id"`
+	Type string `json:"type"`
+	// PaymentRef is the provider id of the payment.
+	PaymentRef string `json:"payment_ref"`
+	// RefundRef is the provider id of the refund of refund events.
+	RefundRef string `json:"refund_ref,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// Provider is a payment service provider.
+type Provider interface {
+	Name() string
+	Authorize(req *AuthorizeRequest) (*Result, error)
+	Capture(ref string, amount types.Money) (*Result, error)
+	Refund(ref string, amount types.Money) (*Result, error)
+	Void(ref string) (*Result, error)
+	// ParseWebhook verifies and decodes a webhook call.
+	ParseWebhook(header http.Header, body []byte) (*Event, error)
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]Provider{}
+)
+
+// Register makes p available by its name.
+func Register(p Provider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[p.Name()] = p
+}
+
+// Lookup returns the provider registered by name.
+func Lookup(name string) (Provider, bool) {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	p, ok := providers[name]
+	return p, ok
+}
+
+// Default returns the provider named by paymentprovider in app.conf.
+func Default() (Provider, bool) {
+	return Lookup(beego.AppConfig.String("paymentprovider"))
+}
+
+// Setup checks that paymentprovider in app.conf names a registered
+// provider, the fake provider is only registered in the dev and test run
+// modes.
+func Setup() error {
+	name := beego.AppConfig.String("paymentprovider")
+	if name == "" {
+		return errors.New("payment: paymentprovider is not configured")
+	}
+	if _, ok := Lookup(name); !ok {
+		return fmt.Errorf("payment: unknown paymentprovider %q in run mode %s", name, beego.BConfig.RunMode)
+	}
+	return nil
+}
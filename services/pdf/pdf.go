@@ -0,0 +1,302 @@
+// Package pdf writes simple PDF documents: text in the standard Helvetica
+// fonts, lines, filled rectangles and images. Coordinates are in points
+// from the top left corner of the page.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	_ "image/gif" // image formats accepted by Image
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// A4 page size in points.
+const (
+	A4Width  = 595.28
+	A4Height = 841.89
+)
+
+// Align is the horizontal alignment of text relative to its x coordinate.
+type Align int
+
+// Alignments of text.
+const (
+	Left Align = iota
+	Right
+	Center
+)
+
+// Document is a PDF document being built.
+type Document struct {
+	width, height float64
+
+	pages  []*bytes.Buffer
+	images []*pdfImage
+	page   *bytes.Buffer
+}
+
+type pdfImage struct {
+	name          string
+	width, height int
+	filter        string
+	colorSpace    string
+	data          []byte
+}
+
+// New creates an empty A4 document.
+func New() *Document {
+	return &Document{width: A4Width, height: A4Height}
+}
+
+// Size returns the page size.
+func (d *Document) Size() (width float64, height float64) {
+	return d.width, d.height
+}
+
+// AddPage starts a new page, following drawing goes to it.
+func (d *Document) AddPage() {
+	d.page = &bytes.Buffer{}
+	d.pages = append(d.pages, d.page)
+}
+
+// Text draws s with its baseline at y. Characters outside of Latin-1 are
+// replaced by "?" as the standard fonts do not have them.
+func (d *Document) Text(x float64, y float64, size float64, bold bool, align Align, s string) {
+	switch align {
+	case Right:
+		x -= TextWidth(s, size, bold)
+	case Center:
+		x -= TextWidth(s, size, bold) / 2
+	}
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(d.page, "BT /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, d.height-y, escape(encode(s)))
+}
+
+// Line draws a line of width w in gray level g, 0 is black and 1 white.
+func (d *Document) Line(x1 float64, y1 float64, x2 float64, y2 float64, w float64, g float64) {
+	fmt.Fprintf(d.page, "q %.2f G %.2f w %.2f %.2f m %.2f %.2f l S Q\n", g, w, x1, d.height-y1, x2, d.height-y2)
+}
+
+// Rect fills a rectangle with its top left corner at x, y in gray level g.
+func (d *Document) Rect(x float64, y float64, w float64, h float64, g float64) {
+	fmt.Fprintf(d.page, "q %.2f g %.2f %.2f %.2f %.2f re f Q\n", g, x, d.height-y-h, w, h)
+}
+
+// Image draws the JPEG, PNG or GIF image read from r in the box of width w
+// and height h whose top left corner is at x, y, keeping its aspect ratio.
+func (d *Document) Image(r io.Reader, x float64, y float64, w float64, h float64) error {
+	raw, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	img, format, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+
+	b := img.Bounds()
+	pi := &pdfImage{
+		name:       fmt.Sprintf("Im%d", len(d.images)+1),
+		width:      b.Dx(),
+		height:     b.Dy(),
+		colorSpace: "DeviceRGB",
+	}
+	if format == "jpeg" {
+		switch img.(type) {
+		case *image.Gray:
+			pi.colorSpace = "DeviceGray"
+		case *image.CMYK:
+			pi.colorSpace = "DeviceCMYK"
+		}
+		pi.filter, pi.data = "DCTDecode", raw
+	} else {
+		// other formats are embedded as RGB pixels over a white background
+		var px bytes.Buffer
+		for yy := b.Min.Y; yy < b.Max.Y; yy++ {
+			for xx := b.Min.X; xx < b.Max.X; xx++ {
+				cr, cg, cb, ca := img.At(xx, yy).RGBA()
+				white := 0xffff - ca
+				px.WriteByte(byte((cr + white) >> 8))
+				px.WriteByte(byte((cg + white) >> 8))
+				px.WriteByte(byte((cb + white) >> 8))
+			}
+		}
+		pi.filter, pi.data = "FlateDecode", deflate(px.Bytes())
+	}
+	d.images = append(d.images, pi)
+
+	scale := w / float64(pi.width)
+	if s := h / float64(pi.height); s < scale {
+		scale = s
+	}
+	iw, ih := float64(pi.width)*scale, float64(pi.height)*scale
+	fmt.Fprintf(d.page, "q %.2f 0 0 %.2f %.2f %.2f cm /%s Do Q\n", iw, ih, x, d.height-y-ih, pi.name)
+	return nil
+}
+
+// WriteTo writes the document to w.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	var out bytes.Buffer
+	var offsets []int
+	obj := func(body string, stream []byte) int {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\n", len(offsets), body)
+		if stream != nil {
+			out.WriteString("stream\n")
+			out.Write(stream)
+			out.WriteString("\nendstream\n")
+		}
+		out.WriteString("endobj\n")
+		return len(offsets)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// objects 1 and 2 are the catalog and the page tree, pages follow the
+	// fonts and images they refer to
+	obj("<< /Type /Catalog /Pages 2 0 R >>", nil)
+	pagesAt := len(offsets)
+	offsets = append(offsets, 0)
+
+	f1 := obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>", nil)
+	f2 := obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>", nil)
+	var xobjects strings.Builder
+	for _, img := range d.images {
+		id := obj(fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /%s /BitsPerComponent 8 /Filter /%s /Length %d >>",
+			img.width, img.height, img.colorSpace, img.filter, len(img.data)), img.data)
+		fmt.Fprintf(&xobjects, " /%s %d 0 R", img.name, id)
+	}
+	resources := fmt.Sprintf("<< /Font << /F1 %d 0 R /F2 %d 0 R >> /XObject <<%s >> >>", f1, f2, xobjects.String())
+
+	var kids []string
+	for _, page := range d.pages {
+		content := deflate(page.Bytes())
+		c := obj(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>", len(content)), content)
+		p := obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources %s /Contents %d 0 R >>",
+			d.width, d.height, resources, c), nil)
+		kids = append(kids, fmt.Sprintf("%d 0 R", p))
+	}
+
+	offsets[pagesAt] = out.Len()
+	fmt.Fprintf(&out, "2 0 obj\n<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n", strings.Join(kids, " "), len(kids))
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return out.WriteTo(w)
+}
+
+// Bytes returns the document.
+func (d *Document) Bytes() []byte {
+	var b bytes.Buffer
+	_, _ = d.WriteTo(&b)
+	return b.Bytes()
+}
+
+// TextWidth returns the width of s in points.
+func TextWidth(s string, size float64, bold bool) float64 {
+	widths := helvetica
+	if bold {
+		widths = helveticaBold
+	}
+	var w int
+	for _, c := range encode(s) {
+		if c >= 32 && c <= 126 {
+			w += widths[c-32]
+		} else {
+			w += 556
+		}
+	}
+	return float64(w) * size / 1000
+}
+
+// Wrap splits s in lines not wider than width.
+func Wrap(s string, size float64, bold bool, width float64) []string {
+	var lines []string
+	for _, para := range strings.Split(s, "\n") {
+		line := ""
+		for _, word := range strings.Fields(para) {
+			next := word
+			if line != "" {
+				next = line + " " + word
+			}
+			if line != "" && TextWidth(next, size, bold) > width {
+				lines = append(lines, line)
+				next = word
+			}
+			line = next
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// encode converts s to WinAnsi bytes.
+func encode(s string) []byte {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r < 0x80 || (r >= 0xa0 && r <= 0xff):
+			b = append(b, byte(r))
+		case r == '€':
+			b = append(b, 0x80)
+		case r == '–':
+			b = append(b, 0x96)
+		default:
+			b = append(b, '?')
+		}
+	}
+	return b
+}
+
+func escape(b []byte) string {
+	var s strings.Builder
+	for _, c := range b {
+		if c == '(' || c == ')' || c == '\\' {
+			s.WriteByte('\\')
+		}
+		s.WriteByte(c)
+	}
+	return s.String()
+}
+
+func deflate(b []byte) []byte {
+	var out bytes.Buffer
+	zw := zlib.NewWriter(&out)
+	_, _ = zw.Write(b)
+	_ = zw.Close()
+	return out.Bytes()
+}
+
+// Character widths of the standard fonts from space to tilde, per 1000
+// units of font size.
+var helvetica = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBold = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
@@ -0,0 +1,46 @@
+// Package periodic runs the background jobs of the services, e.g. sweeping
+// expired holds, at a fixed interval.
+package periodic
+
+import (
+	"time"
+
+	"github.com/astaxie/beego/logs"
+)
+
+// Runner runs a job in background. The zero Runner is ready to use.
+type Runner struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Start calls run at once and then every interval in background until Stop
+// is called. Errors of run are logged as failures of name, e.g.
+// "hold: sweep".
+func (r *Runner) Start(name string, interval time.Duration, run func(now time.Time) error) {
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+
+	go func() {
+		defer close(r.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := run(time.Now()); err != nil {
+				logs.Error("%s failed: %v", name, err)
+			}
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the runner and waits for the current run to finish.
+func (r *Runner) Stop() {
+	close(r.stop)
+	<-r.done
+}
@@ -0,0 +1,319 @@
+// Package pricing prices reservations: the room nights, the discount and
+// the taxes and fees of the city and hotel.
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"easybook/models"
+	"easybook/types"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// Night is the price of a reserved room for a night.
+type Night struct {
+	RoomReserved *models.RoomReserved `json:"-"`
+	RoomId       int                  `json:"roomId"`
+	Date         types.Date           `json:"date"`
+	Price        types.Money          `json:"price"`
+}
+
+// Charge is a tax or fee applied by a TaxRule.
+type Charge struct {
+	RuleId    int         `json:"ruleId"`
+	Name      string      `json:"name"`
+	Category  string      `json:"category"`
+	Quantity  int         `json:"quantity"`
+	UnitPrice types.Money `json:"unitPrice"`
+	Amount    types.Money `json:"amount"`
+}
+
+// Discount is a discount of a reservation, the redemption of the promotion
+// PromotionId or, without one, the discount percent of the reservation.
+type Discount struct {
+	PromotionId int         `json:"promotionId,omitempty"`
+	Name        string      `json:"name"`
+	Amount      types.Money `json:"amount"`
+}
+
+// Breakdown itemizes the price of a reservation. Total is Rooms less
+// Discount, the sum of Discounts, plus Tax and Fees, the sums of the
+// charges of each category.
+type Breakdown struct {
+	Nights    []Night     `json:"nights"`
+	Rooms     types.Money `json:"rooms"`
+	Discounts []Discount  `json:"discounts,omitempty"`
+	Discount  types.Money `json:"discount"`
+	Charges   []Charge    `json:"charges"`
+	Tax       types.Money `json:"tax"`
+	Fees      types.Money `json:"fees"`
+	Total     types.Money `json:"total"`
+}
+
+// Quote prices reservation r of hotel hotelID within the transaction of o
+// from the RoomNight of its reserved rooms, nights without one at the Price
+// of the room reserved. The breakdown is in the currency of the
+// reservation, rooms and rules in another currency fail with
+// types.ErrCurrencyMismatch.
+func Quote(o orm.Ormer, r *models.Reservation, hotelID int) (*Breakdown, error) {
+	currency := r.TotalPrice.Currency
+	if currency == "" {
+		currency = types.DefaultCurrency
+	}
+	zero := types.NewMoney(0, currency)
+	b := &Breakdown{Rooms: zero, Discount: zero, Tax: zero, Fees: zero}
+
+	rooms, err := models.GetRoomReservedByReservation(o, r.Id)
+	if err != nil {
+		return nil, err
+	}
+	priced, err := models.GetRoomNightsByReservation(o, r.Id)
+	if err != nil {
+		return nil, err
+	}
+	prices := make(map[int]map[time.Time]types.Money)
+	for _, n := range priced {
+		if prices[n.RoomReservedId.Id] == nil {
+			prices[n.RoomReservedId.Id] = make(map[time.Time]types.Money)
+		}
+		prices[n.RoomReservedId.Id][day(n.Date)] = n.Price
+	}
+
+	nights := Nights(r)
+	for i := range rooms {
+		rr := &rooms[i]
+		for _, night := range nights {
+			price, ok := prices[rr.Id][night]
+			if !ok {
+				price = rr.Price
+			}
+			if price.Currency != currency && !price.IsZero() {
+				return nil, types.ErrCurrencyMismatch
+			}
+			b.Nights = append(b.Nights, Night{
+				RoomReserved: rr,
+				RoomId:       rr.RoomId.Id,
+				Date:         types.Date{Time: night},
+				Price:        price,
+			})
+			if b.Rooms, err = b.Rooms.Add(price); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if r.DiscountPercent > 0 {
+		b.Discounts = append(b.Discounts, Discount{
+			Name:   fmt.Sprintf("Discount %g%%", r.DiscountPercent),
+			Amount: b.Rooms.Percent(float64(r.DiscountPercent)),
+		})
+	}
+	redemptions, err := models.GetReservationRedemptions(o, r.Id)
+	if err != nil {
+		return nil, err
+	}
+	for _, rd := range redemptions {
+		if rd.Amount.Currency != currency && !rd.Amount.IsZero() {
+			return nil, types.ErrCurrencyMismatch
+		}
+		name := rd.PromotionId.Name
+		if name == "" {
+			name = "Promotion " + rd.PromotionId.Code
+		}
+		b.Discounts = append(b.Discounts, Discount{PromotionId: rd.PromotionId.Id, Name: name, Amount: rd.Amount})
+	}
+	for _, d := range b.Discounts {
+		if b.Discount, err = b.Discount.Add(d.Amount); err != nil {
+			return nil, err
+		}
+	}
+
+	var rules []models.TaxRule
+	if hotelID != 0 && len(nights) != 0 {
+		if rules, err = rulesOf(o, hotelID, nights[0], nights[len(nights)-1].AddDate(0, 0, 1)); err != nil {
+			return nil, err
+		}
+	}
+	if err = b.charge(rules, r, nights, len(rooms)); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// charge applies the taxes and fees of rules to the stay of r for nights in
+// rooms rooms and totals b from its Rooms and Discount.
+func (b *Breakdown) charge(rules []models.TaxRule, r *models.Reservation, nights []time.Time, rooms int) error {
+	currency := b.Rooms.Currency
+	guests := int(r.Guests)
+	if guests == 0 {
+		guests = rooms
+	}
+
+	base, err := b.Rooms.Sub(b.Discount)
+	if err != nil {
+		return err
+	}
+	charged := types.NewMoney(0, currency)
+	for i := range rules {
+		rule := &rules[i]
+		c := Charge{RuleId: rule.Id, Name: rule.Name, Category: rule.Category, Quantity: 1}
+		switch rule.Basis {
+		case models.BasisPercent:
+			if !inEffect(rule, nights[0]) {
+				continue
+			}
+			on := base
+			if rule.Compound != 0 {
+				if on, err = on.Add(charged); err != nil {
+					return err
+				}
+			}
+			c.UnitPrice = on.Percent(rule.Rate)
+			c.Name = fmt.Sprintf("%s %g%%", rule.Name, rule.Rate)
+		case models.BasisNight, models.BasisGuestNight:
+			n := 0
+			for _, night := range nights {
+				if inEffect(rule, night) {
+					n++
+				}
+			}
+			if rule.Basis == models.BasisNight {
+				c.Quantity = n * rooms
+			} else {
+				c.Quantity = n * guests
+			}
+			c.UnitPrice = rule.Amount
+		case models.BasisStay, models.BasisShuttle:
+			if !inEffect(rule, nights[0]) || rule.Basis == models.BasisShuttle && r.AirportShuttle == 0 {
+				continue
+			}
+			c.UnitPrice = rule.Amount
+		default:
+			return fmt.Errorf("pricing: unknown basis %q of tax rule %d", rule.Basis, rule.Id)
+		}
+		if c.Quantity == 0 || c.UnitPrice.IsZero() {
+			continue
+		}
+		if c.UnitPrice.Currency != currency {
+			return types.ErrCurrencyMismatch
+		}
+
+		c.Amount = c.UnitPrice.Mul(int64(c.Quantity))
+		if charged, err = charged.Add(c.Amount); err != nil {
+			return err
+		}
+		if rule.Category == models.CategoryFee {
+			b.Fees, err = b.Fees.Add(c.Amount)
+		} else {
+			b.Tax, err = b.Tax.Add(c.Amount)
+		}
+		if err != nil {
+			return err
+		}
+		b.Charges = append(b.Charges, c)
+	}
+
+	if b.Total, err = base.Add(b.Tax); err != nil {
+		return err
+	}
+	if b.Total, err = b.Total.Add(b.Fees); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ValidateRule checks the scope, category, basis and charge of v.
+func ValidateRule(v *models.TaxRule) error {
+	switch {
+	case v.Name == "":
+		return errors.New("pricing: name is required")
+	case (v.CityId == nil) == (v.HotelId == nil):
+		return errors.New("pricing: either city or hotel is required")
+	case v.Category != models.CategoryTax && v.Category != models.CategoryFee:
+		return fmt.Errorf("pricing: unknown category %q", v.Category)
+	case v.EffectFrom.IsZero():
+		return errors.New("pricing: effective date is required")
+	case !v.ExpireOn.IsZero() && !v.ExpireOn.After(v.EffectFrom):
+		return errors.New("pricing: expiry must follow the effective date")
+	}
+	switch v.Basis {
+	case models.BasisPercent:
+		if v.Rate <= 0 || v.Rate > 100 {
+			return errors.New("pricing: rate must be a percent above 0")
+		}
+	case models.BasisNight, models.BasisGuestNight, models.BasisStay, models.BasisShuttle:
+		if v.Amount.Sign() <= 0 {
+			return errors.New("pricing: amount must be positive")
+		}
+		if types.Stored(v.Amount) != nil {
+			return fmt.Errorf("pricing: amount must be in %s", types.DefaultCurrency)
+		}
+	default:
+		return fmt.Errorf("pricing: unknown basis %q", v.Basis)
+	}
+	return nil
+}
+
+// rulesOf returns the rules of hotelID and its city in effect for a night
+// from from until the day before to. Hotel rules replace the city rules of
+// the same name.
+func rulesOf(o orm.Ormer, hotelID int, from time.Time, to time.Time) ([]models.TaxRule, error) {
+	hotel := &models.Hotel{Id: hotelID}
+	if err := o.Read(hotel); err != nil {
+		return nil, err
+	}
+	var cityID int
+	if hotel.CityId != nil {
+		cityID = hotel.CityId.Id
+	}
+	all, err := models.GetTaxRules(o, cityID, hotelID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	own := make(map[string]bool)
+	for _, rule := range all {
+		if rule.HotelId != nil {
+			own[rule.Name] = true
+		}
+	}
+	var rules []models.TaxRule
+	for _, rule := range all {
+		if rule.HotelId == nil && own[rule.Name] {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// inEffect reports whether rule applies to the night of date.
+func inEffect(rule *models.TaxRule, date time.Time) bool {
+	return effective(rule.EffectFrom, rule.ExpireOn, date)
+}
+
+// effective reports whether the night of date is from effectFrom until the
+// day before expireOn, if any.
+func effective(effectFrom time.Time, expireOn time.Time, date time.Time) bool {
+	return !date.Before(day(effectFrom)) && (expireOn.IsZero() || date.Before(day(expireOn)))
+}
+
+// Nights returns the nights of r, from StartDate to the day before
+// EndDate.
+func Nights(r *models.Reservation) []time.Time {
+	var nights []time.Time
+	end := day(r.EndDate)
+	for night := day(r.StartDate); night.Before(end); night = night.AddDate(0, 0, 1) {
+		nights = append(nights, night)
+	}
+	return nights
+}
+
+// day returns the date of t at midnight UTC, dates are read from the
+// database in the local time zone.
+func day(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
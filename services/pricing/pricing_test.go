@@ -0,0 +1,246 @@
+package pricing
+
+import (
+	"testing"
+	"time"
+
+	"easybook/models"
+	"easybook/types"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func vnd(amount int64) types.Money {
+	return types.NewMoney(amount, "VND")
+}
+
+func TestNights(t *testing.T) {
+	local := time.FixedZone("ICT", 7*60*60)
+	cases := []struct {
+		start, end time.Time
+		want       []time.Time
+	}{
+		{date(2026, 1, 10), date(2026, 1, 10), nil},
+		{date(2026, 1, 10), date(2026, 1, 9), nil},
+		{date(2026, 1, 10), date(2026, 1, 11), []time.Time{date(2026, 1, 10)}},
+		{date(2026, 12, 31), date(2027, 1, 2), []time.Time{date(2026, 12, 31), date(2027, 1, 1)}},
+		{time.Date(2026, 1, 10, 0, 0, 0, 0, local), time.Date(2026, 1, 12, 0, 0, 0, 0, local), []time.Time{date(2026, 1, 10), date(2026, 1, 11)}},
+	}
+	for _, c := range cases {
+		got := Nights(&models.Reservation{StartDate: c.start, EndDate: c.end})
+		if len(got) != len(c.want) {
+			t.Errorf("Nights(%v, %v) = %v, want %v", c.start, c.end, got, c.want)
+			continue
+		}
+		for i := range got {
+			if !got[i].Equal(c.want[i]) || got[i].Location() != time.UTC {
+				t.Errorf("Nights(%v, %v) = %v, want %v", c.start, c.end, got, c.want)
+				break
+			}
+		}
+	}
+}
+
+func TestEffective(t *testing.T) {
+	cases := []struct {
+		from, expire, date time.Time
+		want               bool
+	}{
+		{date(2026, 1, 1), time.Time{}, date(2026, 1, 1), true},
+		{date(2026, 1, 1), time.Time{}, date(2025, 12, 31), false},
+		{date(2026, 1, 1), date(2026, 2, 1), date(2026, 1, 31), true},
+		{date(2026, 1, 1), date(2026, 2, 1), date(2026, 2, 1), false},
+		{time.Date(2026, 1, 1, 23, 0, 0, 0, time.Local), time.Time{}, date(2026, 1, 1), true},
+	}
+	for _, c := range cases {
+		if got := effective(c.from, c.expire, c.date); got != c.want {
+			t.Errorf("effective(%v, %v, %v) = %v, want %v", c.from, c.expire, c.date, got, c.want)
+		}
+	}
+}
+
+func TestCharge(t *testing.T) {
+	from := date(2026, 1, 1)
+	vat := models.TaxRule{Id: 1, Name: "VAT", Category: models.CategoryTax, Basis: models.BasisPercent, Rate: 10, EffectFrom: from}
+	service := models.TaxRule{Id: 2, Name: "Service", Category: models.CategoryFee, Basis: models.BasisPercent, Rate: 5, Compound: 1, EffectFrom: from}
+	tourism := models.TaxRule{Id: 3, Name: "Tourism", Category: models.CategoryTax, Basis: models.BasisGuestNight, Amount: vnd(20000), EffectFrom: from}
+	cleaning := models.TaxRule{Id: 4, Name: "Cleaning", Category: models.CategoryFee, Basis: models.BasisNight, Amount: vnd(10000), EffectFrom: from}
+	booking := models.TaxRule{Id: 5, Name: "Booking", Category: models.CategoryFee, Basis: models.BasisStay, Amount: vnd(50000), EffectFrom: from}
+	shuttle := models.TaxRule{Id: 6, Name: "Shuttle", Category: models.CategoryFee, Basis: models.BasisShuttle, Amount: vnd(300000), EffectFrom: from}
+	expiring := tourism
+	expiring.ExpireOn = date(2026, 1, 11)
+	later := vat
+	later.EffectFrom = date(2026, 1, 11)
+	foreign := booking
+	foreign.Amount = types.NewMoney(500, "USD")
+	unknown := booking
+	unknown.Basis = "month"
+
+	nights := []time.Time{date(2026, 1, 10), date(2026, 1, 11)}
+	cases := []struct {
+		name     string
+		rules    []models.TaxRule
+		r        models.Reservation
+		tax      types.Money
+		fees     types.Money
+		total    types.Money
+		charges  int
+		mismatch bool
+		fails    bool
+	}{
+		{name: "no rules", tax: vnd(0), fees: vnd(0), total: vnd(3600000)},
+		{name: "percent", rules: []models.TaxRule{vat}, tax: vnd(360000), fees: vnd(0), total: vnd(3960000), charges: 1},
+		{name: "compound", rules: []models.TaxRule{vat, service}, tax: vnd(360000), fees: vnd(198000), total: vnd(4158000), charges: 2},
+		{name: "guest nights", rules: []models.TaxRule{tourism}, r: models.Reservation{Guests: 3}, tax: vnd(120000), fees: vnd(0), total: vnd(3720000), charges: 1},
+		{name: "guests default to rooms", rules: []models.TaxRule{tourism}, tax: vnd(80000), fees: vnd(0), total: vnd(3680000), charges: 1},
+		{name: "expired night", rules: []models.TaxRule{expiring}, r: models.Reservation{Guests: 3}, tax: vnd(60000), fees: vnd(0), total: vnd(3660000), charges: 1},
+		{name: "room nights", rules: []models.TaxRule{cleaning}, tax: vnd(0), fees: vnd(40000), total: vnd(3640000), charges: 1},
+		{name: "stay", rules: []models.TaxRule{booking}, tax: vnd(0), fees: vnd(50000), total: vnd(3650000), charges: 1},
+		{name: "no shuttle", rules: []models.TaxRule{shuttle}, tax: vnd(0), fees: vnd(0), total: vnd(3600000)},
+		{name: "shuttle", rules: []models.TaxRule{shuttle}, r: models.Reservation{AirportShuttle: 1}, tax: vnd(0), fees: vnd(300000), total: vnd(3900000), charges: 1},
+		{name: "not yet in effect", rules: []models.TaxRule{later}, tax: vnd(0), fees: vnd(0), total: vnd(3600000)},
+		{name: "foreign amount", rules: []models.TaxRule{foreign}, mismatch: true},
+		{name: "unknown basis", rules: []models.TaxRule{unknown}, fails: true},
+	}
+	for _, c := range cases {
+		b := &Breakdown{Rooms: vnd(4000000), Discount: vnd(400000), Tax: vnd(0), Fees: vnd(0)}
+		err := b.charge(c.rules, &c.r, nights, 2)
+		switch {
+		case c.mismatch:
+			if err != types.ErrCurrencyMismatch {
+				t.Errorf("%s: error = %v, want ErrCurrencyMismatch", c.name, err)
+			}
+		case c.fails:
+			if err == nil {
+				t.Errorf("%s: error = nil, want an error", c.name)
+			}
+		case err != nil:
+			t.Errorf("%s: error: %v", c.name, err)
+		case b.Tax != c.tax || b.Fees != c.fees || b.Total != c.total || len(b.Charges) != c.charges:
+			t.Errorf("%s: tax %v, fees %v, total %v, %d charges, want %v, %v, %v, %d",
+				c.name, b.Tax, b.Fees, b.Total, len(b.Charges), c.tax, c.fees, c.total, c.charges)
+		}
+	}
+}
+
+func TestValidateRule(t *testing.T) {
+	valid := models.TaxRule{
+		Name:       "VAT",
+		CityId:     &models.City{Id: 1},
+		Category:   models.CategoryTax,
+		Basis:      models.BasisPercent,
+		Rate:       10,
+		EffectFrom: date(2026, 1, 1),
+	}
+	cases := []struct {
+		name string
+		edit func(v *models.TaxRule)
+		ok   bool
+	}{
+		{"valid", func(v *models.TaxRule) {}, true},
+		{"hotel", func(v *models.TaxRule) { v.CityId, v.HotelId = nil, &models.Hotel{Id: 1} }, true},
+		{"no name", func(v *models.TaxRule) { v.Name = "" }, false},
+		{"no scope", func(v *models.TaxRule) { v.CityId = nil }, false},
+		{"two scopes", func(v *models.TaxRule) { v.HotelId = &models.Hotel{Id: 1} }, false},
+		{"unknown category", func(v *models.TaxRule) { v.Category = "levy" }, false},
+		{"no effective date", func(v *models.TaxRule) { v.EffectFrom = time.Time{} }, false},
+		{"expiry before effective date", func(v *models.TaxRule) { v.ExpireOn = date(2025, 12, 31) }, false},
+		{"expiry on effective date", func(v *models.TaxRule) { v.ExpireOn = v.EffectFrom }, false},
+		{"rate zero", func(v *models.TaxRule) { v.Rate = 0 }, false},
+		{"rate above 100", func(v *models.TaxRule) { v.Rate = 101 }, false},
+		{"amount", func(v *models.TaxRule) { v.Basis, v.Amount = models.BasisNight, vnd(10000) }, true},
+		{"no amount", func(v *models.TaxRule) { v.Basis = models.BasisStay }, false},
+		{"foreign amount", func(v *models.TaxRule) { v.Basis, v.Amount = models.BasisStay, types.NewMoney(500, "USD") }, false},
+		{"unknown basis", func(v *models.TaxRule) { v.Basis = "month" }, false},
+	}
+	for _, c := range cases {
+		v := valid
+		c.edit(&v)
+		if err := ValidateRule(&v); (err == nil) != c.ok {
+			t.Errorf("%s: ValidateRule error = %v, want ok %v", c.name, err, c.ok)
+		}
+	}
+}
+
+func TestValidatePlan(t *testing.T) {
+	valid := models.RatePlan{
+		RoomId:     &models.Room{Id: 1},
+		Price:      vnd(1000000),
+		Weekdays:   1<<time.Friday | 1<<time.Saturday,
+		EffectFrom: date(2026, 1, 1),
+	}
+	cases := []struct {
+		name string
+		edit func(v *models.RatePlan)
+		ok   bool
+	}{
+		{"valid", func(v *models.RatePlan) {}, true},
+		{"service level", func(v *models.RatePlan) { v.RoomId, v.ServiceLevelId = nil, &models.ServiceLevel{Id: 1} }, true},
+		{"no scope", func(v *models.RatePlan) { v.RoomId = nil }, false},
+		{"two scopes", func(v *models.RatePlan) { v.ServiceLevelId = &models.ServiceLevel{Id: 1} }, false},
+		{"no price", func(v *models.RatePlan) { v.Price = types.Money{} }, false},
+		{"negative price", func(v *models.RatePlan) { v.Price = vnd(-1) }, false},
+		{"foreign price", func(v *models.RatePlan) { v.Price = types.NewMoney(5000, "USD") }, false},
+		{"every day", func(v *models.RatePlan) { v.Weekdays = 0 }, true},
+		{"unknown weekday", func(v *models.RatePlan) { v.Weekdays = 1 << 7 }, false},
+		{"no effective date", func(v *models.RatePlan) { v.EffectFrom = time.Time{} }, false},
+		{"expiry on effective date", func(v *models.RatePlan) { v.ExpireOn = v.EffectFrom }, false},
+	}
+	for _, c := range cases {
+		v := valid
+		c.edit(&v)
+		if err := ValidatePlan(&v); (err == nil) != c.ok {
+			t.Errorf("%s: ValidatePlan error = %v, want ok %v", c.name, err, c.ok)
+		}
+	}
+}
+
+func TestPlanOf(t *testing.T) {
+	from := date(2026, 1, 1)
+	weekend := uint8(1<<time.Saturday | 1<<time.Sunday)
+	// plans of room 7 and of its service level 1
+	plans := []models.RatePlan{
+		{Id: 1, ServiceLevelId: &models.ServiceLevel{Id: 1}, EffectFrom: from},
+		{Id: 2, ServiceLevelId: &models.ServiceLevel{Id: 1}, Weekdays: weekend, Priority: 1, EffectFrom: from},
+		{Id: 3, RoomId: &models.Room{Id: 7}, EffectFrom: from, ExpireOn: date(2026, 1, 17)},
+		{Id: 4, ServiceLevelId: &models.ServiceLevel{Id: 1}, EffectFrom: from},
+	}
+	cases := []struct {
+		name string
+		date time.Time
+		want int
+	}{
+		{"room plan over priority", date(2026, 1, 10), 3},
+		{"room plan", date(2026, 1, 14), 3},
+		{"latest of equal plans", date(2026, 1, 22), 4},
+		{"priority", date(2026, 1, 17), 2},
+		{"before any plan", date(2025, 12, 31), 0},
+	}
+	for _, c := range cases {
+		got := 0
+		if p := planOf(plans, 7, c.date); p != nil {
+			got = p.Id
+		}
+		if got != c.want {
+			t.Errorf("%s: planOf(%v) = plan %d, want %d", c.name, c.date, got, c.want)
+		}
+	}
+}
+
+func TestCheckMinStay(t *testing.T) {
+	cases := []struct {
+		rates []Rate
+		want  error
+	}{
+		{nil, nil},
+		{[]Rate{{MinStay: 0}, {MinStay: 2}}, nil},
+		{[]Rate{{MinStay: 3}, {MinStay: 0}}, ErrMinStay},
+	}
+	for _, c := range cases {
+		if err := CheckMinStay(c.rates); err != c.want {
+			t.Errorf("CheckMinStay(%v) = %v, want %v", c.rates, err, c.want)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"easybook/models"
+	"easybook/types"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// ErrMinStay is returned when a stay is shorter than the minimum stay of
+// the rate plan of one of its nights.
+var ErrMinStay = errors.New("pricing: stay is shorter than the minimum stay")
+
+// Rate is the price of a room for a night, from the RatePlan PlanId or the
+// current price of the room if none.
+type Rate struct {
+	Date    types.Date  `json:"date"`
+	Price   types.Money `json:"price"`
+	PlanId  int         `json:"planId,omitempty"`
+	MinStay int         `json:"minStay,omitempty"`
+}
+
+// Rates returns the rate of room for each of nights from the rate plans of
+// the room and of its service level.
+func Rates(o orm.Ormer, room *models.Room, nights []time.Time) ([]Rate, error) {
+	if len(nights) == 0 {
+		return nil, nil
+	}
+	var levelID int
+	if room.ServiceLevelId != nil {
+		levelID = room.ServiceLevelId.Id
+	}
+	plans, err := models.GetRatePlans(o, room.Id, levelID, nights[0], nights[len(nights)-1].AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	rates := make([]Rate, len(nights))
+	for i, night := range nights {
+		rates[i] = Rate{Date: types.Date{Time: night}, Price: room.CurrentPrice}
+		if p := planOf(plans, room.Id, night); p != nil {
+			rates[i].Price = p.Price
+			rates[i].PlanId = p.Id
+			rates[i].MinStay = int(p.MinStay)
+		}
+	}
+	return rates, nil
+}
+
+// CheckMinStay returns ErrMinStay if the plan of a night of rates needs a
+// longer stay.
+func CheckMinStay(rates []Rate) error {
+	for _, rate := range rates {
+		if rate.MinStay > len(rates) {
+			return ErrMinStay
+		}
+	}
+	return nil
+}
+
+// ReserveRoom prices rr for nights within the transaction of o, checks the
+// minimum stay and inserts it with the price of each night. The room of rr
+// must be loaded.
+func ReserveRoom(o orm.Ormer, rr *models.RoomReserved, nights []time.Time) error {
+	rates, err := Rates(o, rr.RoomId, nights)
+	if err != nil {
+		return err
+	}
+	if err = CheckMinStay(rates); err != nil {
+		return err
+	}
+
+	rr.Price = rr.RoomId.CurrentPrice
+	if len(rates) != 0 {
+		rr.Price = rates[0].Price
+	}
+	if _, err = o.Insert(rr); err != nil {
+		return err
+	}
+	for _, rate := range rates {
+		night := &models.RoomNight{RoomReservedId: rr, Date: rate.Date.Time, Price: rate.Price}
+		if rate.PlanId != 0 {
+			night.RatePlanId = &models.RatePlan{Id: rate.PlanId}
+		}
+		if _, err = o.Insert(night); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RepriceRoom moves rr, reserved already, to nights within the transaction
+// of o and checks the minimum stay. The nights kept keep the price they
+// were booked at, the nights added are priced from the rate plans in
+// effect and the nights dropped are deleted. The room of rr must be loaded.
+func RepriceRoom(o orm.Ormer, rr *models.RoomReserved, nights []time.Time) error {
+	rates, err := Rates(o, rr.RoomId, nights)
+	if err != nil {
+		return err
+	}
+	if err = CheckMinStay(rates); err != nil {
+		return err
+	}
+
+	var l []models.RoomNight
+	if _, err = o.QueryTable(new(models.RoomNight)).Filter("RoomReservedId", rr.Id).All(&l); err != nil {
+		return err
+	}
+	booked := make(map[time.Time]*models.RoomNight, len(l))
+	for i := range l {
+		booked[day(l[i].Date)] = &l[i]
+	}
+	for i, rate := range rates {
+		if night, ok := booked[rate.Date.Time]; ok {
+			rates[i].Price = night.Price
+			delete(booked, rate.Date.Time)
+			continue
+		}
+		night := &models.RoomNight{RoomReservedId: rr, Date: rate.Date.Time, Price: rate.Price}
+		if rate.PlanId != 0 {
+			night.RatePlanId = &models.RatePlan{Id: rate.PlanId}
+		}
+		if _, err = o.Insert(night); err != nil {
+			return err
+		}
+	}
+	for _, night := range booked {
+		if _, err = o.Delete(night); err != nil {
+			return err
+		}
+	}
+
+	if len(rates) != 0 {
+		rr.Price = rates[0].Price
+	}
+	_, err = o.Update(rr, "Price")
+	return err
+}
+
+// ValidatePlan checks the scope, dates and price of v.
+func ValidatePlan(v *models.RatePlan) error {
+	switch {
+	case (v.RoomId == nil) == (v.ServiceLevelId == nil):
+		return errors.New("pricing: either room or service level is required")
+	case v.Price.Sign() <= 0:
+		return errors.New("pricing: price must be positive")
+	case types.Stored(v.Price) != nil:
+		return fmt.Errorf("pricing: price must be in %s", types.DefaultCurrency)
+	case v.Weekdays >= 1<<7:
+		return errors.New("pricing: weekdays must be bits 1<<0 (Sunday) to 1<<6 (Saturday)")
+	case v.EffectFrom.IsZero():
+		return errors.New("pricing: effective date is required")
+	case !v.ExpireOn.IsZero() && !v.ExpireOn.After(v.EffectFrom):
+		return errors.New("pricing: expiry must follow the effective date")
+	}
+	return nil
+}
+
+// planOf returns the plan of plans for the night of date of room roomID,
+// nil if none applies.
+func planOf(plans []models.RatePlan, roomID int, date time.Time) *models.RatePlan {
+	var best *models.RatePlan
+	for i := range plans {
+		p := &plans[i]
+		if !p.On(date.Weekday()) || !effective(p.EffectFrom, p.ExpireOn, date) {
+			continue
+		}
+		if best == nil || better(p, best, roomID) {
+			best = p
+		}
+	}
+	return best
+}
+
+// better reports whether plan p wins over q for room roomID.
+func better(p *models.RatePlan, q *models.RatePlan, roomID int) bool {
+	pRoom := p.RoomId != nil && p.RoomId.Id == roomID
+	qRoom := q.RoomId != nil && q.RoomId.Id == roomID
+	switch {
+	case pRoom != qRoom:
+		return pRoom
+	case p.Priority != q.Priority:
+		return p.Priority > q.Priority
+	}
+	return p.Id > q.Id
+}
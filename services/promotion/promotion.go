@@ -0,0 +1,193 @@
+// Package promotion redeems the discount codes of reservations.
+package promotion
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"easybook/models"
+	"easybook/services/pricing"
+	"easybook/types"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// Errors of codes which cannot be redeemed.
+var (
+	ErrUnknownCode   = errors.New("promotion: unknown code")
+	ErrNotValid      = errors.New("promotion: code is not valid at this time")
+	ErrNotApplicable = errors.New("promotion: code does not apply to the rooms reserved")
+	ErrUsedUp        = errors.New("promotion: code has been used up")
+	ErrGuestUsedUp   = errors.New("promotion: code has been used up by the guest")
+	ErrNotStackable  = errors.New("promotion: code does not combine with other codes")
+)
+
+// IsError reports whether err is caused by the codes redeemed rather than
+// by the database.
+func IsError(err error) bool {
+	return err == types.ErrCurrencyMismatch || strings.HasPrefix(err.Error(), "promotion: ")
+}
+
+// Redeem redeems codes for reservation r within the transaction of o, after
+// its rooms are reserved. The promotions are locked until the transaction
+// ends, so usage limits hold under concurrent bookings. Percent discounts
+// are taken on the room charges of their scope, discounts add up and never
+// exceed the room charges left after the discount percent of r.
+func Redeem(o orm.Ormer, r *models.Reservation, codes []string, now time.Time) ([]*models.PromotionRedemption, error) {
+	b, err := pricing.Quote(o, r, 0)
+	if err != nil {
+		return nil, err
+	}
+	left, err := b.Rooms.Sub(b.Discount)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var redeemed []*models.PromotionRedemption
+	for _, code := range codes {
+		code = strings.TrimSpace(code)
+		if seen[strings.ToUpper(code)] {
+			return nil, fmt.Errorf("promotion: code %q is given twice", code)
+		}
+		seen[strings.ToUpper(code)] = true
+
+		p, err := models.GetPromotionByCodeForUpdate(o, code)
+		if err == orm.ErrNoRows {
+			return nil, ErrUnknownCode
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case now.Before(p.ValidFrom) || !p.ValidTo.IsZero() && !now.Before(p.ValidTo):
+			return nil, ErrNotValid
+		case len(codes) > 1 && p.Stackable == 0:
+			return nil, ErrNotStackable
+		case p.MaxUses > 0 && p.Uses >= p.MaxUses:
+			return nil, ErrUsedUp
+		}
+		if p.MaxUsesPerGuest > 0 {
+			n, err := models.CountGuestRedemptions(o, p.Id, r.GuestId.Id)
+			if err != nil {
+				return nil, err
+			}
+			if n >= int64(p.MaxUsesPerGuest) {
+				return nil, ErrGuestUsedUp
+			}
+		}
+
+		amount, err := discount(p, b)
+		if err != nil {
+			return nil, err
+		}
+		if cmp, err := amount.Cmp(left); err != nil {
+			return nil, err
+		} else if cmp > 0 {
+			amount = left
+		}
+		if left, err = left.Sub(amount); err != nil {
+			return nil, err
+		}
+
+		p.Uses++
+		if _, err = o.Update(p, "Uses"); err != nil {
+			return nil, err
+		}
+		rd := &models.PromotionRedemption{
+			PromotionId:   p,
+			ReservationId: r,
+			GuestId:       r.GuestId,
+			Amount:        amount,
+			CreatedAt:     now,
+		}
+		if _, err = o.Insert(rd); err != nil {
+			return nil, err
+		}
+		redeemed = append(redeemed, rd)
+	}
+	return redeemed, nil
+}
+
+// Release releases the redemptions of a canceled reservation within the
+// transaction of o, so its codes can be used again.
+func Release(o orm.Ormer, reservationID int, now time.Time) error {
+	l, err := models.GetReservationRedemptions(o, reservationID)
+	if err != nil {
+		return err
+	}
+	for i := range l {
+		rd := &l[i]
+		rd.ReleasedAt = now
+		if _, err = o.Update(rd, "ReleasedAt"); err != nil {
+			return err
+		}
+		_, err = o.QueryTable(new(models.Promotion)).
+			Filter("Id", rd.PromotionId.Id).Filter("Uses__gt", 0).
+			Update(orm.Params{"Uses": orm.ColValue(orm.ColMinus, 1)})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks the code, discount and limits of v.
+func Validate(v *models.Promotion) error {
+	v.Code = strings.TrimSpace(v.Code)
+	switch {
+	case v.Code == "":
+		return errors.New("promotion: code is required")
+	case v.HotelId != nil && v.RoomId != nil:
+		return errors.New("promotion: either hotel or room scope, not both")
+	case (v.Percent > 0) == !v.Amount.IsZero():
+		return errors.New("promotion: either percent or amount is required")
+	case v.Percent < 0 || v.Percent > 100:
+		return errors.New("promotion: percent must be from 0 to 100")
+	case v.Amount.Sign() < 0:
+		return errors.New("promotion: amount must be positive")
+	case types.Stored(v.Amount) != nil:
+		return fmt.Errorf("promotion: amount must be in %s", types.DefaultCurrency)
+	case v.MaxUses < 0 || v.MaxUsesPerGuest < 0:
+		return errors.New("promotion: usage limits must be positive")
+	case !v.ValidTo.IsZero() && !v.ValidTo.After(v.ValidFrom):
+		return errors.New("promotion: end of validity must follow its start")
+	}
+	return nil
+}
+
+// discount returns the discount of p on the nights of b in its scope.
+func discount(p *models.Promotion, b *pricing.Breakdown) (types.Money, error) {
+	base := types.NewMoney(0, b.Rooms.Currency)
+	for _, night := range b.Nights {
+		room := night.RoomReserved.RoomId
+		if p.RoomId != nil && room.Id != p.RoomId.Id {
+			continue
+		}
+		if p.HotelId != nil && (room.HotelId == nil || room.HotelId.Id != p.HotelId.Id) {
+			continue
+		}
+		var err error
+		if base, err = base.Add(night.Price); err != nil {
+			return base, err
+		}
+	}
+	if base.IsZero() {
+		return base, ErrNotApplicable
+	}
+
+	if p.Percent > 0 {
+		return base.Percent(p.Percent), nil
+	}
+	if p.Amount.Currency != base.Currency {
+		return base, types.ErrCurrencyMismatch
+	}
+	if cmp, err := p.Amount.Cmp(base); err != nil {
+		return base, err
+	} else if cmp > 0 {
+		return base, nil
+	}
+	return p.Amount, nil
+}
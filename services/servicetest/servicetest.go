@@ -0,0 +1,148 @@
+// Package servicetest runs the tests of the services against a test
+// database, given by the EASYBOOK_TEST_SQLCONN environment variable in the
+// format of sqlconn in app.conf and loaded with database/easybook.sql. The
+// tests which need it are skipped when it is not set.
+//
+// Packages are tested concurrently against the same database, so tests
+// create the rows they use and only check those.
+package servicetest
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"easybook/models"
+	"easybook/types"
+
+	"github.com/astaxie/beego/orm"
+	// the driver of the test database
+	_ "github.com/go-sql-driver/mysql"
+)
+
+var enabled bool
+
+// Main connects the test database, if any, and runs the tests of m.
+func Main(m *testing.M) {
+	if conn := os.Getenv("EASYBOOK_TEST_SQLCONN"); conn != "" {
+		if err := orm.RegisterDataBase("default", "mysql", conn); err != nil {
+			fmt.Fprintf(os.Stderr, "servicetest: connecting %s: %v\n", conn, err)
+			os.Exit(1)
+		}
+		enabled = true
+	}
+	os.Exit(m.Run())
+}
+
+// Ormer returns an Ormer of the test database, or skips t without one.
+func Ormer(t *testing.T) orm.Ormer {
+	t.Helper()
+	if !enabled {
+		t.Skip("EASYBOOK_TEST_SQLCONN is not set")
+	}
+	return orm.NewOrm()
+}
+
+var seq int64
+
+// Name returns a name unique to the test run, starting with prefix.
+func Name(prefix string) string {
+	return fmt.Sprintf("%s %d-%d", prefix, time.Now().UnixNano(), atomic.AddInt64(&seq, 1))
+}
+
+// Date returns the date y-m-d at midnight UTC.
+func Date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// Hotel inserts an active hotel of the first city with a service level and
+// rooms rooms priced price a night.
+func Hotel(t *testing.T, o orm.Ormer, rooms int, price types.Money) (*models.Hotel, []models.Room) {
+	t.Helper()
+	now := time.Now()
+	h := &models.Hotel{
+		Name:      Name("Hotel"),
+		IsActive:  1,
+		Address:   "1 Test street",
+		CityId:    &models.City{Id: 1},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	Insert(t, o, h)
+	level := &models.ServiceLevel{Name: "Standard", EffectFrom: Date(2020, 1, 1), HotelId: h, CreatedAt: now, UpdatedAt: now}
+	Insert(t, o, level)
+
+	l := make([]models.Room, rooms)
+	for i := range l {
+		l[i] = models.Room{
+			Name:           fmt.Sprintf("Room %d", i+1),
+			Number:         101 + i,
+			CurrentPrice:   price,
+			HotelId:        h,
+			ServiceLevelId: level,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+		Insert(t, o, &l[i])
+	}
+	return h, l
+}
+
+// Guest inserts a guest with an email address.
+func Guest(t *testing.T, o orm.Ormer) *models.Guest {
+	t.Helper()
+	now := time.Now()
+	g := &models.Guest{
+		FirstName: "Test",
+		LastName:  Name("Guest"),
+		Email:     fmt.Sprintf("guest%d@easybook.test", atomic.AddInt64(&seq, 1)),
+		Language:  "en",
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	Insert(t, o, g)
+	return g
+}
+
+// Reservation inserts a reservation of guest g with status for the nights
+// from from until the day before to, reserving rooms and each of their
+// nights at their current price.
+func Reservation(t *testing.T, o orm.Ormer, g *models.Guest, rooms []models.Room, from time.Time, to time.Time, status uint8) *models.Reservation {
+	t.Helper()
+	now := time.Now()
+	r := &models.Reservation{
+		GuestId:    g,
+		StartDate:  from,
+		EndDate:    to,
+		Guests:     uint8(len(rooms)),
+		TotalPrice: types.NewMoney(0, types.DefaultCurrency),
+		Status:     status,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	Insert(t, o, r)
+	for i := range rooms {
+		rr := &models.RoomReserved{
+			ReservationId: r,
+			RoomId:        &rooms[i],
+			Price:         rooms[i].CurrentPrice,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		Insert(t, o, rr)
+		for night := from; night.Before(to); night = night.AddDate(0, 0, 1) {
+			Insert(t, o, &models.RoomNight{RoomReservedId: rr, Date: night, Price: rr.Price})
+		}
+	}
+	return r
+}
+
+// Insert inserts md or fails t.
+func Insert(t *testing.T, o orm.Ormer, md interface{}) {
+	t.Helper()
+	if _, err := o.Insert(md); err != nil {
+		t.Fatalf("inserting %T: %v", md, err)
+	}
+}
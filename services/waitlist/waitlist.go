@@ -0,0 +1,358 @@
+// Package waitlist offers the rooms freed by cancellations to the guests
+// waiting for a sold out hotel, one guest at a time in order of arrival.
+package waitlist
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"easybook/models"
+	"easybook/services/availability"
+	"easybook/services/invoice"
+	"easybook/services/notification"
+	"easybook/services/periodic"
+	"easybook/services/pricing"
+	"easybook/types"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/logs"
+	"github.com/astaxie/beego/orm"
+)
+
+// Errors of entries which cannot be accepted or withdrawn.
+var (
+	ErrNotOffered = errors.New("waitlist: entry has no offer to accept")
+	ErrExpired    = errors.New("waitlist: offer has expired")
+	ErrClosed     = errors.New("waitlist: entry is closed")
+)
+
+// IsError reports whether err is caused by the entry rather than by the
+// database.
+func IsError(err error) bool {
+	return strings.HasPrefix(err.Error(), "waitlist: ")
+}
+
+// OfferTTL returns how long a guest has to accept an offer, the
+// waitlistofferttl key of app.conf in seconds.
+func OfferTTL() time.Duration {
+	return time.Duration(beego.AppConfig.DefaultInt("waitlistofferttl", 14400)) * time.Second
+}
+
+// Validate checks the stay and rooms of v.
+func Validate(v *models.WaitlistEntry) error {
+	switch {
+	case v.HotelId == nil:
+		return errors.New("waitlist: hotel is required")
+	case !v.StartDate.Before(v.EndDate):
+		return errors.New("waitlist: end date must follow start date")
+	case v.Rooms == 0:
+		return errors.New("waitlist: rooms must be at least 1")
+	}
+	return nil
+}
+
+// Match offers rooms of hotel hotelID to its waiting entries within the
+// transaction of o, in order of creation. An entry is offered when enough
+// rooms of its service level are free for its whole stay, entries which
+// cannot be satisfied keep waiting while later ones are offered. It
+// returns the entries offered.
+func Match(o orm.Ormer, hotelID int, now time.Time) ([]*models.WaitlistEntry, error) {
+	entries, err := models.GetWaitingEntries(o, hotelID)
+	if err != nil {
+		return nil, err
+	}
+	var offered []*models.WaitlistEntry
+	for i := range entries {
+		e := &entries[i]
+		roomIDs, err := freeRooms(o, e, now)
+		if err != nil {
+			return nil, err
+		}
+		if len(roomIDs) < int(e.Rooms) {
+			continue
+		}
+		ok, err := offer(o, e, roomIDs[:e.Rooms], now)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			offered = append(offered, e)
+		}
+	}
+	return offered, nil
+}
+
+// Accept confirms the offer of entry e, locked, within the transaction of
+// o: its reservation is confirmed, notified and invoiced.
+func Accept(o orm.Ormer, e *models.WaitlistEntry, now time.Time) (*models.Reservation, error) {
+	switch {
+	case e.Status != models.WaitlistOffered:
+		return nil, ErrNotOffered
+	case !now.Before(e.OfferExpiresAt):
+		return nil, ErrExpired
+	}
+	r := &models.Reservation{Id: e.ReservationId.Id}
+	if err := o.ReadForUpdate(r); err != nil {
+		return nil, err
+	}
+	old := *r
+	r.Status = models.ReservationConfirmed
+	if _, err := o.Update(r, "Status"); err != nil {
+		return nil, err
+	}
+	if err := notification.Changed(o, &old, r, now); err != nil {
+		return nil, err
+	}
+	if _, err := invoice.Generate(o, r, e.HotelId.Id, now); err != nil {
+		return nil, err
+	}
+
+	e.Status = models.WaitlistAccepted
+	if _, err := o.Update(e, "Status"); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Withdraw closes entry e, locked, within the transaction of o. An offer
+// is declined, its rooms go to the next guests.
+func Withdraw(o orm.Ormer, e *models.WaitlistEntry, now time.Time) error {
+	switch e.Status {
+	case models.WaitlistWaiting:
+		e.Status = models.WaitlistWithdrawn
+		_, err := o.Update(e, "Status")
+		return err
+	case models.WaitlistOffered:
+		return release(o, e, models.WaitlistDeclined, now)
+	}
+	return ErrClosed
+}
+
+// Freed hands the rooms of reservation r, canceled within the transaction
+// of o, to the waitlist of hotel hotelID. A canceled offer is declined.
+func Freed(o orm.Ormer, r *models.Reservation, hotelID int, now time.Time) error {
+	e, err := models.GetOfferOf(o, r.Id)
+	if err == nil {
+		e.Status = models.WaitlistDeclined
+		_, err = o.Update(e, "Status")
+	}
+	if err != nil && err != orm.ErrNoRows {
+		return err
+	}
+	if hotelID == 0 {
+		return nil
+	}
+	_, err = Match(o, hotelID, now)
+	return err
+}
+
+// release cancels the offer of entry e, closing it with status, and offers
+// the rooms to the next guests.
+func release(o orm.Ormer, e *models.WaitlistEntry, status uint8, now time.Time) error {
+	r := &models.Reservation{Id: e.ReservationId.Id}
+	if err := o.ReadForUpdate(r); err != nil {
+		return err
+	}
+	if r.Status != models.ReservationPending {
+		// confirmed by staff, or canceled, meanwhile
+		if r.Status == models.ReservationConfirmed {
+			e.Status = models.WaitlistAccepted
+		}
+		_, err := o.Update(e, "Status")
+		return err
+	}
+	r.Status = models.ReservationCancelled
+	if _, err := o.Update(r, "Status"); err != nil {
+		return err
+	}
+	if err := notification.Discarded(o, r, now); err != nil {
+		return err
+	}
+	e.Status = status
+	if _, err := o.Update(e, "Status"); err != nil {
+		return err
+	}
+	_, err := Match(o, e.HotelId.Id, now)
+	return err
+}
+
+// Matcher lapses the offers not accepted in time and matches the waiting
+// entries in background, for the rooms freed other than by cancellation,
+// e.g. by expired holds.
+type Matcher struct {
+	// Interval is the time between two runs.
+	Interval time.Duration
+
+	runner periodic.Runner
+}
+
+// NewMatcher creates a Matcher configured by the waitlist* keys of
+// app.conf.
+func NewMatcher() *Matcher {
+	return &Matcher{
+		Interval: time.Duration(beego.AppConfig.DefaultInt("waitlistinterval", 60)) * time.Second,
+	}
+}
+
+// Start runs the matcher in background until Stop is called.
+func (m *Matcher) Start() {
+	m.runner.Start("waitlist: matching", m.Interval, func(now time.Time) error {
+		_, err := m.RunOnce(now)
+		return err
+	})
+}
+
+// Stop stops the matcher and waits for the current run to finish.
+func (m *Matcher) Stop() {
+	m.runner.Stop()
+}
+
+// RunOnce lapses the offers expired at now, expires the entries whose stay
+// started and matches the waiting entries of every hotel. It returns the
+// number of offers made. Each offer and each hotel is handled in a
+// transaction of its own, one which fails is logged and skipped.
+func (m *Matcher) RunOnce(now time.Time) (int, error) {
+	o := orm.NewOrm()
+	lapsed, err := models.GetLapsedOffers(o, now)
+	if err != nil {
+		return 0, err
+	}
+	for i := range lapsed {
+		e := &lapsed[i]
+		_ = o.Begin()
+		// the offer is locked against being accepted meanwhile
+		if err = o.ReadForUpdate(e); err == nil && (e.Status != models.WaitlistOffered || e.OfferExpiresAt.After(now)) {
+			_ = o.Rollback()
+			continue
+		}
+		if err == nil {
+			err = release(o, e, models.WaitlistLapsed, now)
+		}
+		if err != nil {
+			_ = o.Rollback()
+			logs.Error("waitlist: lapsing entry %d: %v", e.Id, err)
+			continue
+		}
+		if err = o.Commit(); err != nil {
+			logs.Error("waitlist: lapsing entry %d: %v", e.Id, err)
+		}
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if _, err = models.ExpireWaitlistEntries(o, today); err != nil {
+		logs.Error("waitlist: expiring entries: %v", err)
+	}
+
+	hotelIDs, err := models.GetWaitingHotelIds(o, now)
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	for _, hotelID := range hotelIDs {
+		_ = o.Begin()
+		offered, err := Match(o, hotelID, now)
+		if err != nil {
+			_ = o.Rollback()
+			logs.Error("waitlist: matching hotel %d: %v", hotelID, err)
+			continue
+		}
+		if err = o.Commit(); err != nil {
+			logs.Error("waitlist: matching hotel %d: %v", hotelID, err)
+			continue
+		}
+		n += len(offered)
+	}
+	return n, nil
+}
+
+// freeRooms returns the ids of the rooms of the hotel and service level of
+// e free for its stay.
+func freeRooms(o orm.Ormer, e *models.WaitlistEntry, now time.Time) ([]int, error) {
+	qs := o.QueryTable(new(models.Room)).Filter("HotelId", e.HotelId.Id)
+	if e.ServiceLevelId != nil {
+		qs = qs.Filter("ServiceLevelId", e.ServiceLevelId.Id)
+	}
+	var rooms []models.Room
+	if _, err := qs.OrderBy("Id").All(&rooms, "Id"); err != nil {
+		return nil, err
+	}
+	roomIDs := make([]int, len(rooms))
+	for i, room := range rooms {
+		roomIDs[i] = room.Id
+	}
+	taken, err := availability.Unavailable(o, roomIDs, e.StartDate, e.EndDate, now, 0)
+	if err != nil {
+		return nil, err
+	}
+	free := roomIDs[:0]
+	for _, id := range roomIDs {
+		if !taken[id] {
+			free = append(free, id)
+		}
+	}
+	return free, nil
+}
+
+// offer reserves roomIDs for entry e as a pending reservation and notifies
+// the guest. It returns false, the entry waiting, if the rooms cannot be
+// reserved for the stay.
+func offer(o orm.Ormer, e *models.WaitlistEntry, roomIDs []int, now time.Time) (bool, error) {
+	err := availability.Check(o, roomIDs, e.StartDate, e.EndDate, now, 0)
+	if err == availability.ErrUnavailable {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	r := &models.Reservation{
+		GuestId:    e.GuestId,
+		StartDate:  e.StartDate,
+		EndDate:    e.EndDate,
+		Guests:     e.Guests,
+		TotalPrice: types.NewMoney(0, types.DefaultCurrency),
+		Status:     models.ReservationPending,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if _, err = o.Insert(r); err != nil {
+		return false, err
+	}
+	nights := pricing.Nights(r)
+	for _, id := range roomIDs {
+		room := &models.Room{Id: id}
+		if err = o.Read(room); err != nil {
+			return false, err
+		}
+		err = pricing.ReserveRoom(o, &models.RoomReserved{ReservationId: r, RoomId: room, CreatedAt: now, UpdatedAt: now}, nights)
+		if err == pricing.ErrMinStay {
+			// the entry cannot be served, the rooms are not reserved
+			logs.Info("waitlist: entry %d: %v", e.Id, err)
+			_, err = o.QueryTable(new(models.RoomReserved)).Filter("ReservationId", r.Id).Delete()
+			if err == nil {
+				_, err = o.Delete(r)
+			}
+			return false, err
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	b, err := pricing.Quote(o, r, e.HotelId.Id)
+	if err != nil {
+		return false, err
+	}
+	r.TotalPrice = b.Total
+	if _, err = o.Update(r, "TotalPrice"); err != nil {
+		return false, err
+	}
+	if err = notification.Offered(o, r, e.HotelId.Id, now); err != nil {
+		return false, err
+	}
+
+	e.Status = models.WaitlistOffered
+	e.ReservationId = r
+	e.OfferExpiresAt = now.Add(OfferTTL())
+	_, err = o.Update(e, "Status", "ReservationId", "OfferExpiresAt")
+	return err == nil, err
+}
@@ -0,0 +1,193 @@
+package waitlist
+
+import (
+	"testing"
+	"time"
+
+	"easybook/models"
+	"easybook/services/servicetest"
+	"easybook/types"
+
+	"github.com/astaxie/beego/orm"
+)
+
+func TestMain(m *testing.M) {
+	servicetest.Main(m)
+}
+
+func TestValidate(t *testing.T) {
+	from := servicetest.Date(2031, 1, 1)
+	hotel := &models.Hotel{Id: 1}
+	cases := []struct {
+		name string
+		v    models.WaitlistEntry
+		ok   bool
+	}{
+		{"valid", models.WaitlistEntry{HotelId: hotel, StartDate: from, EndDate: from.AddDate(0, 0, 1), Rooms: 1}, true},
+		{"no hotel", models.WaitlistEntry{StartDate: from, EndDate: from.AddDate(0, 0, 1), Rooms: 1}, false},
+		{"empty stay", models.WaitlistEntry{HotelId: hotel, StartDate: from, EndDate: from, Rooms: 1}, false},
+		{"no rooms", models.WaitlistEntry{HotelId: hotel, StartDate: from, EndDate: from.AddDate(0, 0, 1)}, false},
+	}
+	for _, c := range cases {
+		if err := Validate(&c.v); (err == nil) != c.ok || err != nil && !IsError(err) {
+			t.Errorf("%s: Validate error = %v, want ok %v", c.name, err, c.ok)
+		}
+	}
+}
+
+func TestMatchAndAccept(t *testing.T) {
+	o := servicetest.Ormer(t)
+	h, rooms := servicetest.Hotel(t, o, 1, types.NewMoney(1000000, types.DefaultCurrency))
+	from := servicetest.Date(2031, 2, 1)
+	to := from.AddDate(0, 0, 2)
+	now := time.Now().Truncate(time.Second)
+	booked := servicetest.Reservation(t, o, servicetest.Guest(t, o), rooms, from, to, models.ReservationConfirmed)
+	first := entry(t, o, h, from, to, now.Add(-2*time.Minute))
+	second := entry(t, o, h, from, to, now.Add(-time.Minute))
+
+	if offered, err := Match(o, h.Id, now); err != nil || len(offered) != 0 {
+		t.Fatalf("Match of a sold out hotel = %v, %v, want no offer", offered, err)
+	}
+
+	booked.Status = models.ReservationCancelled
+	if _, err := o.Update(booked, "Status"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Freed(o, booked, h.Id, now); err != nil {
+		t.Fatalf("Freed error: %v", err)
+	}
+	e := read(t, o, first)
+	if e.Status != models.WaitlistOffered || e.ReservationId == nil || !e.OfferExpiresAt.Equal(now.Add(OfferTTL())) {
+		t.Fatalf("first entry = %+v, want offered until %v", e, now.Add(OfferTTL()))
+	}
+	if s := read(t, o, second).Status; s != models.WaitlistWaiting {
+		t.Errorf("second entry status = %d, want waiting", s)
+	}
+	r := &models.Reservation{Id: e.ReservationId.Id}
+	if err := o.Read(r); err != nil || r.Status != models.ReservationPending || r.GuestId.Id != first.GuestId.Id {
+		t.Errorf("offered reservation = %+v, %v, want pending for guest %d", r, err, first.GuestId.Id)
+	}
+	if n, err := o.QueryTable(new(models.Notification)).Filter("ReservationId", r.Id).Filter("Event", models.EventOffered).Count(); err != nil || n == 0 {
+		t.Errorf("%d offer notices, %v, want the guest notified", n, err)
+	}
+
+	if _, err := Accept(o, e, e.OfferExpiresAt); err != ErrExpired {
+		t.Errorf("Accept of an expired offer error = %v, want ErrExpired", err)
+	}
+	if _, err := Accept(o, read(t, o, second), now); err != ErrNotOffered {
+		t.Errorf("Accept of a waiting entry error = %v, want ErrNotOffered", err)
+	}
+	if r, err := Accept(o, e, now); err != nil || r.Status != models.ReservationConfirmed {
+		t.Fatalf("Accept = %+v, %v, want the reservation confirmed", r, err)
+	}
+	if s := read(t, o, first).Status; s != models.WaitlistAccepted {
+		t.Errorf("accepted entry status = %d, want accepted", s)
+	}
+	if err := Withdraw(o, read(t, o, first), now); err != ErrClosed {
+		t.Errorf("Withdraw of an accepted entry error = %v, want ErrClosed", err)
+	}
+}
+
+func TestWithdraw(t *testing.T) {
+	o := servicetest.Ormer(t)
+	h, _ := servicetest.Hotel(t, o, 1, types.NewMoney(1000000, types.DefaultCurrency))
+	from := servicetest.Date(2031, 3, 1)
+	to := from.AddDate(0, 0, 1)
+	now := time.Now().Truncate(time.Second)
+	first := entry(t, o, h, from, to, now.Add(-3*time.Minute))
+	second := entry(t, o, h, from, to, now.Add(-2*time.Minute))
+	third := entry(t, o, h, from, to, now.Add(-time.Minute))
+
+	if offered, err := Match(o, h.Id, now); err != nil || len(offered) != 1 || offered[0].Id != first.Id {
+		t.Fatalf("Match = %v, %v, want the first entry offered", offered, err)
+	}
+	if err := Withdraw(o, read(t, o, third), now); err != nil {
+		t.Fatalf("Withdraw of a waiting entry error: %v", err)
+	}
+	if s := read(t, o, third).Status; s != models.WaitlistWithdrawn {
+		t.Errorf("withdrawn entry status = %d, want withdrawn", s)
+	}
+
+	// declining an offer hands its rooms to the next guest
+	e := read(t, o, first)
+	if err := Withdraw(o, e, now); err != nil {
+		t.Fatalf("Withdraw of an offer error: %v", err)
+	}
+	if s := read(t, o, first).Status; s != models.WaitlistDeclined {
+		t.Errorf("declined entry status = %d, want declined", s)
+	}
+	r := &models.Reservation{Id: e.ReservationId.Id}
+	if err := o.Read(r); err != nil || r.Status != models.ReservationCancelled {
+		t.Errorf("declined reservation = %+v, %v, want canceled", r, err)
+	}
+	if s := read(t, o, second).Status; s != models.WaitlistOffered {
+		t.Errorf("next entry status = %d, want offered", s)
+	}
+}
+
+func TestMatcherRunOnce(t *testing.T) {
+	o := servicetest.Ormer(t)
+	h, _ := servicetest.Hotel(t, o, 1, types.NewMoney(1000000, types.DefaultCurrency))
+	from := servicetest.Date(2031, 4, 1)
+	to := from.AddDate(0, 0, 1)
+	now := time.Now().Truncate(time.Second)
+	first := entry(t, o, h, from, to, now.Add(-2*time.Minute))
+	second := entry(t, o, h, from, to, now.Add(-time.Minute))
+	started := entry(t, o, h, servicetest.Date(2020, 1, 1), servicetest.Date(2020, 1, 2), now.Add(-3*time.Minute))
+
+	m := NewMatcher()
+	if _, err := m.RunOnce(now); err != nil {
+		t.Fatalf("RunOnce error: %v", err)
+	}
+	if s := read(t, o, started).Status; s != models.WaitlistExpired {
+		t.Errorf("entry of a past stay status = %d, want expired", s)
+	}
+	e := read(t, o, first)
+	if e.Status != models.WaitlistOffered {
+		t.Fatalf("first entry status = %d, want offered", e.Status)
+	}
+
+	// the offer lapses and the rooms go to the next guest
+	if _, err := m.RunOnce(now.Add(OfferTTL())); err != nil {
+		t.Fatalf("RunOnce error: %v", err)
+	}
+	if s := read(t, o, first).Status; s != models.WaitlistLapsed {
+		t.Errorf("first entry status = %d, want lapsed", s)
+	}
+	r := &models.Reservation{Id: e.ReservationId.Id}
+	if err := o.Read(r); err != nil || r.Status != models.ReservationCancelled {
+		t.Errorf("lapsed reservation = %+v, %v, want canceled", r, err)
+	}
+	if s := read(t, o, second).Status; s != models.WaitlistOffered {
+		t.Errorf("second entry status = %d, want offered", s)
+	}
+}
+
+// entry inserts an entry of a new guest waiting for a room of hotel h,
+// created at created.
+func entry(t *testing.T, o orm.Ormer, h *models.Hotel, from time.Time, to time.Time, created time.Time) *models.WaitlistEntry {
+	t.Helper()
+	v := &models.WaitlistEntry{
+		GuestId:   servicetest.Guest(t, o),
+		HotelId:   h,
+		StartDate: from,
+		EndDate:   to,
+		Rooms:     1,
+		Guests:    1,
+		Status:    models.WaitlistWaiting,
+		CreatedAt: created,
+		UpdatedAt: created,
+	}
+	servicetest.Insert(t, o, v)
+	return v
+}
+
+// read reads entry e again.
+func read(t *testing.T, o orm.Ormer, e *models.WaitlistEntry) *models.WaitlistEntry {
+	t.Helper()
+	v, err := models.GetWaitlistEntryById(o, e.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
@@ -0,0 +1,392 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/astaxie/beego/orm"
+)
+
+// DefaultCurrency is the currency of amounts given without one, e.g. a bare
+// JSON number, and of the amounts stored in the database.
+var DefaultCurrency = "VND"
+
+// ErrCurrencyMismatch is returned by arithmetic between amounts in
+// different currencies and when storing an amount in a currency other than
+// DefaultCurrency.
+var ErrCurrencyMismatch = errors.New("types: currency mismatch")
+
+// currencies maps ISO 4217 codes to the number of digits of their minor
+// unit.
+var currencies = map[string]int{
+	"AUD": 2, "BHD": 3, "CAD": 2, "CHF": 2, "CNY": 2, "EUR": 2, "GBP": 2,
+	"HKD": 2, "IDR": 2, "JPY": 0, "KRW": 0, "KWD": 3, "MYR": 2, "PHP": 2,
+	"SGD": 2, "THB": 2, "USD": 2, "VND": 0,
+}
+
+// RegisterCurrency makes the ISO 4217 code known with the given number of
+// digits of its minor unit.
+func RegisterCurrency(code string, digits int) {
+	currencies[code] = digits
+}
+
+// Digits returns the number of digits of the minor unit of currency, and
+// false if the currency is unknown.
+func Digits(currency string) (int, bool) {
+	d, ok := currencies[currency]
+	return d, ok
+}
+
+// Money represents an amount of money as an integer number of minor units,
+// e.g. cents, in an ISO 4217 currency. The zero Money is zero in any
+// currency and formats in DefaultCurrency.
+//
+// Money can be marshal to text and unmarshal from text in format
+// "12.34 USD" in path param, query param and plain text.
+// A text without currency is in DefaultCurrency.
+//
+// Money implements json.Marshaler and json.Unmarshaler to encode to JSON
+// object {"amount": 12.34, "currency": "USD"}. It decodes from the object,
+// from a string in text format and from a number in DefaultCurrency.
+//
+// Money implements driver.Valuer, sql.Scanner and orm.Fielder to store
+// the number of minor units in a BIGINT column, so that amounts filter and
+// sort numerically. Stored amounts are in DefaultCurrency, saving another
+// currency fails, see Stored.
+//
+// Arithmetic fails with ErrCurrencyMismatch on amounts in different
+// currencies, convert them first. Results that do not fall on a minor
+// unit are rounded half away from zero.
+//
+// Programs using money should typically store and pass them as values,
+// not pointers. That is, money variables and struct fields should be of
+// type types.Money, not *types.Money.
+type Money struct {
+	// Amount is the number of minor units.
+	Amount   int64
+	Currency string
+}
+
+var _ json.Marshaler = Money{}
+var _ json.Unmarshaler = &Money{}
+var _ orm.Fielder = &Money{}
+
+// NewMoney creates a Money of amount minor units in currency.
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// ParseMoney parses a string in format "12.34 USD", or "12.34" in
+// DefaultCurrency, and returns a new Money instance. The amount may not
+// have more decimals than the currency.
+func ParseMoney(str string) (Money, error) {
+	fields := strings.Fields(str)
+	switch len(fields) {
+	case 1:
+		return ParseAmount(fields[0], DefaultCurrency)
+	case 2:
+		return ParseAmount(fields[0], fields[1])
+	}
+	return Money{}, fmt.Errorf("types: invalid money %q", str)
+}
+
+// ParseAmount parses a decimal amount such as "12.34" in currency and
+// returns a new Money instance.
+func ParseAmount(str string, currency string) (Money, error) {
+	digits, ok := currencies[currency]
+	if !ok {
+		return Money{}, fmt.Errorf("types: unknown currency %q", currency)
+	}
+
+	s := str
+	neg := strings.HasPrefix(s, "-")
+	if neg || strings.HasPrefix(s, "+") {
+		s = s[1:]
+	}
+	whole, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, frac = s[:i], s[i+1:]
+	}
+	// trailing zeros do not change the amount, e.g. 1500000.00 VND
+	frac = strings.TrimRight(frac, "0")
+	if whole == "" && frac == "" || len(frac) > digits || strings.IndexFunc(whole+frac, notDigit) >= 0 {
+		return Money{}, fmt.Errorf("types: invalid amount %q in %s", str, currency)
+	}
+
+	frac += strings.Repeat("0", digits-len(frac))
+	amount, err := strconv.ParseInt("0"+whole+frac, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("types: invalid amount %q in %s", str, currency)
+	}
+	if neg {
+		amount = -amount
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// MoneyFloat creates a Money from an amount in major units, rounded to the
+// minor unit of currency. It is meant for configuration values and rates,
+// amounts should be parsed with ParseAmount.
+func MoneyFloat(amount float64, currency string) Money {
+	return Money{Amount: roundHalfAway(amount * math.Pow10(currencies[currency])), Currency: currency}
+}
+
+func notDigit(r rune) bool {
+	return r < '0' || r > '9'
+}
+
+func roundHalfAway(f float64) int64 {
+	return int64(math.Round(f))
+}
+
+// currency returns the currency of m, DefaultCurrency if it has none.
+func (m Money) currency() string {
+	if m.Currency == "" {
+		return DefaultCurrency
+	}
+	return m.Currency
+}
+
+// common returns the currency of the result of arithmetic on m and u, or
+// ErrCurrencyMismatch.
+func (m Money) common(u Money) (string, error) {
+	switch {
+	case m.Currency == u.Currency:
+		return m.Currency, nil
+	case m.Currency == "" && m.Amount == 0:
+		return u.Currency, nil
+	case u.Currency == "" && u.Amount == 0:
+		return m.Currency, nil
+	}
+	return "", ErrCurrencyMismatch
+}
+
+// Decimal returns the amount of m in major units, e.g. "12.34".
+func (m Money) Decimal() string {
+	digits := currencies[m.currency()]
+	if digits == 0 {
+		return strconv.FormatInt(m.Amount, 10)
+	}
+
+	a := m.Amount
+	sign := ""
+	if a < 0 {
+		sign, a = "-", -a
+	}
+	s := strconv.FormatInt(a, 10)
+	if len(s) <= digits {
+		s = strings.Repeat("0", digits-len(s)+1) + s
+	}
+	return sign + s[:len(s)-digits] + "." + s[len(s)-digits:]
+}
+
+// String returns a string representing m in format "12.34 USD".
+func (m Money) String() string {
+	return m.Decimal() + " " + m.currency()
+}
+
+// Float returns the amount of m in major units, for display and rates.
+func (m Money) Float() float64 {
+	return float64(m.Amount) / math.Pow10(currencies[m.currency()])
+}
+
+// MarshalJSON encodes Money to JSON object with the amount in major units.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(`{"amount":` + m.Decimal() + `,"currency":"` + m.currency() + `"}`), nil
+}
+
+// UnmarshalJSON decodes a JSON object {"amount": 12.34, "currency": "USD"},
+// a JSON string in format "12.34 USD" or a JSON number to a Money.
+func (m *Money) UnmarshalJSON(b []byte) error {
+	s := strings.TrimSpace(string(b))
+	switch {
+	case s == "null":
+		return nil
+	case strings.HasPrefix(s, `"`):
+		var str string
+		if err := json.Unmarshal(b, &str); err != nil {
+			return err
+		}
+		return m.UnmarshalText([]byte(str))
+	case strings.HasPrefix(s, "{"):
+		var obj struct {
+			Amount   json.Number `json:"amount"`
+			Currency string      `json:"currency"`
+		}
+		if err := json.Unmarshal(b, &obj); err != nil {
+			return err
+		}
+		if obj.Currency == "" {
+			obj.Currency = DefaultCurrency
+		}
+		v, err := ParseAmount(obj.Amount.String(), obj.Currency)
+		if err != nil {
+			return err
+		}
+		*m = v
+		return nil
+	}
+
+	v, err := ParseAmount(s, DefaultCurrency)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+// The money is formatted in format "12.34 USD".
+func (m Money) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+// The money is expected to be in format "12.34 USD" or "12.34".
+func (m *Money) UnmarshalText(data []byte) error {
+	v, err := ParseMoney(string(data))
+	if err != nil {
+		return err
+	}
+
+	*m = v
+	return nil
+}
+
+// Stored returns ErrCurrencyMismatch unless every amount is zero or in
+// DefaultCurrency, the currency in which amounts are stored.
+func Stored(amounts ...Money) error {
+	for _, m := range amounts {
+		if m.currency() != DefaultCurrency && !m.IsZero() {
+			return ErrCurrencyMismatch
+		}
+	}
+	return nil
+}
+
+// Value returns a driver Value, the number of minor units.
+func (m Money) Value() (driver.Value, error) {
+	if err := Stored(m); err != nil {
+		return nil, err
+	}
+	return m.Amount, nil
+}
+
+// Scan assigns a number of minor units in DefaultCurrency from a database
+// driver. NULL is the zero Money.
+func (m *Money) Scan(value interface{}) error {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		*m = Money{}
+		return nil
+	case int64:
+		*m = Money{Amount: v, Currency: DefaultCurrency}
+		return nil
+	case float64:
+		*m = Money{Amount: roundHalfAway(v), Currency: DefaultCurrency}
+		return nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return fmt.Errorf("types: cannot scan %T into Money", value)
+	}
+	amount, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("types: invalid stored amount %q", s)
+	}
+	*m = Money{Amount: amount, Currency: DefaultCurrency}
+	return nil
+}
+
+// FieldType implements orm.Fielder, Money is stored as BIGINT.
+func (m *Money) FieldType() int {
+	return orm.TypeBigIntegerField
+}
+
+// SetRaw implements orm.Fielder.
+func (m *Money) SetRaw(value interface{}) error {
+	return m.Scan(value)
+}
+
+// RawValue implements orm.Fielder, it returns m itself so that the driver
+// stores it through Value, which fails with ErrCurrencyMismatch on amounts
+// in another currency than DefaultCurrency.
+func (m *Money) RawValue() interface{} {
+	return *m
+}
+
+// IsZero reports whether m is zero in its currency.
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// Sign returns -1, 0 or 1 as m is negative, zero or positive.
+func (m Money) Sign() int {
+	switch {
+	case m.Amount < 0:
+		return -1
+	case m.Amount > 0:
+		return 1
+	}
+	return 0
+}
+
+// Cmp compares m and u and returns -1, 0 or 1 as m is less than, equal to
+// or greater than u.
+func (m Money) Cmp(u Money) (int, error) {
+	d, err := m.Sub(u)
+	return d.Sign(), err
+}
+
+// Add returns m+u.
+func (m Money) Add(u Money) (Money, error) {
+	currency, err := m.common(u)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: m.Amount + u.Amount, Currency: currency}, nil
+}
+
+// Sub returns m-u.
+func (m Money) Sub(u Money) (Money, error) {
+	currency, err := m.common(u)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: m.Amount - u.Amount, Currency: currency}, nil
+}
+
+// Neg returns -m.
+func (m Money) Neg() Money {
+	return Money{Amount: -m.Amount, Currency: m.Currency}
+}
+
+// Mul returns m times n, e.g. the price of n nights.
+func (m Money) Mul(n int64) Money {
+	return Money{Amount: m.Amount * n, Currency: m.Currency}
+}
+
+// Scale returns m times f rounded to the minor unit.
+func (m Money) Scale(f float64) Money {
+	return Money{Amount: roundHalfAway(float64(m.Amount) * f), Currency: m.Currency}
+}
+
+// Percent returns p percent of m rounded to the minor unit.
+func (m Money) Percent(p float64) Money {
+	return m.Scale(p / 100)
+}
+
+// Convert returns m in currency at rate, the value of one unit of the
+// currency of m in currency, rounded to the minor unit of currency.
+func (m Money) Convert(currency string, rate float64) Money {
+	shift := currencies[currency] - currencies[m.currency()]
+	return Money{Amount: roundHalfAway(float64(m.Amount) * rate * math.Pow10(shift)), Currency: currency}
+}
@@ -0,0 +1,248 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	cases := []struct {
+		str  string
+		want Money
+	}{
+		{"12.34 USD", Money{Amount: 1234, Currency: "USD"}},
+		{"12 USD", Money{Amount: 1200, Currency: "USD"}},
+		{"12.3 USD", Money{Amount: 1230, Currency: "USD"}},
+		{".5 USD", Money{Amount: 50, Currency: "USD"}},
+		{"-0.01 USD", Money{Amount: -1, Currency: "USD"}},
+		{"+7 EUR", Money{Amount: 700, Currency: "EUR"}},
+		{"1.234 KWD", Money{Amount: 1234, Currency: "KWD"}},
+		{"1500000", Money{Amount: 1500000, Currency: "VND"}},
+		{"1500000.00 VND", Money{Amount: 1500000, Currency: "VND"}},
+		{"  250000   VND ", Money{Amount: 250000, Currency: "VND"}},
+	}
+	for _, c := range cases {
+		got, err := ParseMoney(c.str)
+		if err != nil {
+			t.Errorf("ParseMoney(%q) error: %v", c.str, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("ParseMoney(%q) = %#v, want %#v", c.str, got, c.want)
+		}
+	}
+}
+
+func TestParseMoneyErrors(t *testing.T) {
+	cases := []string{
+		"",
+		"12 USD extra",
+		"12 XXX",
+		"12.345 USD",
+		"1.5 VND",
+		"-",
+		".",
+		"12.3.4 USD",
+		"1e3 USD",
+		"12,34 EUR",
+		"99999999999999999999 VND",
+	}
+	for _, str := range cases {
+		if _, err := ParseMoney(str); err == nil {
+			t.Errorf("ParseMoney(%q) error = nil, want an error", str)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	cases := []struct {
+		m    Money
+		want string
+	}{
+		{Money{}, "0 VND"},
+		{Money{Amount: 1500000, Currency: "VND"}, "1500000 VND"},
+		{Money{Amount: 1234, Currency: "USD"}, "12.34 USD"},
+		{Money{Amount: 5, Currency: "USD"}, "0.05 USD"},
+		{Money{Amount: -5, Currency: "USD"}, "-0.05 USD"},
+		{Money{Amount: -1234, Currency: "USD"}, "-12.34 USD"},
+		{Money{Amount: 1, Currency: "KWD"}, "0.001 KWD"},
+	}
+	for _, c := range cases {
+		if got := c.m.String(); got != c.want {
+			t.Errorf("%#v.String() = %q, want %q", c.m, got, c.want)
+		}
+		if got, err := ParseMoney(c.want); err != nil || got.Amount != c.m.Amount {
+			t.Errorf("ParseMoney(%q) = %#v, %v, want amount %d", c.want, got, err, c.m.Amount)
+		}
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	usd := func(a int64) Money { return Money{Amount: a, Currency: "USD"} }
+	cases := []struct {
+		m, u     Money
+		sum      Money
+		diff     Money
+		cmp      int
+		mismatch bool
+	}{
+		{m: usd(150), u: usd(50), sum: usd(200), diff: usd(100), cmp: 1},
+		{m: usd(50), u: usd(150), sum: usd(200), diff: usd(-100), cmp: -1},
+		{m: usd(50), u: usd(50), sum: usd(100), diff: usd(0), cmp: 0},
+		{m: Money{}, u: usd(50), sum: usd(50), diff: usd(-50), cmp: -1},
+		{m: usd(50), u: Money{}, sum: usd(50), diff: usd(50), cmp: 1},
+		{m: usd(50), u: Money{Amount: 50, Currency: "EUR"}, mismatch: true},
+		{m: Money{Amount: 50}, u: usd(50), mismatch: true},
+	}
+	for _, c := range cases {
+		sum, err := c.m.Add(c.u)
+		if c.mismatch {
+			if err != ErrCurrencyMismatch {
+				t.Errorf("%v.Add(%v) error = %v, want ErrCurrencyMismatch", c.m, c.u, err)
+			}
+			if _, err = c.m.Sub(c.u); err != ErrCurrencyMismatch {
+				t.Errorf("%v.Sub(%v) error = %v, want ErrCurrencyMismatch", c.m, c.u, err)
+			}
+			if _, err = c.m.Cmp(c.u); err != ErrCurrencyMismatch {
+				t.Errorf("%v.Cmp(%v) error = %v, want ErrCurrencyMismatch", c.m, c.u, err)
+			}
+			continue
+		}
+		if err != nil || sum != c.sum {
+			t.Errorf("%v.Add(%v) = %v, %v, want %v", c.m, c.u, sum, err, c.sum)
+		}
+		if diff, err := c.m.Sub(c.u); err != nil || diff != c.diff {
+			t.Errorf("%v.Sub(%v) = %v, %v, want %v", c.m, c.u, diff, err, c.diff)
+		}
+		if cmp, err := c.m.Cmp(c.u); err != nil || cmp != c.cmp {
+			t.Errorf("%v.Cmp(%v) = %d, %v, want %d", c.m, c.u, cmp, err, c.cmp)
+		}
+	}
+}
+
+func TestMoneyRounding(t *testing.T) {
+	cases := []struct {
+		name string
+		got  Money
+		want Money
+	}{
+		{"Percent", Money{Amount: 1005, Currency: "USD"}.Percent(10), Money{Amount: 101, Currency: "USD"}},
+		{"Percent negative", Money{Amount: -1005, Currency: "USD"}.Percent(10), Money{Amount: -101, Currency: "USD"}},
+		{"Percent VND", Money{Amount: 1500000, Currency: "VND"}.Percent(15), Money{Amount: 225000, Currency: "VND"}},
+		{"Scale", Money{Amount: 3, Currency: "USD"}.Scale(0.5), Money{Amount: 2, Currency: "USD"}},
+		{"Mul", Money{Amount: 1234, Currency: "USD"}.Mul(3), Money{Amount: 3702, Currency: "USD"}},
+		{"Neg", Money{Amount: 1234, Currency: "USD"}.Neg(), Money{Amount: -1234, Currency: "USD"}},
+		{"Convert to VND", Money{Amount: 1234, Currency: "USD"}.Convert("VND", 25000), Money{Amount: 308500, Currency: "VND"}},
+		{"Convert to USD", Money{Amount: 308500, Currency: "VND"}.Convert("USD", 0.00004), Money{Amount: 1234, Currency: "USD"}},
+		{"Convert to KWD", Money{Amount: 100, Currency: "USD"}.Convert("KWD", 0.3075), Money{Amount: 308, Currency: "KWD"}},
+		{"MoneyFloat", MoneyFloat(12.345, "USD"), Money{Amount: 1235, Currency: "USD"}},
+	}
+	for _, c := range cases {
+		if c.got != c.want {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	cases := []struct {
+		data string
+		want Money
+	}{
+		{`{"amount":12.34,"currency":"USD"}`, Money{Amount: 1234, Currency: "USD"}},
+		{`{"amount":1500000}`, Money{Amount: 1500000, Currency: "VND"}},
+		{`"12.34 USD"`, Money{Amount: 1234, Currency: "USD"}},
+		{`"250000"`, Money{Amount: 250000, Currency: "VND"}},
+		{`250000`, Money{Amount: 250000, Currency: "VND"}},
+		{`null`, Money{}},
+	}
+	for _, c := range cases {
+		var got Money
+		if err := json.Unmarshal([]byte(c.data), &got); err != nil {
+			t.Errorf("Unmarshal(%s) error: %v", c.data, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("Unmarshal(%s) = %#v, want %#v", c.data, got, c.want)
+		}
+		if c.want.IsZero() {
+			continue
+		}
+
+		b, err := json.Marshal(got)
+		if err != nil {
+			t.Errorf("Marshal(%#v) error: %v", got, err)
+			continue
+		}
+		var back Money
+		if err = json.Unmarshal(b, &back); err != nil || back != got {
+			t.Errorf("Unmarshal(%s) = %#v, %v, want %#v", b, back, err, got)
+		}
+	}
+
+	for _, data := range []string{`{"amount":12.345,"currency":"USD"}`, `{"amount":1,"currency":"XXX"}`, `"12 USD extra"`, `true`} {
+		var m Money
+		if err := json.Unmarshal([]byte(data), &m); err == nil {
+			t.Errorf("Unmarshal(%s) error = nil, want an error", data)
+		}
+	}
+}
+
+func TestMoneyStorage(t *testing.T) {
+	scans := []struct {
+		value interface{}
+		want  Money
+	}{
+		{nil, Money{}},
+		{int64(1500000), Money{Amount: 1500000, Currency: "VND"}},
+		{float64(1500000), Money{Amount: 1500000, Currency: "VND"}},
+		{[]byte("1500000"), Money{Amount: 1500000, Currency: "VND"}},
+		{"-250000", Money{Amount: -250000, Currency: "VND"}},
+	}
+	for _, c := range scans {
+		var got Money
+		if err := got.Scan(c.value); err != nil {
+			t.Errorf("Scan(%#v) error: %v", c.value, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("Scan(%#v) = %#v, want %#v", c.value, got, c.want)
+		}
+		if v, err := got.Value(); err != nil || v != c.want.Amount {
+			t.Errorf("%#v.Value() = %#v, %v, want %d", got, v, err, c.want.Amount)
+		}
+	}
+	for _, value := range []interface{}{"12.34", []byte("1,5"), true} {
+		var m Money
+		if err := m.Scan(value); err == nil {
+			t.Errorf("Scan(%#v) error = nil, want an error", value)
+		}
+	}
+
+	stored := []struct {
+		amounts []Money
+		ok      bool
+	}{
+		{nil, true},
+		{[]Money{{}, {Amount: 5, Currency: "VND"}}, true},
+		{[]Money{{Amount: 5}}, true},
+		{[]Money{{Amount: 0, Currency: "USD"}}, true},
+		{[]Money{{Amount: 5, Currency: "VND"}, {Amount: 5, Currency: "USD"}}, false},
+	}
+	for _, c := range stored {
+		if err := Stored(c.amounts...); (err == nil) != c.ok {
+			t.Errorf("Stored(%v) error = %v, want ok %v", c.amounts, err, c.ok)
+		}
+	}
+	foreign := Money{Amount: 5, Currency: "USD"}
+	if _, err := foreign.Value(); err != ErrCurrencyMismatch {
+		t.Errorf("Value() of USD error = %v, want ErrCurrencyMismatch", err)
+	}
+	if _, err := driver.DefaultParameterConverter.ConvertValue(foreign.RawValue()); err != ErrCurrencyMismatch {
+		t.Errorf("converting RawValue() of USD error = %v, want ErrCurrencyMismatch", err)
+	}
+	if v, err := (Money{Amount: 5, Currency: "VND"}).Value(); err != nil || v != int64(5) {
+		t.Errorf("Value() of VND = %#v, %v, want 5", v, err)
+	}
+}
@@ -175,6 +175,15 @@ func (d Date) Equal(u Date) bool {
 	return dY == uT && dM == uM && dD == uD
 }
 
+// SameDay reports whether a and b fall on the same calendar day, each in
+// its own location.
+func SameDay(a time.Time, b time.Time) bool {
+	aY, aM, aD := a.Date()
+	bY, bM, bD := b.Date()
+
+	return aY == bY && aM == bM && aD == bD
+}
+
 // Before reports whether the date instant d is before u.
 func (d Date) Before(u Date) bool {
 	dY, dM, dD := d.Date()